@@ -67,6 +67,7 @@ var (
 	osdUUID             string
 	osdIsDevice         bool
 	pvcBackedOSD        bool
+	discoveryCommand    string
 )
 
 func addOSDFlags(command *cobra.Command) {
@@ -81,6 +82,7 @@ func addOSDFlags(command *cobra.Command) {
 	provisionCmd.Flags().BoolVar(&cfg.forceFormat, "force-format", false,
 		"true to force the format of any specified devices, even if they already have a filesystem.  BE CAREFUL!")
 	provisionCmd.Flags().BoolVar(&cfg.pvcBacked, "pvc-backed-osd", false, "true to specify a block mode pvc is backing the OSD")
+	provisionCmd.Flags().StringVar(&discoveryCommand, "device-discovery-command", "", "command that prints the candidate devices for osds, one per line, instead of the built-in discovery")
 
 	// flags for generating the osd config
 	osdConfigCmd.Flags().IntVar(&osdID, "osd-id", -1, "osd id for which to generate config")
@@ -248,6 +250,7 @@ func prepareOSD(cmd *cobra.Command, args []string) error {
 	kv := k8sutil.NewConfigMapKVStore(clusterInfo.Name, context.Clientset, ownerRef)
 	agent := osddaemon.NewAgent(context, dataDevices, cfg.metadataDevice, cfg.directories, forceFormat,
 		crushLocation, cfg.storeConfig, &clusterInfo, cfg.nodeName, kv, cfg.pvcBacked)
+	agent.SetDeviceDiscoverer(osddaemon.NewDeviceDiscoverer(discoveryCommand))
 
 	err = osddaemon.Provision(context, agent)
 	if err != nil {
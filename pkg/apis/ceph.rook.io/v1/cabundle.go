@@ -0,0 +1,71 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"path"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	caBundleVolumeName = "rook-ceph-ca-bundle"
+	caBundleMountPath  = "/etc/rook/ca-bundle"
+	caBundleFileName   = "ca-bundle.crt"
+	// DefaultCABundleKey is the key of the CA certificates in the configmap or secret if the spec has no key
+	DefaultCABundleKey = "ca-bundle.crt"
+)
+
+// IsSet returns whether a configmap or a secret with CA certificates is referenced
+func (ca *CABundleSpec) IsSet() bool {
+	return ca.ConfigMapName != "" || ca.SecretName != ""
+}
+
+// ApplyToPodSpec mounts the CA certificates in the containers of the pod and points the TLS clients of openssl and
+// python to them. The bundle replaces the CA certificates of the image, so it must contain all the trusted CAs.
+func (ca *CABundleSpec) ApplyToPodSpec(spec *v1.PodSpec) {
+	if !ca.IsSet() {
+		return
+	}
+	key := ca.Key
+	if key == "" {
+		key = DefaultCABundleKey
+	}
+	items := []v1.KeyToPath{{Key: key, Path: caBundleFileName}}
+	volume := v1.Volume{Name: caBundleVolumeName}
+	if ca.SecretName != "" {
+		volume.Secret = &v1.SecretVolumeSource{SecretName: ca.SecretName, Items: items}
+	} else {
+		volume.ConfigMap = &v1.ConfigMapVolumeSource{LocalObjectReference: v1.LocalObjectReference{Name: ca.ConfigMapName}, Items: items}
+	}
+	spec.Volumes = append(spec.Volumes, volume)
+
+	bundle := path.Join(caBundleMountPath, caBundleFileName)
+	mount := v1.VolumeMount{Name: caBundleVolumeName, MountPath: caBundleMountPath, ReadOnly: true}
+	env := []v1.EnvVar{
+		{Name: "SSL_CERT_FILE", Value: bundle},
+		{Name: "REQUESTS_CA_BUNDLE", Value: bundle},
+	}
+	for i := range spec.InitContainers {
+		spec.InitContainers[i].VolumeMounts = append(spec.InitContainers[i].VolumeMounts, mount)
+		spec.InitContainers[i].Env = append(spec.InitContainers[i].Env, env...)
+	}
+	for i := range spec.Containers {
+		spec.Containers[i].VolumeMounts = append(spec.Containers[i].VolumeMounts, mount)
+		spec.Containers[i].Env = append(spec.Containers[i].Env, env...)
+	}
+}
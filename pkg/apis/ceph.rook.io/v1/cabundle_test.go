@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestCABundle_ApplyToPodSpec(t *testing.T) {
+	// nothing is mounted without a bundle
+	ca := CABundleSpec{}
+	spec := v1.PodSpec{Containers: []v1.Container{{Name: "mgr"}}}
+	ca.ApplyToPodSpec(&spec)
+	assert.Equal(t, 0, len(spec.Volumes))
+	assert.Equal(t, 0, len(spec.Containers[0].Env))
+
+	// the bundle of a configmap is mounted in all the containers
+	ca = CABundleSpec{ConfigMapName: "corporate-ca"}
+	spec = v1.PodSpec{InitContainers: []v1.Container{{Name: "init"}}, Containers: []v1.Container{{Name: "mgr"}}}
+	ca.ApplyToPodSpec(&spec)
+	assert.Equal(t, 1, len(spec.Volumes))
+	assert.Equal(t, "corporate-ca", spec.Volumes[0].ConfigMap.Name)
+	assert.Equal(t, []v1.KeyToPath{{Key: DefaultCABundleKey, Path: "ca-bundle.crt"}}, spec.Volumes[0].ConfigMap.Items)
+	for _, c := range append(spec.InitContainers, spec.Containers...) {
+		assert.Equal(t, "/etc/rook/ca-bundle", c.VolumeMounts[0].MountPath)
+		assert.Equal(t, v1.EnvVar{Name: "SSL_CERT_FILE", Value: "/etc/rook/ca-bundle/ca-bundle.crt"}, c.Env[0])
+	}
+
+	// the bundle of a secret with another key
+	ca = CABundleSpec{SecretName: "corporate-ca", Key: "ca.pem"}
+	spec = v1.PodSpec{Containers: []v1.Container{{Name: "mgr"}}}
+	ca.ApplyToPodSpec(&spec)
+	assert.Nil(t, spec.Volumes[0].ConfigMap)
+	assert.Equal(t, "corporate-ca", spec.Volumes[0].Secret.SecretName)
+	assert.Equal(t, "ca.pem", spec.Volumes[0].Secret.Items[0].Key)
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// ValidateImage checks that the image of the daemons is set. Without an image the version job cannot detect the ceph
+// version and the daemons cannot be started.
+func (s *CephVersionSpec) ValidateImage() error {
+	if strings.TrimSpace(s.Image) == "" {
+		return fmt.Errorf("spec.cephVersion.image is required")
+	}
+	return nil
+}
+
+// ValidateImagePullPolicy checks that the image pull policy is empty or one of the policies of kubernetes
+func (s *CephVersionSpec) ValidateImagePullPolicy() error {
+	switch s.ImagePullPolicy {
+	case "", v1.PullAlways, v1.PullIfNotPresent, v1.PullNever:
+		return nil
+	}
+	return fmt.Errorf("invalid image pull policy %q, it must be %q, %q or %q", s.ImagePullPolicy, v1.PullAlways, v1.PullIfNotPresent, v1.PullNever)
+}
+
+// ApplyImagePullPolicyToPodSpec sets the image pull policy on the containers of the pod. Without a policy in the
+// spec the containers keep the default of kubernetes, which depends on the tag of the image.
+func (s *CephVersionSpec) ApplyImagePullPolicyToPodSpec(spec *v1.PodSpec) {
+	if s.ImagePullPolicy == "" {
+		return
+	}
+	for i := range spec.InitContainers {
+		spec.InitContainers[i].ImagePullPolicy = s.ImagePullPolicy
+	}
+	for i := range spec.Containers {
+		spec.Containers[i].ImagePullPolicy = s.ImagePullPolicy
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+)
+
+func TestImagePullPolicy(t *testing.T) {
+	spec := &CephVersionSpec{}
+	assert.NoError(t, spec.ValidateImagePullPolicy())
+	podSpec := &v1.PodSpec{
+		InitContainers: []v1.Container{{Name: "init"}},
+		Containers:     []v1.Container{{Name: "daemon"}},
+	}
+
+	// the default of kubernetes is kept without a policy
+	spec.ApplyImagePullPolicyToPodSpec(podSpec)
+	assert.Equal(t, v1.PullPolicy(""), podSpec.InitContainers[0].ImagePullPolicy)
+	assert.Equal(t, v1.PullPolicy(""), podSpec.Containers[0].ImagePullPolicy)
+
+	spec.ImagePullPolicy = v1.PullIfNotPresent
+	assert.NoError(t, spec.ValidateImagePullPolicy())
+	spec.ApplyImagePullPolicyToPodSpec(podSpec)
+	assert.Equal(t, v1.PullIfNotPresent, podSpec.InitContainers[0].ImagePullPolicy)
+	assert.Equal(t, v1.PullIfNotPresent, podSpec.Containers[0].ImagePullPolicy)
+
+	spec.ImagePullPolicy = "Sometimes"
+	assert.Error(t, spec.ValidateImagePullPolicy())
+}
+
+func TestValidateImage(t *testing.T) {
+	spec := &CephVersionSpec{}
+	assert.EqualError(t, spec.ValidateImage(), "spec.cephVersion.image is required")
+	spec.Image = " "
+	assert.Error(t, spec.ValidateImage())
+	spec.Image = "ceph/ceph:v14.2.4"
+	assert.NoError(t, spec.ValidateImage())
+}
@@ -16,9 +16,27 @@ limitations under the License.
 
 package v1
 
+import (
+	v1 "k8s.io/api/core/v1"
+)
+
 // IsHost get whether to use host network provider. This method also preserve
 // compatibility with the old HostNetwork field.
 func (net *NetworkSpec) IsHost() bool {
 	rookNet := net.NetworkSpec
 	return (net.HostNetwork && net.Provider == "") || rookNet.IsHost()
 }
+
+// ApplyDNSToPodSpec sets the DNS policy and config of the daemon pods. Pods on the host network need the
+// ClusterFirstWithHostNet policy to resolve the cluster services, unless the policy is overridden.
+func (net *NetworkSpec) ApplyDNSToPodSpec(spec *v1.PodSpec) {
+	if net.IsHost() {
+		spec.DNSPolicy = v1.DNSClusterFirstWithHostNet
+	}
+	if net.DNSPolicy != "" {
+		spec.DNSPolicy = net.DNSPolicy
+	}
+	if net.DNSConfig != nil {
+		spec.DNSConfig = net.DNSConfig.DeepCopy()
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/ghodss/yaml"
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
 )
 
 func TestNetworkCeph_SpecLegacy(t *testing.T) {
@@ -45,3 +46,30 @@ func TestNetworkCeph_IsHostLegacy(t *testing.T) {
 
 	assert.True(t, net.IsHost())
 }
+
+func TestNetworkCeph_ApplyDNSToPodSpec(t *testing.T) {
+	// no host network keeps the default policy
+	net := NetworkSpec{}
+	spec := v1.PodSpec{}
+	net.ApplyDNSToPodSpec(&spec)
+	assert.Equal(t, v1.DNSPolicy(""), spec.DNSPolicy)
+	assert.Nil(t, spec.DNSConfig)
+
+	// host network needs to resolve the cluster services
+	net = NetworkSpec{HostNetwork: true}
+	net.ApplyDNSToPodSpec(&spec)
+	assert.Equal(t, v1.DNSClusterFirstWithHostNet, spec.DNSPolicy)
+
+	// the policy and config can be overridden
+	ndots := "2"
+	net.DNSPolicy = v1.DNSNone
+	net.DNSConfig = &v1.PodDNSConfig{
+		Nameservers: []string{"10.0.0.10"},
+		Options:     []v1.PodDNSConfigOption{{Name: "ndots", Value: &ndots}},
+	}
+	spec = v1.PodSpec{}
+	net.ApplyDNSToPodSpec(&spec)
+	assert.Equal(t, v1.DNSNone, spec.DNSPolicy)
+	assert.Equal(t, []string{"10.0.0.10"}, spec.DNSConfig.Nameservers)
+	assert.False(t, spec.DNSConfig == net.DNSConfig)
+}
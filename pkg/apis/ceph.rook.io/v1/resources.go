@@ -16,8 +16,11 @@ limitations under the License.
 package v1
 
 import (
+	"fmt"
+
 	rook "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 const (
@@ -46,3 +49,69 @@ func GetOSDResources(p rook.ResourceSpec) v1.ResourceRequirements {
 func GetRBDMirrorResources(p rook.ResourceSpec) v1.ResourceRequirements {
 	return p[ResourcesKeyRBDMirror]
 }
+
+// resourceProfiles are the resources of the daemons in each profile. The memory limits are above the minimum memory
+// that the operator accepts for each daemon.
+var resourceProfiles = map[ResourceProfile]rook.ResourceSpec{
+	ResourceProfileSmall: {
+		ResourcesKeyMon:       profileResources("250m", "1Gi", "1Gi"),
+		ResourcesKeyMgr:       profileResources("250m", "512Mi", "1Gi"),
+		ResourcesKeyOSD:       profileResources("500m", "4Gi", "4Gi"),
+		ResourcesKeyRBDMirror: profileResources("100m", "512Mi", "512Mi"),
+	},
+	ResourceProfileMedium: {
+		ResourcesKeyMon:       profileResources("500m", "1Gi", "2Gi"),
+		ResourcesKeyMgr:       profileResources("500m", "1Gi", "2Gi"),
+		ResourcesKeyOSD:       profileResources("1", "4Gi", "8Gi"),
+		ResourcesKeyRBDMirror: profileResources("250m", "512Mi", "1Gi"),
+	},
+	ResourceProfileLarge: {
+		ResourcesKeyMon:       profileResources("1", "2Gi", "4Gi"),
+		ResourcesKeyMgr:       profileResources("1", "2Gi", "4Gi"),
+		ResourcesKeyOSD:       profileResources("2", "8Gi", "16Gi"),
+		ResourcesKeyRBDMirror: profileResources("500m", "1Gi", "2Gi"),
+	},
+}
+
+func profileResources(cpuRequest, memoryRequest, memoryLimit string) v1.ResourceRequirements {
+	return v1.ResourceRequirements{
+		Requests: v1.ResourceList{
+			v1.ResourceCPU:    resource.MustParse(cpuRequest),
+			v1.ResourceMemory: resource.MustParse(memoryRequest),
+		},
+		Limits: v1.ResourceList{
+			v1.ResourceMemory: resource.MustParse(memoryLimit),
+		},
+	}
+}
+
+// ValidateResourceProfile checks that the resource profile is one of the known profiles
+func ValidateResourceProfile(profile ResourceProfile) error {
+	if profile == "" {
+		return nil
+	}
+	if _, ok := resourceProfiles[profile]; !ok {
+		return fmt.Errorf("invalid resource profile %q, it must be %q, %q or %q", profile, ResourceProfileSmall, ResourceProfileMedium, ResourceProfileLarge)
+	}
+	return nil
+}
+
+// ResolveResourceProfile returns the resources of the daemons with the resources of the profile for the daemons
+// that have no resources set. The resources that are set for a daemon always override the profile.
+func ResolveResourceProfile(profile ResourceProfile, p rook.ResourceSpec) rook.ResourceSpec {
+	defaults, ok := resourceProfiles[profile]
+	if !ok {
+		return p
+	}
+	resolved := rook.ResourceSpec{}
+	for key, resources := range defaults {
+		resolved[key] = *resources.DeepCopy()
+	}
+	for key, resources := range p {
+		if len(resources.Requests) == 0 && len(resources.Limits) == 0 {
+			continue
+		}
+		resolved[key] = resources
+	}
+	return resolved
+}
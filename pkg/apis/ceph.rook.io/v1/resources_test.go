@@ -0,0 +1,50 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1
+
+import (
+	"testing"
+
+	rook "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestResolveResourceProfile(t *testing.T) {
+	assert.Nil(t, ValidateResourceProfile(""))
+	assert.Nil(t, ValidateResourceProfile(ResourceProfileMedium))
+	assert.NotNil(t, ValidateResourceProfile("huge"))
+
+	// without a profile the resources are unchanged
+	resources := rook.ResourceSpec{}
+	assert.Equal(t, resources, ResolveResourceProfile("", resources))
+
+	// the mgr resources override the profile, the other daemons get the resources of the profile
+	mgr := v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("3Gi")}}
+	resources = rook.ResourceSpec{ResourcesKeyMgr: mgr, ResourcesKeyMon: v1.ResourceRequirements{}}
+	resolved := ResolveResourceProfile(ResourceProfileSmall, resources)
+	assert.Equal(t, mgr, GetMgrResources(resolved))
+	monMemory := GetMonResources(resolved).Limits[v1.ResourceMemory]
+	assert.Equal(t, "1Gi", monMemory.String())
+	osdCPU := GetOSDResources(resolved).Requests[v1.ResourceCPU]
+	assert.Equal(t, "500m", osdCPU.String())
+	assert.NotEqual(t, v1.ResourceRequirements{}, GetRBDMirrorResources(resolved))
+
+	// the spec of the cluster is not modified
+	assert.Equal(t, 2, len(resources))
+	assert.Equal(t, v1.ResourceRequirements{}, resources[ResourcesKeyMon])
+}
@@ -18,6 +18,7 @@ package v1
 import (
 	"time"
 
+	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
@@ -31,7 +32,6 @@ import (
 // ***************************************************************************
 
 // +genclient
-// +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
 type CephCluster struct {
@@ -56,6 +56,9 @@ type ClusterSpec struct {
 	// A spec for available storage in the cluster and how it should be used
 	Storage rook.StorageScopeSpec `json:"storage,omitempty"`
 
+	// Whether the OSDs of the nodes removed from the storage spec are removed without confirming the removal
+	SkipNodeRemovalConfirmation bool `json:"skipNodeRemovalConfirmation,omitempty"`
+
 	// The annotations-related configuration to add/set on each Pod related object.
 	Annotations rook.AnnotationsSpec `json:"annotations,omitempty"`
 
@@ -68,9 +71,39 @@ type ClusterSpec struct {
 	// Resources set resource requests and limits
 	Resources rook.ResourceSpec `json:"resources,omitempty"`
 
+	// ResourceProfile sets the resources of the daemons that have no resources set: "small", "medium" or "large"
+	ResourceProfile ResourceProfile `json:"resourceProfile,omitempty"`
+
+	// PriorityClassNames sets the priority classes of the pods, by daemon type or for "all" the pods
+	PriorityClassNames rook.PriorityClassNamesSpec `json:"priorityClassNames,omitempty"`
+
+	// TerminationGracePeriodSeconds sets how long the pods may take to stop, by daemon type or for "all" the pods
+	TerminationGracePeriodSeconds rook.TerminationGracePeriodSecondsSpec `json:"terminationGracePeriodSeconds,omitempty"`
+
 	// The path on the host where config and data can be persisted.
 	DataDirHostPath string `json:"dataDirHostPath,omitempty"`
 
+	// Whether to allow another cluster to use the same DataDirHostPath. Only set this if the clusters run on separate nodes.
+	AllowSharedDataDirHostPath bool `json:"allowSharedDataDirHostPath,omitempty"`
+
+	// Whether to block the deletion of the cluster CR. The cluster is only torn down after the protection is disabled.
+	DeletionProtection bool `json:"deletionProtection,omitempty"`
+
+	// Whether to only start the mons and establish the identity of the cluster. The other daemons are started once
+	// the setting is removed.
+	BootstrapOnly bool `json:"bootstrapOnly,omitempty"`
+
+	// The number of revisions of the cluster spec that are kept in ConfigMaps. The default is 10, a negative
+	// value disables the history.
+	SpecHistoryLimit int `json:"specHistoryLimit,omitempty"`
+
+	// The CA certificates that the daemons and the version job trust, e.g. for the TLS connections to internal services
+	CABundle CABundleSpec `json:"caBundle,omitempty"`
+
+	// SchedulerName is the scheduler of the mgr pods and the version job pods. The default scheduler is used if
+	// it is not set.
+	SchedulerName string `json:"schedulerName,omitempty"`
+
 	// Ceph config overrides to apply.
 	ConfigOverrides ConfigOverridesSpec `json:"configOverrides,omitempty"`
 
@@ -80,6 +113,9 @@ type ClusterSpec struct {
 	// A spec for mon related options
 	Mon MonSpec `json:"mon,omitempty"`
 
+	// A spec for mgr related options
+	Mgr MgrSpec `json:"mgr,omitempty"`
+
 	// A spec for rbd mirroring
 	RBDMirroring RBDMirroringSpec `json:"rbdMirroring"`
 
@@ -92,8 +128,41 @@ type ClusterSpec struct {
 	// Whether the Ceph Cluster is running external to this Kubernetes cluster
 	// mon, mgr, osd, mds, and discover daemons will not be created for external clusters.
 	External ExternalSpec `json:"external"`
+
+	// Whether an orchestration waits for the PGs to be active+clean before it is completed
+	WaitForCleanPGs WaitForCleanPGsSpec `json:"waitForCleanPGs,omitempty"`
+
+	// Whether the balancer is started after OSDs were added to the cluster
+	BalanceOSDs BalanceOSDsSpec `json:"balanceOSDs,omitempty"`
+
+	// The crush rules that are created once the OSDs are started
+	CrushRules []CrushRuleSpec `json:"crushRules,omitempty"`
+
+	// Whether the daemons are rolled back to the previous image when an upgrade leaves the cluster unhealthy
+	UpgradeRollback UpgradeRollbackSpec `json:"upgradeRollback,omitempty"`
+
+	// When the controllers of the pools, filesystems and other child CRs are notified of the changes of the cluster
+	ChildNotification ChildNotificationSpec `json:"childNotification,omitempty"`
+
+	// When a new cluster is considered initialized after its daemons were started
+	Initialization InitializationSpec `json:"initialization,omitempty"`
+
+	// FeatureGates enable the experimental behaviors of the orchestration by name, e.g. UpgradeRollback: true
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
 }
 
+// ResourceProfile is a named set of resources for the daemons
+type ResourceProfile string
+
+const (
+	// ResourceProfileSmall is the profile of a small or test cluster
+	ResourceProfileSmall ResourceProfile = "small"
+	// ResourceProfileMedium is the profile of a production cluster with moderate load
+	ResourceProfileMedium ResourceProfile = "medium"
+	// ResourceProfileLarge is the profile of a production cluster with heavy load
+	ResourceProfileLarge ResourceProfile = "large"
+)
+
 // VersionSpec represents the settings for the Ceph version that Rook is orchestrating.
 type CephVersionSpec struct {
 	// Image is the container image used to launch the ceph daemons, such as ceph/ceph:v13.2.6 or ceph/ceph:v14.2.2
@@ -101,6 +170,38 @@ type CephVersionSpec struct {
 
 	// Whether to allow unsupported versions (do not set to true in production)
 	AllowUnsupported bool `json:"allowUnsupported,omitempty"`
+
+	// Whether to allow versions older than the minimum version required by Rook. The orchestration of these versions
+	// is known to be incomplete (do not set to true in production)
+	AllowBelowMinimum bool `json:"allowBelowMinimum,omitempty"`
+
+	// Whether to allow the development builds and release candidates of ceph (do not set to true in production)
+	AllowPreRelease bool `json:"allowPreRelease,omitempty"`
+
+	// ImagePullPolicy is the pull policy of the ceph image for the daemons and the jobs. The default of kubernetes
+	// applies if it is not set.
+	ImagePullPolicy v1.PullPolicy `json:"imagePullPolicy,omitempty"`
+
+	// ImageJobResources are the resources of the job that detects the version of the image
+	ImageJobResources v1.ResourceRequirements `json:"imageJobResources,omitempty"`
+
+	// ImageJobNodeSelector pins the job that detects the version of the image to the matching nodes. If not set,
+	// the selector is derived from the required node affinity of the "all" placement.
+	ImageJobNodeSelector map[string]string `json:"imageJobNodeSelector,omitempty"`
+
+	// PinnedVersion is the ceph version of the image, such as 14.2.4 or 15.1.0-rc. If set, the job that detects the version of
+	// the image is skipped.
+	PinnedVersion string `json:"pinnedVersion,omitempty"`
+}
+
+// CABundleSpec references a configmap or a secret in the namespace of the cluster with the trusted CA certificates
+type CABundleSpec struct {
+	// ConfigMapName is the name of the configmap with the CA certificates
+	ConfigMapName string `json:"configMapName,omitempty"`
+	// SecretName is the name of the secret with the CA certificates, it cannot be set with the ConfigMapName
+	SecretName string `json:"secretName,omitempty"`
+	// Key is the key of the PEM encoded CA certificates. The default is "ca-bundle.crt".
+	Key string `json:"key,omitempty"`
 }
 
 // DashboardSpec represents the settings for the Ceph dashboard
@@ -113,8 +214,16 @@ type DashboardSpec struct {
 	Port int `json:"port,omitempty"`
 	// Whether SSL should be used
 	SSL *bool `json:"ssl,omitempty"`
+	// The address the dashboard webserver binds to. If empty, the dashboard listens on all
+	// interfaces. Set to "podIP" to bind only to the IP of the mgr pod.
+	BindAddress string `json:"bindAddress,omitempty"`
 }
 
+const (
+	// DashboardBindAddressPodIP instructs the dashboard to bind only to the IP of the mgr pod
+	DashboardBindAddressPodIP = "podIP"
+)
+
 // MonitoringSpec represents the settings for Prometheus based Ceph monitoring
 type MonitoringSpec struct {
 	// Whether to create the prometheus rules for the ceph cluster. If true, the prometheus
@@ -124,14 +233,128 @@ type MonitoringSpec struct {
 	// The namespace where the prometheus rules and alerts should be created.
 	// If empty, the same namespace as the cluster will be used.
 	RulesNamespace string `json:"rulesNamespace,omitempty"`
+
+	// Labels applied to the prometheus rules. These must match the ruleSelector of the
+	// Prometheus resource or the rules will not be loaded by the prometheus operator.
+	RuleLabels map[string]string `json:"ruleLabels,omitempty"`
+
+	// Labels applied to the service monitors. These must match the serviceMonitorSelector of the
+	// Prometheus resource or the ceph metrics will not be scraped.
+	ServiceMonitorLabels map[string]string `json:"serviceMonitorLabels,omitempty"`
 }
 
 type ClusterStatus struct {
-	State      ClusterState `json:"state,omitempty"`
-	Message    string       `json:"message,omitempty"`
-	CephStatus *CephStatus  `json:"ceph,omitempty"`
+	State      ClusterState       `json:"state,omitempty"`
+	Message    string             `json:"message,omitempty"`
+	CephStatus *CephStatus        `json:"ceph,omitempty"`
+	Conditions []ClusterCondition `json:"conditions,omitempty"`
+	// The time when the last orchestration of the cluster completed successfully
+	LastOrchestrationTime string `json:"lastOrchestrationTime,omitempty"`
+	// The duration of the last successful orchestration
+	LastOrchestrationDuration string `json:"lastOrchestrationDuration,omitempty"`
+	// The generation of the CephCluster CR that was applied by the last successful orchestration
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// The nodes that were removed from the storage spec, but still have OSDs until the removal is confirmed
+	PendingNodeRemovals []string `json:"pendingNodeRemovals,omitempty"`
+	// The debug level of the mgr daemons that was applied from the ceph.rook.io/mgr-debug-level annotation
+	MgrDebugLevel string `json:"mgrDebugLevel,omitempty"`
+	// The ID of the OSD that was marked out and stopped by the ceph.rook.io/osd-out annotation
+	OutOSD string `json:"outOSD,omitempty"`
+	// The state of the OSD provisioning on each node or PVC during the last orchestration
+	OSDProvisioning map[string]NodeOSDProvisioningStatus `json:"osdProvisioning,omitempty"`
+	// The error of the last orchestration if it failed. Cleared when an orchestration succeeds.
+	LastOrchestrationError string `json:"lastOrchestrationError,omitempty"`
+	// The key messages of the last orchestration if it failed, the oldest first
+	LastOrchestrationMessages []string `json:"lastOrchestrationMessages,omitempty"`
+	// The number of daemons running each ceph version by type of daemon, updated during the upgrades
+	DaemonVersions map[string]string `json:"daemonVersions,omitempty"`
+	// The crush rules that were created from the spec, so they can be deleted when they are removed from the spec
+	CrushRules []string `json:"crushRules,omitempty"`
+	// The mon count recommended for the number of storage nodes when the mons are scaled automatically
+	RecommendedMonCount int `json:"recommendedMonCount,omitempty"`
+	// The ceph image of the last successful orchestration, the image the daemons are rolled back to
+	LastAppliedCephImage string `json:"lastAppliedCephImage,omitempty"`
+	// The ceph image of the upgrade that was rolled back. The upgrade is not retried until the image of the spec changes.
+	RolledBackCephImage string `json:"rolledBackCephImage,omitempty"`
+}
+
+// NodeOSDProvisioningStatus is the state of the OSD provisioning on a node or PVC
+type NodeOSDProvisioningStatus struct {
+	State   OSDProvisioningState `json:"state,omitempty"`
+	Message string               `json:"message,omitempty"`
+}
+
+// OSDProvisioningState is the progress of the OSD provisioning on a node or PVC
+type OSDProvisioningState string
+
+const (
+	// OSDProvisioningPending means the provisioning job was started but did not run yet
+	OSDProvisioningPending OSDProvisioningState = "Pending"
+	// OSDProvisioningInProgress means the OSDs are being prepared
+	OSDProvisioningInProgress OSDProvisioningState = "InProgress"
+	// OSDProvisioningComplete means the OSDs were prepared and started
+	OSDProvisioningComplete OSDProvisioningState = "Complete"
+	// OSDProvisioningFailed means the OSDs could not be provisioned, see the message for the reason
+	OSDProvisioningFailed OSDProvisioningState = "Failed"
+	// OSDProvisioningRemoved means the node was removed from the storage spec and its OSDs were removed
+	OSDProvisioningRemoved OSDProvisioningState = "Removed"
+)
+
+// ClusterCondition represents the state of an aspect of the cluster that the operator verified
+type ClusterCondition struct {
+	Type               ClusterConditionType `json:"type,omitempty"`
+	Status             v1.ConditionStatus   `json:"status,omitempty"`
+	Reason             string               `json:"reason,omitempty"`
+	Message            string               `json:"message,omitempty"`
+	LastTransitionTime string               `json:"lastTransitionTime,omitempty"`
 }
 
+type ClusterConditionType string
+
+const (
+	// ClusterConditionVersionMismatch is true when the daemons are not running the ceph version detected from the image
+	ClusterConditionVersionMismatch ClusterConditionType = "VersionMismatch"
+	// ClusterConditionFSIDMismatch is true when the fsid reported by the mons is not the fsid of the cluster
+	ClusterConditionFSIDMismatch ClusterConditionType = "FSIDMismatch"
+	// ClusterConditionMgrResourcesLow is true when resource-heavy mgr modules are enabled with mgr resource limits
+	// below the recommended minimum
+	ClusterConditionMgrResourcesLow ClusterConditionType = "MgrResourcesLow"
+	// ClusterConditionMonSplitBrain is true when the mons report different quorums, so the orchestration is stopped
+	ClusterConditionMonSplitBrain ClusterConditionType = "MonSplitBrain"
+	// ClusterConditionNetworkChangePending is true when the network of the spec changed on a running cluster, but the
+	// change is not applied until it is confirmed
+	ClusterConditionNetworkChangePending ClusterConditionType = "NetworkChangePending"
+	// ClusterConditionDashboardNotHighlyAvailable is true when the dashboard is enabled with a single mgr, so a
+	// failure of the mgr takes the dashboard down
+	ClusterConditionDashboardNotHighlyAvailable ClusterConditionType = "DashboardNotHighlyAvailable"
+	// ClusterConditionUpgradeRolledBack is true when the daemons were rolled back to the previous image since the
+	// cluster was unhealthy after the upgrade
+	ClusterConditionUpgradeRolledBack ClusterConditionType = "UpgradeRolledBack"
+	// ClusterConditionUpgrading is true while the orchestration of an upgrade of the ceph version is running
+	ClusterConditionUpgrading ClusterConditionType = "Upgrading"
+	// ClusterConditionInsufficientRBAC is true when the operator is missing permissions that the orchestration needs,
+	// so the orchestration is not started
+	ClusterConditionInsufficientRBAC ClusterConditionType = "InsufficientRBAC"
+	// ClusterConditionMonitoringRulesNamespaceUnavailable is true when the prometheus rules cannot be deployed since
+	// the rules namespace of the monitoring spec does not exist or the operator cannot write to it
+	ClusterConditionMonitoringRulesNamespaceUnavailable ClusterConditionType = "MonitoringRulesNamespaceUnavailable"
+	// ClusterConditionMgrUnschedulable is true when the placement of the mgr does not match any node, so the mgr
+	// deployments are not created
+	ClusterConditionMgrUnschedulable ClusterConditionType = "MgrUnschedulable"
+	// ClusterConditionMgrActiveAmbiguous is true when ceph reported an inconsistent active mgr, e.g. during a failover,
+	// so the operator did not act on the active mgr
+	ClusterConditionMgrActiveAmbiguous ClusterConditionType = "MgrActiveAmbiguous"
+	// ClusterConditionDaemonsNotReady is true when the mon or mgr deployments of a new cluster were not ready after
+	// its first orchestration
+	ClusterConditionDaemonsNotReady ClusterConditionType = "DaemonsNotReady"
+	// ClusterConditionDataDirHostPathConflict is true when the dataDirHostPath of the cluster overlaps with the
+	// dataDirHostPath of another cluster on the same nodes, so the newer of the clusters is not started
+	ClusterConditionDataDirHostPathConflict ClusterConditionType = "DataDirHostPathConflict"
+	// ClusterConditionRBDMirroringInvalid is true when the rbd mirroring spec is invalid, so the orchestration is not
+	// started. Otherwise its message tells whether the rbd mirroring is enabled.
+	ClusterConditionRBDMirroringInvalid ClusterConditionType = "RBDMirroringInvalid"
+)
+
 type CephStatus struct {
 	Health         string                       `json:"health,omitempty"`
 	Details        map[string]CephHealthMessage `json:"details,omitempty"`
@@ -148,12 +371,13 @@ type CephHealthMessage struct {
 type ClusterState string
 
 const (
-	ClusterStateCreating   ClusterState = "Creating"
-	ClusterStateCreated    ClusterState = "Created"
-	ClusterStateUpdating   ClusterState = "Updating"
-	ClusterStateConnecting ClusterState = "Connecting"
-	ClusterStateConnected  ClusterState = "Connected"
-	ClusterStateError      ClusterState = "Error"
+	ClusterStateCreating     ClusterState = "Creating"
+	ClusterStateCreated      ClusterState = "Created"
+	ClusterStateBootstrapped ClusterState = "Bootstrapped"
+	ClusterStateUpdating     ClusterState = "Updating"
+	ClusterStateConnecting   ClusterState = "Connecting"
+	ClusterStateConnected    ClusterState = "Connected"
+	ClusterStateError        ClusterState = "Error"
 	// DefaultFailureDomain for PoolSpec
 	DefaultFailureDomain = "host"
 )
@@ -174,6 +398,88 @@ type MonSpec struct {
 	Count                int                       `json:"count,omitempty"`
 	AllowMultiplePerNode bool                      `json:"allowMultiplePerNode,omitempty"`
 	VolumeClaimTemplate  *v1.PersistentVolumeClaim `json:"volumeClaimTemplate,omitempty"`
+	// ZoneSpread places each mon in a different zone so the quorum survives the failure of a full zone
+	ZoneSpread bool `json:"zoneSpread,omitempty"`
+	// ZoneTopologyKey is the node label that identifies the zone of a node. Defaults to failure-domain.beta.kubernetes.io/zone.
+	ZoneTopologyKey string `json:"zoneTopologyKey,omitempty"`
+	// AutoScale recommends or applies a mon count that grows with the number of storage nodes
+	AutoScale MonAutoScaleSpec `json:"autoScale,omitempty"`
+}
+
+// MonAutoScaleSpec represents the scaling of the mon count with the number of storage nodes
+type MonAutoScaleSpec struct {
+	// Mode is "recommend" to only record the recommended mon count in the status, or "apply" to run the recommended
+	// number of mons instead of the count of the spec. The count of the spec is used when empty.
+	Mode string `json:"mode,omitempty"`
+	// MinCount is the smallest mon count that is recommended. The default is 3.
+	MinCount int `json:"minCount,omitempty"`
+	// MaxCount is the largest mon count that is recommended. The default is 5.
+	MaxCount int `json:"maxCount,omitempty"`
+}
+
+const (
+	// MonAutoScaleRecommend records the recommended mon count in the status without changing the mons
+	MonAutoScaleRecommend = "recommend"
+	// MonAutoScaleApply runs the recommended number of mons
+	MonAutoScaleApply = "apply"
+)
+
+// MgrSpec represents options to configure a ceph mgr
+type MgrSpec struct {
+	// Volumes are added to the mgr pod, for example to provide certificates or configs to mgr modules.
+	// The names must not collide with the volumes created by Rook.
+	Volumes []v1.Volume `json:"volumes,omitempty"`
+	// VolumeMounts are added to the mgr container. The mount paths must not collide with the mounts created by Rook.
+	VolumeMounts []v1.VolumeMount `json:"volumeMounts,omitempty"`
+	// Env is added to the environment of the mgr container, for example to configure mgr modules.
+	// The env vars set by Rook cannot be overridden.
+	Env []v1.EnvVar `json:"env,omitempty"`
+	// EnvFrom adds the keys of configmaps or secrets to the environment of the mgr container
+	EnvFrom []v1.EnvFromSource `json:"envFrom,omitempty"`
+	// Sidecars are additional containers in the mgr pod such as log shippers or proxies.
+	// The names must not collide with the containers created by Rook.
+	Sidecars []v1.Container `json:"sidecars,omitempty"`
+	// Whether the sidecars are allowed to mount the data dir of the mgr
+	AllowSidecarDataDirAccess bool `json:"allowSidecarDataDirAccess,omitempty"`
+	// Modules are the mgr modules to enable. The ports of the modules are exposed by the mgr pod and the metrics service.
+	Modules []MgrModuleSpec `json:"modules,omitempty"`
+	// PodSecurityContext is the security context of the mgr pod
+	PodSecurityContext *v1.PodSecurityContext `json:"podSecurityContext,omitempty"`
+	// SecurityContext is merged over the security context Rook sets on the mgr containers, for example to drop
+	// capabilities. The privileges required by the mgr cannot be removed.
+	SecurityContext *v1.SecurityContext `json:"securityContext,omitempty"`
+	// UpdateStrategy is the strategy to replace the pods of the mgr deployments. The default is Recreate.
+	UpdateStrategy *apps.DeploymentStrategy `json:"updateStrategy,omitempty"`
+	// MonAntiAffinity keeps the mgr pods off the nodes of the mons, either "preferred" or "required". The mgr pods
+	// may run on the nodes of the mons if it is not set.
+	MonAntiAffinity MonAntiAffinityType `json:"monAntiAffinity,omitempty"`
+	// HostPID shares the process namespace of the host with the mgr pod, e.g. for profiling tools. The default is false.
+	HostPID bool `json:"hostPID,omitempty"`
+	// HostIPC shares the IPC namespace of the host with the mgr pod. The default is false.
+	HostIPC bool `json:"hostIPC,omitempty"`
+}
+
+// MonAntiAffinityType is how strictly the mgr pods avoid the nodes of the mons
+type MonAntiAffinityType string
+
+const (
+	// MonAntiAffinityPreferred places the mgr pods on other nodes than the mons when possible
+	MonAntiAffinityPreferred MonAntiAffinityType = "preferred"
+	// MonAntiAffinityRequired requires the mgr pods to run on other nodes than the mons when there are enough nodes
+	// without a mon for all the mgrs. With too few nodes the anti-affinity is only preferred.
+	MonAntiAffinityRequired MonAntiAffinityType = "required"
+)
+
+// MgrModuleSpec represents a mgr module to enable
+type MgrModuleSpec struct {
+	// Name is the name of the mgr module, e.g. restful
+	Name string `json:"name"`
+	// Port is the port the module listens on. If not set, the default port of the module is exposed if it is known,
+	// for example 8003 for the restful module.
+	Port int `json:"port,omitempty"`
+	// Settings are the config keys of the module and their values, e.g. mode: upmap for the balancer module. The
+	// settings removed from the spec are removed from the mgr.
+	Settings map[string]string `json:"settings,omitempty"`
 }
 
 // ExternalSpec represents the options supported by an external cluster
@@ -185,6 +491,63 @@ type RBDMirroringSpec struct {
 	Workers int `json:"workers"`
 }
 
+// WaitForCleanPGsSpec represents the options of the wait for the recovery of the PGs at the end of an orchestration
+type WaitForCleanPGsSpec struct {
+	// Enabled waits for all the PGs to be active+clean before the orchestration is completed
+	Enabled bool `json:"enabled,omitempty"`
+	// TimeoutMinutes is how long to wait for the PGs before the orchestration fails and is retried. The default is 30.
+	TimeoutMinutes int `json:"timeoutMinutes,omitempty"`
+}
+
+// ChildNotificationSpec represents the options of the notification of the child controllers at the end of an orchestration
+type ChildNotificationSpec struct {
+	// WaitForHealthy delays the notification of the child controllers until the cluster is healthy
+	WaitForHealthy bool `json:"waitForHealthy,omitempty"`
+	// TimeoutMinutes is how long to wait for the cluster to be healthy before the child controllers are notified
+	// anyway. The default is 10.
+	TimeoutMinutes int `json:"timeoutMinutes,omitempty"`
+}
+
+// InitializationSpec represents the readiness checks of the daemons after the first orchestration of a new cluster
+type InitializationSpec struct {
+	// ReadinessTimeoutMinutes is how long to wait for the mon and mgr deployments to be ready. The default is 10.
+	ReadinessTimeoutMinutes int `json:"readinessTimeoutMinutes,omitempty"`
+	// StabilizationSeconds is how long the deployments must stay ready before they are reported ready. The
+	// default is 0, they are reported ready as soon as the deployments are ready.
+	StabilizationSeconds int `json:"stabilizationSeconds,omitempty"`
+}
+
+// CrushRuleSpec represents a replicated crush rule that is created with the cluster
+type CrushRuleSpec struct {
+	// Name of the crush rule, referenced by the crushRule of the pools
+	Name string `json:"name"`
+	// Root is the crush root of the rule. The default is "default".
+	Root string `json:"root,omitempty"`
+	// FailureDomain is the crush type across which the replicas are placed. The default is host.
+	FailureDomain string `json:"failureDomain,omitempty"`
+	// DeviceClass limits the rule to the OSDs of the device class, e.g. hdd or ssd
+	DeviceClass string `json:"deviceClass,omitempty"`
+}
+
+// BalanceOSDsSpec represents the options of the balancing of the data after OSDs were added to the cluster
+type BalanceOSDsSpec struct {
+	// Enabled turns on the balancer of the mgr after an orchestration added OSDs and the PGs are active+clean.
+	// Deprecated: enable the BalanceOSDs feature gate instead.
+	Enabled bool `json:"enabled,omitempty"`
+	// Mode is the mode of the balancer, upmap or crush-compat. The default is upmap.
+	Mode string `json:"mode,omitempty"`
+}
+
+// UpgradeRollbackSpec represents the options of the rollback of an upgrade that leaves the cluster unhealthy
+type UpgradeRollbackSpec struct {
+	// Enabled rolls back the daemons of an upgrade to the previous image if the cluster is unhealthy after their upgrade.
+	// Deprecated: enable the UpgradeRollback feature gate instead.
+	Enabled bool `json:"enabled,omitempty"`
+	// UnhealthyTimeoutMinutes is how long the cluster may be unhealthy after the upgrade of a type of daemons before
+	// they are rolled back. The default is 10 minutes.
+	UnhealthyTimeoutMinutes int `json:"unhealthyTimeoutMinutes,omitempty"`
+}
+
 // +genclient
 // +genclient:noStatus
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
@@ -422,6 +785,13 @@ type NetworkSpec struct {
 
 	// HostNetwork to enable host network
 	HostNetwork bool `json:"hostNetwork"`
+
+	// DNSPolicy overrides the DNS policy of the daemon pods. By default ClusterFirstWithHostNet is used
+	// with host networking.
+	DNSPolicy v1.DNSPolicy `json:"dnsPolicy,omitempty"`
+
+	// DNSConfig sets additional DNS parameters of the daemon pods
+	DNSConfig *v1.PodDNSConfig `json:"dnsConfig,omitempty"`
 }
 
 // DisruptionManagementSpec configures mangement of daemon disruptions
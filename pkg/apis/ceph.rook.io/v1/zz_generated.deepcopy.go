@@ -22,10 +22,43 @@ package v1
 
 import (
 	v1alpha2 "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BalanceOSDsSpec) DeepCopyInto(out *BalanceOSDsSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BalanceOSDsSpec.
+func (in *BalanceOSDsSpec) DeepCopy() *BalanceOSDsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BalanceOSDsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CABundleSpec) DeepCopyInto(out *CABundleSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CABundleSpec.
+func (in *CABundleSpec) DeepCopy() *CABundleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CABundleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CephBlockPool) DeepCopyInto(out *CephBlockPool) {
 	*out = *in
@@ -429,6 +462,14 @@ func (in *CephStatus) DeepCopy() *CephStatus {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *CephVersionSpec) DeepCopyInto(out *CephVersionSpec) {
 	*out = *in
+	in.ImageJobResources.DeepCopyInto(&out.ImageJobResources)
+	if in.ImageJobNodeSelector != nil {
+		in, out := &in.ImageJobNodeSelector, &out.ImageJobNodeSelector
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -442,10 +483,42 @@ func (in *CephVersionSpec) DeepCopy() *CephVersionSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ChildNotificationSpec) DeepCopyInto(out *ChildNotificationSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ChildNotificationSpec.
+func (in *ChildNotificationSpec) DeepCopy() *ChildNotificationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(ChildNotificationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ClusterCondition) DeepCopyInto(out *ClusterCondition) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ClusterCondition.
+func (in *ClusterCondition) DeepCopy() *ClusterCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(ClusterCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 	*out = *in
-	out.CephVersion = in.CephVersion
+	in.CephVersion.DeepCopyInto(&out.CephVersion)
 	in.Storage.DeepCopyInto(&out.Storage)
 	if in.Annotations != nil {
 		in, out := &in.Annotations, &out.Annotations
@@ -479,6 +552,21 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 			(*out)[key] = *val.DeepCopy()
 		}
 	}
+	if in.PriorityClassNames != nil {
+		in, out := &in.PriorityClassNames, &out.PriorityClassNames
+		*out = make(v1alpha2.PriorityClassNamesSpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.TerminationGracePeriodSeconds != nil {
+		in, out := &in.TerminationGracePeriodSeconds, &out.TerminationGracePeriodSeconds
+		*out = make(v1alpha2.TerminationGracePeriodSecondsSpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	out.CABundle = in.CABundle
 	if in.ConfigOverrides != nil {
 		in, out := &in.ConfigOverrides, &out.ConfigOverrides
 		*out = make(ConfigOverridesSpec, len(*in))
@@ -486,10 +574,28 @@ func (in *ClusterSpec) DeepCopyInto(out *ClusterSpec) {
 	}
 	out.DisruptionManagement = in.DisruptionManagement
 	in.Mon.DeepCopyInto(&out.Mon)
+	in.Mgr.DeepCopyInto(&out.Mgr)
 	out.RBDMirroring = in.RBDMirroring
 	in.Dashboard.DeepCopyInto(&out.Dashboard)
-	out.Monitoring = in.Monitoring
+	in.Monitoring.DeepCopyInto(&out.Monitoring)
 	out.External = in.External
+	out.WaitForCleanPGs = in.WaitForCleanPGs
+	out.BalanceOSDs = in.BalanceOSDs
+	if in.CrushRules != nil {
+		in, out := &in.CrushRules, &out.CrushRules
+		*out = make([]CrushRuleSpec, len(*in))
+		copy(*out, *in)
+	}
+	out.UpgradeRollback = in.UpgradeRollback
+	out.ChildNotification = in.ChildNotification
+	out.Initialization = in.Initialization
+	if in.FeatureGates != nil {
+		in, out := &in.FeatureGates, &out.FeatureGates
+		*out = make(map[string]bool, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -511,6 +617,40 @@ func (in *ClusterStatus) DeepCopyInto(out *ClusterStatus) {
 		*out = new(CephStatus)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]ClusterCondition, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingNodeRemovals != nil {
+		in, out := &in.PendingNodeRemovals, &out.PendingNodeRemovals
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.OSDProvisioning != nil {
+		in, out := &in.OSDProvisioning, &out.OSDProvisioning
+		*out = make(map[string]NodeOSDProvisioningStatus, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.LastOrchestrationMessages != nil {
+		in, out := &in.LastOrchestrationMessages, &out.LastOrchestrationMessages
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DaemonVersions != nil {
+		in, out := &in.DaemonVersions, &out.DaemonVersions
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CrushRules != nil {
+		in, out := &in.CrushRules, &out.CrushRules
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -560,6 +700,22 @@ func (in ConfigOverridesSpec) DeepCopy() ConfigOverridesSpec {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CrushRuleSpec) DeepCopyInto(out *CrushRuleSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CrushRuleSpec.
+func (in *CrushRuleSpec) DeepCopy() *CrushRuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CrushRuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *DashboardSpec) DeepCopyInto(out *DashboardSpec) {
 	*out = *in
@@ -718,6 +874,22 @@ func (in *GatewaySpec) DeepCopy() *GatewaySpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *InitializationSpec) DeepCopyInto(out *InitializationSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new InitializationSpec.
+func (in *InitializationSpec) DeepCopy() *InitializationSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(InitializationSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MetadataServerSpec) DeepCopyInto(out *MetadataServerSpec) {
 	*out = *in
@@ -743,6 +915,118 @@ func (in *MetadataServerSpec) DeepCopy() *MetadataServerSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MgrModuleSpec) DeepCopyInto(out *MgrModuleSpec) {
+	*out = *in
+	if in.Settings != nil {
+		in, out := &in.Settings, &out.Settings
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MgrModuleSpec.
+func (in *MgrModuleSpec) DeepCopy() *MgrModuleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MgrModuleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MgrSpec) DeepCopyInto(out *MgrSpec) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]corev1.Volume, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.VolumeMounts != nil {
+		in, out := &in.VolumeMounts, &out.VolumeMounts
+		*out = make([]corev1.VolumeMount, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Env != nil {
+		in, out := &in.Env, &out.Env
+		*out = make([]corev1.EnvVar, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EnvFrom != nil {
+		in, out := &in.EnvFrom, &out.EnvFrom
+		*out = make([]corev1.EnvFromSource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Sidecars != nil {
+		in, out := &in.Sidecars, &out.Sidecars
+		*out = make([]corev1.Container, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Modules != nil {
+		in, out := &in.Modules, &out.Modules
+		*out = make([]MgrModuleSpec, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.PodSecurityContext != nil {
+		in, out := &in.PodSecurityContext, &out.PodSecurityContext
+		*out = new(corev1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(corev1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.UpdateStrategy != nil {
+		in, out := &in.UpdateStrategy, &out.UpdateStrategy
+		*out = new(appsv1.DeploymentStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MgrSpec.
+func (in *MgrSpec) DeepCopy() *MgrSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MgrSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MonAutoScaleSpec) DeepCopyInto(out *MonAutoScaleSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MonAutoScaleSpec.
+func (in *MonAutoScaleSpec) DeepCopy() *MonAutoScaleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(MonAutoScaleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MonSpec) DeepCopyInto(out *MonSpec) {
 	*out = *in
@@ -751,6 +1035,7 @@ func (in *MonSpec) DeepCopyInto(out *MonSpec) {
 		*out = new(corev1.PersistentVolumeClaim)
 		(*in).DeepCopyInto(*out)
 	}
+	out.AutoScale = in.AutoScale
 	return
 }
 
@@ -767,6 +1052,20 @@ func (in *MonSpec) DeepCopy() *MonSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MonitoringSpec) DeepCopyInto(out *MonitoringSpec) {
 	*out = *in
+	if in.RuleLabels != nil {
+		in, out := &in.RuleLabels, &out.RuleLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.ServiceMonitorLabels != nil {
+		in, out := &in.ServiceMonitorLabels, &out.ServiceMonitorLabels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 	return
 }
 
@@ -802,6 +1101,11 @@ func (in *NFSGaneshaSpec) DeepCopy() *NFSGaneshaSpec {
 func (in *NetworkSpec) DeepCopyInto(out *NetworkSpec) {
 	*out = *in
 	in.NetworkSpec.DeepCopyInto(&out.NetworkSpec)
+	if in.DNSConfig != nil {
+		in, out := &in.DNSConfig, &out.DNSConfig
+		*out = new(corev1.PodDNSConfig)
+		(*in).DeepCopyInto(*out)
+	}
 	return
 }
 
@@ -815,6 +1119,22 @@ func (in *NetworkSpec) DeepCopy() *NetworkSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NodeOSDProvisioningStatus) DeepCopyInto(out *NodeOSDProvisioningStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeOSDProvisioningStatus.
+func (in *NodeOSDProvisioningStatus) DeepCopy() *NodeOSDProvisioningStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(NodeOSDProvisioningStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ObjectStoreSpec) DeepCopyInto(out *ObjectStoreSpec) {
 	*out = *in
@@ -899,3 +1219,35 @@ func (in *ReplicatedSpec) DeepCopy() *ReplicatedSpec {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UpgradeRollbackSpec) DeepCopyInto(out *UpgradeRollbackSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UpgradeRollbackSpec.
+func (in *UpgradeRollbackSpec) DeepCopy() *UpgradeRollbackSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(UpgradeRollbackSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WaitForCleanPGsSpec) DeepCopyInto(out *WaitForCleanPGsSpec) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WaitForCleanPGsSpec.
+func (in *WaitForCleanPGsSpec) DeepCopy() *WaitForCleanPGsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WaitForCleanPGsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
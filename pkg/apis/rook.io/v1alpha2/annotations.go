@@ -38,9 +38,13 @@ func (a Annotations) ApplyToObjectMeta(t *metav1.ObjectMeta) {
 
 // Merge returns an Annotations which results from merging the attributes of the
 // original Annotations with the attributes of the supplied one. The supplied
-// Placement's attributes will override the original ones if defined.
+// Placement's attributes will override the original ones if defined. The original
+// Annotations are not modified, so they may be nil.
 func (a Annotations) Merge(with Annotations) Annotations {
-	ret := a
+	ret := Annotations{}
+	for k, v := range a {
+		ret[k] = v
+	}
 	for k, v := range with {
 		if _, ok := ret[k]; !ok {
 			ret[k] = v
@@ -81,4 +81,9 @@ func TestAnnotations_Merge(t *testing.T) {
 		"bar":   "foo",
 		"hello": "world",
 	}, testAnnotationsPart1.Merge(testAnnotationsPart2).GetMapStringString())
+	assert.Equal(t, 2, len(testAnnotationsPart1))
+
+	// the original annotations may be nil, e.g. when the spec has no annotations for all the daemons
+	var none Annotations
+	assert.Equal(t, testAnnotationsPart2.GetMapStringString(), none.Merge(testAnnotationsPart2).GetMapStringString())
 }
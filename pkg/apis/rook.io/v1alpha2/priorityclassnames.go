@@ -0,0 +1,30 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha2
+
+// All returns the priority class name for all the components
+func (p PriorityClassNamesSpec) All() string {
+	return p[KeyAll]
+}
+
+// Get returns the priority class name of the given component, falling back to the name for all the components
+func (p PriorityClassNamesSpec) Get(key KeyType) string {
+	if name, ok := p[key]; ok && name != "" {
+		return name
+	}
+	return p.All()
+}
@@ -81,6 +81,13 @@ type Placement struct {
 
 type ResourceSpec map[string]v1.ResourceRequirements
 
+// PriorityClassNamesSpec is a map of the priority class names of the pods, keyed by daemon type or "all"
+type PriorityClassNamesSpec map[KeyType]string
+
+// TerminationGracePeriodSecondsSpec is a map of the termination grace periods of the pods in seconds, keyed by daemon
+// type or "all"
+type TerminationGracePeriodSecondsSpec map[KeyType]int64
+
 // NetworkSpec represents cluster network settings
 type NetworkSpec struct {
 	// Provider is what provides network connectivity to the cluster e.g. "host" or "multus"
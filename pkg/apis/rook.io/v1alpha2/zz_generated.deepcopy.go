@@ -271,6 +271,28 @@ func (in PlacementSpec) DeepCopy() PlacementSpec {
 	return *out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in PriorityClassNamesSpec) DeepCopyInto(out *PriorityClassNamesSpec) {
+	{
+		in := &in
+		*out = make(PriorityClassNamesSpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+		return
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PriorityClassNamesSpec.
+func (in PriorityClassNamesSpec) DeepCopy() PriorityClassNamesSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PriorityClassNamesSpec)
+	in.DeepCopyInto(out)
+	return *out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in ResourceSpec) DeepCopyInto(out *ResourceSpec) {
 	{
@@ -412,6 +434,28 @@ func (in *StorageScopeSpec) DeepCopy() *StorageScopeSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in TerminationGracePeriodSecondsSpec) DeepCopyInto(out *TerminationGracePeriodSecondsSpec) {
+	{
+		in := &in
+		*out = make(TerminationGracePeriodSecondsSpec, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+		return
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TerminationGracePeriodSecondsSpec.
+func (in TerminationGracePeriodSecondsSpec) DeepCopy() TerminationGracePeriodSecondsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(TerminationGracePeriodSecondsSpec)
+	in.DeepCopyInto(out)
+	return *out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Volume) DeepCopyInto(out *Volume) {
 	*out = *in
@@ -39,6 +39,7 @@ type CephClustersGetter interface {
 type CephClusterInterface interface {
 	Create(*v1.CephCluster) (*v1.CephCluster, error)
 	Update(*v1.CephCluster) (*v1.CephCluster, error)
+	UpdateStatus(*v1.CephCluster) (*v1.CephCluster, error)
 	Delete(name string, options *metav1.DeleteOptions) error
 	DeleteCollection(options *metav1.DeleteOptions, listOptions metav1.ListOptions) error
 	Get(name string, options metav1.GetOptions) (*v1.CephCluster, error)
@@ -132,6 +133,22 @@ func (c *cephClusters) Update(cephCluster *v1.CephCluster) (result *v1.CephClust
 	return
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+
+func (c *cephClusters) UpdateStatus(cephCluster *v1.CephCluster) (result *v1.CephCluster, err error) {
+	result = &v1.CephCluster{}
+	err = c.client.Put().
+		Namespace(c.ns).
+		Resource("cephclusters").
+		Name(cephCluster.Name).
+		SubResource("status").
+		Body(cephCluster).
+		Do().
+		Into(result)
+	return
+}
+
 // Delete takes name of the cephCluster and deletes it. Returns an error if one occurs.
 func (c *cephClusters) Delete(name string, options *metav1.DeleteOptions) error {
 	return c.client.Delete().
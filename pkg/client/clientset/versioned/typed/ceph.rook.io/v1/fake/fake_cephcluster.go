@@ -100,6 +100,18 @@ func (c *FakeCephClusters) Update(cephCluster *cephrookiov1.CephCluster) (result
 	return obj.(*cephrookiov1.CephCluster), err
 }
 
+// UpdateStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating UpdateStatus().
+func (c *FakeCephClusters) UpdateStatus(cephCluster *cephrookiov1.CephCluster) (*cephrookiov1.CephCluster, error) {
+	obj, err := c.Fake.
+		Invokes(testing.NewUpdateSubresourceAction(cephclustersResource, "status", c.ns, cephCluster), &cephrookiov1.CephCluster{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*cephrookiov1.CephCluster), err
+}
+
 // Delete takes name of the cephCluster and deletes it. Returns an error if one occurs.
 func (c *FakeCephClusters) Delete(name string, options *v1.DeleteOptions) error {
 	_, err := c.Fake.
@@ -82,6 +82,17 @@ func AuthUpdateCaps(context *clusterd.Context, clusterName, name string, caps []
 	return err
 }
 
+// AuthImport imports the users and keys of the keyring at the given path. The keys and capabilities
+// of existing users are replaced.
+func AuthImport(context *clusterd.Context, clusterName, keyringPath string) error {
+	args := []string{"auth", "import", "-i", keyringPath}
+	_, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return fmt.Errorf("failed to auth import %s. %+v", keyringPath, err)
+	}
+	return nil
+}
+
 // AuthDelete will delete the given user.
 func AuthDelete(context *clusterd.Context, clusterName, name string) error {
 	args := []string{"auth", "del", name}
@@ -109,6 +109,22 @@ func CrushRemove(context *clusterd.Context, clusterName, name string) (string, e
 	return string(buf), nil
 }
 
+// SetDeviceClass sets the crush device class of an osd. The current class must be removed before a new
+// class can be set.
+func SetDeviceClass(context *clusterd.Context, clusterName string, osdID int, deviceClass string) error {
+	osdName := fmt.Sprintf("osd.%d", osdID)
+	args := []string{"osd", "crush", "rm-device-class", osdName}
+	if buf, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to remove the device class of %s: %+v, %s", osdName, err, string(buf))
+	}
+
+	args = []string{"osd", "crush", "set-device-class", deviceClass, osdName}
+	if buf, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to set the device class of %s to %s: %+v, %s", osdName, deviceClass, err, string(buf))
+	}
+	return nil
+}
+
 func FindOSDInCrushMap(context *clusterd.Context, clusterName string, osdID int) (*CrushFindResult, error) {
 	args := []string{"osd", "find", strconv.Itoa(osdID)}
 	buf, err := NewCephCommand(context, clusterName, args).Run()
@@ -180,3 +196,47 @@ func isCrushFieldSet(fieldName string, pairs []string) bool {
 func formatProperty(name, value string) string {
 	return fmt.Sprintf("%s=%s", name, value)
 }
+
+// CreateReplicatedCrushRule creates a crush rule that replicates the data across the failure domain under the root,
+// optionally only on the OSDs of the device class
+func CreateReplicatedCrushRule(context *clusterd.Context, clusterName, name, root, failureDomain, deviceClass string) error {
+	args := []string{"osd", "crush", "rule", "create-replicated", name, root, failureDomain}
+	if deviceClass != "" {
+		args = append(args, deviceClass)
+	}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to create crush rule %s. %+v", name, err)
+	}
+	return nil
+}
+
+// DeleteCrushRule deletes the crush rule. Ceph refuses to delete a rule that is used by a pool.
+func DeleteCrushRule(context *clusterd.Context, clusterName, name string) error {
+	args := []string{"osd", "crush", "rule", "rm", name}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to delete crush rule %s. %+v", name, err)
+	}
+	return nil
+}
+
+// GetPoolsByCrushRule returns the names of the pools by the id of the crush rule they use
+func GetPoolsByCrushRule(context *clusterd.Context, clusterName string) (map[int][]string, error) {
+	args := []string{"osd", "pool", "ls", "detail"}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the pools. %+v", err)
+	}
+
+	var pools []struct {
+		Name      string `json:"pool_name"`
+		CrushRule int    `json:"crush_rule"`
+	}
+	if err := json.Unmarshal(buf, &pools); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal the pools. %+v", err)
+	}
+	poolsByRule := map[int][]string{}
+	for _, pool := range pools {
+		poolsByRule[pool.CrushRule] = append(poolsByRule[pool.CrushRule], pool.Name)
+	}
+	return poolsByRule, nil
+}
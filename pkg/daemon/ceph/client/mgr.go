@@ -63,6 +63,15 @@ func MgrSetConfig(context *clusterd.Context, clusterName, mgrName string, cephVe
 	return hasChanged, nil
 }
 
+// MgrFail fails the mgr over to a standby mgr
+func MgrFail(context *clusterd.Context, clusterName, mgrName string) error {
+	args := []string{"mgr", "fail", mgrName}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to fail over mgr %s: %+v", mgrName, err)
+	}
+	return nil
+}
+
 func enableModule(context *clusterd.Context, clusterName, name string, force bool, action string) error {
 	args := []string{"mgr", "module", action, name}
 	if force {
@@ -75,3 +84,30 @@ func enableModule(context *clusterd.Context, clusterName, name string, force boo
 
 	return nil
 }
+
+// BalancerSetMode sets the mode of the balancer module, e.g. upmap or crush-compat
+func BalancerSetMode(context *clusterd.Context, clusterName, mode string) error {
+	args := []string{"balancer", "mode", mode}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to set the balancer mode to %s: %+v", mode, err)
+	}
+	return nil
+}
+
+// BalancerOn turns on the automatic balancing of the PGs across the OSDs
+func BalancerOn(context *clusterd.Context, clusterName string) error {
+	args := []string{"balancer", "on"}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to turn on the balancer: %+v", err)
+	}
+	return nil
+}
+
+// BalancerOff turns off the automatic balancing of the PGs across the OSDs
+func BalancerOff(context *clusterd.Context, clusterName string) error {
+	args := []string{"balancer", "off"}
+	if _, err := NewCephCommand(context, clusterName, args).Run(); err != nil {
+		return fmt.Errorf("failed to turn off the balancer: %+v", err)
+	}
+	return nil
+}
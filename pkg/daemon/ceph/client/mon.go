@@ -18,6 +18,7 @@ package client
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/rook/rook/pkg/clusterd"
 )
@@ -29,6 +30,8 @@ type MonStatusResponse struct {
 	MonMap struct {
 		Mons []MonMapEntry `json:"mons"`
 	} `json:"monmap"`
+	// the names of the mons in quorum, only returned by quorum_status
+	QuorumNames []string `json:"quorum_names,omitempty"`
 }
 
 // request to simplify deserialization of a test request
@@ -78,6 +81,44 @@ func GetMonStatus(context *clusterd.Context, clusterName string, debug bool) (Mo
 	return resp, nil
 }
 
+// GetMonQuorumStatus calls quorum_status mon_command. The response only includes the mons in quorum
+// and the mon map, which are the same fields as returned by mon_status.
+func GetMonQuorumStatus(context *clusterd.Context, clusterName string, debug bool) (MonStatusResponse, error) {
+	args := []string{"quorum_status"}
+	cmd := NewCephCommand(context, clusterName, args)
+	cmd.Debug = debug
+	buf, err := cmd.Run()
+	if err != nil {
+		return MonStatusResponse{}, fmt.Errorf("quorum status failed. %+v", err)
+	}
+
+	var resp MonStatusResponse
+	err = json.Unmarshal(buf, &resp)
+	if err != nil {
+		return MonStatusResponse{}, fmt.Errorf("unmarshal failed: %+v.  raw buffer response: %s", err, buf)
+	}
+
+	return resp, nil
+}
+
+// GetMonQuorumStatusFromMon calls quorum_status on the mon at the endpoint instead of any mon of the cluster, so the
+// views of the quorum of the mons can be compared. A mon that is not in a quorum does not respond before the timeout.
+func GetMonQuorumStatusFromMon(context *clusterd.Context, clusterName, monEndpoint string, timeout time.Duration) (MonStatusResponse, error) {
+	args := []string{"quorum_status", "-m", monEndpoint}
+	buf, err := NewCephCommand(context, clusterName, args).RunWithTimeout(timeout)
+	if err != nil {
+		return MonStatusResponse{}, fmt.Errorf("quorum status of mon %s failed. %+v", monEndpoint, err)
+	}
+
+	var resp MonStatusResponse
+	err = json.Unmarshal(buf, &resp)
+	if err != nil {
+		return MonStatusResponse{}, fmt.Errorf("unmarshal failed: %+v.  raw buffer response: %s", err, buf)
+	}
+
+	return resp, nil
+}
+
 type MonTimeStatus struct {
 	Skew   map[string]MonTimeSkewStatus `json:"time_skew_status"`
 	Checks struct {
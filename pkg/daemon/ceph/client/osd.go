@@ -253,6 +253,12 @@ func OSDOut(context *clusterd.Context, clusterName string, osdID int) (string, e
 	return string(buf), err
 }
 
+func OSDIn(context *clusterd.Context, clusterName string, osdID int) (string, error) {
+	args := []string{"osd", "in", strconv.Itoa(osdID)}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	return string(buf), err
+}
+
 func OSDRemove(context *clusterd.Context, clusterName string, osdID int) (string, error) {
 	args := []string{"osd", "rm", strconv.Itoa(osdID)}
 	buf, err := NewCephCommand(context, clusterName, args).Run()
@@ -287,6 +293,32 @@ func (usage *OSDUsage) ByID(osdID int) *OSDNodeUsage {
 	return nil
 }
 
+// OSDMetadata is the metadata an osd reports about itself
+type OSDMetadata struct {
+	ID       int    `json:"id"`
+	Hostname string `json:"hostname"`
+	// comma separated list of the devices the osd is running on
+	Devices string `json:"devices"`
+}
+
+// GetOSDMetadata returns the metadata of all the osds
+func GetOSDMetadata(context *clusterd.Context, clusterName string) ([]OSDMetadata, error) {
+	var output []OSDMetadata
+
+	args := []string{"osd", "metadata"}
+	buf, err := NewCephCommand(context, clusterName, args).Run()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get osd metadata: %+v", err)
+	}
+
+	err = json.Unmarshal(buf, &output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal 'osd metadata' response: %+v", err)
+	}
+
+	return output, nil
+}
+
 // HostTree returns the osd tree
 func HostTree(context *clusterd.Context, clusterName string) (OsdTree, error) {
 	var output OsdTree
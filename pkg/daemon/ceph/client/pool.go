@@ -254,20 +254,7 @@ func createReplicationCrushRule(context *clusterd.Context, clusterName string, n
 	if newPool.CrushRoot != "" {
 		crushRoot = newPool.CrushRoot
 	}
-	args := []string{"osd", "crush", "rule", "create-replicated", ruleName, crushRoot, failureDomain}
-
-	var deviceClass string
-	if newPool.DeviceClass != "" {
-		deviceClass = newPool.DeviceClass
-		args = append(args, deviceClass)
-	}
-
-	_, err := NewCephCommand(context, clusterName, args).Run()
-	if err != nil {
-		return fmt.Errorf("failed to create crush rule %s. %+v", ruleName, err)
-	}
-
-	return nil
+	return CreateReplicatedCrushRule(context, clusterName, ruleName, crushRoot, failureDomain, newPool.DeviceClass)
 }
 
 func SetPoolProperty(context *clusterd.Context, clusterName, name, propName string, propVal string) error {
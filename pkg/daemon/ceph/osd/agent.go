@@ -59,6 +59,8 @@ type OsdAgent struct {
 	pvcBacked      bool
 	configCounter  int32
 	osdsCompleted  chan struct{}
+	// enumerates the candidate devices on the node
+	deviceDiscoverer DeviceDiscoverer
 }
 
 type device struct {
@@ -82,9 +84,16 @@ func NewAgent(context *clusterd.Context, devices []DesiredDevice, metadataDevice
 		pvcBacked:      pvcBacked,
 		procMan:        proc.New(context.Executor),
 		osdProc:        make(map[int]*proc.MonitoredProc),
+		// the built-in discovery unless a custom discoverer is set
+		deviceDiscoverer: &localDeviceDiscoverer{},
 	}
 }
 
+// SetDeviceDiscoverer overrides the discovery of the candidate devices on the node
+func (a *OsdAgent) SetDeviceDiscoverer(discoverer DeviceDiscoverer) {
+	a.deviceDiscoverer = discoverer
+}
+
 func (a *OsdAgent) configureDirs(context *clusterd.Context, dirs map[string]int) ([]oposd.OSDInfo, error) {
 	var osds []oposd.OSDInfo
 	if len(dirs) == 0 {
@@ -133,7 +133,7 @@ func Provision(context *clusterd.Context, agent *OsdAgent) error {
 		}
 		rawDevices = append(rawDevices, clusterd.PopulateDeviceInfo(agent.devices[0].Name, context.Executor))
 	} else {
-		rawDevices, err = clusterd.DiscoverDevices(context.Executor)
+		rawDevices, err = agent.deviceDiscoverer.DiscoverDevices(context)
 		if err != nil {
 			return fmt.Errorf("failed initial hardware discovery. %+v", err)
 		}
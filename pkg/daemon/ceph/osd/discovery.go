@@ -0,0 +1,83 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/util/sys"
+)
+
+// DeviceDiscoverer enumerates the candidate devices for OSDs on the local node. The devices are still filtered
+// by the devices and device filter of the storage spec, and the devices that are in use are skipped.
+//
+// Implementations must be idempotent: every call must return the same devices for the same hardware, and a
+// device must always be returned with the same name, since the OSDs are matched to their devices by name
+// across provisioning runs.
+type DeviceDiscoverer interface {
+	DiscoverDevices(context *clusterd.Context) ([]*sys.LocalDisk, error)
+}
+
+// NewDeviceDiscoverer returns the discoverer that runs the command to list the devices, or the built-in
+// discovery of the local devices if the command is empty
+func NewDeviceDiscoverer(command string) DeviceDiscoverer {
+	if strings.TrimSpace(command) == "" {
+		return &localDeviceDiscoverer{}
+	}
+	return &commandDeviceDiscoverer{command: strings.Fields(command)}
+}
+
+// localDeviceDiscoverer discovers all the local devices with lsblk
+type localDeviceDiscoverer struct{}
+
+func (d *localDeviceDiscoverer) DiscoverDevices(context *clusterd.Context) ([]*sys.LocalDisk, error) {
+	return clusterd.DiscoverDevices(context.Executor)
+}
+
+// commandDeviceDiscoverer runs a custom command that prints the names of the candidate devices, one per line,
+// for storage that the built-in discovery does not find, e.g. NVMe-oF devices. The names may be given with or
+// without the /dev/ prefix.
+type commandDeviceDiscoverer struct {
+	command []string
+}
+
+func (d *commandDeviceDiscoverer) DiscoverDevices(context *clusterd.Context) ([]*sys.LocalDisk, error) {
+	output, err := context.Executor.ExecuteCommandWithOutput(false, "discover devices", d.command[0], d.command[1:]...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run the device discovery command %q. %+v", strings.Join(d.command, " "), err)
+	}
+
+	var disks []*sys.LocalDisk
+	seen := map[string]bool{}
+	for _, line := range strings.Split(output, "\n") {
+		name := strings.TrimPrefix(strings.TrimSpace(line), "/dev/")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		disk := clusterd.PopulateDeviceInfo(name, context.Executor)
+		if disk == nil {
+			continue
+		}
+		disks = append(disks, clusterd.PopulateDeviceUdevInfo(name, context.Executor, disk))
+	}
+	logger.Infof("device discovery command %q found %d devices", strings.Join(d.command, " "), len(disks))
+	return disks, nil
+}
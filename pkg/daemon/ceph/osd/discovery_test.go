@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommandDeviceDiscoverer(t *testing.T) {
+	discoverOutput := "/dev/nvme1n1\nnvme2n1\n\nnvme1n1\n"
+	var discoverErr error
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, name string, command string, args ...string) (string, error) {
+			switch command {
+			case "list-nvmeof":
+				assert.Equal(t, []string{"--all"}, args)
+				return discoverOutput, discoverErr
+			case "lsblk":
+				device := strings.TrimPrefix(args[0], "/dev/")
+				return fmt.Sprintf(`NAME="%s" SIZE="100" TYPE="disk" PKNAME=""`, device), nil
+			case "udevadm", "sgdisk":
+				return "", nil
+			}
+			return "", fmt.Errorf("unknown command %s %+v", command, args)
+		},
+	}
+	context := &clusterd.Context{Executor: executor}
+
+	discoverer := NewDeviceDiscoverer("list-nvmeof --all")
+	devices, err := discoverer.DiscoverDevices(context)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(devices))
+	assert.Equal(t, "nvme1n1", devices[0].Name)
+	assert.Equal(t, "nvme2n1", devices[1].Name)
+
+	// the discovery fails if the command fails
+	discoverErr = fmt.Errorf("mock failure")
+	_, err = discoverer.DiscoverDevices(context)
+	assert.NotNil(t, err)
+
+	// the built-in discovery is the default
+	_, ok := NewDeviceDiscoverer(" ").(*localDeviceDiscoverer)
+	assert.True(t, ok)
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+const (
+	balancerModuleName = "balancer"
+	// the balancer mode if none is set in the spec
+	defaultBalancerMode = "upmap"
+)
+
+var (
+	// the interval of the retries of a balancing that waits for the PGs to recover
+	balanceRetryInterval = time.Minute
+	// how long the balancer runs after it was turned on for the added OSDs
+	balancerRunTime = time.Hour
+)
+
+// validateBalanceOSDs checks the mode of the balancer
+func validateBalanceOSDs(spec cephv1.BalanceOSDsSpec) error {
+	switch spec.Mode {
+	case "", "upmap", "crush-compat":
+		return nil
+	}
+	return fmt.Errorf("invalid balanceOSDs mode %q, it must be %q or %q", spec.Mode, "upmap", "crush-compat")
+}
+
+// countOSDs returns the number of OSDs in the cluster, or -1 if they cannot be listed
+func (c *cluster) countOSDs() int {
+	osds, err := client.OsdListNum(c.context, c.Info.Name)
+	if err != nil {
+		logger.Warningf("failed to list the osds of cluster %s. %+v", c.Namespace, err)
+		return -1
+	}
+	return len(osds)
+}
+
+// balanceOSDsAfterExpansion turns on the balancer when OSDs were added by the orchestration and balancing is enabled
+// in the spec or by the feature gate, so the data is spread evenly over the new OSDs. The other orchestrations do not
+// touch the balancer. The balancer is not started while the PGs are recovering, it is retried until the PGs are clean
+// instead. Once started, the balancer is turned off again after balancerRunTime.
+func (c *cluster) balanceOSDsAfterExpansion(spec cephv1.BalanceOSDsSpec, mgrSpec cephv1.MgrSpec, osdsBefore int) {
+	c.balanceMux.Lock()
+	defer c.balanceMux.Unlock()
+	if !c.balanceOSDsEnabled(spec) {
+		c.osdBalancePending = false
+		return
+	}
+	if osdsAfter := c.countOSDs(); osdsBefore >= 0 && osdsAfter > osdsBefore {
+		logger.Infof("%d osds were added to cluster %s, balancing the osds", osdsAfter-osdsBefore, c.Namespace)
+		c.osdBalancePending = true
+	}
+	c.balancePendingOSDs(spec, mgrSpec)
+}
+
+// balancePendingOSDs starts the balancer if the balancing is pending and the PGs are clean, or else schedules a
+// retry. The caller must hold balanceMux.
+func (c *cluster) balancePendingOSDs(spec cephv1.BalanceOSDsSpec, mgrSpec cephv1.MgrSpec) {
+	if !c.osdBalancePending {
+		return
+	}
+
+	msg, clean, err := client.IsClusterClean(c.context, c.Info.Name)
+	if err != nil {
+		logger.Warningf("failed to check whether the pgs of cluster %s are clean, retrying to balance the osds. %+v", c.Namespace, err)
+		c.scheduleBalanceRetry(spec, mgrSpec)
+		return
+	}
+	if !clean {
+		logger.Infof("not balancing the osds of cluster %s while the pgs are recovering, retrying in %s. %s", c.Namespace, balanceRetryInterval.String(), msg)
+		c.scheduleBalanceRetry(spec, mgrSpec)
+		return
+	}
+
+	if err := c.startBalancer(balancerMode(spec, mgrSpec)); err != nil {
+		logger.Errorf("failed to balance the osds of cluster %s. %+v", c.Namespace, err)
+		c.scheduleBalanceRetry(spec, mgrSpec)
+		return
+	}
+	c.osdBalancePending = false
+	c.scheduleBalancerStop(mgrSpec)
+}
+
+// scheduleBalanceRetry retries the pending balancing after balanceRetryInterval, unless a retry is already scheduled.
+// The caller must hold balanceMux.
+func (c *cluster) scheduleBalanceRetry(spec cephv1.BalanceOSDsSpec, mgrSpec cephv1.MgrSpec) {
+	if c.balanceRetryScheduled {
+		return
+	}
+	c.balanceRetryScheduled = true
+	time.AfterFunc(balanceRetryInterval, func() { c.retryBalanceOSDs(spec, mgrSpec) })
+}
+
+// retryBalanceOSDs retries the pending balancing, which is reset by the orchestrations that disable the balancing
+func (c *cluster) retryBalanceOSDs(spec cephv1.BalanceOSDsSpec, mgrSpec cephv1.MgrSpec) {
+	c.balanceMux.Lock()
+	defer c.balanceMux.Unlock()
+	c.balanceRetryScheduled = false
+	if c.ctx.Err() != nil {
+		return
+	}
+	c.balancePendingOSDs(spec, mgrSpec)
+}
+
+// scheduleBalancerStop turns off the balancer after balancerRunTime. The balancer stays on if the balancer module is
+// enabled in the mgr spec. The caller must hold balanceMux.
+func (c *cluster) scheduleBalancerStop(mgrSpec cephv1.MgrSpec) {
+	for _, module := range mgrSpec.Modules {
+		if module.Name == balancerModuleName {
+			return
+		}
+	}
+	if c.balancerStopTimer != nil {
+		c.balancerStopTimer.Stop()
+	}
+	c.balancerStopTimer = time.AfterFunc(balancerRunTime, c.stopBalancer)
+}
+
+// stopBalancer turns off the balancer that was turned on for the added OSDs
+func (c *cluster) stopBalancer() {
+	c.balanceMux.Lock()
+	defer c.balanceMux.Unlock()
+	c.balancerStopTimer = nil
+	if c.ctx.Err() != nil {
+		return
+	}
+	if err := client.BalancerOff(c.context, c.Info.Name); err != nil {
+		logger.Errorf("failed to turn off the balancer of cluster %s. %+v", c.Namespace, err)
+		return
+	}
+	logger.Infof("turned off the balancer of cluster %s after %s", c.Namespace, balancerRunTime.String())
+}
+
+// balanceOSDsEnabled returns whether the balancing is enabled in the spec or with the BalanceOSDs feature gate
+func (c *cluster) balanceOSDsEnabled(spec cephv1.BalanceOSDsSpec) bool {
+	return spec.Enabled || c.featureEnabled(featureBalanceOSDs)
+}
+
+// balancerMode returns the mode of the balancer from the balanceOSDs spec, or else the mode from the settings of the
+// balancer module in the mgr spec, so the mode set by the user is not overwritten, or else the default mode
+func balancerMode(spec cephv1.BalanceOSDsSpec, mgrSpec cephv1.MgrSpec) string {
+	if spec.Mode != "" {
+		return spec.Mode
+	}
+	for _, module := range mgrSpec.Modules {
+		if module.Name == balancerModuleName && module.Settings["mode"] != "" {
+			return module.Settings["mode"]
+		}
+	}
+	return defaultBalancerMode
+}
+
+func (c *cluster) startBalancer(mode string) error {
+	if err := client.MgrEnableModule(c.context, c.Info.Name, balancerModuleName, false); err != nil {
+		return fmt.Errorf("failed to enable the balancer module. %+v", err)
+	}
+	if err := client.BalancerSetMode(c.context, c.Info.Name, mode); err != nil {
+		return err
+	}
+	if err := client.BalancerOn(c.context, c.Info.Name); err != nil {
+		return err
+	}
+	logger.Infof("turned on the balancer of cluster %s in %s mode", c.Namespace, mode)
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBalanceOSDsAfterExpansion(t *testing.T) {
+	// the scheduled retries and the stop of the balancer are run by the test
+	defer func(retry, run time.Duration) { balanceRetryInterval, balancerRunTime = retry, run }(balanceRetryInterval, balancerRunTime)
+	balanceRetryInterval, balancerRunTime = time.Hour, time.Hour
+	osds := `[0,1,2]`
+	pgs := `{"pgmap":{"num_pgs":100,"pgs_by_state":[{"state_name":"active+clean","count":90},{"state_name":"active+remapped+backfilling","count":10}]}}`
+	balancerCommands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			switch args[0] {
+			case "osd":
+				return osds, nil
+			case "status":
+				return pgs, nil
+			case "balancer":
+				balancerCommands = append(balancerCommands, strings.Join(args[:2], " "))
+			}
+			return "", nil
+		},
+	}
+	c := &cluster{
+		Namespace: "ns",
+		Info:      &cephconfig.ClusterInfo{Name: "ns"},
+		context:   &clusterd.Context{Executor: executor},
+		ctx:       context.Background(),
+	}
+	spec := cephv1.BalanceOSDsSpec{Enabled: true}
+
+	// no osds were added
+	c.balanceOSDsAfterExpansion(spec, cephv1.MgrSpec{}, 3)
+	assert.False(t, c.osdBalancePending)
+	assert.Equal(t, 0, len(balancerCommands))
+
+	// osds were added while the pgs are recovering, a retry is scheduled
+	c.balanceOSDsAfterExpansion(spec, cephv1.MgrSpec{}, 2)
+	assert.True(t, c.osdBalancePending)
+	assert.True(t, c.balanceRetryScheduled)
+	assert.Equal(t, 0, len(balancerCommands))
+
+	// the retry starts the balancer when the pgs are clean, which is turned off later
+	pgs = `{"pgmap":{"num_pgs":100,"pgs_by_state":[{"state_name":"active+clean","count":100}]}}`
+	c.retryBalanceOSDs(spec, cephv1.MgrSpec{})
+	assert.False(t, c.osdBalancePending)
+	assert.False(t, c.balanceRetryScheduled)
+	assert.Equal(t, []string{"balancer mode", "balancer on"}, balancerCommands)
+	assert.NotNil(t, c.balancerStopTimer)
+	c.balancerStopTimer.Stop()
+	c.stopBalancer()
+	assert.Nil(t, c.balancerStopTimer)
+	assert.Equal(t, []string{"balancer mode", "balancer on", "balancer off"}, balancerCommands)
+
+	// the balancer stays on when the balancer module is enabled in the mgr spec
+	balancerCommands = []string{}
+	mgrSpec := cephv1.MgrSpec{Modules: []cephv1.MgrModuleSpec{{Name: "balancer"}}}
+	c.balanceOSDsAfterExpansion(spec, mgrSpec, 2)
+	assert.False(t, c.osdBalancePending)
+	assert.Equal(t, []string{"balancer mode", "balancer on"}, balancerCommands)
+	assert.Nil(t, c.balancerStopTimer)
+
+	// the balancer is not touched when balancing is disabled
+	balancerCommands = []string{}
+	c.balanceOSDsAfterExpansion(cephv1.BalanceOSDsSpec{}, cephv1.MgrSpec{}, 0)
+	assert.Equal(t, 0, len(balancerCommands))
+}
+
+func TestBalancerMode(t *testing.T) {
+	assert.Equal(t, "upmap", balancerMode(cephv1.BalanceOSDsSpec{}, cephv1.MgrSpec{}))
+
+	// the mode set in the settings of the balancer module is kept
+	mgrSpec := cephv1.MgrSpec{Modules: []cephv1.MgrModuleSpec{{Name: "balancer", Settings: map[string]string{"mode": "crush-compat"}}}}
+	assert.Equal(t, "crush-compat", balancerMode(cephv1.BalanceOSDsSpec{}, mgrSpec))
+
+	// the mode of the balanceOSDs spec takes precedence
+	assert.Equal(t, "upmap", balancerMode(cephv1.BalanceOSDsSpec{Mode: "upmap"}, mgrSpec))
+
+	assert.Nil(t, validateBalanceOSDs(cephv1.BalanceOSDsSpec{}))
+	assert.Nil(t, validateBalanceOSDs(cephv1.BalanceOSDsSpec{Mode: "crush-compat"}))
+	assert.NotNil(t, validateBalanceOSDs(cephv1.BalanceOSDsSpec{Mode: "none"}))
+}
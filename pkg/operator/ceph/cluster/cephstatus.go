@@ -39,6 +39,8 @@ type cephStatusChecker struct {
 	namespace    string
 	resourceName string
 	interval     time.Duration
+	// onStatus is called with the ceph status of each successful check
+	onStatus func(status *client.CephStatus)
 }
 
 // newCephStatusChecker creates a new HealthChecker object
@@ -88,6 +90,9 @@ func (c *cephStatusChecker) checkStatus() {
 	}
 
 	logger.Debugf("Cluster status: %+v", status)
+	if c.onStatus != nil {
+		c.onStatus(&status)
+	}
 	if err := c.updateCephStatus(&status); err != nil {
 		logger.Errorf("failed to query cluster status in namespace %s", c.namespace)
 	}
@@ -104,7 +109,7 @@ func (c *cephStatusChecker) updateCephStatus(status *client.CephStatus) error {
 
 	// translate the ceph status struct to the crd status
 	cluster.Status.CephStatus = toCustomResourceStatus(cluster.Status, status)
-	if _, err := c.context.RookClientset.CephV1().CephClusters(c.namespace).Update(cluster); err != nil {
+	if err := updateClusterStatus(c.context, cluster); err != nil {
 		return fmt.Errorf("failed to update cluster %s status: %+v", c.namespace, err)
 	}
 
@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+)
+
+// the default minutes to wait for the cluster to be healthy before the child controllers are notified
+const defaultChildNotificationTimeoutMinutes = 10
+
+// how often the health is checked while the notification of the child controllers is delayed
+var childNotificationInterval = 15 * time.Second
+
+// childNotification is a pending notification of the child controllers
+type childNotification struct {
+	spec        cephv1.ClusterSpec
+	clusterInfo *cephconfig.ClusterInfo
+	isUpgrade   bool
+}
+
+// queueChildNotification notifies the child controllers in the background. The notifications of a cluster are sent
+// by a single worker in the order of the orchestrations. A notification that is still pending when a newer one is
+// queued is dropped, since the children only need the latest spec of the cluster.
+func (c *cluster) queueChildNotification(ctx context.Context, spec cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade bool) {
+	c.childNotificationMux.Lock()
+	defer c.childNotificationMux.Unlock()
+	c.pendingChildNotification = &childNotification{spec: spec, clusterInfo: clusterInfo, isUpgrade: isUpgrade}
+	if c.childNotificationRunning {
+		return
+	}
+	c.childNotificationRunning = true
+	go c.sendChildNotifications(ctx)
+}
+
+// sendChildNotifications sends the pending notifications of the child controllers until none is left
+func (c *cluster) sendChildNotifications(ctx context.Context) {
+	for {
+		c.childNotificationMux.Lock()
+		n := c.pendingChildNotification
+		c.pendingChildNotification = nil
+		if n == nil {
+			c.childNotificationRunning = false
+			c.childNotificationMux.Unlock()
+			return
+		}
+		c.childNotificationMux.Unlock()
+		c.notifyChildControllers(ctx, n.spec, n.clusterInfo, n.isUpgrade)
+	}
+}
+
+// notifyChildControllers notifies the child controllers that the cluster spec might have changed. When the spec
+// asks to wait for a healthy cluster, the notification is delayed until the cluster is healthy or the timeout
+// elapsed, in which case the children are notified that the cluster is unhealthy. The children are not notified
+// when the context of the cluster is canceled while waiting, i.e. when the cluster is removed.
+func (c *cluster) notifyChildControllers(ctx context.Context, spec cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade bool) {
+	healthy := true
+	if spec.ChildNotification.WaitForHealthy {
+		timeoutMinutes := spec.ChildNotification.TimeoutMinutes
+		if timeoutMinutes <= 0 {
+			timeoutMinutes = defaultChildNotificationTimeoutMinutes
+		}
+		var stopped bool
+		healthy, stopped = c.waitForHealthy(ctx, time.Duration(timeoutMinutes)*time.Minute)
+		if stopped {
+			logger.Infof("not notifying the child controllers since cluster %s is being removed", c.Namespace)
+			return
+		}
+		if !healthy {
+			logger.Warningf("cluster %s is not healthy after %d minutes, notifying the child controllers anyway", c.Namespace, timeoutMinutes)
+		}
+	}
+
+	for _, child := range c.childControllers {
+		child.ParentClusterChanged(spec, clusterInfo, isUpgrade, healthy)
+	}
+}
+
+// waitForHealthy waits up to the timeout for the cluster to be healthy. It returns whether the cluster is healthy
+// and whether the wait was stopped since the context was canceled.
+func (c *cluster) waitForHealthy(ctx context.Context, timeout time.Duration) (bool, bool) {
+	deadline := time.Now().Add(timeout)
+	for {
+		if client.IsCephHealthy(c.context, c.Info.Name) {
+			return true, false
+		}
+		if !time.Now().Before(deadline) {
+			return false, false
+		}
+		logger.Infof("waiting for cluster %s to be healthy before notifying the child controllers", c.Namespace)
+		select {
+		case <-ctx.Done():
+			return false, true
+		case <-time.After(childNotificationInterval):
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeChildController struct {
+	notifications []bool
+	upgrades      []bool
+}
+
+func (f *fakeChildController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade, healthy bool) {
+	f.notifications = append(f.notifications, healthy)
+	f.upgrades = append(f.upgrades, isUpgrade)
+}
+
+func TestNotifyChildControllers(t *testing.T) {
+	childNotificationInterval = time.Millisecond
+	health := "HEALTH_ERR"
+	statusCalls := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			if args[0] == "status" {
+				statusCalls++
+				if statusCalls > 2 {
+					health = "HEALTH_WARN"
+				}
+				return `{"health":{"status":"` + health + `"}}`, nil
+			}
+			return "", nil
+		},
+	}
+	child := &fakeChildController{}
+	c := &cluster{
+		Namespace:        "ns",
+		Info:             &cephconfig.ClusterInfo{Name: "ns"},
+		Spec:             &cephv1.ClusterSpec{},
+		context:          &clusterd.Context{Executor: executor},
+		childControllers: []childController{child},
+	}
+	info := &cephconfig.ClusterInfo{}
+
+	// the children are notified right away by default
+	c.notifyChildControllers(context.TODO(), cephv1.ClusterSpec{}, info, false)
+	assert.Equal(t, []bool{true}, child.notifications)
+	assert.Equal(t, 0, statusCalls)
+
+	// the notification waits for the cluster to be healthy
+	waitSpec := cephv1.ClusterSpec{ChildNotification: cephv1.ChildNotificationSpec{WaitForHealthy: true}}
+	c.notifyChildControllers(context.TODO(), waitSpec, info, false)
+	assert.Equal(t, []bool{true, true}, child.notifications)
+	assert.Equal(t, 3, statusCalls)
+
+	// the cluster is still unhealthy after the timeout
+	health = "HEALTH_ERR"
+	statusCalls = -100
+	healthy, stopped := c.waitForHealthy(context.TODO(), 10*time.Millisecond)
+	assert.False(t, healthy)
+	assert.False(t, stopped)
+
+	// the children are not notified when the cluster is removed while waiting
+	childNotificationInterval = time.Hour
+	ctx, cancel := context.WithCancel(context.TODO())
+	cancel()
+	c.notifyChildControllers(ctx, waitSpec, info, false)
+	assert.Equal(t, []bool{true, true}, child.notifications)
+}
+
+func TestQueueChildNotification(t *testing.T) {
+	child := &fakeChildController{}
+	c := &cluster{Namespace: "ns", Spec: &cephv1.ClusterSpec{}, childControllers: []childController{child}}
+	info := &cephconfig.ClusterInfo{}
+
+	// the notification queued while the worker runs is sent by the running worker
+	c.childNotificationRunning = true
+	c.queueChildNotification(context.TODO(), cephv1.ClusterSpec{}, info, false)
+	c.queueChildNotification(context.TODO(), cephv1.ClusterSpec{}, info, true)
+	assert.Equal(t, 0, len(child.notifications))
+
+	// only the latest pending notification is sent
+	c.sendChildNotifications(context.TODO())
+	assert.Equal(t, []bool{true}, child.upgrades)
+	assert.False(t, c.childNotificationRunning)
+	assert.Nil(t, c.pendingChildNotification)
+
+	// a new worker is started when none is running
+	c.queueChildNotification(context.TODO(), cephv1.ClusterSpec{}, info, false)
+	for i := 0; i < 100; i++ {
+		c.childNotificationMux.Lock()
+		running := c.childNotificationRunning
+		c.childNotificationMux.Unlock()
+		if !running {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	c.childNotificationMux.Lock()
+	assert.Equal(t, []bool{true, false}, child.upgrades)
+	c.childNotificationMux.Unlock()
+}
@@ -0,0 +1,225 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+)
+
+const (
+	// the env var to set the number of failed orchestrations caused by api server errors after which the
+	// orchestrations are paused. 0 disables the circuit breaker.
+	apiCircuitBreakerThresholdEnvVar  = "ROOK_API_CIRCUIT_BREAKER_THRESHOLD"
+	defaultAPICircuitBreakerThreshold = 5
+	// the failures older than the window are not counted
+	apiCircuitBreakerWindow = 10 * time.Minute
+	// how long the orchestrations are paused before the recovery of the api server is tested
+	apiCircuitBreakerCooldown = 2 * time.Minute
+)
+
+type circuitState string
+
+const (
+	circuitClosed   circuitState = "closed"
+	circuitOpen     circuitState = "open"
+	circuitHalfOpen circuitState = "half-open"
+)
+
+// apiCircuitBreaker pauses the orchestrations of all the clusters when the orchestrations keep failing because of
+// api server errors, so the retries of the operator do not add to the load of a struggling api server. After the
+// cooldown a single orchestration is allowed to test whether the api server recovered. The orchestrations that were
+// paused are retried after the cooldown, and all of them once the breaker closed.
+type apiCircuitBreaker struct {
+	mux       sync.Mutex
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	state     circuitState
+	failures  []time.Time
+	openedAt  time.Time
+	// whether the orchestration that tests the recovery is running in the half-open state
+	trialRunning bool
+	// the retries of the paused orchestrations by the name of their cluster
+	paused map[string]func()
+	// whether the retry of the paused orchestrations is scheduled
+	retryScheduled bool
+	// checks whether the api server responds
+	apiAvailable func() bool
+	now          func() time.Time
+	// runs the function after the delay
+	schedule func(delay time.Duration, f func())
+}
+
+// newAPICircuitBreaker creates a circuit breaker that tests the api server of the context
+func newAPICircuitBreaker(context *clusterd.Context, threshold int) *apiCircuitBreaker {
+	return &apiCircuitBreaker{
+		threshold: threshold,
+		window:    apiCircuitBreakerWindow,
+		cooldown:  apiCircuitBreakerCooldown,
+		state:     circuitClosed,
+		apiAvailable: func() bool {
+			if _, err := context.Clientset.Discovery().ServerVersion(); err != nil {
+				logger.Warningf("failed to reach the api server. %+v", err)
+				return false
+			}
+			return true
+		},
+		now:      time.Now,
+		schedule: func(delay time.Duration, f func()) { time.AfterFunc(delay, f) },
+	}
+}
+
+// apiCircuitBreakerThreshold parses the threshold of the circuit breaker
+func apiCircuitBreakerThreshold(value string) int {
+	if value == "" {
+		return defaultAPICircuitBreakerThreshold
+	}
+	threshold, err := strconv.Atoi(value)
+	if err != nil || threshold < 0 {
+		logger.Warningf("invalid value %q for %s, it must be a number of at least 0. using %d", value, apiCircuitBreakerThresholdEnvVar, defaultAPICircuitBreakerThreshold)
+		return defaultAPICircuitBreakerThreshold
+	}
+	return threshold
+}
+
+// allow returns whether an orchestration may run. When it returns true, the result of the orchestration must be
+// reported with done, or with cancel if no orchestration was run.
+func (b *apiCircuitBreaker) allow() bool {
+	if b == nil || b.threshold == 0 {
+		return true
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		logger.Infof("testing whether the api server recovered before resuming the orchestrations")
+		b.state = circuitHalfOpen
+		b.trialRunning = true
+		return true
+	case circuitHalfOpen:
+		// only a single orchestration tests the recovery
+		if b.trialRunning {
+			return false
+		}
+		b.trialRunning = true
+		return true
+	}
+	return true
+}
+
+// cancel reports that no orchestration was run after it was allowed, e.g. because another orchestration of the
+// cluster was already running
+func (b *apiCircuitBreaker) cancel() {
+	if b == nil || b.threshold == 0 {
+		return
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.trialRunning = false
+}
+
+// pause records the retry of an orchestration that was not allowed, which is run after the cooldown or once the
+// breaker closed. Only the latest retry of a cluster is kept.
+func (b *apiCircuitBreaker) pause(name string, retry func()) {
+	if b == nil || b.threshold == 0 {
+		return
+	}
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	if b.paused == nil {
+		b.paused = map[string]func(){}
+	}
+	b.paused[name] = retry
+	if b.retryScheduled {
+		return
+	}
+	delay := b.cooldown
+	if b.state == circuitOpen {
+		delay = b.cooldown - b.now().Sub(b.openedAt)
+	}
+	b.retryScheduled = true
+	b.schedule(delay, b.resume)
+}
+
+// resume runs the retries of the paused orchestrations. The retries that are still not allowed pause again.
+func (b *apiCircuitBreaker) resume() {
+	b.mux.Lock()
+	paused := b.paused
+	b.paused = nil
+	b.retryScheduled = false
+	b.mux.Unlock()
+	for name, retry := range paused {
+		logger.Infof("retrying the paused orchestration of cluster %s", name)
+		go retry()
+	}
+}
+
+// done reports the result of an orchestration. A failed orchestration is only counted if the api server does
+// not respond, since most orchestration errors are not caused by the api server.
+func (b *apiCircuitBreaker) done(orchestrationErr error) {
+	if b == nil || b.threshold == 0 {
+		return
+	}
+	apiFailed := orchestrationErr != nil && !b.apiAvailable()
+
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	b.trialRunning = false
+
+	if !apiFailed {
+		if b.state != circuitClosed {
+			logger.Infof("the api server recovered. resuming the orchestrations")
+			// the paused orchestrations do not wait for the scheduled retry
+			if len(b.paused) > 0 {
+				go b.resume()
+			}
+		}
+		b.state = circuitClosed
+		b.failures = nil
+		return
+	}
+
+	now := b.now()
+	if b.state == circuitHalfOpen {
+		logger.Warningf("the api server did not recover. pausing the orchestrations for %s", b.cooldown)
+		b.state = circuitOpen
+		b.openedAt = now
+		return
+	}
+
+	// only count the failures within the window
+	failures := []time.Time{}
+	for _, failure := range b.failures {
+		if now.Sub(failure) < b.window {
+			failures = append(failures, failure)
+		}
+	}
+	b.failures = append(failures, now)
+	if b.state == circuitClosed && len(b.failures) >= b.threshold {
+		logger.Errorf("%d orchestrations failed because of api server errors within %s. pausing the orchestrations for %s", len(b.failures), b.window, b.cooldown)
+		b.state = circuitOpen
+		b.openedAt = now
+	}
+}
@@ -0,0 +1,173 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestCircuitBreaker(threshold int) (*apiCircuitBreaker, *bool, *time.Time) {
+	apiUp := true
+	now := time.Now()
+	b := &apiCircuitBreaker{
+		threshold:    threshold,
+		window:       time.Minute,
+		cooldown:     30 * time.Second,
+		state:        circuitClosed,
+		apiAvailable: func() bool { return apiUp },
+		now:          func() time.Time { return now },
+		schedule:     func(delay time.Duration, f func()) {},
+	}
+	return b, &apiUp, &now
+}
+
+func TestCircuitBreakerOpens(t *testing.T) {
+	b, apiUp, now := newTestCircuitBreaker(3)
+	orchestrationErr := fmt.Errorf("failed")
+
+	// failures that are not caused by the api server are not counted
+	for i := 0; i < 5; i++ {
+		assert.True(t, b.allow())
+		b.done(orchestrationErr)
+	}
+	assert.Equal(t, circuitClosed, b.state)
+
+	// the breaker opens after the threshold of api errors
+	*apiUp = false
+	for i := 0; i < 3; i++ {
+		assert.True(t, b.allow())
+		b.done(orchestrationErr)
+	}
+	assert.Equal(t, circuitOpen, b.state)
+	assert.False(t, b.allow())
+
+	// after the cooldown a single orchestration tests the recovery
+	*now = now.Add(31 * time.Second)
+	assert.True(t, b.allow())
+	assert.Equal(t, circuitHalfOpen, b.state)
+	assert.False(t, b.allow())
+
+	// the api is still failing, so the breaker opens again
+	b.done(orchestrationErr)
+	assert.Equal(t, circuitOpen, b.state)
+	assert.False(t, b.allow())
+
+	// the api recovered
+	*now = now.Add(31 * time.Second)
+	*apiUp = true
+	assert.True(t, b.allow())
+	b.done(nil)
+	assert.Equal(t, circuitClosed, b.state)
+	assert.True(t, b.allow())
+	assert.True(t, b.allow())
+}
+
+func TestCircuitBreakerWindow(t *testing.T) {
+	b, apiUp, now := newTestCircuitBreaker(3)
+	*apiUp = false
+	orchestrationErr := fmt.Errorf("failed")
+
+	// the failures outside of the window are not counted
+	for i := 0; i < 5; i++ {
+		assert.True(t, b.allow())
+		b.done(orchestrationErr)
+		*now = now.Add(40 * time.Second)
+	}
+	assert.Equal(t, circuitClosed, b.state)
+
+	// a success resets the failures
+	b.done(nil)
+	assert.Equal(t, 0, len(b.failures))
+}
+
+func TestCircuitBreakerCancel(t *testing.T) {
+	b, apiUp, now := newTestCircuitBreaker(1)
+	*apiUp = false
+	assert.True(t, b.allow())
+	b.done(fmt.Errorf("failed"))
+	assert.Equal(t, circuitOpen, b.state)
+
+	// a canceled trial allows another orchestration to test the recovery
+	*now = now.Add(31 * time.Second)
+	assert.True(t, b.allow())
+	b.cancel()
+	assert.Equal(t, circuitHalfOpen, b.state)
+	assert.True(t, b.allow())
+}
+
+func TestCircuitBreakerRetriesPaused(t *testing.T) {
+	b, apiUp, now := newTestCircuitBreaker(1)
+	var delays []time.Duration
+	b.schedule = func(delay time.Duration, f func()) { delays = append(delays, delay) }
+	retried := make(chan string, 2)
+
+	*apiUp = false
+	assert.True(t, b.allow())
+	b.done(fmt.Errorf("failed"))
+	assert.False(t, b.allow())
+
+	// the paused orchestrations are retried once after the rest of the cooldown
+	*now = now.Add(10 * time.Second)
+	b.pause("ns1", func() { retried <- "ns1" })
+	b.pause("ns2", func() { retried <- "ns2" })
+	assert.Equal(t, []time.Duration{20 * time.Second}, delays)
+	assert.Equal(t, 2, len(b.paused))
+
+	// the scheduled retry runs the paused orchestrations
+	b.resume()
+	assert.ElementsMatch(t, []string{"ns1", "ns2"}, []string{<-retried, <-retried})
+	assert.Equal(t, 0, len(b.paused))
+	assert.False(t, b.retryScheduled)
+
+	// an orchestration that is paused while the recovery is tested is retried once the breaker closed
+	*now = now.Add(31 * time.Second)
+	*apiUp = true
+	assert.True(t, b.allow())
+	b.pause("ns1", func() { retried <- "ns1" })
+	b.done(nil)
+	assert.Equal(t, circuitClosed, b.state)
+	assert.Equal(t, "ns1", <-retried)
+
+	// the disabled breaker does not pause
+	var nilBreaker *apiCircuitBreaker
+	nilBreaker.pause("ns1", func() {})
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b, apiUp, _ := newTestCircuitBreaker(0)
+	*apiUp = false
+	for i := 0; i < 10; i++ {
+		assert.True(t, b.allow())
+		b.done(fmt.Errorf("failed"))
+	}
+
+	// the clusters of the unit tests have no breaker
+	var nilBreaker *apiCircuitBreaker
+	assert.True(t, nilBreaker.allow())
+	nilBreaker.done(fmt.Errorf("failed"))
+	nilBreaker.cancel()
+
+	assert.Equal(t, 5, apiCircuitBreakerThreshold(""))
+	assert.Equal(t, 0, apiCircuitBreakerThreshold("0"))
+	assert.Equal(t, 10, apiCircuitBreakerThreshold("10"))
+	assert.Equal(t, 5, apiCircuitBreakerThreshold("-1"))
+	assert.Equal(t, 5, apiCircuitBreakerThreshold("abc"))
+}
@@ -18,9 +18,14 @@ limitations under the License.
 package cluster
 
 import (
+	"context"
 	"fmt"
+	"os"
 	"reflect"
+	"regexp"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -42,37 +47,172 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 const (
 	detectVersionName = "rook-ceph-detect-version"
+	// the env var on the operator to keep the job that detects the ceph version, e.g. for debugging
+	keepVersionJobEnvVar = "ROOK_CEPH_KEEP_VERSION_JOB"
+	// the env var on the operator to run the job that detects the ceph version with another image than the ceph
+	// image of the cluster, e.g. a lighter image with the same ceph version in air-gapped environments
+	versionJobImageEnvVar = "ROOK_CEPH_VERSION_JOB_IMAGE"
+	// allowUnsupportedOnceAnnotation on the CephCluster CR allows an unsupported ceph version for a single
+	// orchestration without setting allowUnsupported in the spec. The annotation is removed once it was used.
+	allowUnsupportedOnceAnnotation = "ceph.rook.io/allow-unsupported-once"
+	// rotateAdminKeyAnnotation on the CephCluster CR requests a new admin key. The annotation is removed once
+	// the key was rotated.
+	rotateAdminKeyAnnotation = "ceph.rook.io/rotate-admin-key"
+	// confirmNodeRemovalAnnotation on the CephCluster CR confirms the removal of the OSDs of the nodes that
+	// were removed from the storage spec. The value is a comma separated list of the node names. The annotation
+	// is removed once the nodes were removed.
+	confirmNodeRemovalAnnotation = "ceph.rook.io/confirm-node-removal"
+	// mgrDebugLevelAnnotation on the CephCluster CR sets the debug_mgr level of the mgr daemons, e.g. "20". The
+	// level is applied without an orchestration and reset to the default when the annotation is removed.
+	mgrDebugLevelAnnotation = "ceph.rook.io/mgr-debug-level"
+	// osdOutAnnotation on the CephCluster CR marks an OSD out and stops it for maintenance, e.g. "5" for osd.5. The
+	// data of the OSD is kept. The OSD is marked in and started again when the annotation is removed.
+	osdOutAnnotation = "ceph.rook.io/osd-out"
 )
 
+// errNamespaceTerminating is returned instead of orchestrating a cluster whose namespace is being deleted. The
+// callers must not retry the orchestration nor report its state.
+var errNamespaceTerminating = fmt.Errorf("the namespace is being deleted")
+
+// matches the ceph version in the tag of an image, e.g. ceph/ceph:v14.2.4-20190917
+var imageTagVersionPattern = regexp.MustCompile(`:v?(\d{1,3})(?:\.(\d+))?(?:\.(\d+))?(?:-[^:/]*)?$`)
+
+// matches the pinned ceph version of the spec, e.g. 14.2.4 or the release candidate 15.1.0-rc
+var pinnedVersionPattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+(-rc)?$`)
+
+// matches the tag of an image of a release candidate, e.g. ceph/ceph:v15.1.0-rc1
+var imageTagPreReleasePattern = regexp.MustCompile(`:v?\d[^:/]*-rc[^:/]*$`)
+
+// the default minutes to wait for the PGs to be active+clean at the end of an orchestration
+const defaultWaitForCleanPGsTimeoutMinutes = 30
+
+// how often the PGs are checked while waiting for them to be active+clean
+var waitForCleanPGsInterval = 15 * time.Second
+
+// the retries of the creation of the override configmap, for about 15s in total
+var overrideConfigMapBackoff = wait.Backoff{
+	Duration: 1 * time.Second,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
 type cluster struct {
-	Info                 *cephconfig.ClusterInfo
-	context              *clusterd.Context
-	Namespace            string
-	Spec                 *cephv1.ClusterSpec
-	crdName              string
-	mons                 *mon.Cluster
-	initCompleted        bool
-	stopCh               chan struct{}
-	ownerRef             metav1.OwnerReference
-	orchestrationRunning bool
-	orchestrationNeeded  bool
-	orchMux              sync.Mutex
-	childControllers     []childController
-	isUpgrade            bool
+	Info      *cephconfig.ClusterInfo
+	context   *clusterd.Context
+	Namespace string
+	Spec      *cephv1.ClusterSpec
+	crdName   string
+	mons      *mon.Cluster
+	// initCompleted is set once the daemons of the cluster are ready, guarded by initMux
+	initCompleted bool
+	// orchestrated is set once an orchestration of the cluster succeeded, guarded by initMux
+	orchestrated bool
+	// readinessCheckRunning is set while the readiness of the daemons is checked, guarded by initMux
+	readinessCheckRunning bool
+	initMux               sync.Mutex
+	stopCh                chan struct{}
+	ownerRef              metav1.OwnerReference
+	orchestrationRunning  bool
+	orchestrationNeeded   bool
+	orchMux               sync.Mutex
+	childControllers      []childController
+	isUpgrade             bool
+	// the number of spec changes since the last orchestration started, guarded by orchMux
+	pendingOrchestrations int
+	// notified when an upgrade starts and finishes
+	upgradeNotifier UpgradeNotifier
+	// pauses the orchestrations after repeated api server errors, shared by all the clusters
+	apiCircuitBreaker *apiCircuitBreaker
+	// limits the number of clusters orchestrated at the same time, shared by all the clusters
+	orchestrationLimiter *orchestrationLimiter
+	// traces the orchestrations
+	tracer Tracer
+	// how the changes of the spec are logged, "full" or "paths"
+	specDiffLogging string
+	// the context of the orchestrations, canceled when the cluster is deleted
+	ctx    context.Context
+	cancel context.CancelFunc
+	// when the CephCluster CR was created, which decides which cluster keeps a shared dataDirHostPath
+	creationTimestamp metav1.Time
+	// whether the start of an upgrade was notified, but not yet its completion, guarded by orchMux
+	upgradeInProgress bool
+	// incremented by each upgrade that is started, so an orchestration only completes the upgrade it orchestrated,
+	// guarded by orchMux
+	upgradeGeneration int
+	// the latest notification of the child controllers that was not sent yet and whether the worker that sends
+	// the notifications is running, guarded by childNotificationMux
+	pendingChildNotification *childNotification
+	childNotificationRunning bool
+	childNotificationMux     sync.Mutex
+	// the resourceVersion of the CephCluster CR that was last processed by the controller, guarded by crMux
+	lastResourceVersion string
+	// guards the state read from the CephCluster CR, which the debug endpoint reads from another goroutine
+	crMux sync.Mutex
+	// the number of mons started by the last orchestration, which differs from the spec when the mons are scaled
+	// automatically
+	startedMonCount int
+	// the generation of the CephCluster CR that the Spec was read from, guarded by crMux
+	specGeneration int64
+	// whether the ceph version detected from the image is a development build or a release candidate
+	cephPreRelease bool
+	// whether an unsupported ceph version is allowed until the next successful orchestration
+	allowUnsupportedOnce bool
+	// whether an unsupported ceph version was allowed by the annotation, which is removed once the orchestration succeeded
+	unsupportedAllowedOnce bool
+	// whether the admin key should be rotated in the next orchestration
+	rotateAdminKeyRequested bool
+	// the removed nodes of which the removal of the OSDs was confirmed, guarded by crMux
+	confirmedNodeRemovals []string
+	// whether OSDs were added, but the balancer was not started yet since the PGs were recovering, whether a retry of
+	// the balancing is scheduled and the timer that turns off the balancer, guarded by balanceMux
+	balanceMux            sync.Mutex
+	osdBalancePending     bool
+	balanceRetryScheduled bool
+	balancerStopTimer     *time.Timer
+	// whether the change of the network of the running cluster was confirmed
+	networkChangeConfirmed bool
+	// the pending network change that was reported with an event, so the event is not repeated for each update
+	reportedNetworkChange string
+	// the key messages of the running orchestration, reported in the status if it fails
+	orchestrationLog orchestrationLog
+	// the mgr debug level requested by the annotation of the CR, and the level that was applied to the mgrs. The
+	// level is applied by the update events and by the orchestrations, so both are guarded by mgrDebugMux.
+	mgrDebugMux            sync.Mutex
+	requestedMgrDebugLevel string
+	mgrDebugLevel          string
+	// the OSD requested to be out by the annotation of the CR, and the OSD that was marked out and stopped, guarded by
+	// osdOutMux
+	osdOutMux       sync.Mutex
+	requestedOSDOut string
+	outOSD          string
+	// the content of the override configmap when it was last seen, which is restored if the configmap is deleted
+	lastOverrideConfig string
+	// when the last orchestration completed, guarded by orchMux
+	lastOrchestrationTime time.Time
+	// the feature gates of the spec of the running orchestration
+	featureGates map[string]bool
+	// the health summary from the last check of the ceph status checker
+	healthMux     sync.Mutex
+	healthSummary *CephHealthSummary
 }
 
 // ChildController is implemented by CRs that are owned by the CephCluster
 type childController interface {
-	// ParentClusterChanged is called when the CephCluster CR is updated, for example for a newer ceph version
-	ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade bool)
+	// ParentClusterChanged is called when the CephCluster CR is updated, for example for a newer ceph version.
+	// healthy is false when the cluster was still unhealthy after the wait for a healthy cluster timed out.
+	ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade, healthy bool)
 }
 
-func newCluster(c *cephv1.CephCluster, context *clusterd.Context, csiMutex *sync.Mutex) *cluster {
+func newCluster(c *cephv1.CephCluster, clusterdContext *clusterd.Context, csiMutex *sync.Mutex) *cluster {
 	ownerRef := ClusterOwnerRef(c.Name, string(c.UID))
+	ctx, cancel := context.WithCancel(context.Background())
 	return &cluster{
 		// at this phase of the cluster creation process, the identity components of the cluster are
 		// not yet established. we reserve this struct which is filled in as soon as the cluster's
@@ -80,32 +220,123 @@ func newCluster(c *cephv1.CephCluster, context *clusterd.Context, csiMutex *sync
 		Info:      nil,
 		Namespace: c.Namespace,
 		Spec:      &c.Spec,
-		context:   context,
+		context:   clusterdContext,
 		crdName:   c.Name,
 		stopCh:    make(chan struct{}),
 		ownerRef:  ownerRef,
+		// the oldest cluster keeps its dataDirHostPath when the paths of the clusters overlap
+		creationTimestamp: c.CreationTimestamp,
 		// we set isUpgrade to false since it's a new cluster
-		mons: mon.New(context, c.Namespace, c.Spec.DataDirHostPath, c.Spec.Network, ownerRef, csiMutex, false),
+		mons: mon.New(clusterdContext, c.Namespace, c.Spec.DataDirHostPath, c.Spec.Network, ownerRef, csiMutex, false),
+		// the operator can replace the notifier of the upgrades
+		upgradeNotifier: nullUpgradeNotifier{},
+		tracer:          nullTracer{},
+		specDiffLogging: defaultSpecDiffLogging,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+}
+
+// cancelOrchestration cancels the running orchestration of the cluster, if any, and the orchestrations that
+// would start later
+func (c *cluster) cancelOrchestration() {
+	if c.cancel != nil {
+		c.cancel()
+	}
+}
+
+// cephImageVersion returns the pinned version of the spec, or else loads the ceph version from the image
+func (c *cluster) cephImageVersion(ctx context.Context, rookImage string, versionSpec cephv1.CephVersionSpec, timeout time.Duration) (*cephver.CephVersion, error) {
+	c.cephPreRelease = false
+	if versionSpec.PinnedVersion == "" {
+		return c.detectCephVersion(ctx, rookImage, versionSpec, timeout)
+	}
+	version, err := pinnedCephVersion(versionSpec.PinnedVersion)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkPinnedVersionTag(versionSpec.Image, *version); err != nil {
+		return nil, err
+	}
+	// the image is not run, so a release candidate is only known from the pinned version or the tag of the image
+	c.cephPreRelease = cephver.IsPreRelease("ceph version "+strings.TrimPrefix(versionSpec.PinnedVersion, "v")) ||
+		imageTagPreReleasePattern.MatchString(versionSpec.Image)
+	logger.Infof("skipped the detection of the ceph image version for image %s, using the pinned version %s", versionSpec.Image, version)
+	return version, nil
+}
+
+// pinnedCephVersion parses the pinned ceph version of the spec
+func pinnedCephVersion(pinned string) (*cephver.CephVersion, error) {
+	if !pinnedVersionPattern.MatchString(pinned) {
+		return nil, fmt.Errorf("invalid pinned ceph version %q, it must be in the form 14.2.4 or 15.1.0-rc", pinned)
 	}
+	version, err := cephver.ExtractCephVersion("ceph version " + strings.TrimPrefix(pinned, "v"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the pinned ceph version. %+v", err)
+	}
+	return version, nil
 }
 
-// detectCephVersion loads the ceph version from the image and checks that it meets the version requirements to
-// run in the cluster
-func (c *cluster) detectCephVersion(rookImage, cephImage string, timeout time.Duration) (*cephver.CephVersion, error) {
-	logger.Infof("detecting the ceph image version for image %s...", cephImage)
+// checkPinnedVersionTag checks that the pinned version matches the parts of the version in the tag of the image, so
+// the daemons don't run another version than pinned. An image without a version in its tag cannot be checked.
+func checkPinnedVersionTag(image string, pinned cephver.CephVersion) error {
+	match := imageTagVersionPattern.FindStringSubmatch(image)
+	if match == nil {
+		logger.Warningf("the pinned ceph version %s cannot be validated since the tag of image %s has no version", pinned.String(), image)
+		return nil
+	}
+	for i, part := range []int{pinned.Major, pinned.Minor, pinned.Extra} {
+		if match[i+1] != "" && match[i+1] != strconv.Itoa(part) {
+			return fmt.Errorf("the pinned ceph version %s does not match the tag of image %s", pinned.String(), image)
+		}
+	}
+	return nil
+}
+
+// detectCephVersion loads the ceph version from the image of the version spec and checks that it meets the version
+// requirements to run in the cluster. The job is configured from the version spec that is detected, which is not yet
+// the spec of the cluster during an update.
+func (c *cluster) detectCephVersion(ctx context.Context, rookImage string, versionSpec cephv1.CephVersionSpec, timeout time.Duration) (*cephver.CephVersion, error) {
+	cephImage := versionSpec.Image
+	if label := os.Getenv(versionImageLabelEnvVar); label != "" {
+		version, preRelease, err := versionFromImageLabel(ctx, cephImage, label)
+		if err == nil {
+			logger.Infof("Detected ceph image version from label %s of image %s: %s", label, cephImage, version)
+			c.cephPreRelease = preRelease
+			return version, nil
+		}
+		logger.Warningf("failed to read the ceph version from label %s of image %s, detecting it with a job instead. %+v", label, cephImage, err)
+	}
+
+	jobImage := versionJobImage(cephImage)
+	if jobImage != cephImage {
+		logger.Infof("detecting the ceph image version for image %s with image %s...", cephImage, jobImage)
+	} else {
+		logger.Infof("detecting the ceph image version for image %s...", cephImage)
+	}
 	versionReporter, err := cmdreporter.New(
 		c.context.Clientset, &c.ownerRef,
 		detectVersionName, detectVersionName, c.Namespace,
 		[]string{"ceph"}, []string{"--version"},
-		rookImage, cephImage)
+		rookImage, jobImage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to set up ceph version job. %+v", err)
 	}
 
 	job := versionReporter.Job()
+	k8sutil.AddRookVersionAnnotationToObjectMeta(&job.ObjectMeta)
 	job.Spec.Template.Spec.ServiceAccountName = "rook-ceph-cmd-reporter"
+	setJobResources(&job.Spec.Template.Spec, versionSpec.ImageJobResources)
+	job.Spec.Template.Spec.NodeSelector = versionJobNodeSelector(c.Spec)
+	logger.Debugf("node selector of the ceph version job: %v", job.Spec.Template.Spec.NodeSelector)
+	job.Spec.Template.Spec.PriorityClassName = c.Spec.PriorityClassNames.All()
+	c.Spec.Network.ApplyDNSToPodSpec(&job.Spec.Template.Spec)
+	c.Spec.CABundle.ApplyToPodSpec(&job.Spec.Template.Spec)
+	job.Spec.Template.Spec.SchedulerName = c.Spec.SchedulerName
+	versionSpec.ApplyImagePullPolicyToPodSpec(&job.Spec.Template.Spec)
+	versionReporter.KeepJob = os.Getenv(keepVersionJobEnvVar) == "true"
 
-	stdout, stderr, retcode, err := versionReporter.Run(timeout)
+	stdout, stderr, retcode, err := versionReporter.Run(ctx, timeout)
 	if err != nil {
 		return nil, fmt.Errorf("failed to complete ceph version job. %+v", err)
 	}
@@ -120,18 +351,117 @@ func (c *cluster) detectCephVersion(rookImage, cephImage string, timeout time.Du
 		return nil, fmt.Errorf("failed to extract ceph version. %+v", err)
 	}
 	logger.Infof("Detected ceph image version: %s", version)
+	c.cephPreRelease = cephver.IsPreRelease(stdout)
+	if jobImage != cephImage {
+		checkVersionJobImage(cephImage, jobImage, *version)
+	}
 	return version, nil
 }
 
+// versionJobImage returns the image that runs the job to detect the version of the ceph image, which is the ceph
+// image unless another image is set in the env of the operator
+func versionJobImage(cephImage string) string {
+	if image := os.Getenv(versionJobImageEnvVar); image != "" {
+		return image
+	}
+	return cephImage
+}
+
+// checkVersionJobImage warns if the version detected with another image than the ceph image does not belong to
+// the ceph release in the tag of the ceph image, since the daemons would then run another release than detected
+func checkVersionJobImage(cephImage, jobImage string, detected cephver.CephVersion) {
+	expected, ok := versionFromImageTag(cephImage)
+	if !ok {
+		logger.Warningf("the ceph version was detected with image %s, but it cannot be validated since the tag of image %s has no version", jobImage, cephImage)
+		return
+	}
+	if expected.Major != detected.Major {
+		logger.Warningf("image %s used to detect the ceph version reports ceph %s, but the tag of image %s is ceph %d (%s). set %s to an image of the same ceph release",
+			jobImage, detected.String(), cephImage, expected.Major, expected.ReleaseName(), versionJobImageEnvVar)
+	}
+}
+
+// versionFromImageTag returns the ceph version in the tag of the image, e.g. 14.2.4 for ceph/ceph:v14.2.4-20190917
+func versionFromImageTag(image string) (*cephver.CephVersion, bool) {
+	match := imageTagVersionPattern.FindStringSubmatch(image)
+	if match == nil {
+		return nil, false
+	}
+	version := &cephver.CephVersion{}
+	version.Major, _ = strconv.Atoi(match[1])
+	if match[2] != "" {
+		version.Minor, _ = strconv.Atoi(match[2])
+	}
+	if match[3] != "" {
+		version.Extra, _ = strconv.Atoi(match[3])
+	}
+	return version, true
+}
+
+// setJobResources sets the resources on all the containers of a job. The job is short lived, so its resources
+// are configured separately from the resources of the daemons.
+func setJobResources(spec *v1.PodSpec, resources v1.ResourceRequirements) {
+	for i := range spec.InitContainers {
+		spec.InitContainers[i].Resources = resources
+	}
+	for i := range spec.Containers {
+		spec.Containers[i].Resources = resources
+	}
+}
+
+// versionJobNodeSelector returns the node selector of the version job, so the job runs on the nodes of the daemons
+// where the image is likely cached and the architecture matches. The explicit selector takes precedence over the
+// selector derived from the "all" placement.
+func versionJobNodeSelector(spec *cephv1.ClusterSpec) map[string]string {
+	if len(spec.CephVersion.ImageJobNodeSelector) > 0 {
+		return spec.CephVersion.ImageJobNodeSelector
+	}
+	return nodeSelectorFromAffinity(spec.Placement.All().NodeAffinity)
+}
+
+// nodeSelectorFromAffinity converts a required node affinity to a node selector. This is only possible for a single
+// term where each expression requires a label to have a single value, otherwise nil is returned.
+func nodeSelectorFromAffinity(affinity *v1.NodeAffinity) map[string]string {
+	if affinity == nil || affinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return nil
+	}
+	terms := affinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	if len(terms) != 1 || len(terms[0].MatchExpressions) == 0 || len(terms[0].MatchFields) > 0 {
+		return nil
+	}
+	selector := map[string]string{}
+	for _, expr := range terms[0].MatchExpressions {
+		if expr.Operator != v1.NodeSelectorOpIn || len(expr.Values) != 1 {
+			return nil
+		}
+		selector[expr.Key] = expr.Values[0]
+	}
+	return selector
+}
+
 func (c *cluster) validateCephVersion(version *cephver.CephVersion) error {
 	if !version.IsAtLeast(cephver.Minimum) {
-		return fmt.Errorf("the version does not meet the minimum version: %s", cephver.Minimum.String())
+		if !c.Spec.CephVersion.AllowBelowMinimum {
+			return fmt.Errorf("the version does not meet the minimum version: %s. allowBelowMinimum must be set to true to run with this version: %v", cephver.Minimum.String(), version)
+		}
+		logger.Warningf("ceph version %s is older than the minimum version %s. the orchestration of this version may be incomplete", version, cephver.Minimum.String())
+	}
+
+	if c.cephPreRelease {
+		if !c.Spec.CephVersion.AllowPreRelease {
+			return fmt.Errorf("ceph version %s is a development build or a release candidate. allowPreRelease must be set to true to run with this version", version)
+		}
+		logger.Warningf("RUNNING A PRE-RELEASE OF CEPH. ceph version %s is a development build or a release candidate and must not be used in production", version)
 	}
 
 	if !version.Supported() {
 		logger.Warningf("unsupported ceph version detected: %s.", version)
 		if !c.Spec.CephVersion.AllowUnsupported {
-			return fmt.Errorf("allowUnsupported must be set to true to run with this version: %v", version)
+			if !c.allowUnsupportedOnce {
+				return fmt.Errorf("allowUnsupported must be set to true to run with this version: %v", version)
+			}
+			logger.Warningf("allowing unsupported ceph version %s once since the cluster has the annotation %s", version, allowUnsupportedOnceAnnotation)
+			c.unsupportedAllowedOnce = true
 		}
 	}
 
@@ -142,19 +472,27 @@ func (c *cluster) validateCephVersion(version *cephver.CephVersion) error {
 
 	// Try to load clusterInfo so we can compare the running version with the one from the spec image
 	clusterInfo, _, _, err := mon.LoadClusterInfo(c.context, c.Namespace)
-	if err == nil {
-		// Write connection info (ceph config file and keyring) for ceph commands
-		err = mon.WriteConnectionConfig(c.context, clusterInfo)
-		if err != nil {
-			logger.Errorf("failed to write config. Attempting to continue. %+v", err)
-		}
-	}
-
-	if !clusterInfo.IsInitialized() {
-		// If not initialized, this is likely a new cluster so there is nothing to do
+	if err == mon.ErrClusterInfoNotFound {
+		// If the cluster info does not exist, this is a new cluster so there is nothing to do
 		logger.Debug("cluster not initialized, nothing to validate")
 		return nil
 	}
+	if err != nil {
+		return fmt.Errorf("failed to load the cluster info of the existing cluster. %+v", err)
+	}
+	if !clusterInfo.IsInitialized() {
+		// The cluster info exists but is incomplete. Treating the cluster as new would bootstrap new mons over the
+		// existing cluster, so the orchestration is refused until the cluster info is repaired.
+		msg := fmt.Sprintf("the cluster info in secret %s is incomplete, refusing to orchestrate the existing cluster as a new cluster", mon.AppName)
+		c.recordEvent(v1.EventTypeWarning, "CorruptClusterInfo", msg)
+		return fmt.Errorf("%s", msg)
+	}
+
+	// Write connection info (ceph config file and keyring) for ceph commands
+	err = mon.WriteConnectionConfig(c.context, clusterInfo)
+	if err != nil {
+		logger.Errorf("failed to write config. Attempting to continue. %+v", err)
+	}
 
 	// Get cluster running versions
 	versions, err := client.GetAllCephDaemonVersions(c.context, c.Namespace)
@@ -180,44 +518,182 @@ func (c *cluster) validateCephVersion(version *cephver.CephVersion) error {
 		if !cephHealthy {
 			return fmt.Errorf("ceph status in namespace %s is not healthy, refusing to upgrade. fix the cluster and re-edit the cluster CR to trigger a new orchestation update", c.Namespace)
 		}
-		c.isUpgrade = true
+		c.startUpgrade(runningVersions, *version)
 	}
 
 	return nil
 }
 
-// initialized checks if the cluster has ever completed a successful orchestration since the operator has started
+// completedState returns the state of the cluster after a successful orchestration
+func (c *cluster) completedState() cephv1.ClusterState {
+	if c.Spec != nil && c.Spec.BootstrapOnly {
+		return cephv1.ClusterStateBootstrapped
+	}
+	return cephv1.ClusterStateCreated
+}
+
+// initialized checks if the daemons of the cluster were ready after a successful orchestration since the operator
+// has started
 func (c *cluster) initialized() bool {
+	c.initMux.Lock()
+	defer c.initMux.Unlock()
 	return c.initCompleted
 }
 
-func (c *cluster) createInstance(rookImage string, cephVersion cephver.CephVersion) error {
-	var err error
+// orchestratedOnce checks if the cluster has ever completed a successful orchestration since the operator has
+// started, even if its daemons are not ready yet
+func (c *cluster) orchestratedOnce() bool {
+	c.initMux.Lock()
+	defer c.initMux.Unlock()
+	return c.orchestrated
+}
+
+// setInitialized marks the cluster initialized without checking the readiness of its daemons, e.g. for an external
+// cluster whose daemons are not managed by the operator
+func (c *cluster) setInitialized() {
+	c.initMux.Lock()
+	defer c.initMux.Unlock()
+	c.orchestrated = true
+	c.initCompleted = true
+}
+
+func (c *cluster) createInstance(ctx context.Context, rookImage string, cephVersion cephver.CephVersion) error {
+	// the running orchestration applies the change when it completes, so a second orchestration must not run in
+	// parallel for the same cluster
 	c.setOrchestrationNeeded()
+	if !c.checkSetOrchestrationStatus() {
+		logger.Infof("an orchestration of cluster %s is already running, the change is applied once it completes", c.Namespace)
+		return nil
+	}
+	// do not add to the load of a struggling api server
+	if !c.apiCircuitBreaker.allow() {
+		c.unsetOrchestrationStatus()
+		// the change is not lost, the orchestration is retried when the breaker allows it again
+		c.apiCircuitBreaker.pause(c.Namespace, func() {
+			if c.ctx.Err() != nil {
+				return
+			}
+			if err := c.createInstance(c.ctx, rookImage, cephVersion); err != nil {
+				logger.Errorf("failed the paused orchestration of cluster %s. %+v", c.Namespace, err)
+			}
+		})
+		return fmt.Errorf("the orchestration of cluster %s is paused after repeated api server errors", c.Namespace)
+	}
+	var err error
+	ctx, span := c.startSpan(ctx, "createInstance", cephVersion)
+	defer func() { span.End(err) }()
+	orchestrated := false
+	defer func() {
+		if orchestrated {
+			c.apiCircuitBreaker.done(err)
+		} else {
+			c.apiCircuitBreaker.cancel()
+		}
+	}()
+
+	// resources cannot be created in a namespace that is being deleted, so there is nothing to orchestrate
+	if c.namespaceTerminating() {
+		logger.Infof("skipping the orchestration of cluster %s since the namespace is being deleted", c.Namespace)
+		c.unsetOrchestrationStatus()
+		return errNamespaceTerminating
+	}
 
 	// execute an orchestration until
 	// there are no more unapplied changes to the cluster definition and
 	// while no other goroutine is already running a cluster update
-	for c.checkSetOrchestrationStatus() == true {
+	for {
+		// the cluster is being deleted
+		if ctx.Err() != nil {
+			c.unsetOrchestrationStatus()
+			err = fmt.Errorf("canceled the orchestration of cluster %s. %+v", c.Namespace, ctx.Err())
+			return err
+		}
 		if err != nil {
 			logger.Errorf("There was an orchestration error, but there is another orchestration pending; proceeding with next orchestration run (which may succeed). %+v", err)
 		}
 		// Use a DeepCopy of the spec to avoid using an inconsistent data-set
 		spec := c.Spec.DeepCopy()
+		generation := c.currentSpecGeneration()
 
-		err = c.doOrchestration(rookImage, cephVersion, spec)
+		upgrading := c.IsUpgrading()
+		if upgrading {
+			c.updateUpgradingCondition(true, cephVersion, nil)
+		}
+		if err = c.orchestrationLimiter.acquire(ctx, c.Namespace); err != nil {
+			c.unsetOrchestrationStatus()
+			return err
+		}
+		// an upgrade started during the orchestration is applied by the next orchestration
+		isUpgrade, upgradeGeneration := c.upgradeState()
+		err = c.doOrchestration(ctx, rookImage, cephVersion, spec, generation, isUpgrade)
+		c.orchestrationLimiter.release()
+		orchestrated = true
+		if err != nil {
+			c.updateOrchestrationFailure(err)
+		}
+		c.finishUpgrade(cephVersion, err, upgradeGeneration)
+		if upgrading {
+			c.updateUpgradingCondition(false, cephVersion, err)
+		}
 
 		c.unsetOrchestrationStatus()
+		if !c.checkSetOrchestrationStatus() {
+			break
+		}
 	}
 
 	return err
 }
 
-func (c *cluster) doOrchestration(rookImage string, cephVersion cephver.CephVersion, spec *cephv1.ClusterSpec) error {
-	// Create a configmap for overriding ceph config settings
-	// These settings should only be modified by a user after they are initialized
+// namespaceTerminating returns whether the namespace of the cluster is being deleted
+func (c *cluster) namespaceTerminating() bool {
+	namespace, err := c.context.Clientset.CoreV1().Namespaces().Get(c.Namespace, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Warningf("failed to get namespace %s. %+v", c.Namespace, err)
+		}
+		return false
+	}
+	return namespace.DeletionTimestamp != nil
+}
+
+// validatePriorityClassNames checks that the priority classes of the pods exist, otherwise the pods would be
+// rejected when they are created
+func validatePriorityClassNames(context *clusterd.Context, names rookv1alpha2.PriorityClassNamesSpec) error {
+	for key, name := range names {
+		if name == "" {
+			continue
+		}
+		if _, err := context.Clientset.SchedulingV1().PriorityClasses().Get(name, metav1.GetOptions{}); err != nil {
+			return fmt.Errorf("failed to get the priority class %s for %s. %+v", name, key, err)
+		}
+	}
+	return nil
+}
+
+// createOverrideConfigMap creates the configmap for overriding ceph config settings. These settings should only be
+// modified by a user after they are initialized. The create is retried with a backoff, so a transient error of the
+// api server does not fail the orchestration before the mons are started. The configmap is checked by every
+// orchestration, and if it was deleted it is recreated with the overrides it had when it was last seen.
+func (c *cluster) createOverrideConfigMap() error {
+	overrideConfig := ""
+	existing, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(k8sutil.ConfigOverrideName, metav1.GetOptions{})
+	if err == nil {
+		// remember the overrides in case the configmap is deleted
+		c.lastOverrideConfig = existing.Data[k8sutil.ConfigOverrideVal]
+		return nil
+	}
+	if !errors.IsNotFound(err) {
+		logger.Warningf("failed to get override configmap %s. %+v", c.Namespace, err)
+	} else if c.lastOverrideConfig != "" {
+		message := fmt.Sprintf("override configmap %s was deleted, recreating it with the overrides it had before", c.Namespace)
+		logger.Warning(message)
+		c.recordEvent(v1.EventTypeWarning, "OverrideConfigMapRecreated", message)
+		overrideConfig = c.lastOverrideConfig
+	}
+
 	placeholderConfig := map[string]string{
-		k8sutil.ConfigOverrideVal: "",
+		k8sutil.ConfigOverrideVal: overrideConfig,
 	}
 	cm := &v1.ConfigMap{
 		ObjectMeta: metav1.ObjectMeta{
@@ -226,58 +702,298 @@ func (c *cluster) doOrchestration(rookImage string, cephVersion cephver.CephVers
 		Data: placeholderConfig,
 	}
 	k8sutil.SetOwnerRef(&cm.ObjectMeta, &c.ownerRef)
-	_, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Create(cm)
-	if err != nil && !errors.IsAlreadyExists(err) {
+
+	var lastErr error
+	err = wait.ExponentialBackoff(overrideConfigMapBackoff, func() (bool, error) {
+		_, lastErr = c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Create(cm)
+		if lastErr == nil || errors.IsAlreadyExists(lastErr) {
+			return true, nil
+		}
+		if errors.IsInvalid(lastErr) || errors.IsForbidden(lastErr) || errors.IsBadRequest(lastErr) {
+			// retrying does not help
+			return false, lastErr
+		}
+		logger.Warningf("failed to create override configmap %s, retrying. %+v", c.Namespace, lastErr)
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return fmt.Errorf("failed to create override configmap %s after %d attempts. %+v", c.Namespace, overrideConfigMapBackoff.Steps, lastErr)
+	} else if err != nil {
 		return fmt.Errorf("failed to create override configmap %s. %+v", c.Namespace, err)
 	}
+	return nil
+}
+
+// validateTerminationGracePeriods checks that the termination grace periods of the pods are not negative
+func validateTerminationGracePeriods(periods rookv1alpha2.TerminationGracePeriodSecondsSpec) error {
+	for key, seconds := range periods {
+		if seconds < 0 {
+			return fmt.Errorf("the termination grace period of %s must not be negative, got %d", key, seconds)
+		}
+	}
+	return nil
+}
+
+// validateCABundle checks that the CA certificates are read from either a configmap or a secret
+func validateCABundle(ca cephv1.CABundleSpec) error {
+	if ca.ConfigMapName != "" && ca.SecretName != "" {
+		return fmt.Errorf("the caBundle must reference either the configmap %s or the secret %s, not both", ca.ConfigMapName, ca.SecretName)
+	}
+	return nil
+}
+
+// validateSchedulerName checks that the scheduler name is a valid DNS name, otherwise the pods would be rejected
+func validateSchedulerName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return fmt.Errorf("invalid scheduler name %q. %s", name, strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// checkRBDMirroring validates the rbd mirroring spec and reports the result with the RBDMirroringInvalid condition,
+// so a cluster without rbd mirroring workers is visibly disabled instead of silently losing its rbd mirrors
+func (c *cluster) checkRBDMirroring(spec cephv1.RBDMirroringSpec) error {
+	if err := rbd.ValidateSpec(spec); err != nil {
+		c.reportCondition(cephv1.ClusterConditionRBDMirroringInvalid, err.Error(), "", "")
+		return err
+	}
+	if spec.Workers == 0 {
+		c.reportCondition(cephv1.ClusterConditionRBDMirroringInvalid, "", "RBDMirroringDisabled",
+			"the rbd mirroring is disabled with 0 workers, no rbd mirror is running")
+		return nil
+	}
+	c.reportCondition(cephv1.ClusterConditionRBDMirroringInvalid, "", "RBDMirroringEnabled",
+		fmt.Sprintf("the rbd mirroring is enabled with %d workers", spec.Workers))
+	return nil
+}
+
+func (c *cluster) doOrchestration(ctx context.Context, rookImage string, cephVersion cephver.CephVersion, spec *cephv1.ClusterSpec, generation int64, isUpgrade bool) error {
+	startTime := time.Now()
+	c.orchestrationLog.reset()
+	c.logOrchestration("starting the orchestration of cluster %s with ceph version %s", c.Namespace, cephVersion.String())
+	// the gates of the spec snapshot apply to the whole orchestration, also if it stops early
+	c.setFeatureGates(spec.FeatureGates)
+
+	if err := validatePriorityClassNames(c.context, spec.PriorityClassNames); err != nil {
+		return fmt.Errorf("invalid priority class names. %+v", err)
+	}
+	if err := validateTerminationGracePeriods(spec.TerminationGracePeriodSeconds); err != nil {
+		return fmt.Errorf("invalid termination grace periods. %+v", err)
+	}
+	if err := validateMonAutoScale(spec.Mon.AutoScale); err != nil {
+		return err
+	}
+	if err := validateBalanceOSDs(spec.BalanceOSDs); err != nil {
+		return err
+	}
+	if err := validateCABundle(spec.CABundle); err != nil {
+		return err
+	}
+	if err := validateSchedulerName(spec.SchedulerName); err != nil {
+		return err
+	}
+	if err := spec.CephVersion.ValidateImagePullPolicy(); err != nil {
+		return err
+	}
+	if err := cephv1.ValidateResourceProfile(spec.ResourceProfile); err != nil {
+		return err
+	}
+	if err := c.checkRBDMirroring(spec.RBDMirroring); err != nil {
+		return err
+	}
+	// the daemons without resources get the resources of the profile
+	spec.Resources = cephv1.ResolveResourceProfile(spec.ResourceProfile, spec.Resources)
+
+	// Report the missing permissions before the daemons are started
+	if err := c.checkRBAC(); err != nil {
+		return err
+	}
+
+	if err := c.createOverrideConfigMap(); err != nil {
+		return err
+	}
+
+	// Do not retry an upgrade that was rolled back until the image changes
+	if err := c.checkRolledBackUpgrade(spec.CephVersion.Image); err != nil {
+		return err
+	}
+	previousImage := c.rollbackImage(spec.UpgradeRollback, spec.CephVersion.Image, isUpgrade)
 
 	// This gets triggered on CR update so let's not run that (mon/mgr/osd daemons)
 	// Start the mon pods
-	clusterInfo, err := c.mons.Start(c.Info, rookImage, cephVersion, *c.Spec, c.isUpgrade)
+	c.logOrchestration("starting the mons")
+	_, span := c.startSpan(ctx, "mons", cephVersion)
+	monClusterSpec := *c.Spec
+	monClusterSpec.Mon.Count = c.monCount(&monClusterSpec)
+	monClusterSpec.Resources = spec.Resources
+	c.startedMonCount = monClusterSpec.Mon.Count
+	clusterInfo, err := c.mons.Start(ctx, c.Info, rookImage, cephVersion, monClusterSpec, isUpgrade)
+	span.End(err)
+	c.recordOrchestrationPhase(phaseMon, err)
 	if err != nil {
 		return fmt.Errorf("failed to start the mons. %+v", err)
 	}
 	c.Info = clusterInfo // mons return the cluster's info
+	c.recordUpgradeProgress(isUpgrade)
+	if err := c.checkUpgradeHealth(ctx, spec.UpgradeRollback, mon.AppName, spec.CephVersion.Image, previousImage); err != nil {
+		return err
+	}
 
 	// The cluster Identity must be established at this point
 	if !c.Info.IsInitialized() {
 		return fmt.Errorf("the cluster identity was not established: %+v", c.Info)
 	}
 
+	// Do not start the other daemons before a majority of the mons is in quorum
+	c.logOrchestration("waiting for a majority of the mons to be in quorum")
+	_, span = c.startSpan(ctx, "quorum", cephVersion)
+	err = c.mons.WaitForQuorumMajority()
+	span.End(err)
+	if err != nil {
+		return fmt.Errorf("failed to wait for mon quorum. %+v", err)
+	}
+
+	// Refuse to orchestrate if the mons belong to a different cluster
+	if err := c.validateClusterFSID(); err != nil {
+		return err
+	}
+
+	// Stop when the mons disagree on the quorum instead of making a split brain worse
+	if err := c.checkMonSplitBrain(ctx); err != nil {
+		return err
+	}
+
+	if err := c.rotateAdminKey(); err != nil {
+		return fmt.Errorf("failed to rotate the admin key. %+v", err)
+	}
+
+	// Only the mons are started while the cluster is bootstrapped
+	if spec.BootstrapOnly {
+		logger.Infof("cluster %s is bootstrapped. remove bootstrapOnly from the spec to start the other daemons", c.Namespace)
+		c.markInitialized(ctx, spec.Initialization)
+		c.updateOrchestrationStatus(startTime, generation, spec.CephVersion.Image)
+		return nil
+	}
+
 	mgrs := mgr.New(c.Info, c.context, c.Namespace, rookImage,
 		spec.CephVersion, cephv1.GetMgrPlacement(spec.Placement), cephv1.GetMgrAnnotations(c.Spec.Annotations),
-		spec.Network, spec.Dashboard, spec.Monitoring, cephv1.GetMgrResources(spec.Resources), c.ownerRef, c.Spec.DataDirHostPath, c.isUpgrade)
-	err = mgrs.Start()
+		spec.Network, spec.Dashboard, spec.Monitoring, cephv1.GetMgrResources(spec.Resources), c.ownerRef, c.Spec.DataDirHostPath, isUpgrade)
+	mgrs.MgrSpec = spec.Mgr
+	mgrs.PriorityClassName = cephv1.GetMgrPriorityClassName(spec.PriorityClassNames)
+	mgrs.GracePeriod = cephv1.GetMgrTerminationGracePeriodSeconds(spec.TerminationGracePeriodSeconds)
+	mgrs.CABundle = spec.CABundle
+	mgrs.SchedulerName = spec.SchedulerName
+	mgrs.SkipPrometheusRule = !c.checkMonitoringRulesNamespace(spec.Monitoring)
+	c.logOrchestration("starting the mgrs")
+	_, span = c.startSpan(ctx, "mgrs", cephVersion)
+	err = mgrs.Start(ctx)
+	span.End(err)
+	c.recordOrchestrationPhase(phaseMgr, err)
+	c.checkMgrSchedulable(mgrs.UnschedulableMessage(), mgrs.PlacementChecked())
+	c.checkMgrActiveAmbiguous(mgrs.AmbiguousActiveMgrMessage())
 	if err != nil {
 		return fmt.Errorf("failed to start the ceph mgr. %+v", err)
 	}
+	c.recordUpgradeProgress(isUpgrade)
+	if err := c.checkUpgradeHealth(ctx, spec.UpgradeRollback, mgr.AppName, spec.CephVersion.Image, previousImage); err != nil {
+		return err
+	}
+	c.checkMgrResources(mgrs.ModuleResourceWarnings())
+	c.checkDashboardAvailability(mgrs.DashboardAvailabilityWarning())
+	if err := c.applyMgrDebugLevel(); err != nil {
+		logger.Errorf("failed to apply the mgr debug level. %+v", err)
+	}
 
 	// Start the OSDs
 	osds := osd.New(c.Info, c.context, c.Namespace, rookImage, spec.CephVersion, spec.Storage, spec.DataDirHostPath,
 		cephv1.GetOSDPlacement(spec.Placement), cephv1.GetOSDAnnotations(spec.Annotations), spec.Network,
-		cephv1.GetOSDResources(spec.Resources), c.ownerRef, c.isUpgrade)
-	err = osds.Start()
+		cephv1.GetOSDResources(spec.Resources), c.ownerRef, isUpgrade)
+	osds.NodeRemoval = osd.NodeRemovalSettings{
+		SkipConfirmation: spec.SkipNodeRemovalConfirmation,
+		Confirmed:        c.confirmedRemovals(),
+	}
+	osds.ProvisioningStatus = c.updateOSDProvisioningStatus
+	c.resetOSDProvisioningStatus()
+	osdsBefore := -1
+	if c.balanceOSDsEnabled(spec.BalanceOSDs) {
+		osdsBefore = c.countOSDs()
+	}
+	c.logOrchestration("starting the osds")
+	_, span = c.startSpan(ctx, "osds", cephVersion)
+	// the maintenance of an osd waits until the osds are started, otherwise the osd stopped in between would be
+	// started again while it is out
+	c.osdOutMux.Lock()
+	osds.StoppedOSDs = c.stoppedOSDs()
+	err = osds.Start(ctx)
+	c.osdOutMux.Unlock()
+	span.End(err)
+	c.recordOrchestrationPhase(phaseOSD, err)
+	c.updatePendingNodeRemovals(osds.PendingNodeRemovals)
 	if err != nil {
 		return fmt.Errorf("failed to start the osds. %+v", err)
 	}
+	c.clearConfirmedNodeRemovals(osds.SkippedNodeRemovals)
+	c.recordUpgradeProgress(isUpgrade)
+	if err := c.checkUpgradeHealth(ctx, spec.UpgradeRollback, osd.AppName, spec.CephVersion.Image, previousImage); err != nil {
+		return err
+	}
+	c.balanceOSDsAfterExpansion(spec.BalanceOSDs, spec.Mgr, osdsBefore)
+	if err := c.applyOSDOut(); err != nil {
+		logger.Errorf("failed to apply the out osd. %+v", err)
+	}
+
+	// Create the crush rules once the OSDs are up
+	crushRules, err := c.reconcileCrushRules(spec.CrushRules, c.crushRulesStatus())
+	c.updateCrushRulesStatus(crushRules)
+	if err != nil {
+		return fmt.Errorf("failed to reconcile the crush rules. %+v", err)
+	}
 
 	// Start the rbd mirroring daemon(s)
 	rbdmirror := rbd.New(c.Info, c.context, c.Namespace, rookImage, spec.CephVersion, cephv1.GetRBDMirrorPlacement(spec.Placement),
 		cephv1.GetRBDMirrorAnnotations(spec.Annotations), spec.Network, spec.RBDMirroring,
-		cephv1.GetRBDMirrorResources(spec.Resources), c.ownerRef, c.Spec.DataDirHostPath, c.isUpgrade)
-	err = rbdmirror.Start()
+		cephv1.GetRBDMirrorResources(spec.Resources), c.ownerRef, c.Spec.DataDirHostPath, isUpgrade)
+	c.logOrchestration("starting %d rbd mirrors", spec.RBDMirroring.Workers)
+	_, span = c.startSpan(ctx, "rbdMirrors", cephVersion)
+	err = rbdmirror.Start(ctx)
+	span.End(err)
+	c.recordOrchestrationPhase(phaseRBDMirror, err)
 	if err != nil {
 		return fmt.Errorf("failed to start the rbd mirrors. %+v", err)
 	}
 
-	logger.Infof("Done creating rook instance in namespace %s", c.Namespace)
-	c.initCompleted = true
+	// Confirm the daemons are running the version that was detected from the image
+	c.verifyCephDaemonVersions(cephVersion)
+
+	_, span = c.startSpan(ctx, "waitForCleanPGs", cephVersion)
+	err = c.waitForCleanPGs(spec.WaitForCleanPGs)
+	span.End(err)
+	if err != nil {
+		return err
+	}
+
+	// the daemons were moved to the new network
+	c.clearNetworkChangeConfirmation()
+	// the unsupported version is running
+	c.clearAllowUnsupportedOnce()
 
-	// Notify the child controllers that the cluster spec might have changed
-	for _, child := range c.childControllers {
-		child.ParentClusterChanged(*c.Spec, clusterInfo, c.isUpgrade)
+	// the resources of the cluster must be garbage collected when the cluster is deleted
+	if err := c.repairOwnerRefs(); err != nil {
+		logger.Errorf("failed to repair the owner references of the resources of cluster %s. %+v", c.Namespace, err)
 	}
 
+	logger.Infof("Done creating rook instance in namespace %s", c.Namespace)
+	c.markInitialized(ctx, spec.Initialization)
+	c.updateOrchestrationStatus(startTime, generation, spec.CephVersion.Image)
+
+	// Notify the child controllers that the cluster spec might have changed. The notification may wait for the
+	// cluster to be healthy, which must not block the orchestrations. The upgrade is finished once the orchestration
+	// returns, so whether it is an upgrade is read right away.
+	c.queueChildNotification(c.ctx, *spec, clusterInfo, isUpgrade)
+
 	return nil
 }
 
@@ -300,6 +1016,12 @@ func clusterChanged(oldCluster, newCluster cephv1.ClusterSpec, clusterRef *clust
 			// resource.Quantity has non-exportable fields, so we use its comparator method
 			resourceQtyComparer := cmp.Comparer(func(x, y resource.Quantity) bool { return x.Cmp(y) == 0 })
 			diff = cmp.Diff(oldCluster, newCluster, resourceQtyComparer)
+			if clusterRef != nil && clusterRef.specDiffLogging == specDiffLoggingPaths {
+				// the values of the spec may be sensitive, they are only logged at the debug level
+				logger.Infof("The Cluster CR has changed. fields=%s", strings.Join(changedSpecFields(oldCluster, newCluster), ","))
+				logger.Debugf("The Cluster CR has changed. diff=%s", diff)
+				return
+			}
 			logger.Infof("The Cluster CR has changed. diff=%s", diff)
 		}()
 		return true, diff
@@ -308,9 +1030,151 @@ func clusterChanged(oldCluster, newCluster cephv1.ClusterSpec, clusterRef *clust
 	return false, ""
 }
 
+// ChangeImpact describes which parts of the cluster are affected by a change of the cluster spec
+type ChangeImpact struct {
+	Mon        bool
+	Mgr        bool
+	OSD        bool
+	RBDMirror  bool
+	Network    bool
+	Monitoring bool
+	// Settings is true when the settings of the orchestration changed that do not change the daemons, such as the
+	// feature gates, the initialization or the upgrade rollback
+	Settings bool
+	// Benign is true when only the annotations of the daemons changed
+	Benign bool
+	// Structural is true when the storage topology, the network or the data location of the cluster changed.
+	// These changes may require extra safety checks before they are applied.
+	Structural bool
+}
+
+// Changed returns whether the spec change affects any part of the cluster
+func (c ChangeImpact) Changed() bool {
+	return c.Mon || c.Mgr || c.OSD || c.RBDMirror || c.Network || c.Monitoring || c.Settings || c.Benign || c.Structural
+}
+
+// String returns the parts of the cluster that are affected by the change
+func (c ChangeImpact) String() string {
+	parts := []string{}
+	for _, part := range []struct {
+		name     string
+		affected bool
+	}{
+		{"mon", c.Mon}, {"mgr", c.Mgr}, {"osd", c.OSD}, {"rbdmirror", c.RBDMirror}, {"network", c.Network},
+		{"monitoring", c.Monitoring}, {"settings", c.Settings}, {"benign", c.Benign}, {"structural", c.Structural},
+	} {
+		if part.affected {
+			parts = append(parts, part.name)
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	return strings.Join(parts, ",")
+}
+
+// ClassifyChange compares two cluster specs and returns the parts of the cluster that are affected by the change
+func ClassifyChange(oldCluster, newCluster cephv1.ClusterSpec) ChangeImpact {
+	impact := ChangeImpact{}
+
+	// sort the nodes by name so the order of the nodes is not considered a change
+	sort.Sort(rookv1alpha2.NodesByName(oldCluster.Storage.Nodes))
+	sort.Sort(rookv1alpha2.NodesByName(newCluster.Storage.Nodes))
+
+	// resource.Quantity has non-exportable fields, so we use its comparator method
+	resourceQtyComparer := cmp.Comparer(func(x, y resource.Quantity) bool { return x.Cmp(y) == 0 })
+	resourcesChanged := func(x, y v1.ResourceRequirements) bool { return !cmp.Equal(x, y, resourceQtyComparer) }
+	// a change of the profile only affects the daemons whose resources are resolved differently
+	oldCluster.Resources = cephv1.ResolveResourceProfile(oldCluster.ResourceProfile, oldCluster.Resources)
+	newCluster.Resources = cephv1.ResolveResourceProfile(newCluster.ResourceProfile, newCluster.Resources)
+
+	// changes to the version, network or data location affect all the daemons, as well as the settings that are
+	// applied to the pods of all the daemons
+	allDaemons := !reflect.DeepEqual(oldCluster.CephVersion, newCluster.CephVersion) ||
+		!reflect.DeepEqual(oldCluster.Network, newCluster.Network) ||
+		!reflect.DeepEqual(oldCluster.PriorityClassNames, newCluster.PriorityClassNames) ||
+		!reflect.DeepEqual(oldCluster.TerminationGracePeriodSeconds, newCluster.TerminationGracePeriodSeconds) ||
+		!reflect.DeepEqual(oldCluster.CABundle, newCluster.CABundle) ||
+		oldCluster.SchedulerName != newCluster.SchedulerName
+	// only a change that moves the daemons to another network is structural, not e.g. a change of the DNS of the pods
+	if daemonNetworkChanged(oldCluster.Network, newCluster.Network) {
+		impact.Network = true
+		impact.Structural = true
+	}
+	if oldCluster.DataDirHostPath != newCluster.DataDirHostPath || !reflect.DeepEqual(oldCluster.External, newCluster.External) {
+		allDaemons = true
+		impact.Structural = true
+	}
+
+	if !reflect.DeepEqual(oldCluster.Storage, newCluster.Storage) {
+		impact.OSD = true
+		impact.Structural = true
+	}
+
+	impact.Mon = allDaemons ||
+		!reflect.DeepEqual(oldCluster.Mon, newCluster.Mon) ||
+		!reflect.DeepEqual(oldCluster.ConfigOverrides, newCluster.ConfigOverrides) ||
+		!reflect.DeepEqual(cephv1.GetMonPlacement(oldCluster.Placement), cephv1.GetMonPlacement(newCluster.Placement)) ||
+		resourcesChanged(cephv1.GetMonResources(oldCluster.Resources), cephv1.GetMonResources(newCluster.Resources))
+	impact.Mgr = allDaemons ||
+		!reflect.DeepEqual(oldCluster.Mgr, newCluster.Mgr) ||
+		!reflect.DeepEqual(oldCluster.Dashboard, newCluster.Dashboard) ||
+		!reflect.DeepEqual(cephv1.GetMgrPlacement(oldCluster.Placement), cephv1.GetMgrPlacement(newCluster.Placement)) ||
+		resourcesChanged(cephv1.GetMgrResources(oldCluster.Resources), cephv1.GetMgrResources(newCluster.Resources))
+	impact.OSD = impact.OSD || allDaemons ||
+		!reflect.DeepEqual(oldCluster.DisruptionManagement, newCluster.DisruptionManagement) ||
+		!reflect.DeepEqual(cephv1.GetOSDPlacement(oldCluster.Placement), cephv1.GetOSDPlacement(newCluster.Placement)) ||
+		resourcesChanged(cephv1.GetOSDResources(oldCluster.Resources), cephv1.GetOSDResources(newCluster.Resources))
+	impact.RBDMirror = allDaemons ||
+		!reflect.DeepEqual(oldCluster.RBDMirroring, newCluster.RBDMirroring) ||
+		!reflect.DeepEqual(cephv1.GetRBDMirrorPlacement(oldCluster.Placement), cephv1.GetRBDMirrorPlacement(newCluster.Placement)) ||
+		resourcesChanged(cephv1.GetRBDMirrorResources(oldCluster.Resources), cephv1.GetRBDMirrorResources(newCluster.Resources))
+	impact.Monitoring = !reflect.DeepEqual(oldCluster.Monitoring, newCluster.Monitoring)
+	impact.Settings = !reflect.DeepEqual(oldCluster.FeatureGates, newCluster.FeatureGates) ||
+		!reflect.DeepEqual(oldCluster.Initialization, newCluster.Initialization) ||
+		!reflect.DeepEqual(oldCluster.UpgradeRollback, newCluster.UpgradeRollback) ||
+		!reflect.DeepEqual(oldCluster.WaitForCleanPGs, newCluster.WaitForCleanPGs) ||
+		!reflect.DeepEqual(oldCluster.BalanceOSDs, newCluster.BalanceOSDs) ||
+		!reflect.DeepEqual(oldCluster.CrushRules, newCluster.CrushRules) ||
+		!reflect.DeepEqual(oldCluster.ChildNotification, newCluster.ChildNotification) ||
+		oldCluster.BootstrapOnly != newCluster.BootstrapOnly ||
+		oldCluster.SpecHistoryLimit != newCluster.SpecHistoryLimit ||
+		oldCluster.DeletionProtection != newCluster.DeletionProtection ||
+		oldCluster.SkipNodeRemovalConfirmation != newCluster.SkipNodeRemovalConfirmation ||
+		oldCluster.AllowSharedDataDirHostPath != newCluster.AllowSharedDataDirHostPath
+
+	// a change that is limited to annotations only needs the daemons to be updated in place
+	monAnnotations := !reflect.DeepEqual(cephv1.GetMonAnnotations(oldCluster.Annotations), cephv1.GetMonAnnotations(newCluster.Annotations))
+	mgrAnnotations := !reflect.DeepEqual(cephv1.GetMgrAnnotations(oldCluster.Annotations), cephv1.GetMgrAnnotations(newCluster.Annotations))
+	osdAnnotations := !reflect.DeepEqual(cephv1.GetOSDAnnotations(oldCluster.Annotations), cephv1.GetOSDAnnotations(newCluster.Annotations))
+	rbdMirrorAnnotations := !reflect.DeepEqual(cephv1.GetRBDMirrorAnnotations(oldCluster.Annotations), cephv1.GetRBDMirrorAnnotations(newCluster.Annotations))
+	impact.Benign = !impact.Changed() && (monAnnotations || mgrAnnotations || osdAnnotations || rbdMirrorAnnotations)
+	impact.Mon = impact.Mon || monAnnotations
+	impact.Mgr = impact.Mgr || mgrAnnotations
+	impact.OSD = impact.OSD || osdAnnotations
+	impact.RBDMirror = impact.RBDMirror || rbdMirrorAnnotations
+
+	return impact
+}
+
+// resourceVersionChanged records the given resourceVersion of the CephCluster CR and returns
+// whether it differs from the one processed previously. Informer resyncs deliver the same object
+// several times, which can then be skipped without computing the spec diff.
+func (c *cluster) resourceVersionChanged(resourceVersion string) bool {
+	c.crMux.Lock()
+	defer c.crMux.Unlock()
+	if resourceVersion != "" && resourceVersion == c.lastResourceVersion {
+		return false
+	}
+	c.lastResourceVersion = resourceVersion
+	return true
+}
+
 func (c *cluster) setOrchestrationNeeded() {
 	c.orchMux.Lock()
 	c.orchestrationNeeded = true
+	c.pendingOrchestrations++
+	c.updatePendingOrchestrationsMetric()
 	c.orchMux.Unlock()
 }
 
@@ -319,6 +1183,26 @@ func (c *cluster) unsetOrchestrationStatus() {
 	c.orchMux.Lock()
 	defer c.orchMux.Unlock()
 	c.orchestrationRunning = false
+	c.updatePendingOrchestrationsMetric()
+}
+
+// orchestrating returns whether an orchestration of the cluster is running
+func (c *cluster) orchestrating() bool {
+	c.orchMux.Lock()
+	defer c.orchMux.Unlock()
+	return c.orchestrationRunning
+}
+
+// waitForOrchestrationStop waits until the running orchestration of the cluster has returned after it was canceled,
+// so the cluster is not deleted while the orchestration still creates its resources
+func (c *cluster) waitForOrchestrationStop(interval, timeout time.Duration) error {
+	err := wait.Poll(interval, timeout, func() (bool, error) {
+		return !c.orchestrating(), nil
+	})
+	if err != nil {
+		return fmt.Errorf("the orchestration of cluster %s did not stop. %+v", c.Namespace, err)
+	}
+	return nil
 }
 
 // checkSetOrchestrationStatus is responsible to do orchestration as long as there is a request needed
@@ -331,12 +1215,329 @@ func (c *cluster) checkSetOrchestrationStatus() bool {
 		// allow to enter the orchestration-loop
 		c.orchestrationNeeded = false
 		c.orchestrationRunning = true
+		// all the pending changes are applied by this orchestration
+		c.pendingOrchestrations = 0
+		c.updatePendingOrchestrationsMetric()
 		return true
 	}
 
 	return false
 }
 
+// recordUpgradeProgress records the versions of the daemons in the status of the cluster while it is upgraded, so the
+// progress of the upgrade can be followed on the CR after each type of daemon was updated
+func (c *cluster) recordUpgradeProgress(isUpgrade bool) {
+	if !isUpgrade {
+		return
+	}
+	versions, err := client.GetAllCephDaemonVersions(c.context, c.Namespace)
+	if err != nil {
+		logger.Warningf("failed to get the ceph versions of the daemons to record the upgrade progress. %+v", err)
+		return
+	}
+	c.updateDaemonVersionsStatus(*versions)
+}
+
+// verifyCephDaemonVersions checks that the daemons started by the orchestration are running the expected ceph
+// version. A mutable image tag may have been resolved to a different (e.g. cached) image on some nodes.
+// A mismatch does not fail the orchestration but is reported with a warning event and a status condition.
+func (c *cluster) verifyCephDaemonVersions(expected cephver.CephVersion) {
+	versions, err := client.GetAllCephDaemonVersions(c.context, c.Namespace)
+	if err != nil {
+		logger.Warningf("failed to verify the ceph version of the daemons. %+v", err)
+		return
+	}
+
+	c.updateDaemonVersionsStatus(*versions)
+
+	mismatches, err := findMismatchedDaemonVersions(expected, *versions)
+	if err != nil {
+		logger.Warningf("failed to verify the ceph version of the daemons. %+v", err)
+		return
+	}
+
+	condition := cephv1.ClusterCondition{
+		Type:    cephv1.ClusterConditionVersionMismatch,
+		Status:  v1.ConditionFalse,
+		Reason:  "VersionsMatch",
+		Message: fmt.Sprintf("the daemons are running the expected ceph version %s", expected.String()),
+	}
+	if len(mismatches) > 0 {
+		message := fmt.Sprintf("expected the daemons to run ceph version %s but found %s", expected.String(), strings.Join(mismatches, ", "))
+		logger.Warning(message)
+		c.recordEvent(v1.EventTypeWarning, string(cephv1.ClusterConditionVersionMismatch), message)
+		condition.Status = v1.ConditionTrue
+		condition.Reason = string(cephv1.ClusterConditionVersionMismatch)
+		condition.Message = message
+	} else {
+		logger.Infof("all daemons are running the expected ceph version %s", expected.String())
+	}
+	c.updateStatusCondition(condition)
+}
+
+// waitForCleanPGs waits for all the PGs to be active+clean if it is enabled in the spec, so the orchestration is
+// only completed after the data recovered from a change of the OSDs. The orchestration fails if the PGs are not
+// clean before the timeout, so it is retried.
+func (c *cluster) waitForCleanPGs(spec cephv1.WaitForCleanPGsSpec) error {
+	if !spec.Enabled {
+		return nil
+	}
+	timeoutMinutes := spec.TimeoutMinutes
+	if timeoutMinutes <= 0 {
+		timeoutMinutes = defaultWaitForCleanPGsTimeoutMinutes
+	}
+	timeout := time.Duration(timeoutMinutes) * time.Minute
+	deadline := time.Now().Add(timeout)
+
+	logger.Infof("waiting up to %s for the pgs of cluster %s to be active+clean", timeout, c.Namespace)
+	for {
+		msg, clean, err := client.IsClusterClean(c.context, c.Info.Name)
+		if err != nil {
+			logger.Warningf("failed to check whether the pgs of cluster %s are clean. %+v", c.Namespace, err)
+		} else if clean {
+			logger.Infof("the pgs of cluster %s are clean. %s", c.Namespace, msg)
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("timed out after %s waiting for the pgs of cluster %s to be active+clean. %s", timeout, c.Namespace, msg)
+		}
+		logger.Infof("waiting for the pgs of cluster %s to be active+clean. %s", c.Namespace, msg)
+
+		select {
+		case <-c.stopCh:
+			return fmt.Errorf("stopped waiting for the pgs of cluster %s to be clean since the cluster is being removed", c.Namespace)
+		case <-time.After(waitForCleanPGsInterval):
+		}
+	}
+}
+
+// validateClusterFSID checks that the mons in quorum report the fsid that the operator loaded for the cluster. A
+// different fsid means the mons belong to another cluster, e.g. after the mon data on a replaced node was
+// restored from the wrong cluster, so the orchestration must not continue. The orchestration also stops when the fsid
+// cannot be read and is retried later.
+func (c *cluster) validateClusterFSID() error {
+	status, err := client.Status(c.context, c.Info.Name, false)
+	if err != nil {
+		return fmt.Errorf("failed to get the ceph status to validate the fsid of cluster %s. %+v", c.Namespace, err)
+	}
+
+	condition := cephv1.ClusterCondition{
+		Type:    cephv1.ClusterConditionFSIDMismatch,
+		Status:  v1.ConditionFalse,
+		Reason:  "FSIDMatches",
+		Message: fmt.Sprintf("the mons report the expected fsid %s", c.Info.FSID),
+	}
+	var mismatchErr error
+	if status.FSID != c.Info.FSID {
+		message := fmt.Sprintf("the mons report the fsid %s but the fsid of cluster %s is %s. refusing to orchestrate a different cluster", status.FSID, c.Namespace, c.Info.FSID)
+		logger.Error(message)
+		c.recordEvent(v1.EventTypeWarning, string(cephv1.ClusterConditionFSIDMismatch), message)
+		condition.Status = v1.ConditionTrue
+		condition.Reason = string(cephv1.ClusterConditionFSIDMismatch)
+		condition.Message = message
+		mismatchErr = fmt.Errorf("%s", message)
+	}
+	c.updateStatusCondition(condition)
+	return mismatchErr
+}
+
+// checkMgrSchedulable reports in the status of the cluster whether the placement of the mgr matches any node. The mgr
+// deployments are not created until the placement is fixed. The condition is not changed if the placement was not
+// checked, e.g. since the nodes could not be listed.
+func (c *cluster) checkMgrSchedulable(message string, checked bool) {
+	if !checked {
+		return
+	}
+	c.reportCondition(cephv1.ClusterConditionMgrUnschedulable, message, "MgrSchedulable", "the placement of the mgr matches at least one node")
+}
+
+// checkMgrActiveAmbiguous reports in the status of the cluster whether ceph reported an inconsistent active mgr. The
+// condition is cleared by the next orchestration that finds a consistent active mgr.
+func (c *cluster) checkMgrActiveAmbiguous(message string) {
+	c.reportCondition(cephv1.ClusterConditionMgrActiveAmbiguous, message, "MgrActiveConsistent", "ceph reported a consistent active mgr")
+}
+
+// checkMgrResources reports in the status of the cluster whether the resource limits of the mgr are below the
+// recommendations for its enabled modules. The orchestration continues since the mgr may still run fine.
+func (c *cluster) checkMgrResources(warnings []string) {
+	c.reportCondition(cephv1.ClusterConditionMgrResourcesLow, strings.Join(warnings, "; "),
+		"MgrResourcesSufficient", "the mgr resource limits meet the recommendations for the enabled modules")
+}
+
+// checkDashboardAvailability reports in the status of the cluster whether the dashboard is served by a single mgr.
+// This is only guidance, the orchestration continues.
+func (c *cluster) checkDashboardAvailability(warning string) {
+	c.reportCondition(cephv1.ClusterConditionDashboardNotHighlyAvailable, warning,
+		"DashboardHighlyAvailable", "the dashboard is disabled or served by more than one mgr")
+}
+
+// findMismatchedDaemonVersions returns a description of the mon, mgr, osd and rbd-mirror daemons that are not
+// running the expected ceph version
+func findMismatchedDaemonVersions(expected cephver.CephVersion, runningVersions client.CephDaemonsVersions) ([]string, error) {
+	daemons := []struct {
+		name     string
+		versions map[string]int
+	}{
+		{"mon", runningVersions.Mon},
+		{"mgr", runningVersions.Mgr},
+		{"osd", runningVersions.Osd},
+		{"rbd-mirror", runningVersions.RbdMirror},
+	}
+
+	mismatches := []string{}
+	for _, daemon := range daemons {
+		for v, count := range daemon.versions {
+			version, err := cephver.ExtractCephVersion(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to extract the ceph version of the %s daemons. %+v", daemon.name, err)
+			}
+			if !cephver.IsIdentical(*version, expected) {
+				mismatches = append(mismatches, fmt.Sprintf("%d %s running %s", count, daemon.name, version.String()))
+			}
+		}
+	}
+	sort.Strings(mismatches)
+
+	return mismatches, nil
+}
+
+// allowUnsupportedOnce returns whether the CephCluster CR allows an unsupported version for the next orchestration
+func allowUnsupportedOnce(cephCluster *cephv1.CephCluster) bool {
+	return cephCluster.GetAnnotations()[allowUnsupportedOnceAnnotation] == "true"
+}
+
+// clearAllowUnsupportedOnce removes the annotation that allowed an unsupported version once the orchestration with
+// that version succeeded, so the permissive setting does not apply to the following orchestrations. The retries of
+// a failed orchestration are still allowed.
+func (c *cluster) clearAllowUnsupportedOnce() {
+	if !c.unsupportedAllowedOnce {
+		return
+	}
+	c.allowUnsupportedOnce = false
+	c.unsupportedAllowedOnce = false
+	c.removeAnnotation(allowUnsupportedOnceAnnotation)
+}
+
+// rotateAdminKeyRequested returns whether the CephCluster CR requests a rotation of the admin key
+func rotateAdminKeyRequested(cephCluster *cephv1.CephCluster) bool {
+	return cephCluster.GetAnnotations()[rotateAdminKeyAnnotation] == "true"
+}
+
+// rotateAdminKey rotates the admin key if it was requested and removes the annotation that requested it
+func (c *cluster) rotateAdminKey() error {
+	if !c.rotateAdminKeyRequested {
+		return nil
+	}
+	if err := c.mons.RotateAdminKey(); err != nil {
+		return err
+	}
+	c.rotateAdminKeyRequested = false
+	c.removeAnnotation(rotateAdminKeyAnnotation)
+	return nil
+}
+
+// confirmedNodeRemovals returns the nodes of which the CephCluster CR confirms the removal
+func confirmedNodeRemovals(cephCluster *cephv1.CephCluster) []string {
+	value := cephCluster.GetAnnotations()[confirmNodeRemovalAnnotation]
+	nodes := []string{}
+	for _, node := range strings.Split(value, ",") {
+		if node = strings.TrimSpace(node); node != "" {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// setSpecGeneration records the generation of the CephCluster CR that the spec was read from
+func (c *cluster) setSpecGeneration(generation int64) {
+	c.crMux.Lock()
+	defer c.crMux.Unlock()
+	c.specGeneration = generation
+}
+
+// currentSpecGeneration returns the generation of the CephCluster CR that the spec was read from
+func (c *cluster) currentSpecGeneration() int64 {
+	c.crMux.Lock()
+	defer c.crMux.Unlock()
+	return c.specGeneration
+}
+
+// setConfirmedNodeRemovals records the nodes of which the CephCluster CR confirms the removal
+func (c *cluster) setConfirmedNodeRemovals(nodes []string) {
+	c.crMux.Lock()
+	defer c.crMux.Unlock()
+	c.confirmedNodeRemovals = nodes
+}
+
+// confirmedRemovals returns the nodes of which the removal was confirmed
+func (c *cluster) confirmedRemovals() []string {
+	c.crMux.Lock()
+	defer c.crMux.Unlock()
+	return c.confirmedNodeRemovals
+}
+
+// clearConfirmedNodeRemovals removes the confirmation annotation once the OSDs of the confirmed nodes were removed.
+// The confirmation of the nodes that were skipped, e.g. since their removal was not safe, is kept for the next
+// orchestration.
+func (c *cluster) clearConfirmedNodeRemovals(skippedNodes []string) {
+	skipped := map[string]bool{}
+	for _, node := range skippedNodes {
+		skipped[node] = true
+	}
+	remaining := []string{}
+	c.crMux.Lock()
+	for _, node := range c.confirmedNodeRemovals {
+		if skipped[node] {
+			remaining = append(remaining, node)
+		}
+	}
+	changed := len(remaining) != len(c.confirmedNodeRemovals)
+	if changed {
+		c.confirmedNodeRemovals = remaining
+	}
+	c.crMux.Unlock()
+	if !changed {
+		return
+	}
+	if len(remaining) == 0 {
+		c.removeAnnotation(confirmNodeRemovalAnnotation)
+		return
+	}
+	c.setAnnotation(confirmNodeRemovalAnnotation, strings.Join(remaining, ","))
+}
+
+// setAnnotation sets an annotation of the CephCluster CR
+func (c *cluster) setAnnotation(name, value string) {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to set the annotation %s. %+v", c.Namespace, name, err)
+		return
+	}
+	if cephCluster.Annotations == nil {
+		cephCluster.Annotations = map[string]string{}
+	}
+	cephCluster.Annotations[name] = value
+	if _, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Update(cephCluster); err != nil {
+		logger.Errorf("failed to set the annotation %s of cluster %s. %+v", name, c.Namespace, err)
+	}
+}
+
+// removeAnnotation removes an annotation from the CephCluster CR
+func (c *cluster) removeAnnotation(name string) {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to remove the annotation %s. %+v", c.Namespace, name, err)
+		return
+	}
+	if _, ok := cephCluster.Annotations[name]; !ok {
+		return
+	}
+	delete(cephCluster.Annotations, name)
+	if _, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Update(cephCluster); err != nil {
+		logger.Errorf("failed to remove the annotation %s from cluster %s. %+v", name, c.Namespace, err)
+	}
+}
+
 // This function compare the Ceph spec image and the cluster running version
 // It returns false if the image is different and true if identical
 func diffImageSpecAndClusterRunningVersion(imageSpecVersion cephver.CephVersion, runningVersions client.CephDaemonsVersions) (bool, error) {
@@ -18,15 +18,33 @@ limitations under the License.
 package cluster
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
 	"testing"
+	"time"
 
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/k8sutil"
 	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestDiffImageSpecAndClusterRunningVersion(t *testing.T) {
@@ -147,6 +165,63 @@ func TestSupportedVersion(t *testing.T) {
 	assert.NoError(t, c.validateCephVersion(v))
 }
 
+func TestAllowPreReleaseVersion(t *testing.T) {
+	c := testSpec()
+	v := &cephver.CephVersion{Major: 14, Minor: 2, Extra: 5}
+
+	// a pre-release of a supported version is not valid
+	c.cephPreRelease = true
+	assert.Error(t, c.validateCephVersion(v))
+
+	// allowing the unsupported versions does not allow the pre-releases
+	c.Spec.CephVersion.AllowUnsupported = true
+	assert.Error(t, c.validateCephVersion(v))
+
+	c.Spec.CephVersion.AllowPreRelease = true
+	assert.NoError(t, c.validateCephVersion(v))
+}
+
+func TestAllowBelowMinimumVersion(t *testing.T) {
+	// luminous is both unsupported and older than the minimum version
+	luminous := &cephver.CephVersion{Major: 12, Minor: 2, Extra: 10}
+	// a supported release, but older than the minimum version
+	oldMimic := &cephver.CephVersion{Major: 13, Minor: 2, Extra: 3}
+
+	tests := []struct {
+		allowUnsupported  bool
+		allowBelowMinimum bool
+		luminousValid     bool
+		oldMimicValid     bool
+	}{
+		{allowUnsupported: false, allowBelowMinimum: false, luminousValid: false, oldMimicValid: false},
+		{allowUnsupported: true, allowBelowMinimum: false, luminousValid: false, oldMimicValid: false},
+		{allowUnsupported: false, allowBelowMinimum: true, luminousValid: false, oldMimicValid: true},
+		{allowUnsupported: true, allowBelowMinimum: true, luminousValid: true, oldMimicValid: true},
+	}
+	for _, test := range tests {
+		c := testSpec()
+		c.Spec.CephVersion.AllowUnsupported = test.allowUnsupported
+		c.Spec.CephVersion.AllowBelowMinimum = test.allowBelowMinimum
+
+		err := c.validateCephVersion(luminous)
+		assert.Equal(t, test.luminousValid, err == nil, "allowUnsupported=%t allowBelowMinimum=%t", test.allowUnsupported, test.allowBelowMinimum)
+		err = c.validateCephVersion(oldMimic)
+		assert.Equal(t, test.oldMimicValid, err == nil, "allowUnsupported=%t allowBelowMinimum=%t", test.allowUnsupported, test.allowBelowMinimum)
+
+		// the supported versions are always valid
+		assert.NoError(t, c.validateCephVersion(&cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}))
+	}
+}
+
+func TestCompletedState(t *testing.T) {
+	c := &cluster{Spec: &cephv1.ClusterSpec{}}
+	assert.Equal(t, cephv1.ClusterStateCreated, c.completedState())
+
+	// the cluster with only the mons is bootstrapped
+	c.Spec.BootstrapOnly = true
+	assert.Equal(t, cephv1.ClusterStateBootstrapped, c.completedState())
+}
+
 func testSpec() cluster {
 	clientset := testop.New(1)
 	context := &clusterd.Context{
@@ -154,3 +229,721 @@ func testSpec() cluster {
 	}
 	return cluster{Spec: &cephv1.ClusterSpec{}, context: context}
 }
+
+func TestResourceVersionChanged(t *testing.T) {
+	c := testSpec()
+
+	// the first resourceVersion is always a change
+	assert.True(t, c.resourceVersionChanged("100"))
+
+	// the same resourceVersion was already processed
+	assert.False(t, c.resourceVersionChanged("100"))
+
+	// a new resourceVersion must be processed
+	assert.True(t, c.resourceVersionChanged("101"))
+	assert.False(t, c.resourceVersionChanged("101"))
+
+	// an unknown resourceVersion is never skipped
+	assert.True(t, c.resourceVersionChanged(""))
+	assert.True(t, c.resourceVersionChanged(""))
+}
+
+func TestFindMismatchedDaemonVersions(t *testing.T) {
+	expected := cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}
+	runningVersions := []byte(`
+	{
+		"mon": {
+			"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 3
+		},
+		"mgr": {
+			"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 1
+		},
+		"osd": {
+			"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 2
+		}
+	}`)
+	var versions client.CephDaemonsVersions
+	err := json.Unmarshal(runningVersions, &versions)
+	assert.Nil(t, err)
+
+	mismatches, err := findMismatchedDaemonVersions(expected, versions)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(mismatches))
+
+	// some osds are running an older image
+	runningVersions = []byte(`
+	{
+		"mon": {
+			"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 3
+		},
+		"osd": {
+			"ceph version 14.2.2 (4f8fa0a0024755aae7d95567c63f11d6862d55be) nautilus (stable)": 1,
+			"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 2
+		}
+	}`)
+	versions = client.CephDaemonsVersions{}
+	err = json.Unmarshal(runningVersions, &versions)
+	assert.Nil(t, err)
+
+	mismatches, err = findMismatchedDaemonVersions(expected, versions)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"1 osd running 14.2.2 nautilus"}, mismatches)
+
+	// the version cannot be parsed
+	versions = client.CephDaemonsVersions{Mgr: map[string]int{"unknown": 1}}
+	_, err = findMismatchedDaemonVersions(expected, versions)
+	assert.NotNil(t, err)
+}
+
+func TestClassifyChange(t *testing.T) {
+	old := cephv1.ClusterSpec{
+		CephVersion: cephv1.CephVersionSpec{Image: "ceph/ceph:v14.2.4"},
+		Mon:         cephv1.MonSpec{Count: 3},
+		Storage: rookalpha.StorageScopeSpec{
+			Nodes: []rookalpha.Node{{Name: "b"}, {Name: "a"}},
+		},
+	}
+
+	// no change, the order of the nodes is ignored
+	new := *old.DeepCopy()
+	new.Storage.Nodes = []rookalpha.Node{{Name: "a"}, {Name: "b"}}
+	impact := ClassifyChange(*old.DeepCopy(), new)
+	assert.False(t, impact.Changed())
+
+	// the annotations of the mgr changed
+	new = *old.DeepCopy()
+	new.Annotations = rookalpha.AnnotationsSpec{cephv1.KeyMgr: {"key": "value"}}
+	impact = ClassifyChange(*old.DeepCopy(), new)
+	assert.True(t, impact.Benign)
+	assert.True(t, impact.Mgr)
+	assert.False(t, impact.Mon)
+	assert.False(t, impact.Structural)
+
+	// the mon count changed
+	new = *old.DeepCopy()
+	new.Mon.Count = 5
+	impact = ClassifyChange(*old.DeepCopy(), new)
+	assert.True(t, impact.Mon)
+	assert.False(t, impact.Mgr)
+	assert.False(t, impact.OSD)
+	assert.False(t, impact.Benign)
+	assert.False(t, impact.Structural)
+
+	// the resource profile changed, but the osds keep their own resources
+	new = *old.DeepCopy()
+	new.ResourceProfile = cephv1.ResourceProfileSmall
+	new.Resources = rookalpha.ResourceSpec{cephv1.ResourcesKeyOSD: cephv1.GetOSDResources(cephv1.ResolveResourceProfile(cephv1.ResourceProfileLarge, nil))}
+	oldWithOSDResources := *old.DeepCopy()
+	oldWithOSDResources.Resources = new.Resources
+	impact = ClassifyChange(oldWithOSDResources, new)
+	assert.True(t, impact.Mon)
+	assert.True(t, impact.Mgr)
+	assert.True(t, impact.RBDMirror)
+	assert.False(t, impact.OSD)
+
+	// the storage topology changed
+	new = *old.DeepCopy()
+	new.Storage.Nodes = append(new.Storage.Nodes, rookalpha.Node{Name: "c"})
+	impact = ClassifyChange(*old.DeepCopy(), new)
+	assert.True(t, impact.OSD)
+	assert.True(t, impact.Structural)
+	assert.False(t, impact.Mon)
+
+	// the monitoring settings changed
+	new = *old.DeepCopy()
+	new.Monitoring.Enabled = true
+	impact = ClassifyChange(*old.DeepCopy(), new)
+	assert.True(t, impact.Monitoring)
+	assert.False(t, impact.Mgr)
+
+	// a new ceph version affects all the daemons
+	new = *old.DeepCopy()
+	new.CephVersion.Image = "ceph/ceph:v14.2.5"
+	impact = ClassifyChange(*old.DeepCopy(), new)
+	assert.True(t, impact.Mon)
+	assert.True(t, impact.Mgr)
+	assert.True(t, impact.OSD)
+	assert.True(t, impact.RBDMirror)
+	assert.False(t, impact.Structural)
+
+	// the network is a structural change for all daemons
+	new = *old.DeepCopy()
+	new.Network.HostNetwork = true
+	impact = ClassifyChange(*old.DeepCopy(), new)
+	assert.True(t, impact.Network)
+	assert.True(t, impact.Structural)
+	assert.True(t, impact.Mon)
+	assert.True(t, impact.OSD)
+	assert.Equal(t, "mon,mgr,osd,rbdmirror,network,structural", impact.String())
+
+	// the DNS of the pods changes all the daemons, but does not move them to another network
+	new = *old.DeepCopy()
+	new.Network.DNSConfig = &v1.PodDNSConfig{Nameservers: []string{"10.0.0.10"}}
+	impact = ClassifyChange(*old.DeepCopy(), new)
+	assert.False(t, impact.Network)
+	assert.False(t, impact.Structural)
+	assert.True(t, impact.Mon)
+	assert.True(t, impact.OSD)
+
+	// the settings of the orchestration do not change the daemons
+	for _, update := range []func(spec *cephv1.ClusterSpec){
+		func(spec *cephv1.ClusterSpec) { spec.FeatureGates = map[string]bool{"UpgradeRollback": true} },
+		func(spec *cephv1.ClusterSpec) { spec.Initialization.StabilizationSeconds = 30 },
+		func(spec *cephv1.ClusterSpec) { spec.UpgradeRollback.Enabled = true },
+	} {
+		new = *old.DeepCopy()
+		update(&new)
+		impact = ClassifyChange(*old.DeepCopy(), new)
+		assert.True(t, impact.Settings)
+		assert.True(t, impact.Changed())
+		assert.False(t, impact.Mon)
+		assert.False(t, impact.Structural)
+	}
+	assert.Equal(t, "none", ChangeImpact{}.String())
+}
+
+func TestAllowUnsupportedOnce(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{
+		Name:        "my-cluster",
+		Namespace:   "ns",
+		Annotations: map[string]string{allowUnsupportedOnceAnnotation: "true"},
+	}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+	assert.True(t, allowUnsupportedOnce(cephCluster))
+	c.allowUnsupportedOnce = allowUnsupportedOnce(cephCluster)
+
+	// the unsupported version is allowed until the orchestration succeeded, including its retries
+	v := &cephver.CephVersion{Major: 15, Minor: 2, Extra: 0}
+	assert.NoError(t, c.validateCephVersion(v))
+	assert.NoError(t, c.validateCephVersion(v))
+	assert.True(t, c.allowUnsupportedOnce)
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.True(t, allowUnsupportedOnce(updated))
+
+	// the annotation is removed from the CR after the successful orchestration
+	c.clearAllowUnsupportedOnce()
+	assert.False(t, c.allowUnsupportedOnce)
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.False(t, allowUnsupportedOnce(updated))
+
+	// the next validation fails again
+	assert.Error(t, c.validateCephVersion(v))
+}
+
+func TestCorruptClusterInfo(t *testing.T) {
+	c := testSpec()
+	c.Namespace = "ns"
+	v := &cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}
+
+	// there is no cluster info for a new cluster
+	assert.NoError(t, c.validateCephVersion(v))
+
+	// the cluster info exists but the keys are missing
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon", Namespace: "ns"},
+		Data: map[string][]byte{
+			"cluster-name": []byte("ns"),
+			"fsid":         []byte("fsid"),
+		},
+	}
+	_, err := c.context.Clientset.CoreV1().Secrets("ns").Create(secret)
+	assert.Nil(t, err)
+	assert.Error(t, c.validateCephVersion(v))
+	events, err := c.context.Clientset.CoreV1().Events("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events.Items))
+	assert.Equal(t, "CorruptClusterInfo", events.Items[0].Reason)
+}
+
+func TestSetJobResources(t *testing.T) {
+	spec := &v1.PodSpec{
+		InitContainers: []v1.Container{{Name: "init"}},
+		Containers:     []v1.Container{{Name: "cmd"}},
+	}
+	resources := v1.ResourceRequirements{
+		Limits:   v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m")},
+		Requests: v1.ResourceList{v1.ResourceMemory: resource.MustParse("32Mi")},
+	}
+
+	setJobResources(spec, resources)
+	assert.Equal(t, resources, spec.InitContainers[0].Resources)
+	assert.Equal(t, resources, spec.Containers[0].Resources)
+}
+
+func TestNamespaceTerminating(t *testing.T) {
+	c := testSpec()
+	c.Namespace = "ns"
+
+	// the namespace does not exist
+	assert.False(t, c.namespaceTerminating())
+
+	namespace := &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "ns"}}
+	_, err := c.context.Clientset.CoreV1().Namespaces().Create(namespace)
+	assert.Nil(t, err)
+	assert.False(t, c.namespaceTerminating())
+
+	now := metav1.Now()
+	namespace.DeletionTimestamp = &now
+	_, err = c.context.Clientset.CoreV1().Namespaces().Update(namespace)
+	assert.Nil(t, err)
+	assert.True(t, c.namespaceTerminating())
+
+	// the orchestration is skipped
+	assert.Equal(t, errNamespaceTerminating, c.createInstance(context.Background(), "rook/rook:myversion", cephver.Nautilus))
+	assert.False(t, c.orchestrationNeeded)
+}
+
+func TestCreateInstanceCanceled(t *testing.T) {
+	c := newCluster(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}, testSpec().context, nil)
+
+	// the orchestration does not start after the cluster is deleted
+	c.cancelOrchestration()
+	err := c.createInstance(c.ctx, "rook/rook:myversion", cephver.Nautilus)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "canceled the orchestration of cluster ns")
+	assert.False(t, c.orchestrationRunning)
+	assert.False(t, c.orchestrationNeeded)
+}
+
+func TestWaitForOrchestrationStop(t *testing.T) {
+	c := newCluster(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}, testSpec().context, nil)
+	assert.Nil(t, c.waitForOrchestrationStop(time.Millisecond, 10*time.Millisecond))
+
+	// the deletion waits until the canceled orchestration returns
+	c.orchestrationRunning = true
+	err := c.waitForOrchestrationStop(time.Millisecond, 10*time.Millisecond)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "the orchestration of cluster ns did not stop")
+
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		c.unsetOrchestrationStatus()
+	}()
+	assert.Nil(t, c.waitForOrchestrationStop(time.Millisecond, time.Second))
+}
+
+func TestCreateInstanceWhileRunning(t *testing.T) {
+	c := newCluster(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}, testSpec().context, nil)
+	c.orchestrationRunning = true
+
+	// the duplicate reconciles return immediately and leave the changes to the running orchestration
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, c.createInstance(c.ctx, "rook/rook:myversion", cephver.Nautilus))
+		}()
+	}
+	wg.Wait()
+	assert.True(t, c.orchestrationRunning)
+	assert.True(t, c.orchestrationNeeded)
+	assert.Equal(t, 10, c.pendingOrchestrations)
+
+	// the running orchestration picks up the changes once
+	c.unsetOrchestrationStatus()
+	assert.True(t, c.checkSetOrchestrationStatus())
+	assert.False(t, c.checkSetOrchestrationStatus())
+	assert.Equal(t, 0, c.pendingOrchestrations)
+}
+
+func TestValidatePriorityClassNames(t *testing.T) {
+	c := testSpec()
+	names := rookalpha.PriorityClassNamesSpec{rookalpha.KeyAll: "rook-critical", cephv1.KeyMgr: ""}
+
+	// the priority class does not exist
+	assert.NotNil(t, validatePriorityClassNames(c.context, names))
+
+	priorityClass := &schedulingv1.PriorityClass{ObjectMeta: metav1.ObjectMeta{Name: "rook-critical"}, Value: 1000}
+	_, err := c.context.Clientset.SchedulingV1().PriorityClasses().Create(priorityClass)
+	assert.Nil(t, err)
+	assert.Nil(t, validatePriorityClassNames(c.context, names))
+
+	// the mgr falls back to the priority class of all the pods
+	assert.Equal(t, "rook-critical", cephv1.GetMgrPriorityClassName(names))
+	names[cephv1.KeyMgr] = "mgr-critical"
+	assert.Equal(t, "mgr-critical", cephv1.GetMgrPriorityClassName(names))
+}
+
+func TestValidateCABundle(t *testing.T) {
+	assert.Nil(t, validateCABundle(cephv1.CABundleSpec{}))
+	assert.Nil(t, validateCABundle(cephv1.CABundleSpec{ConfigMapName: "ca"}))
+	assert.Nil(t, validateCABundle(cephv1.CABundleSpec{SecretName: "ca", Key: "ca.pem"}))
+	assert.NotNil(t, validateCABundle(cephv1.CABundleSpec{ConfigMapName: "ca", SecretName: "ca"}))
+}
+
+func TestValidateSchedulerName(t *testing.T) {
+	assert.Nil(t, validateSchedulerName(""))
+	assert.Nil(t, validateSchedulerName("topology-scheduler"))
+	assert.Nil(t, validateSchedulerName("scheduler.example.com"))
+	assert.NotNil(t, validateSchedulerName("Topology_Scheduler"))
+	assert.NotNil(t, validateSchedulerName("-scheduler"))
+}
+
+func TestValidateTerminationGracePeriods(t *testing.T) {
+	periods := rookalpha.TerminationGracePeriodSecondsSpec{}
+	assert.Nil(t, validateTerminationGracePeriods(periods))
+	assert.Nil(t, cephv1.GetMgrTerminationGracePeriodSeconds(periods))
+
+	// the mgr falls back to the grace period of all the pods
+	periods[rookalpha.KeyAll] = 60
+	assert.Nil(t, validateTerminationGracePeriods(periods))
+	assert.Equal(t, int64(60), *cephv1.GetMgrTerminationGracePeriodSeconds(periods))
+	periods[cephv1.KeyMgr] = 0
+	assert.Equal(t, int64(0), *cephv1.GetMgrTerminationGracePeriodSeconds(periods))
+
+	periods[cephv1.KeyMgr] = -1
+	assert.NotNil(t, validateTerminationGracePeriods(periods))
+}
+
+func TestRotateAdminKeyRequested(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}}
+	assert.False(t, rotateAdminKeyRequested(cephCluster))
+
+	cephCluster.Annotations = map[string]string{rotateAdminKeyAnnotation: "true"}
+	assert.True(t, rotateAdminKeyRequested(cephCluster))
+
+	// nothing to rotate when it was not requested
+	c := testSpec()
+	assert.Nil(t, c.rotateAdminKey())
+}
+
+func TestConfirmedNodeRemovals(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}}
+	assert.Equal(t, []string{}, confirmedNodeRemovals(cephCluster))
+
+	cephCluster.Annotations = map[string]string{confirmNodeRemovalAnnotation: "node1, node2,"}
+	assert.Equal(t, []string{"node1", "node2"}, confirmedNodeRemovals(cephCluster))
+}
+
+func TestClearConfirmedNodeRemovals(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns",
+		Annotations: map[string]string{confirmNodeRemovalAnnotation: "node1,node2"}}}
+	context := &clusterd.Context{RookClientset: rookfake.NewSimpleClientset(cephCluster)}
+	c := &cluster{Namespace: "ns", crdName: "my-cluster", context: context, confirmedNodeRemovals: confirmedNodeRemovals(cephCluster)}
+	annotation := func() string {
+		updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+		assert.Nil(t, err)
+		return updated.Annotations[confirmNodeRemovalAnnotation]
+	}
+
+	// the confirmation of a skipped node is kept
+	c.clearConfirmedNodeRemovals([]string{"node2"})
+	assert.Equal(t, []string{"node2"}, c.confirmedNodeRemovals)
+	assert.Equal(t, "node2", annotation())
+
+	// nothing changes while the node is skipped
+	c.clearConfirmedNodeRemovals([]string{"node2"})
+	assert.Equal(t, "node2", annotation())
+
+	// the annotation is removed once the osds of the last confirmed node were removed
+	c.clearConfirmedNodeRemovals([]string{})
+	assert.Equal(t, 0, len(c.confirmedNodeRemovals))
+	assert.Equal(t, "", annotation())
+}
+
+func TestValidateClusterFSID(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	reportedFSID := "myfsid"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			if args[0] == "status" {
+				if reportedFSID == "" {
+					return "", fmt.Errorf("timed out")
+				}
+				return `{"fsid":"` + reportedFSID + `"}`, nil
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+		Executor:      executor,
+	}
+	c := newCluster(cephCluster, context, nil)
+	c.Info = &cephconfig.ClusterInfo{Name: "ns", FSID: "myfsid"}
+
+	// the orchestration does not continue without the fsid of the mons
+	reportedFSID = ""
+	assert.NotNil(t, c.validateClusterFSID())
+	reportedFSID = "myfsid"
+
+	assert.Nil(t, c.validateClusterFSID())
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1.ConditionFalse, updated.Status.Conditions[0].Status)
+
+	// the mons belong to another cluster
+	reportedFSID = "otherfsid"
+	assert.NotNil(t, c.validateClusterFSID())
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cephv1.ClusterConditionFSIDMismatch, updated.Status.Conditions[0].Type)
+	assert.Equal(t, v1.ConditionTrue, updated.Status.Conditions[0].Status)
+}
+
+func TestWaitForCleanPGs(t *testing.T) {
+	waitForCleanPGsInterval = time.Millisecond
+	statusCalls := 0
+	cleanAfter := 3
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			if args[0] == "status" {
+				statusCalls++
+				if statusCalls < cleanAfter {
+					return `{"pgmap":{"num_pgs":100,"pgs_by_state":[{"state_name":"active+clean","count":90},{"state_name":"active+remapped+backfilling","count":10}]}}`, nil
+				}
+				return `{"pgmap":{"num_pgs":100,"pgs_by_state":[{"state_name":"active+clean","count":100}]}}`, nil
+			}
+			return "", nil
+		},
+	}
+	c := &cluster{
+		Namespace: "ns",
+		Info:      &cephconfig.ClusterInfo{Name: "ns"},
+		context:   &clusterd.Context{Executor: executor},
+		stopCh:    make(chan struct{}),
+	}
+
+	// the pgs are not checked if the wait is not enabled
+	assert.Nil(t, c.waitForCleanPGs(cephv1.WaitForCleanPGsSpec{}))
+	assert.Equal(t, 0, statusCalls)
+
+	// wait until the backfilling completed
+	assert.Nil(t, c.waitForCleanPGs(cephv1.WaitForCleanPGsSpec{Enabled: true}))
+	assert.Equal(t, 3, statusCalls)
+
+	// the wait stops when the cluster is removed
+	statusCalls = 0
+	cleanAfter = 1000
+	waitForCleanPGsInterval = time.Hour
+	close(c.stopCh)
+	assert.NotNil(t, c.waitForCleanPGs(cephv1.WaitForCleanPGsSpec{Enabled: true, TimeoutMinutes: 1}))
+	assert.Equal(t, 1, statusCalls)
+}
+
+func TestVersionJobImage(t *testing.T) {
+	assert.Equal(t, "ceph/ceph:v14.2.4", versionJobImage("ceph/ceph:v14.2.4"))
+
+	os.Setenv(versionJobImageEnvVar, "registry.local/ceph-version:v14")
+	defer os.Unsetenv(versionJobImageEnvVar)
+	assert.Equal(t, "registry.local/ceph-version:v14", versionJobImage("ceph/ceph:v14.2.4"))
+}
+
+func TestPinnedCephVersion(t *testing.T) {
+	version, err := pinnedCephVersion("14.2.4")
+	assert.Nil(t, err)
+	assert.Equal(t, cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}, *version)
+	version, err = pinnedCephVersion("v13.2.6")
+	assert.Nil(t, err)
+	assert.Equal(t, cephver.CephVersion{Major: 13, Minor: 2, Extra: 6}, *version)
+
+	for _, pinned := range []string{"14", "14.2", "nautilus", "14.2.4-1", " 14.2.4"} {
+		_, err = pinnedCephVersion(pinned)
+		assert.NotNil(t, err, pinned)
+	}
+
+	// the version job is skipped when the version is pinned, so no context is needed
+	c := &cluster{Namespace: "ns"}
+	version, err = c.cephImageVersion(context.TODO(), "rook/ceph:master", cephv1.CephVersionSpec{Image: "ceph/ceph:v14.2.4", PinnedVersion: "14.2.4"}, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}, *version)
+	_, err = c.cephImageVersion(context.TODO(), "rook/ceph:master", cephv1.CephVersionSpec{Image: "ceph/ceph:v14.2.4", PinnedVersion: "14"}, time.Minute)
+	assert.NotNil(t, err)
+
+	// the pinned version must match the version in the tag of the image
+	_, err = c.cephImageVersion(context.TODO(), "rook/ceph:master", cephv1.CephVersionSpec{Image: "ceph/ceph:v14.2.4", PinnedVersion: "14.2.5"}, time.Minute)
+	assert.NotNil(t, err)
+	_, err = c.cephImageVersion(context.TODO(), "rook/ceph:master", cephv1.CephVersionSpec{Image: "ceph/ceph:v13", PinnedVersion: "14.2.4"}, time.Minute)
+	assert.NotNil(t, err)
+	_, err = c.cephImageVersion(context.TODO(), "rook/ceph:master", cephv1.CephVersionSpec{Image: "ceph/ceph:v14.2-20190917", PinnedVersion: "14.2.4"}, time.Minute)
+	assert.Nil(t, err)
+	_, err = c.cephImageVersion(context.TODO(), "rook/ceph:master", cephv1.CephVersionSpec{Image: "ceph/ceph:latest", PinnedVersion: "14.2.4"}, time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, c.cephPreRelease)
+
+	// a pinned release candidate requires allowPreRelease like a detected one
+	version, err = c.cephImageVersion(context.TODO(), "rook/ceph:master", cephv1.CephVersionSpec{Image: "ceph/ceph:v15.1.0-rc", PinnedVersion: "15.1.0-rc"}, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, cephver.CephVersion{Major: 15, Minor: 1, Extra: 0}, *version)
+	assert.True(t, c.cephPreRelease)
+	_, err = c.cephImageVersion(context.TODO(), "rook/ceph:master", cephv1.CephVersionSpec{Image: "ceph/ceph:v15.1.0-rc1", PinnedVersion: "15.1.0"}, time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, c.cephPreRelease)
+	_, err = c.cephImageVersion(context.TODO(), "rook/ceph:master", cephv1.CephVersionSpec{Image: "ceph/ceph:v14.2.4", PinnedVersion: "14.2.4"}, time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, c.cephPreRelease)
+}
+
+func TestVersionFromImageTag(t *testing.T) {
+	version, ok := versionFromImageTag("ceph/ceph:v14.2.4-20190917")
+	assert.True(t, ok)
+	assert.Equal(t, cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}, *version)
+
+	version, ok = versionFromImageTag("registry.local:5000/ceph/ceph:v13")
+	assert.True(t, ok)
+	assert.Equal(t, 13, version.Major)
+
+	// no version in the tag
+	_, ok = versionFromImageTag("registry.local:5000/ceph/ceph")
+	assert.False(t, ok)
+	_, ok = versionFromImageTag("ceph/ceph:latest")
+	assert.False(t, ok)
+	_, ok = versionFromImageTag("ceph/daemon-base:latest-20190917")
+	assert.False(t, ok)
+}
+
+func TestVersionJobNodeSelector(t *testing.T) {
+	spec := &cephv1.ClusterSpec{}
+	assert.Nil(t, versionJobNodeSelector(spec))
+
+	// derived from the all placement
+	spec.Placement = rookalpha.PlacementSpec{
+		rookalpha.KeyAll: rookalpha.Placement{
+			NodeAffinity: &v1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+					NodeSelectorTerms: []v1.NodeSelectorTerm{{
+						MatchExpressions: []v1.NodeSelectorRequirement{
+							{Key: "role", Operator: v1.NodeSelectorOpIn, Values: []string{"storage"}},
+							{Key: "kubernetes.io/arch", Operator: v1.NodeSelectorOpIn, Values: []string{"amd64"}},
+						},
+					}},
+				},
+			},
+		},
+	}
+	assert.Equal(t, map[string]string{"role": "storage", "kubernetes.io/arch": "amd64"}, versionJobNodeSelector(spec))
+
+	// expressions that are not a single value cannot be converted
+	terms := spec.Placement[rookalpha.KeyAll].NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	terms[0].MatchExpressions[1].Values = []string{"amd64", "arm64"}
+	assert.Nil(t, versionJobNodeSelector(spec))
+
+	// the explicit selector takes precedence
+	spec.CephVersion.ImageJobNodeSelector = map[string]string{"ceph-image": "cached"}
+	assert.Equal(t, map[string]string{"ceph-image": "cached"}, versionJobNodeSelector(spec))
+}
+
+func TestCreateOverrideConfigMap(t *testing.T) {
+	overrideConfigMapBackoff = wait.Backoff{Duration: time.Millisecond, Factor: 1, Steps: 3}
+	clientset := testop.New(1)
+	c := &cluster{Namespace: "ns", context: &clusterd.Context{Clientset: clientset}}
+
+	// the create succeeds after transient errors
+	failures := 2
+	clientset.PrependReactor("create", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if failures > 0 {
+			failures--
+			return true, nil, errors.NewServiceUnavailable("api server is down")
+		}
+		return false, nil, nil
+	})
+	assert.Nil(t, c.createOverrideConfigMap())
+	_, err := clientset.CoreV1().ConfigMaps("ns").Get(k8sutil.ConfigOverrideName, metav1.GetOptions{})
+	assert.Nil(t, err)
+
+	// the configmap already exists
+	assert.Nil(t, c.createOverrideConfigMap())
+
+	// the errors persist
+	failures = 10
+	assert.Nil(t, clientset.CoreV1().ConfigMaps("ns").Delete(k8sutil.ConfigOverrideName, &metav1.DeleteOptions{}))
+	err = c.createOverrideConfigMap()
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "after 3 attempts")
+	assert.Equal(t, 7, failures)
+}
+
+func TestRecreateOverrideConfigMap(t *testing.T) {
+	clientset := testop.New(1)
+	c := &cluster{Namespace: "ns", context: &clusterd.Context{Clientset: clientset}}
+
+	// the overrides of the user are seen by the orchestration
+	assert.Nil(t, c.createOverrideConfigMap())
+	cm, err := clientset.CoreV1().ConfigMaps("ns").Get(k8sutil.ConfigOverrideName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	cm.Data[k8sutil.ConfigOverrideVal] = "[global]\nosd pool default size = 2"
+	_, err = clientset.CoreV1().ConfigMaps("ns").Update(cm)
+	assert.Nil(t, err)
+	assert.Nil(t, c.createOverrideConfigMap())
+
+	// the deleted configmap is recreated with the overrides
+	assert.Nil(t, clientset.CoreV1().ConfigMaps("ns").Delete(k8sutil.ConfigOverrideName, &metav1.DeleteOptions{}))
+	assert.Nil(t, c.createOverrideConfigMap())
+	cm, err = clientset.CoreV1().ConfigMaps("ns").Get(k8sutil.ConfigOverrideName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "[global]\nosd pool default size = 2", cm.Data[k8sutil.ConfigOverrideVal])
+}
+
+func TestUpdateDaemonVersionsStatus(t *testing.T) {
+	runningVersions := []byte(`
+	{
+		"mon": {
+			"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 3
+		},
+		"osd": {
+			"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 10,
+			"ceph version 13.2.6 (7b695f835b03642f85998b2ae7b6dd093d9fbce4) mimic (stable)": 2
+		},
+		"overall": {
+			"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 13,
+			"ceph version 13.2.6 (7b695f835b03642f85998b2ae7b6dd093d9fbce4) mimic (stable)": 2
+		}
+	}`)
+	var versions client.CephDaemonsVersions
+	assert.Nil(t, json.Unmarshal(runningVersions, &versions))
+
+	expected := map[string]string{
+		"mon":     "3 on 14.2.4",
+		"osd":     "10 on 14.2.4, 2 on 13.2.6",
+		"overall": "13 on 14.2.4, 2 on 13.2.6",
+	}
+	assert.Equal(t, expected, formatDaemonVersions(versions))
+
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+	c.updateDaemonVersionsStatus(versions)
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, expected, updated.Status.DaemonVersions)
+}
+
+func TestCheckRBDMirroring(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+	condition := func() cephv1.ClusterCondition {
+		updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+		assert.Nil(t, err)
+		return updated.Status.Conditions[0]
+	}
+
+	// zero workers disable the rbd mirroring, which is reported in the status
+	assert.Nil(t, c.checkRBDMirroring(cephv1.RBDMirroringSpec{Workers: 0}))
+	assert.Equal(t, cephv1.ClusterConditionRBDMirroringInvalid, condition().Type)
+	assert.Equal(t, v1.ConditionFalse, condition().Status)
+	assert.Equal(t, "RBDMirroringDisabled", condition().Reason)
+
+	assert.Nil(t, c.checkRBDMirroring(cephv1.RBDMirroringSpec{Workers: 2}))
+	assert.Equal(t, v1.ConditionFalse, condition().Status)
+	assert.Equal(t, "RBDMirroringEnabled", condition().Reason)
+
+	// an invalid spec stops the orchestration and sets the condition
+	assert.NotNil(t, c.checkRBDMirroring(cephv1.RBDMirroringSpec{Workers: -1}))
+	assert.Equal(t, v1.ConditionTrue, condition().Status)
+	assert.Contains(t, condition().Message, "invalid number of rbd mirroring workers -1")
+}
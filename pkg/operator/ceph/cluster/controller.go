@@ -20,13 +20,16 @@ package cluster
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/coreos/pkg/capnslog"
 	opkit "github.com/rook/operator-kit"
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	"github.com/rook/rook/pkg/daemon/ceph/agent/flexvolume/attachment"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
@@ -47,20 +50,21 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
-	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/cache"
 )
 
 const (
-	crushConfigMapName       = "rook-crush-config"
-	crushmapCreatedKey       = "initialCrushMapCreated"
-	clusterCreateInterval    = 6 * time.Second
-	clusterCreateTimeout     = 60 * time.Minute
-	updateClusterInterval    = 30 * time.Second
-	updateClusterTimeout     = 1 * time.Hour
-	detectCephVersionTimeout = 15 * time.Minute
+	crushConfigMapName        = "rook-crush-config"
+	crushmapCreatedKey        = "initialCrushMapCreated"
+	clusterCreateInterval     = 6 * time.Second
+	clusterCreateTimeout      = 60 * time.Minute
+	updateClusterInterval     = 30 * time.Second
+	updateClusterTimeout      = 1 * time.Hour
+	detectCephVersionTimeout  = 15 * time.Minute
+	orchestrationStopInterval = 2 * time.Second
+	orchestrationStopTimeout  = 5 * time.Minute
 )
 
 const (
@@ -96,6 +100,30 @@ type ClusterController struct {
 	addClusterCallbacks []func(*cephv1.ClusterSpec) error
 	csiConfigMutex      *sync.Mutex
 	nodeStore           cache.Store
+	retryJitter         float64
+	upgradeNotifier     UpgradeNotifier
+	// computes the order of the clusters that are orchestrated for the same event
+	orchestrationPriority OrchestrationPriorityFunc
+	apiCircuitBreaker     *apiCircuitBreaker
+	orchestrationLimiter  *orchestrationLimiter
+	tracer                Tracer
+	specDiffLogging       string
+	// the updates of a CR without a spec change are ignored for this duration after a successful orchestration
+	orchestrationCooldown time.Duration
+	// guards the changes of the clusterMap and the reads outside of the informer, e.g. by the debug endpoint
+	clusterMapMux sync.Mutex
+	// the clusters that were rejected since their dataDirHostPath overlaps with an older cluster, by namespace,
+	// guarded by clusterMapMux
+	rejectedDataDirHostPaths map[string]rejectedDataDirHostPath
+}
+
+// rejectedDataDirHostPath is the dataDirHostPath of a rejected cluster. The cluster is only added again once its
+// dataDirHostPath changed or the older cluster was deleted.
+type rejectedDataDirHostPath struct {
+	path        string
+	allowShared bool
+	// the namespace of the older cluster that keeps the path
+	olderNamespace string
 }
 
 // NewClusterController create controller for watching cluster custom resources created
@@ -107,9 +135,35 @@ func NewClusterController(context *clusterd.Context, rookImage string, volumeAtt
 		clusterMap:          make(map[string]*cluster),
 		addClusterCallbacks: addClusterCallbacks,
 		csiConfigMutex:      &sync.Mutex{},
+		retryJitter:         orchestrationRetryJitter(os.Getenv(orchestrationRetryJitterEnvVar)),
+		upgradeNotifier:     nullUpgradeNotifier{},
+		// the priority can be overridden before the controller is started
+		orchestrationPriority: DefaultOrchestrationPriority,
+		apiCircuitBreaker:     newAPICircuitBreaker(context, apiCircuitBreakerThreshold(os.Getenv(apiCircuitBreakerThresholdEnvVar))),
+		orchestrationLimiter:  newOrchestrationLimiter(maxConcurrentOrchestrations(os.Getenv(maxConcurrentOrchestrationsEnvVar))),
+		tracer:                newTracer(os.Getenv(orchestrationTracingEnvVar)),
+		specDiffLogging:       specDiffLogging(os.Getenv(specDiffLoggingEnvVar)),
+		orchestrationCooldown: orchestrationCooldown(os.Getenv(orchestrationCooldownEnvVar)),
 	}
 }
 
+// SetUpgradeNotifier sets the notifier of the ceph upgrades. It must be set before the controller is started.
+func (c *ClusterController) SetUpgradeNotifier(notifier UpgradeNotifier) {
+	c.upgradeNotifier = notifier
+}
+
+// SetOrchestrationPriority sets the function that orders the clusters that need to be orchestrated for the same
+// event. It must be set before the controller is started.
+func (c *ClusterController) SetOrchestrationPriority(priority OrchestrationPriorityFunc) {
+	c.orchestrationPriority = priority
+}
+
+// SetTracer sets the tracer of the orchestrations, e.g. an adapter to an OpenTelemetry exporter. It must be set
+// before the controller is started.
+func (c *ClusterController) SetTracer(tracer Tracer) {
+	c.tracer = tracer
+}
+
 // StartWatch watches instances of cluster resources
 func (c *ClusterController) StartWatch(namespace string, stopCh chan struct{}) error {
 	resourceHandlerFuncs := cache.ResourceEventHandlerFuncs{
@@ -124,7 +178,11 @@ func (c *ClusterController) StartWatch(namespace string, stopCh chan struct{}) e
 		logger.Infof("start watching clusters in namespace: %v", namespace)
 	}
 	watcher := opkit.NewWatcher(ClusterResource, namespace, resourceHandlerFuncs, c.context.RookClientset.CephV1().RESTClient())
-	go watcher.Watch(&cephv1.CephCluster{}, stopCh)
+	go func() {
+		// the clusters that already exist, e.g. after a restart of the operator, are orchestrated by priority
+		c.addExistingClusters(namespace)
+		watcher.Watch(&cephv1.CephCluster{}, stopCh)
+	}()
 
 	// Watch for events on new/updated K8s Nodes objects
 
@@ -169,12 +227,32 @@ func (c *ClusterController) StartWatch(namespace string, stopCh chan struct{}) e
 }
 
 func (c *ClusterController) StopWatch() {
+	c.clusterMapMux.Lock()
+	defer c.clusterMapMux.Unlock()
 	for _, cluster := range c.clusterMap {
+		cluster.cancelOrchestration()
 		close(cluster.stopCh)
 	}
 	c.clusterMap = make(map[string]*cluster)
 }
 
+// stopOrchestration cancels the orchestration of the cluster in the namespace and waits until it has returned.
+// Returns false if the orchestration is still running, in which case the cluster must not be deleted yet.
+func (c *ClusterController) stopOrchestration(namespace string) bool {
+	c.clusterMapMux.Lock()
+	cluster, ok := c.clusterMap[namespace]
+	c.clusterMapMux.Unlock()
+	if !ok {
+		return true
+	}
+	cluster.cancelOrchestration()
+	if err := cluster.waitForOrchestrationStop(orchestrationStopInterval, orchestrationStopTimeout); err != nil {
+		logger.Errorf("failed to delete cluster %s. %+v", namespace, err)
+		return false
+	}
+	return true
+}
+
 // ************************************************************************************************
 // Add event functions
 // ************************************************************************************************
@@ -189,7 +267,7 @@ func (c *ClusterController) onK8sNodeAdd(obj interface{}) {
 		return
 	}
 
-	for _, cluster := range c.clusterMap {
+	for _, cluster := range c.clustersByPriority() {
 		if k8sutil.NodeIsTolerable(*newNode, cephv1.GetOSDPlacement(cluster.Spec.Placement).Tolerations, false) == false {
 			logger.Debugf("Skipping -> Node is not tolerable for cluster %s", cluster.Namespace)
 			continue
@@ -205,7 +283,7 @@ func (c *ClusterController) onK8sNodeAdd(obj interface{}) {
 
 		if valid, _ := k8sutil.ValidNode(*newNode, cluster.Spec.Placement.All()); valid == true {
 			logger.Debugf("Adding %s to cluster %s", newNode.Labels[v1.LabelHostname], cluster.Namespace)
-			err := cluster.createInstance(c.rookImage, cluster.Info.CephVersion)
+			err := cluster.createInstance(cluster.ctx, c.rookImage, cluster.Info.CephVersion)
 			if err != nil {
 				logger.Errorf("Failed to update cluster in namespace %s. Was not able to add %s. %+v", cluster.Namespace, newNode.Labels[v1.LabelHostname], err)
 			}
@@ -225,13 +303,24 @@ func (c *ClusterController) onAdd(obj interface{}) {
 	}
 
 	if existing, ok := c.clusterMap[clusterObj.Namespace]; ok {
+		if existing.ownerRef.UID == clusterObj.UID {
+			logger.Debugf("cluster %s in namespace %s was already added when the controller started", clusterObj.Name, clusterObj.Namespace)
+			return
+		}
 		logger.Errorf("Failed to add cluster cr %s in namespace %s. Cluster cr %s already exists in this namespace. Only one cluster cr per namespace is supported.",
 			clusterObj.Name, clusterObj.Namespace, existing.crdName)
 		return
 	}
 
 	cluster := newCluster(clusterObj, c.context, c.csiConfigMutex)
+	cluster.upgradeNotifier = c.upgradeNotifier
+	cluster.apiCircuitBreaker = c.apiCircuitBreaker
+	cluster.orchestrationLimiter = c.orchestrationLimiter
+	cluster.tracer = c.tracer
+	cluster.specDiffLogging = c.specDiffLogging
+	c.clusterMapMux.Lock()
 	c.clusterMap[cluster.Namespace] = cluster
+	c.clusterMapMux.Unlock()
 
 	logger.Infof("starting cluster in namespace %s", cluster.Namespace)
 
@@ -293,7 +382,7 @@ func (c *ClusterController) configureExternalCephCluster(namespace, name string,
 	}
 
 	logger.Infof("detecting the image version provided for the external cluster...")
-	specCephVersionImage, err := cluster.detectCephVersion(c.rookImage, cluster.Spec.CephVersion.Image, detectCephVersionTimeout)
+	specCephVersionImage, err := cluster.cephImageVersion(cluster.ctx, c.rookImage, cluster.Spec.CephVersion, detectCephVersionTimeout)
 	if err != nil {
 		return fmt.Errorf("unknown ceph major version. %+v", err)
 	}
@@ -317,7 +406,7 @@ func (c *ClusterController) configureExternalCephCluster(namespace, name string,
 	c.updateClusterStatus(namespace, name, cephv1.ClusterStateConnected, "")
 
 	// Mark initialization has done
-	cluster.initCompleted = true
+	cluster.setInitialized()
 
 	return nil
 }
@@ -336,6 +425,21 @@ func (c *ClusterController) configureLocalCephCluster(namespace, name string, cl
 		return fmt.Errorf("using all devices in more than one namespace is not supported")
 	}
 
+	if older, err := c.validateDataDirHostPath(cluster); err != nil {
+		c.updateClusterStatus(clusterObj.Namespace, clusterObj.Name, cephv1.ClusterStateError, err.Error())
+		// the rejected cluster must not block the path of the other clusters. it is added again when its
+		// dataDirHostPath is changed or the older cluster is deleted
+		c.rejectDataDirHostPath(cluster, older, err)
+		c.removeCluster(cluster)
+		return err
+	}
+	c.acceptDataDirHostPath(cluster)
+
+	if err := validatePriorityClassNames(c.context, cluster.Spec.PriorityClassNames); err != nil {
+		c.updateClusterStatus(clusterObj.Namespace, clusterObj.Name, cephv1.ClusterStateError, err.Error())
+		return err
+	}
+
 	if cluster.Spec.Storage.AnyUseAllDevices() {
 		c.devicesInUse = true
 	}
@@ -344,9 +448,13 @@ func (c *ClusterController) configureLocalCephCluster(namespace, name string, cl
 	failedMessage := ""
 	state := cephv1.ClusterStateError
 
-	err := wait.Poll(clusterCreateInterval, clusterCreateTimeout,
+	err := pollWithJitter(clusterCreateInterval, clusterCreateTimeout, c.retryJitter,
 		func() (bool, error) {
-			cephVersion, canRetry, err := c.detectAndValidateCephVersion(cluster, cluster.Spec.CephVersion.Image)
+			// stop retrying when the cluster is deleted
+			if err := cluster.ctx.Err(); err != nil {
+				return false, fmt.Errorf("canceled the creation of cluster %s. %+v", cluster.Namespace, err)
+			}
+			cephVersion, canRetry, err := c.detectAndValidateCephVersion(cluster, cluster.Spec)
 			if err != nil {
 				failedMessage = fmt.Sprintf("failed the ceph version check. %+v", err)
 				logger.Errorf(failedMessage)
@@ -359,14 +467,18 @@ func (c *ClusterController) configureLocalCephCluster(namespace, name string, cl
 
 			c.updateClusterStatus(clusterObj.Namespace, clusterObj.Name, cephv1.ClusterStateCreating, "")
 
-			err = cluster.createInstance(c.rookImage, *cephVersion)
+			err = cluster.createInstance(cluster.ctx, c.rookImage, *cephVersion)
+			if err == errNamespaceTerminating {
+				// stop retrying without reporting a state
+				return false, err
+			}
 			if err != nil {
 				failedMessage = fmt.Sprintf("failed to create cluster in namespace %s. %+v", cluster.Namespace, err)
 				logger.Errorf(failedMessage)
 				return false, nil
 			}
 
-			state = cephv1.ClusterStateCreated
+			state = cluster.completedState()
 			failedMessage = ""
 			return true, nil
 		})
@@ -387,8 +499,21 @@ func (c *ClusterController) configureLocalCephCluster(namespace, name string, cl
 
 func (c *ClusterController) initializeCluster(cluster *cluster, clusterObj *cephv1.CephCluster) {
 	cluster.Spec = &clusterObj.Spec
+	cluster.setSpecGeneration(clusterObj.Generation)
+	cluster.resourceVersionChanged(clusterObj.ResourceVersion)
+	cluster.allowUnsupportedOnce = allowUnsupportedOnce(clusterObj)
+	cluster.rotateAdminKeyRequested = rotateAdminKeyRequested(clusterObj)
+	cluster.setConfirmedNodeRemovals(confirmedNodeRemovals(clusterObj))
+	cluster.requestedMgrDebugLevel = mgrDebugLevel(clusterObj)
+	cluster.mgrDebugLevel = clusterObj.Status.MgrDebugLevel
+	cluster.requestedOSDOut = osdOutRequested(clusterObj)
+	cluster.outOSD = clusterObj.Status.OutOSD
 
 	if !cluster.Spec.External.Enable {
+		// the cluster is initialized again with the next update of the CR
+		if !cluster.checkHeldNetworkChange(clusterObj) {
+			return
+		}
 		if err := c.configureLocalCephCluster(clusterObj.Namespace, clusterObj.Name, cluster, clusterObj); err != nil {
 			logger.Errorf("failed to configure local ceph cluster. %+v", err)
 			return
@@ -400,12 +525,14 @@ func (c *ClusterController) initializeCluster(cluster *cluster, clusterObj *ceph
 		}
 	}
 
+	isUpgrade, _ := cluster.upgradeState()
+
 	// Start pool CRD watcher
 	poolController := pool.NewPoolController(c.context, cluster.Spec)
 	poolController.StartWatch(cluster.Namespace, cluster.stopCh)
 
 	// Start object store CRD watcher
-	objectStoreController := object.NewObjectStoreController(cluster.Info, c.context, cluster.Namespace, c.rookImage, cluster.Spec, cluster.ownerRef, cluster.Spec.DataDirHostPath, cluster.isUpgrade)
+	objectStoreController := object.NewObjectStoreController(cluster.Info, c.context, cluster.Namespace, c.rookImage, cluster.Spec, cluster.ownerRef, cluster.Spec.DataDirHostPath, isUpgrade)
 	objectStoreController.StartWatch(cluster.Namespace, cluster.stopCh)
 
 	// Start object store user CRD watcher
@@ -420,7 +547,7 @@ func (c *ClusterController) initializeCluster(cluster *cluster, clusterObj *ceph
 	go bucketController.Run(cluster.stopCh)
 
 	// Start file system CRD watcher
-	fileController := file.NewFilesystemController(cluster.Info, c.context, cluster.Namespace, c.rookImage, cluster.Spec, cluster.ownerRef, cluster.Spec.DataDirHostPath, cluster.isUpgrade)
+	fileController := file.NewFilesystemController(cluster.Info, c.context, cluster.Namespace, c.rookImage, cluster.Spec, cluster.ownerRef, cluster.Spec.DataDirHostPath, isUpgrade)
 	fileController.StartWatch(cluster.Namespace, cluster.stopCh)
 
 	// Start nfs ganesha CRD watcher
@@ -450,6 +577,7 @@ func (c *ClusterController) initializeCluster(cluster *cluster, clusterObj *ceph
 
 	// Start the ceph status checker
 	cephChecker := newCephStatusChecker(c.context, cluster.Namespace, clusterObj.Name)
+	cephChecker.onStatus = cluster.updateHealthSummary
 	go cephChecker.checkCephStatus(cluster.stopCh)
 
 	// add the finalizer to the crd
@@ -494,14 +622,14 @@ func (c *ClusterController) onK8sNodeUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
-	for _, cluster := range c.clusterMap {
+	for _, cluster := range c.clustersByPriority() {
 		if cluster.Info == nil {
 			logger.Infof("Cluster %s is not ready. Skipping orchestration.", cluster.Namespace)
 			continue
 		}
 		if valid, _ := k8sutil.ValidNode(*newNode, cephv1.GetOSDPlacement(cluster.Spec.Placement)); valid == true {
 			logger.Debugf("Adding %s to cluster %s", newNode.Labels[v1.LabelHostname], cluster.Namespace)
-			err := cluster.createInstance(c.rookImage, cluster.Info.CephVersion)
+			err := cluster.createInstance(cluster.ctx, c.rookImage, cluster.Info.CephVersion)
 			if err != nil {
 				logger.Errorf("Failed adding the updated node %s to cluster in namespace %s. %+v", newNode.Labels[v1.LabelHostname], cluster.Namespace, err)
 				continue
@@ -539,6 +667,13 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 	// K8s will only delete the crd and child resources when the finalizers have been removed from the crd.
 	if newClust.DeletionTimestamp != nil {
 		logger.Infof("cluster %s has a deletion timestamp", newClust.Namespace)
+		if c.deletionProtected(newClust) {
+			return
+		}
+		// do not keep orchestrating the daemons of the deleted cluster while its resources are removed
+		if !c.stopOrchestration(newClust.Namespace) {
+			return
+		}
 		err := c.handleDelete(newClust, time.Duration(clusterDeleteRetryInterval)*time.Second)
 		if err != nil {
 			logger.Errorf("failed finalizer for cluster. %+v", err)
@@ -550,31 +685,104 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 	}
 	cluster, ok := c.clusterMap[newClust.Namespace]
 	if !ok {
-		logger.Errorf("Cannot update cluster %s that does not exist", newClust.Namespace)
+		// the cluster was rejected when it was added, e.g. since its dataDirHostPath overlapped with another cluster
+		if !c.rejectedDataDirHostPathChanged(newClust) {
+			logger.Debugf("skipping update event for cluster %s until its dataDirHostPath %s is changed", newClust.Namespace, newClust.Spec.DataDirHostPath)
+			return
+		}
+		logger.Infof("update event for cluster %s that is not running. adding it again", newClust.Namespace)
+		c.onAdd(newObj)
 		return
 	}
 
-	// If the cluster was never initialized during the OnAdd() method due to a failure, we must
+	// If the cluster was never orchestrated during the OnAdd() method due to a failure, we must
 	// treat the cluster as if it was just created.
-	if !cluster.initialized() {
+	if !cluster.orchestratedOnce() {
 		logger.Infof("Update event for uninitialized cluster %s. Initializing...", newClust.Namespace)
 		c.initializeCluster(cluster, newClust)
 		return
 	}
 
+	if !cluster.resourceVersionChanged(newClust.ResourceVersion) {
+		logger.Debugf("update event for cluster %s with resourceVersion %s was already processed", newClust.Namespace, newClust.ResourceVersion)
+		return
+	}
+
+	cluster.allowUnsupportedOnce = allowUnsupportedOnce(newClust)
+	cluster.rotateAdminKeyRequested = rotateAdminKeyRequested(newClust)
+	cluster.setConfirmedNodeRemovals(confirmedNodeRemovals(newClust))
+
+	// the mgr debug level is applied right away instead of waiting for an orchestration
+	cluster.requestMgrDebugLevel(mgrDebugLevel(newClust))
+	if !newClust.Spec.External.Enable {
+		if err := cluster.applyMgrDebugLevel(); err != nil {
+			logger.Errorf("failed to apply the mgr debug level of cluster %s. %+v", newClust.Namespace, err)
+		}
+	}
+
+	// the maintenance of an osd starts and ends right away instead of waiting for an orchestration
+	cluster.requestOSDOut(osdOutRequested(newClust))
+	if !newClust.Spec.External.Enable {
+		if err := cluster.applyOSDOut(); err != nil {
+			logger.Errorf("failed to apply the out osd of cluster %s. %+v", newClust.Namespace, err)
+		}
+	}
+
+	// the network of the running daemons is only changed after a confirmation
+	networkChanged := cluster.networkChanged(newClust.Spec)
+	if networkChanged && !cluster.checkNetworkChange(newClust) {
+		return
+	}
+
 	changed, _ := clusterChanged(oldClust.Spec, newClust.Spec, cluster)
+	specChanged := changed
+	if specChanged {
+		impact := ClassifyChange(*oldClust.Spec.DeepCopy(), *newClust.Spec.DeepCopy())
+		logger.Infof("the spec change of cluster %s affects: %s", newClust.Namespace, impact)
+		if impact.Structural {
+			logger.Warningf("the spec change of cluster %s changes the storage topology, the network or the data location of the cluster", newClust.Namespace)
+		}
+	}
+	// the status written by the last orchestration must not trigger another one, but the changes of the spec and the
+	// annotations that request an action are always applied
+	if !specChanged && reflect.DeepEqual(oldClust.Annotations, newClust.Annotations) && cluster.inOrchestrationCooldown(c.orchestrationCooldown) {
+		logger.Debugf("ignoring the update event for cluster %s without a spec change during the orchestration cooldown of %s", newClust.Namespace, c.orchestrationCooldown)
+		return
+	}
+	if cluster.rotateAdminKeyRequested {
+		logger.Infof("the rotation of the admin key was requested for cluster %s", newClust.Namespace)
+		changed = true
+	}
+	// the status writes of the operator must not start an orchestration while the confirmation stays on the CR
+	if confirmed := cluster.confirmedRemovals(); len(confirmed) > 0 &&
+		oldClust.GetAnnotations()[confirmNodeRemovalAnnotation] != newClust.GetAnnotations()[confirmNodeRemovalAnnotation] {
+		logger.Infof("the removal of the nodes %v was confirmed for cluster %s", confirmed, newClust.Namespace)
+		changed = true
+	}
+	// the confirmation of a network change may be the only change of the CR
+	if networkChanged {
+		changed = true
+	}
 	if !changed {
 		logger.Debugf("update event for cluster %s is not supported", newClust.Namespace)
 		return
 	}
 
+	// keep a history of the applied specs
+	if specChanged {
+		if err := cluster.saveSpecRevision(newClust.Spec); err != nil {
+			logger.Warningf("failed to save the history of the cluster spec. %+v", err)
+		}
+	}
+
 	logger.Infof("update event for cluster %s is supported, orchestrating update now", newClust.Namespace)
 
-	// if the image changed, we need to detect the new image version
+	// if the image or the pinned version changed, we need to detect the new image version
 	versionChanged := false
-	if oldClust.Spec.CephVersion.Image != newClust.Spec.CephVersion.Image {
-		logger.Infof("the ceph version changed from %s to %s", oldClust.Spec.CephVersion.Image, newClust.Spec.CephVersion.Image)
-		version, _, err := c.detectAndValidateCephVersion(cluster, newClust.Spec.CephVersion.Image)
+	if oldClust.Spec.CephVersion.Image != newClust.Spec.CephVersion.Image || oldClust.Spec.CephVersion.PinnedVersion != newClust.Spec.CephVersion.PinnedVersion {
+		logger.Infof("the ceph version changed from %s (pinned %q) to %s (pinned %q)", oldClust.Spec.CephVersion.Image, oldClust.Spec.CephVersion.PinnedVersion,
+			newClust.Spec.CephVersion.Image, newClust.Spec.CephVersion.PinnedVersion)
+		version, _, err := c.detectAndValidateCephVersion(cluster, &newClust.Spec)
 		if err != nil {
 			logger.Errorf("unknown ceph major version. %+v", err)
 			return
@@ -587,6 +795,7 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 	logger.Debugf("new cluster: %+v", newClust.Spec)
 
 	cluster.Spec = &newClust.Spec
+	cluster.setSpecGeneration(newClust.Generation)
 
 	// Get cluster running versions
 	versions, err := client.GetAllCephDaemonVersions(c.context, cluster.Namespace)
@@ -618,20 +827,20 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 				return
 			}
 			// If Ceph is healthy let's start the upgrade!
-			cluster.isUpgrade = true
+			cluster.startUpgrade(runningVersions, cluster.Info.CephVersion)
 		}
 	} else {
 		logger.Infof("ceph daemons running versions are: %+v", runningVersions)
 	}
 
-	// attempt to update the cluster.  note this is done outside of wait.Poll because that function
+	// attempt to update the cluster.  note this is done outside of the poll because that function
 	// will wait for the retry interval before trying for the first time.
 	done, _ := c.handleUpdate(newClust.Name, cluster)
 	if done {
 		return
 	}
 
-	err = wait.Poll(updateClusterInterval, updateClusterTimeout, func() (bool, error) {
+	err = pollWithJitter(updateClusterInterval, updateClusterTimeout, c.retryJitter, func() (bool, error) {
 		return c.handleUpdate(newClust.Name, cluster)
 	})
 	if err != nil {
@@ -646,10 +855,25 @@ func (c *ClusterController) onUpdate(oldObj, newObj interface{}) {
 	}
 }
 
-func (c *ClusterController) detectAndValidateCephVersion(cluster *cluster, image string) (*cephver.CephVersion, bool, error) {
-	version, err := cluster.detectCephVersion(c.rookImage, image, detectCephVersionTimeout)
+// detectAndValidateCephVersion detects the ceph version of the image of the spec, which is the new spec during an
+// update while the cluster still holds the old spec
+func (c *ClusterController) detectAndValidateCephVersion(cluster *cluster, spec *cephv1.ClusterSpec) (*cephver.CephVersion, bool, error) {
+	versionSpec := spec.CephVersion
+	// the version job would fail to start without an image
+	if err := versionSpec.ValidateImage(); err != nil {
+		return nil, false, err
+	}
+	// the version job would be created with the invalid policy
+	if err := versionSpec.ValidateImagePullPolicy(); err != nil {
+		return nil, false, err
+	}
+	if err := validateSchedulerName(spec.SchedulerName); err != nil {
+		return nil, false, err
+	}
+	version, err := cluster.cephImageVersion(cluster.ctx, c.rookImage, versionSpec, detectCephVersionTimeout)
 	if err != nil {
-		return nil, true, err
+		// an invalid pinned version does not change until the spec is updated
+		return nil, versionSpec.PinnedVersion == "", err
 	}
 	if err := cluster.validateCephVersion(version); err != nil {
 		return nil, false, err
@@ -660,12 +884,17 @@ func (c *ClusterController) detectAndValidateCephVersion(cluster *cluster, image
 func (c *ClusterController) handleUpdate(crdName string, cluster *cluster) (bool, error) {
 	c.updateClusterStatus(cluster.Namespace, crdName, cephv1.ClusterStateUpdating, "")
 
-	if err := cluster.createInstance(c.rookImage, cluster.Info.CephVersion); err != nil {
+	err := cluster.createInstance(cluster.ctx, c.rookImage, cluster.Info.CephVersion)
+	if err == errNamespaceTerminating {
+		// the cluster is not updated anymore, so its state is not reported as completed
+		return true, nil
+	}
+	if err != nil {
 		logger.Errorf("failed to update cluster in namespace %s. %+v", cluster.Namespace, err)
 		return false, nil
 	}
 
-	c.updateClusterStatus(cluster.Namespace, crdName, cephv1.ClusterStateCreated, "")
+	c.updateClusterStatus(cluster.Namespace, crdName, cluster.completedState(), "")
 
 	logger.Infof("succeeded updating cluster in namespace %s", cluster.Namespace)
 	return true, nil
@@ -709,13 +938,13 @@ func (c *ClusterController) onDeviceCMUpdate(oldObj, newObj interface{}) {
 		return
 	}
 
-	for _, cluster := range c.clusterMap {
+	for _, cluster := range c.clustersByPriority() {
 		if cluster.Info == nil {
 			logger.Infof("Cluster %s is not ready. Skipping orchestration on device change", cluster.Namespace)
 			continue
 		}
 		logger.Infof("Running orchestration for namespace %s after device change", cluster.Namespace)
-		err := cluster.createInstance(c.rookImage, cluster.Info.CephVersion)
+		err := cluster.createInstance(cluster.ctx, c.rookImage, cluster.Info.CephVersion)
 		if err != nil {
 			logger.Errorf("Failed orchestration after device change in namespace %s. %+v", cluster.Namespace, err)
 			continue
@@ -742,14 +971,23 @@ func (c *ClusterController) onDelete(obj interface{}) {
 
 	logger.Infof("delete event for cluster %s in namespace %s", clust.Name, clust.Namespace)
 
+	// do not keep orchestrating the daemons of the deleted cluster
+	if !c.stopOrchestration(clust.Namespace) {
+		return
+	}
+
 	err = c.handleDelete(clust, time.Duration(clusterDeleteRetryInterval)*time.Second)
 	if err != nil {
 		logger.Errorf("failed to delete cluster. %+v", err)
 	}
 	if cluster, ok := c.clusterMap[clust.Namespace]; ok {
 		close(cluster.stopCh)
+		c.clusterMapMux.Lock()
 		delete(c.clusterMap, clust.Namespace)
+		c.clusterMapMux.Unlock()
+		deletePendingOrchestrationsMetric(clust.Namespace)
 	}
+	c.forgetRejectedDataDirHostPaths(clust.Namespace)
 	// Only valid when the cluster is not external
 	if !clust.Spec.External.Enable {
 		if clust.Spec.Storage.AnyUseAllDevices() {
@@ -932,11 +1170,143 @@ func (c *ClusterController) updateClusterStatus(namespace, name string, state ce
 	// do not overwrite the ceph status that is updated in a separate goroutine
 	cluster.Status.State = state
 	cluster.Status.Message = message
-	if _, err := c.context.RookClientset.CephV1().CephClusters(namespace).Update(cluster); err != nil {
+	if err := updateClusterStatus(c.context, cluster); err != nil {
 		logger.Errorf("failed to update cluster %s status: %+v", namespace, err)
 	}
 }
 
+// validateDataDirHostPath checks that no older cluster stores its data in the same dataDirHostPath on the same nodes,
+// in which case the clusters would overwrite each other's data. The clusters are added in any order after a restart
+// of the operator, so the cluster created first always keeps the path and the newer cluster is rejected. The older
+// cluster is returned with the error.
+func (c *ClusterController) validateDataDirHostPath(cluster *cluster) (*cluster, error) {
+	if cluster.Spec.DataDirHostPath == "" || cluster.Spec.AllowSharedDataDirHostPath {
+		return nil, nil
+	}
+
+	for namespace, other := range c.clusterMap {
+		if namespace == cluster.Namespace || other.Spec == nil || other.Spec.External.Enable {
+			continue
+		}
+		if !dataDirHostPathsOverlap(cluster.Spec.DataDirHostPath, other.Spec.DataDirHostPath) ||
+			!storageNodesOverlap(cluster.Spec.Storage, other.Spec.Storage) {
+			continue
+		}
+		if cluster.createdBefore(other) {
+			logger.Warningf("dataDirHostPath %s of cluster %s overlaps with the dataDirHostPath %s of the newer cluster in namespace %s, which is rejected",
+				cluster.Spec.DataDirHostPath, cluster.Namespace, other.Spec.DataDirHostPath, namespace)
+			continue
+		}
+		return other, fmt.Errorf("dataDirHostPath %s overlaps with the dataDirHostPath %s of the older cluster in namespace %s. set allowSharedDataDirHostPath if the clusters run on separate nodes",
+			cluster.Spec.DataDirHostPath, other.Spec.DataDirHostPath, namespace)
+	}
+	return nil, nil
+}
+
+// rejectDataDirHostPath reports the conflict of the dataDirHostPaths on the CRs of both clusters and remembers the
+// rejected path, so the updates of the rejected CR do not add the cluster again with the same path
+func (c *ClusterController) rejectDataDirHostPath(cluster, older *cluster, err error) {
+	cluster.reportCondition(cephv1.ClusterConditionDataDirHostPathConflict, err.Error(), "", "")
+	older.reportCondition(cephv1.ClusterConditionDataDirHostPathConflict,
+		fmt.Sprintf("the newer cluster in namespace %s was rejected since its dataDirHostPath %s overlaps with the dataDirHostPath %s",
+			cluster.Namespace, cluster.Spec.DataDirHostPath, older.Spec.DataDirHostPath), "", "")
+
+	c.clusterMapMux.Lock()
+	defer c.clusterMapMux.Unlock()
+	if c.rejectedDataDirHostPaths == nil {
+		c.rejectedDataDirHostPaths = map[string]rejectedDataDirHostPath{}
+	}
+	c.rejectedDataDirHostPaths[cluster.Namespace] = rejectedDataDirHostPath{
+		path:           cluster.Spec.DataDirHostPath,
+		allowShared:    cluster.Spec.AllowSharedDataDirHostPath,
+		olderNamespace: older.Namespace,
+	}
+}
+
+// acceptDataDirHostPath clears the conflict of a cluster that was rejected before from the CRs of both clusters
+func (c *ClusterController) acceptDataDirHostPath(cluster *cluster) {
+	c.clusterMapMux.Lock()
+	rejected, ok := c.rejectedDataDirHostPaths[cluster.Namespace]
+	delete(c.rejectedDataDirHostPaths, cluster.Namespace)
+	older, olderFound := c.clusterMap[rejected.olderNamespace]
+	c.clusterMapMux.Unlock()
+	if !ok {
+		return
+	}
+	message := "the dataDirHostPath does not overlap with another cluster"
+	cluster.reportCondition(cephv1.ClusterConditionDataDirHostPathConflict, "", "DataDirHostPathAvailable", message)
+	if olderFound {
+		older.reportCondition(cephv1.ClusterConditionDataDirHostPathConflict, "", "DataDirHostPathAvailable", message)
+	}
+}
+
+// rejectedDataDirHostPathChanged returns whether a cluster that was rejected for its dataDirHostPath may be added
+// again, i.e. whether its path was changed or it was not rejected for its path
+func (c *ClusterController) rejectedDataDirHostPathChanged(cephCluster *cephv1.CephCluster) bool {
+	c.clusterMapMux.Lock()
+	defer c.clusterMapMux.Unlock()
+	rejected, ok := c.rejectedDataDirHostPaths[cephCluster.Namespace]
+	if !ok {
+		return true
+	}
+	return rejected.path != cephCluster.Spec.DataDirHostPath || rejected.allowShared != cephCluster.Spec.AllowSharedDataDirHostPath
+}
+
+// forgetRejectedDataDirHostPaths forgets the rejection of the deleted cluster and of the clusters that were rejected
+// since the deleted cluster kept their path, so they are added again with the next update of their CR
+func (c *ClusterController) forgetRejectedDataDirHostPaths(namespace string) {
+	c.clusterMapMux.Lock()
+	defer c.clusterMapMux.Unlock()
+	for rejectedNamespace, rejected := range c.rejectedDataDirHostPaths {
+		if rejectedNamespace == namespace || rejected.olderNamespace == namespace {
+			delete(c.rejectedDataDirHostPaths, rejectedNamespace)
+		}
+	}
+}
+
+// removeCluster stops tracking a cluster that was rejected before it was started
+func (c *ClusterController) removeCluster(cluster *cluster) {
+	cluster.cancelOrchestration()
+	c.clusterMapMux.Lock()
+	delete(c.clusterMap, cluster.Namespace)
+	c.clusterMapMux.Unlock()
+}
+
+// createdBefore returns whether the CephCluster CR of the cluster was created before the CR of the other cluster.
+// The namespaces order the clusters created in the same second.
+func (c *cluster) createdBefore(other *cluster) bool {
+	if !c.creationTimestamp.Equal(&other.creationTimestamp) {
+		return c.creationTimestamp.Before(&other.creationTimestamp)
+	}
+	return c.Namespace < other.Namespace
+}
+
+// dataDirHostPathsOverlap returns whether the paths are the same or one of them is inside the other
+func dataDirHostPathsOverlap(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	a = filepath.Clean(a)
+	b = filepath.Clean(b)
+	return a == b || strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// storageNodesOverlap returns whether the clusters may run on the same nodes. This is only known not to be
+// the case when both clusters list their nodes explicitly and no node is used by both.
+func storageNodesOverlap(a, b rookalpha.StorageScopeSpec) bool {
+	if a.UseAllNodes || b.UseAllNodes {
+		return true
+	}
+	for _, nodeA := range a.Nodes {
+		for _, nodeB := range b.Nodes {
+			if nodeA.Name == nodeB.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func ClusterOwnerRef(clusterName, clusterID string) metav1.OwnerReference {
 	blockOwner := true
 	return metav1.OwnerReference{
@@ -17,6 +17,7 @@ limitations under the License.
 package cluster
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -31,6 +32,7 @@ import (
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	testop "github.com/rook/rook/pkg/operator/test"
 	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -193,6 +195,43 @@ func TestRemoveFinalizer(t *testing.T) {
 	assert.Len(t, cluster.Finalizers, 0)
 }
 
+func TestDeletionProtection(t *testing.T) {
+	context := &clusterd.Context{
+		Clientset:     testop.New(3),
+		RookClientset: rookfake.NewSimpleClientset(),
+	}
+	controller := NewClusterController(context, "", &attachment.MockAttachment{}, nil)
+
+	now := metav1.Now()
+	cluster := &cephv1.CephCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "cluster-1893",
+			Namespace:         "namespace-6551",
+			Finalizers:        []string{finalizerName},
+			DeletionTimestamp: &now,
+		},
+		Spec:   cephv1.ClusterSpec{DeletionProtection: true},
+		Status: cephv1.ClusterStatus{State: cephv1.ClusterStateCreated},
+	}
+	cluster, err := context.RookClientset.CephV1().CephClusters(cluster.Namespace).Create(cluster)
+	assert.NoError(t, err)
+
+	// the finalizer is kept while the cluster is protected
+	controller.onUpdate(cluster, cluster)
+	cluster, err = context.RookClientset.CephV1().CephClusters(cluster.Namespace).Get(cluster.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, cluster.Finalizers, 1)
+	assert.Equal(t, cephv1.ClusterStateCreated, cluster.Status.State)
+	assert.Contains(t, cluster.Status.Message, "deletionProtection")
+
+	// the deletion proceeds after the protection is disabled
+	cluster.Spec.DeletionProtection = false
+	controller.onUpdate(cluster, cluster)
+	cluster, err = context.RookClientset.CephV1().CephClusters(cluster.Namespace).Get(cluster.Name, metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, cluster.Finalizers, 0)
+}
+
 func TestValidateExternalClusterSpec(t *testing.T) {
 	c := &cluster{Spec: &cephv1.ClusterSpec{}, mons: &mon.Cluster{}}
 	err := validateExternalClusterSpec(c)
@@ -206,3 +245,134 @@ func TestValidateExternalClusterSpec(t *testing.T) {
 	err = validateExternalClusterSpec(c)
 	assert.NoError(t, err)
 }
+
+func TestValidateDataDirHostPath(t *testing.T) {
+	created := metav1.NewTime(time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC))
+	newTestCluster := func(namespace, path string, storage rookalpha.StorageScopeSpec) *cluster {
+		return &cluster{Namespace: namespace, Spec: &cephv1.ClusterSpec{DataDirHostPath: path, Storage: storage}, creationTimestamp: created}
+	}
+	allNodes := rookalpha.StorageScopeSpec{UseAllNodes: true}
+	controller := &ClusterController{clusterMap: map[string]*cluster{
+		"ns1": newTestCluster("ns1", "/var/lib/rook", allNodes),
+	}}
+	validate := func(cluster *cluster) error {
+		_, err := controller.validateDataDirHostPath(cluster)
+		return err
+	}
+
+	// the cluster does not conflict with itself
+	assert.Nil(t, validate(newTestCluster("ns1", "/var/lib/rook", allNodes)))
+
+	// same or nested paths conflict
+	assert.NotNil(t, validate(newTestCluster("ns2", "/var/lib/rook", allNodes)))
+	assert.NotNil(t, validate(newTestCluster("ns2", "/var/lib/rook/", allNodes)))
+	assert.NotNil(t, validate(newTestCluster("ns2", "/var/lib/rook/ns2", allNodes)))
+	assert.Nil(t, validate(newTestCluster("ns2", "/var/lib/rook2", allNodes)))
+
+	// the user can allow the shared path
+	shared := newTestCluster("ns2", "/var/lib/rook", allNodes)
+	shared.Spec.AllowSharedDataDirHostPath = true
+	assert.Nil(t, validate(shared))
+
+	// clusters on separate nodes do not conflict
+	controller.clusterMap["ns1"] = newTestCluster("ns1", "/var/lib/rook", rookalpha.StorageScopeSpec{Nodes: []rookalpha.Node{{Name: "a"}}})
+	assert.Nil(t, validate(newTestCluster("ns2", "/var/lib/rook", rookalpha.StorageScopeSpec{Nodes: []rookalpha.Node{{Name: "b"}}})))
+	assert.NotNil(t, validate(newTestCluster("ns2", "/var/lib/rook", rookalpha.StorageScopeSpec{Nodes: []rookalpha.Node{{Name: "b"}, {Name: "a"}}})))
+	assert.NotNil(t, validate(newTestCluster("ns2", "/var/lib/rook", allNodes)))
+
+	// the older cluster keeps the path even if the newer cluster was added first
+	older := newTestCluster("ns2", "/var/lib/rook", allNodes)
+	older.creationTimestamp = metav1.NewTime(created.Add(-time.Hour))
+	assert.Nil(t, validate(older))
+	controller.clusterMap["ns2"] = older
+	conflict, err := controller.validateDataDirHostPath(controller.clusterMap["ns1"])
+	assert.NotNil(t, err)
+	assert.Equal(t, older, conflict)
+
+	// the rejected cluster does not block the path of the other clusters anymore
+	controller.removeCluster(controller.clusterMap["ns1"])
+	_, ok := controller.clusterMap["ns1"]
+	assert.False(t, ok)
+}
+
+func TestRejectDataDirHostPath(t *testing.T) {
+	newTestCluster := func(namespace string) (*cluster, *cephv1.CephCluster) {
+		cephCluster := &cephv1.CephCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: namespace},
+			Spec:       cephv1.ClusterSpec{DataDirHostPath: "/var/lib/rook"},
+		}
+		return &cluster{Namespace: namespace, crdName: "my-cluster", Spec: &cephCluster.Spec}, cephCluster
+	}
+	older, olderCR := newTestCluster("ns1")
+	newer, newerCR := newTestCluster("ns2")
+	context := &clusterd.Context{Clientset: testop.New(1), RookClientset: rookfake.NewSimpleClientset(olderCR, newerCR)}
+	older.context = context
+	newer.context = context
+	controller := &ClusterController{clusterMap: map[string]*cluster{"ns1": older}}
+	conflict := func(namespace string) cephv1.ClusterCondition {
+		cephCluster, err := context.RookClientset.CephV1().CephClusters(namespace).Get("my-cluster", metav1.GetOptions{})
+		assert.Nil(t, err)
+		for _, condition := range cephCluster.Status.Conditions {
+			if condition.Type == cephv1.ClusterConditionDataDirHostPathConflict {
+				return condition
+			}
+		}
+		return cephv1.ClusterCondition{}
+	}
+
+	// the conflict is reported on both CRs
+	controller.rejectDataDirHostPath(newer, older, fmt.Errorf("overlaps"))
+	assert.Equal(t, v1.ConditionTrue, conflict("ns1").Status)
+	assert.Equal(t, v1.ConditionTrue, conflict("ns2").Status)
+	assert.Equal(t, "overlaps", conflict("ns2").Message)
+	for _, namespace := range []string{"ns1", "ns2"} {
+		events, err := context.Clientset.CoreV1().Events(namespace).List(metav1.ListOptions{})
+		assert.Nil(t, err)
+		assert.Equal(t, 1, len(events.Items))
+	}
+
+	// the rejected cluster is not added again until its path changes
+	assert.False(t, controller.rejectedDataDirHostPathChanged(newerCR))
+	newerCR.Spec.DataDirHostPath = "/var/lib/rook2"
+	assert.True(t, controller.rejectedDataDirHostPathChanged(newerCR))
+	newerCR.Spec.DataDirHostPath = "/var/lib/rook"
+	newerCR.Spec.AllowSharedDataDirHostPath = true
+	assert.True(t, controller.rejectedDataDirHostPathChanged(newerCR))
+	newerCR.Spec.AllowSharedDataDirHostPath = false
+
+	// the conflict is cleared once the cluster is accepted
+	controller.acceptDataDirHostPath(newer)
+	assert.Equal(t, v1.ConditionFalse, conflict("ns1").Status)
+	assert.Equal(t, v1.ConditionFalse, conflict("ns2").Status)
+	assert.True(t, controller.rejectedDataDirHostPathChanged(newerCR))
+
+	// the rejected cluster is added again once the older cluster is deleted
+	controller.rejectDataDirHostPath(newer, older, fmt.Errorf("overlaps"))
+	controller.forgetRejectedDataDirHostPaths("ns1")
+	assert.True(t, controller.rejectedDataDirHostPathChanged(newerCR))
+}
+
+func TestDetectCephVersionWithoutImage(t *testing.T) {
+	c := testSpec()
+	c.Namespace = "ns"
+	c.ctx = context.Background()
+	controller := &ClusterController{clusterMap: map[string]*cluster{}}
+
+	// the version job is not started without an image
+	version, canRetry, err := controller.detectAndValidateCephVersion(&c, c.Spec)
+	assert.Nil(t, version)
+	assert.False(t, canRetry)
+	assert.EqualError(t, err, "spec.cephVersion.image is required")
+	jobs, err := c.context.Clientset.BatchV1().Jobs("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(jobs.Items))
+
+	// the scheduler of the new spec is validated, not the one of the running cluster
+	newSpec := *c.Spec
+	newSpec.CephVersion.Image = "ceph/ceph:v14.2.4"
+	newSpec.SchedulerName = "invalid_scheduler"
+	_, canRetry, err = controller.detectAndValidateCephVersion(&c, &newSpec)
+	assert.NotNil(t, err)
+	assert.False(t, canRetry)
+	assert.Equal(t, "", c.Spec.SchedulerName)
+}
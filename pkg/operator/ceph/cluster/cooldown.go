@@ -0,0 +1,53 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"time"
+)
+
+const (
+	// the env var to set how long the updates of a cluster CR without a spec change are ignored after a successful
+	// orchestration
+	orchestrationCooldownEnvVar  = "ROOK_ORCHESTRATION_COOLDOWN"
+	defaultOrchestrationCooldown = 10 * time.Second
+)
+
+// orchestrationCooldown parses the cooldown after a successful orchestration. A cooldown of 0 disables it.
+func orchestrationCooldown(value string) time.Duration {
+	if value == "" {
+		return defaultOrchestrationCooldown
+	}
+	cooldown, err := time.ParseDuration(value)
+	if err != nil || cooldown < 0 {
+		logger.Warningf("invalid value %q for %s, it must be a duration of at least 0s. using %s", value, orchestrationCooldownEnvVar, defaultOrchestrationCooldown)
+		return defaultOrchestrationCooldown
+	}
+	return cooldown
+}
+
+// inOrchestrationCooldown returns whether the last successful orchestration of the cluster completed less than the
+// cooldown ago. The operator updates the status of the CR at the end of the orchestration, and these updates must
+// not trigger another orchestration.
+func (c *cluster) inOrchestrationCooldown(cooldown time.Duration) bool {
+	if cooldown <= 0 {
+		return false
+	}
+	c.orchMux.Lock()
+	defer c.orchMux.Unlock()
+	return !c.lastOrchestrationTime.IsZero() && time.Since(c.lastOrchestrationTime) < cooldown
+}
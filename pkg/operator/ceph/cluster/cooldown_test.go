@@ -0,0 +1,46 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrchestrationCooldown(t *testing.T) {
+	assert.Equal(t, defaultOrchestrationCooldown, orchestrationCooldown(""))
+	assert.Equal(t, 30*time.Second, orchestrationCooldown("30s"))
+	assert.Equal(t, time.Duration(0), orchestrationCooldown("0s"))
+	assert.Equal(t, defaultOrchestrationCooldown, orchestrationCooldown("-1s"))
+	assert.Equal(t, defaultOrchestrationCooldown, orchestrationCooldown("10"))
+}
+
+func TestInOrchestrationCooldown(t *testing.T) {
+	c := testSpec()
+
+	// the cluster was never orchestrated
+	assert.False(t, c.inOrchestrationCooldown(time.Minute))
+
+	c.lastOrchestrationTime = time.Now().Add(-30 * time.Second)
+	assert.True(t, c.inOrchestrationCooldown(time.Minute))
+	assert.False(t, c.inOrchestrationCooldown(10*time.Second))
+
+	// the cooldown is disabled
+	assert.False(t, c.inOrchestrationCooldown(0))
+}
@@ -0,0 +1,166 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"reflect"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// the crush root of the rules without a root in the spec
+	defaultCrushRuleRoot = "default"
+)
+
+// validateCrushRules checks that the crush rules of the spec have a unique name
+func validateCrushRules(rules []cephv1.CrushRuleSpec) error {
+	names := map[string]bool{}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("the name of a crush rule is required")
+		}
+		if names[rule.Name] {
+			return fmt.Errorf("the crush rule %s is defined more than once", rule.Name)
+		}
+		names[rule.Name] = true
+	}
+	return nil
+}
+
+// reconcileCrushRules creates the crush rules of the spec that do not exist yet and deletes the rules that were
+// created from the spec and removed from it since. The existing rules are not modified. A removed rule that is still
+// used by a pool is kept until the pool uses another rule.
+func (c *cluster) reconcileCrushRules(rules []cephv1.CrushRuleSpec, created []string) ([]string, error) {
+	if err := validateCrushRules(rules); err != nil {
+		return created, fmt.Errorf("invalid crush rules. %+v", err)
+	}
+	if len(rules) == 0 && len(created) == 0 {
+		return created, nil
+	}
+
+	crushMap, err := client.GetCrushMap(c.context, c.Info.Name)
+	if err != nil {
+		return created, fmt.Errorf("failed to get the crush map to reconcile the crush rules. %+v", err)
+	}
+	existing := map[string]int{}
+	for _, rule := range crushMap.Rules {
+		existing[rule.Name] = rule.ID
+	}
+
+	wasCreated := map[string]bool{}
+	for _, name := range created {
+		wasCreated[name] = true
+	}
+	managed := []string{}
+	// on a failure the rules created so far are still recorded, and the removed rules are deleted by the next try
+	failed := func(err error) ([]string, error) {
+		for _, name := range managed {
+			if !wasCreated[name] {
+				created = append(created, name)
+			}
+		}
+		return created, err
+	}
+	inSpec := map[string]bool{}
+	for _, rule := range rules {
+		inSpec[rule.Name] = true
+		if _, ok := existing[rule.Name]; ok {
+			// the rules that existed before they were added to the spec are never deleted
+			if wasCreated[rule.Name] {
+				managed = append(managed, rule.Name)
+			}
+			logger.Debugf("crush rule %s already exists", rule.Name)
+			continue
+		}
+		root := rule.Root
+		if root == "" {
+			root = defaultCrushRuleRoot
+		}
+		failureDomain := rule.FailureDomain
+		if failureDomain == "" {
+			failureDomain = cephv1.DefaultFailureDomain
+		}
+		if err := client.CreateReplicatedCrushRule(c.context, c.Info.Name, rule.Name, root, failureDomain, rule.DeviceClass); err != nil {
+			return failed(err)
+		}
+		logger.Infof("created crush rule %s", rule.Name)
+		managed = append(managed, rule.Name)
+	}
+
+	var poolsByRule map[int][]string
+	for _, name := range created {
+		if inSpec[name] {
+			continue
+		}
+		id, ok := existing[name]
+		if !ok {
+			// already deleted
+			continue
+		}
+		if poolsByRule == nil {
+			if poolsByRule, err = client.GetPoolsByCrushRule(c.context, c.Info.Name); err != nil {
+				return failed(fmt.Errorf("failed to get the pools to delete the crush rule %s. %+v", name, err))
+			}
+		}
+		if pools := poolsByRule[id]; len(pools) > 0 {
+			message := fmt.Sprintf("not deleting the crush rule %s removed from the spec since it is used by the pools %v", name, pools)
+			logger.Warning(message)
+			c.recordEvent(v1.EventTypeWarning, "CrushRuleInUse", message)
+			managed = append(managed, name)
+			continue
+		}
+		if err := client.DeleteCrushRule(c.context, c.Info.Name, name); err != nil {
+			return failed(err)
+		}
+		logger.Infof("deleted crush rule %s", name)
+	}
+	return managed, nil
+}
+
+// updateCrushRulesStatus records the crush rules that were created from the spec in the status of the CephCluster CR
+func (c *cluster) updateCrushRulesStatus(rules []string) {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to update the crush rules. %+v", c.Namespace, err)
+		return
+	}
+	if len(rules) == 0 {
+		rules = nil
+	}
+	if reflect.DeepEqual(cephCluster.Status.CrushRules, rules) {
+		return
+	}
+	cephCluster.Status.CrushRules = rules
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to update the crush rules of cluster %s. %+v", c.Namespace, err)
+	}
+}
+
+// crushRulesStatus returns the crush rules that were created from the spec according to the status of the CR
+func (c *cluster) crushRulesStatus() []string {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get cluster %s to read the crush rules. %+v", c.Namespace, err)
+		return nil
+	}
+	return cephCluster.Status.CrushRules
+}
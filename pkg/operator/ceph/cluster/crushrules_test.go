@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateCrushRules(t *testing.T) {
+	assert.Nil(t, validateCrushRules(nil))
+	assert.Nil(t, validateCrushRules([]cephv1.CrushRuleSpec{{Name: "ssd"}, {Name: "hdd"}}))
+	assert.NotNil(t, validateCrushRules([]cephv1.CrushRuleSpec{{Name: ""}}))
+	assert.NotNil(t, validateCrushRules([]cephv1.CrushRuleSpec{{Name: "ssd"}, {Name: "ssd"}}))
+}
+
+func TestReconcileCrushRules(t *testing.T) {
+	crushRules := `[{"rule_id":0,"rule_name":"replicated_rule"}]`
+	pools := `[{"pool_name":"rbd","crush_rule":0}]`
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			if args[0] != "osd" {
+				return "", nil
+			}
+			switch args[1] {
+			case "crush":
+				if args[2] == "dump" {
+					return `{"rules":` + crushRules + `}`, nil
+				}
+				commands = append(commands, strings.Join(leadingArgs(args), " "))
+			case "pool":
+				return pools, nil
+			}
+			return "", nil
+		},
+	}
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Executor:      executor,
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+	c.Info = &cephconfig.ClusterInfo{Name: "ns"}
+
+	// the new rule is created, the existing rule is kept as is
+	rules := []cephv1.CrushRuleSpec{{Name: "replicated_rule"}, {Name: "ssd", FailureDomain: "rack", DeviceClass: "ssd"}}
+	created, err := c.reconcileCrushRules(rules, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ssd"}, created)
+	assert.Equal(t, []string{"osd crush rule create-replicated ssd default rack ssd"}, commands)
+
+	// nothing changes once the rule exists
+	commands = []string{}
+	crushRules = `[{"rule_id":0,"rule_name":"replicated_rule"},{"rule_id":1,"rule_name":"ssd"}]`
+	created, err = c.reconcileCrushRules(rules, created)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ssd"}, created)
+	assert.Equal(t, 0, len(commands))
+
+	// the removed rule is kept while a pool uses it
+	pools = `[{"pool_name":"rbd","crush_rule":0},{"pool_name":"fast","crush_rule":1}]`
+	created, err = c.reconcileCrushRules(nil, created)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"ssd"}, created)
+	assert.Equal(t, 0, len(commands))
+
+	// the unused rule is deleted, the rules that were not created from the spec are never deleted
+	pools = `[{"pool_name":"rbd","crush_rule":0}]`
+	created, err = c.reconcileCrushRules(nil, created)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(created))
+	assert.Equal(t, []string{"osd crush rule rm ssd"}, commands)
+
+	// the rules are recorded in the status
+	c.updateCrushRulesStatus([]string{"ssd"})
+	assert.Equal(t, []string{"ssd"}, c.crushRulesStatus())
+	c.updateCrushRulesStatus(created)
+	assert.Equal(t, 0, len(c.crushRulesStatus()))
+}
+
+// leadingArgs returns the args of the ceph command before the flags such as --cluster appended by the client
+func leadingArgs(args []string) []string {
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "--") {
+			return args[:i]
+		}
+	}
+	return args
+}
@@ -0,0 +1,152 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+)
+
+const (
+	// DebugClustersPath is the path of the debug endpoint that dumps the clusters tracked by the operator
+	DebugClustersPath = "/debug/clusters"
+	// DebugDiagnosePath is the path of the debug endpoint that diagnoses the cluster of the namespace query parameter
+	DebugDiagnosePath = "/debug/diagnose"
+)
+
+// clusterDebugInfo is the state of a cluster tracked by the operator. The secrets of the cluster (keyrings) must
+// never be added.
+type clusterDebugInfo struct {
+	Namespace             string               `json:"namespace"`
+	Name                  string               `json:"name"`
+	FSID                  string               `json:"fsid,omitempty"`
+	CephVersion           string               `json:"cephVersion,omitempty"`
+	Monitors              []cephconfig.MonInfo `json:"monitors,omitempty"`
+	InitCompleted         bool                 `json:"initCompleted"`
+	IsUpgrade             bool                 `json:"isUpgrade"`
+	UpgradeInProgress     bool                 `json:"upgradeInProgress"`
+	OrchestrationRunning  bool                 `json:"orchestrationRunning"`
+	OrchestrationNeeded   bool                 `json:"orchestrationNeeded"`
+	PendingOrchestrations int                  `json:"pendingOrchestrations"`
+	SpecGeneration        int64                `json:"specGeneration"`
+	LastResourceVersion   string               `json:"lastResourceVersion,omitempty"`
+	ConfirmedNodeRemovals []string             `json:"confirmedNodeRemovals,omitempty"`
+	Health                *CephHealthSummary   `json:"health,omitempty"`
+}
+
+// debugInfo returns the state of the cluster without its secrets
+func (c *cluster) debugInfo() clusterDebugInfo {
+	info := clusterDebugInfo{
+		Namespace:     c.Namespace,
+		Name:          c.crdName,
+		InitCompleted: c.initialized(),
+	}
+	c.crMux.Lock()
+	info.SpecGeneration = c.specGeneration
+	info.LastResourceVersion = c.lastResourceVersion
+	info.ConfirmedNodeRemovals = c.confirmedNodeRemovals
+	c.crMux.Unlock()
+	if c.mons != nil {
+		fsid, cephVersion, monitors := c.mons.InfoSnapshot()
+		if fsid != "" {
+			info.FSID = fsid
+			info.CephVersion = cephVersion.String()
+			info.Monitors = monitors
+		}
+	}
+
+	c.orchMux.Lock()
+	info.IsUpgrade = c.isUpgrade
+	info.UpgradeInProgress = c.upgradeInProgress
+	info.OrchestrationRunning = c.orchestrationRunning
+	info.OrchestrationNeeded = c.orchestrationNeeded
+	info.PendingOrchestrations = c.pendingOrchestrations
+	c.orchMux.Unlock()
+
+	info.Health = c.HealthSummary()
+	return info
+}
+
+// trackedClusters returns the clusters tracked by the controller
+func (c *ClusterController) trackedClusters() []*cluster {
+	c.clusterMapMux.Lock()
+	defer c.clusterMapMux.Unlock()
+	clusters := make([]*cluster, 0, len(c.clusterMap))
+	for _, cluster := range c.clusterMap {
+		clusters = append(clusters, cluster)
+	}
+	return clusters
+}
+
+// debugInfo returns the state of all the clusters tracked by the controller, sorted by namespace
+func (c *ClusterController) debugInfo() []clusterDebugInfo {
+	clusters := c.trackedClusters()
+	infos := make([]clusterDebugInfo, 0, len(clusters))
+	for _, cluster := range clusters {
+		infos = append(infos, cluster.debugInfo())
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Namespace < infos[j].Namespace })
+	return infos
+}
+
+// ServeDebugClusters is the handler of the debug endpoint that dumps the clusters tracked by the operator as json
+func (c *ClusterController) ServeDebugClusters(w http.ResponseWriter, req *http.Request) {
+	body, err := json.MarshalIndent(c.debugInfo(), "", "  ")
+	if err != nil {
+		logger.Errorf("failed to serialize the clusters for the debug endpoint. %+v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		logger.Warningf("failed to write the clusters to the debug endpoint. %+v", err)
+	}
+}
+
+// ServeDiagnosis is the handler of the debug endpoint that compares the spec of a cluster with its running state,
+// e.g. /debug/diagnose?namespace=rook-ceph
+func (c *ClusterController) ServeDiagnosis(w http.ResponseWriter, req *http.Request) {
+	namespace := req.URL.Query().Get("namespace")
+	c.clusterMapMux.Lock()
+	cluster, ok := c.clusterMap[namespace]
+	c.clusterMapMux.Unlock()
+	if !ok {
+		http.Error(w, fmt.Sprintf("cluster %q is not tracked by the operator", namespace), http.StatusNotFound)
+		return
+	}
+
+	report, err := cluster.Diagnose()
+	if err != nil {
+		logger.Errorf("failed to diagnose cluster %s. %+v", namespace, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		logger.Errorf("failed to serialize the diagnosis of cluster %s. %+v", namespace, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		logger.Warningf("failed to write the diagnosis of cluster %s. %+v", namespace, err)
+	}
+}
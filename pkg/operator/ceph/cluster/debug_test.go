@@ -0,0 +1,82 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServeDebugClusters(t *testing.T) {
+	c := &ClusterController{clusterMap: map[string]*cluster{}}
+	c.clusterMap["ns2"] = &cluster{Namespace: "ns2", crdName: "cluster2"}
+	c.clusterMap["ns1"] = &cluster{
+		Namespace:             "ns1",
+		crdName:               "cluster1",
+		initCompleted:         true,
+		isUpgrade:             true,
+		orchestrationRunning:  true,
+		pendingOrchestrations: 2,
+		specGeneration:        5,
+		mons: &mon.Cluster{ClusterInfo: &cephconfig.ClusterInfo{
+			FSID:          "myfsid",
+			MonitorSecret: "monsecret",
+			AdminSecret:   "adminsecret",
+			CephVersion:   cephver.Nautilus,
+			Monitors: map[string]*cephconfig.MonInfo{
+				"b": {Name: "b", Endpoint: "1.2.3.5:6789"},
+				"a": {Name: "a", Endpoint: "1.2.3.4:6789"},
+			},
+		}},
+	}
+	c.clusterMap["ns1"].mons.RefreshInfoSnapshot()
+
+	w := httptest.NewRecorder()
+	c.ServeDebugClusters(w, httptest.NewRequest("GET", DebugClustersPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	// the secrets are never dumped
+	body := w.Body.String()
+	assert.False(t, strings.Contains(body, "monsecret"))
+	assert.False(t, strings.Contains(body, "adminsecret"))
+
+	var infos []clusterDebugInfo
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &infos))
+	assert.Equal(t, 2, len(infos))
+	assert.Equal(t, "ns1", infos[0].Namespace)
+	assert.Equal(t, "cluster1", infos[0].Name)
+	assert.Equal(t, "myfsid", infos[0].FSID)
+	assert.Equal(t, cephver.Nautilus.String(), infos[0].CephVersion)
+	assert.True(t, infos[0].InitCompleted)
+	assert.True(t, infos[0].IsUpgrade)
+	assert.True(t, infos[0].OrchestrationRunning)
+	assert.Equal(t, 2, infos[0].PendingOrchestrations)
+	assert.Equal(t, int64(5), infos[0].SpecGeneration)
+	assert.Equal(t, 2, len(infos[0].Monitors))
+	assert.Equal(t, "a", infos[0].Monitors[0].Name)
+	assert.Equal(t, "ns2", infos[1].Namespace)
+	assert.Equal(t, "", infos[1].FSID)
+}
@@ -0,0 +1,46 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+// deletionProtected returns whether the teardown of the deleted cluster is blocked by the deletion protection of the
+// spec. The finalizer is kept until the protection is disabled, which triggers another update of the CR.
+func (c *ClusterController) deletionProtected(clust *cephv1.CephCluster) bool {
+	if !clust.Spec.DeletionProtection {
+		return false
+	}
+	message := fmt.Sprintf("the deletion of cluster %s is blocked by the deletion protection. set deletionProtection to false in the spec to delete the cluster", clust.Namespace)
+	logger.Warning(message)
+	// the status and the event are only recorded once, the CR is updated again by the resyncs
+	if clust.Status.Message == message {
+		return true
+	}
+	c.clusterMapMux.Lock()
+	cluster, ok := c.clusterMap[clust.Namespace]
+	c.clusterMapMux.Unlock()
+	if ok {
+		cluster.recordEvent(v1.EventTypeWarning, "DeletionProtected", message)
+	}
+	c.updateClusterStatus(clust.Namespace, clust.Name, clust.Status.State, message)
+	return true
+}
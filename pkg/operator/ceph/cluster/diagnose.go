@@ -0,0 +1,170 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// DiagnosisFinding is a difference between the spec of the cluster and its running state
+type DiagnosisFinding struct {
+	// Component is the part of the cluster the finding is about, e.g. mon
+	Component string `json:"component"`
+	// Message describes the difference, e.g. "spec wants 3 mons, 2 running"
+	Message string `json:"message"`
+}
+
+// DiagnosisReport is the result of the comparison of the spec of a cluster with its running state
+type DiagnosisReport struct {
+	Namespace string             `json:"namespace"`
+	Time      string             `json:"time"`
+	Findings  []DiagnosisFinding `json:"findings"`
+}
+
+// Healthy returns whether the running state of the cluster matches its spec
+func (r *DiagnosisReport) Healthy() bool {
+	return len(r.Findings) == 0
+}
+
+func (r *DiagnosisReport) add(component, format string, args ...interface{}) {
+	r.Findings = append(r.Findings, DiagnosisFinding{Component: component, Message: fmt.Sprintf(format, args...)})
+}
+
+// Diagnose compares the spec of the cluster with the running daemons and reports the differences, such as missing
+// mons or daemons running another ceph version. Nothing is changed in the cluster, so it is safe to run at any time.
+func (c *cluster) Diagnose() (*DiagnosisReport, error) {
+	if c.mons == nil {
+		return nil, fmt.Errorf("cluster %s is not initialized", c.Namespace)
+	}
+	fsid, cephVersion, _ := c.mons.InfoSnapshot()
+	if fsid == "" {
+		return nil, fmt.Errorf("cluster %s is not initialized", c.Namespace)
+	}
+	report := &DiagnosisReport{Namespace: c.Namespace, Time: formatTime(time.Now().UTC()), Findings: []DiagnosisFinding{}}
+
+	status, err := client.Status(c.context, c.Namespace, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the status of cluster %s. %+v", c.Namespace, err)
+	}
+
+	// the daemons of external clusters are not managed by the operator
+	if !c.Spec.External.Enable {
+		c.diagnoseMons(report, &status)
+		c.diagnoseMgrs(report, &status)
+	}
+	diagnoseOSDs(report, &status)
+
+	versions, err := client.GetAllCephDaemonVersions(c.context, c.Namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the versions of the daemons of cluster %s. %+v", c.Namespace, err)
+	}
+	mismatches, err := findMismatchedDaemonVersions(cephVersion, *versions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compare the versions of the daemons of cluster %s. %+v", c.Namespace, err)
+	}
+	for _, mismatch := range mismatches {
+		report.add("version", "spec wants ceph version %s, %s", cephVersion.String(), mismatch)
+	}
+	if !c.Spec.External.Enable {
+		c.diagnoseRBDMirrors(report, versions)
+	}
+
+	return report, nil
+}
+
+func (c *cluster) diagnoseMons(report *DiagnosisReport, status *client.CephStatus) {
+	wanted := c.Spec.Mon.Count
+	if c.startedMonCount > 0 {
+		wanted = c.startedMonCount
+	}
+	if wanted != len(status.MonMap.Mons) {
+		report.add("mon", "spec wants %d mons, %d in the monmap", wanted, len(status.MonMap.Mons))
+	}
+	if len(status.QuorumNames) < len(status.MonMap.Mons) {
+		inQuorum := map[string]bool{}
+		for _, name := range status.QuorumNames {
+			inQuorum[name] = true
+		}
+		for _, m := range status.MonMap.Mons {
+			if !inQuorum[m.Name] {
+				report.add("mon", "mon %s is not in quorum", m.Name)
+			}
+		}
+	}
+
+	pods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", mon.AppName)})
+	if err != nil {
+		report.add("mon", "failed to list the mon pods. %+v", err)
+		return
+	}
+	monsByNode := map[string][]string{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != v1.PodRunning {
+			report.add("mon", "mon pod %s is %s", pod.Name, pod.Status.Phase)
+		}
+		if pod.Spec.NodeName != "" {
+			monsByNode[pod.Spec.NodeName] = append(monsByNode[pod.Spec.NodeName], pod.Name)
+		}
+	}
+	if !c.Spec.Mon.AllowMultiplePerNode {
+		nodes := []string{}
+		for node := range monsByNode {
+			nodes = append(nodes, node)
+		}
+		sort.Strings(nodes)
+		for _, node := range nodes {
+			if len(monsByNode[node]) > 1 {
+				sort.Strings(monsByNode[node])
+				report.add("mon", "spec does not allow multiple mons per node, but node %s runs %s", node, strings.Join(monsByNode[node], ", "))
+			}
+		}
+	}
+}
+
+func (c *cluster) diagnoseMgrs(report *DiagnosisReport, status *client.CephStatus) {
+	if status.MgrMap.ActiveName == "" || !status.MgrMap.Available {
+		report.add("mgr", "there is no active mgr available")
+	}
+}
+
+func diagnoseOSDs(report *DiagnosisReport, status *client.CephStatus) {
+	osdMap := status.OsdMap.OsdMap
+	if osdMap.NumUpOsd < osdMap.NumOsd {
+		report.add("osd", "%d of %d osds are down", osdMap.NumOsd-osdMap.NumUpOsd, osdMap.NumOsd)
+	}
+	if osdMap.NumInOsd < osdMap.NumOsd {
+		report.add("osd", "%d of %d osds are out", osdMap.NumOsd-osdMap.NumInOsd, osdMap.NumOsd)
+	}
+}
+
+func (c *cluster) diagnoseRBDMirrors(report *DiagnosisReport, versions *client.CephDaemonsVersions) {
+	running := 0
+	for _, count := range versions.RbdMirror {
+		running += count
+	}
+	if c.Spec.RBDMirroring.Workers != running {
+		report.add("rbd-mirror", "spec wants %d rbd mirrors, %d running", c.Spec.RBDMirroring.Workers, running)
+	}
+}
@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	diagnoseTestStatus = `{
+	"quorum_names": ["a", "b"],
+	"monmap": {"mons": [{"name": "a"}, {"name": "b"}, {"name": "c"}]},
+	"osdmap": {"osdmap": {"num_osds": 3, "num_up_osds": 2, "num_in_osds": 3}},
+	"mgrmap": {"active_name": "a", "available": true}
+}`
+	diagnoseTestVersions = `{
+	"mon": {"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 3},
+	"mgr": {"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 1},
+	"osd": {
+		"ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)": 2,
+		"ceph version 14.2.2 (4f8fa0a0024755aae7d95567c63f11d6862d55be) nautilus (stable)": 1
+	}
+}`
+)
+
+func TestDiagnose(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			return diagnoseTestStatus, nil
+		},
+		MockExecuteCommandWithOutput: func(debug bool, actionName, command string, args ...string) (string, error) {
+			return diagnoseTestVersions, nil
+		},
+	}
+	clientset := testop.New(3)
+	context := &clusterd.Context{Clientset: clientset, Executor: executor}
+	for _, monPod := range []struct{ name, node string }{{"mon-a", "node0"}, {"mon-b", "node0"}, {"mon-c", "node1"}} {
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: monPod.name, Namespace: "ns", Labels: map[string]string{"app": mon.AppName}},
+			Spec:       v1.PodSpec{NodeName: monPod.node},
+			Status:     v1.PodStatus{Phase: v1.PodRunning},
+		}
+		_, err := clientset.CoreV1().Pods("ns").Create(pod)
+		assert.Nil(t, err)
+	}
+
+	c := &cluster{
+		Namespace: "ns",
+		context:   context,
+		Spec:      &cephv1.ClusterSpec{Mon: cephv1.MonSpec{Count: 3}},
+		mons:      &mon.Cluster{ClusterInfo: &cephconfig.ClusterInfo{Name: "ns", FSID: "myfsid", CephVersion: cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}}},
+	}
+	c.mons.RefreshInfoSnapshot()
+
+	report, err := c.Diagnose()
+	assert.Nil(t, err)
+	assert.False(t, report.Healthy())
+	assert.Equal(t, "ns", report.Namespace)
+	assert.Equal(t, []DiagnosisFinding{
+		{Component: "mon", Message: "mon c is not in quorum"},
+		{Component: "mon", Message: "spec does not allow multiple mons per node, but node node0 runs mon-a, mon-b"},
+		{Component: "osd", Message: "1 of 3 osds are down"},
+		{Component: "version", Message: "spec wants ceph version 14.2.4 nautilus, 1 osd running 14.2.2 nautilus"},
+	}, report.Findings)
+
+	// the mons may share the nodes
+	c.Spec.Mon.AllowMultiplePerNode = true
+	c.Spec.RBDMirroring.Workers = 1
+	report, err = c.Diagnose()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(report.Findings))
+	assert.Equal(t, DiagnosisFinding{Component: "rbd-mirror", Message: "spec wants 1 rbd mirrors, 0 running"}, report.Findings[3])
+
+	// the report is served on the debug endpoint
+	controller := &ClusterController{clusterMap: map[string]*cluster{"ns": c}}
+	w := httptest.NewRecorder()
+	controller.ServeDiagnosis(w, httptest.NewRequest("GET", DebugDiagnosePath+"?namespace=ns", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var served DiagnosisReport
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &served))
+	assert.Equal(t, report.Findings, served.Findings)
+	w = httptest.NewRecorder()
+	controller.ServeDiagnosis(w, httptest.NewRequest("GET", DebugDiagnosePath+"?namespace=other", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// an uninitialized cluster cannot be diagnosed
+	c.mons.ClusterInfo = nil
+	_, err = c.Diagnose()
+	assert.NotNil(t, err)
+	w = httptest.NewRecorder()
+	controller.ServeDiagnosis(w, httptest.NewRequest("GET", DebugDiagnosePath+"?namespace=ns", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sort"
+)
+
+const (
+	// featureUpgradeRollback rolls back an upgrade that leaves the cluster unhealthy. It replaces the deprecated
+	// upgradeRollback.enabled of the spec.
+	featureUpgradeRollback = "UpgradeRollback"
+	// featureBalanceOSDs turns on the balancer after OSDs were added. It replaces the deprecated balanceOSDs.enabled
+	// of the spec.
+	featureBalanceOSDs = "BalanceOSDs"
+)
+
+// the feature gates that are consulted by the orchestration
+var knownFeatureGates = map[string]bool{
+	featureUpgradeRollback: true,
+	featureBalanceOSDs:     true,
+}
+
+// setFeatureGates sets the feature gates of the spec of the orchestration. The unknown gates are ignored with a
+// warning, so a typo or a gate of another version of the operator does not fail the orchestration.
+func (c *cluster) setFeatureGates(gates map[string]bool) {
+	unknown := []string{}
+	for name := range gates {
+		if !knownFeatureGates[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		logger.Warningf("ignoring the unknown feature gates %v of cluster %s", unknown, c.Namespace)
+	}
+	c.featureGates = gates
+}
+
+// featureEnabled returns whether the feature gate is enabled in the spec of the orchestration
+func (c *cluster) featureEnabled(name string) bool {
+	return knownFeatureGates[name] && c.featureGates[name]
+}
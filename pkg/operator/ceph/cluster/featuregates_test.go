@@ -0,0 +1,45 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeatureGates(t *testing.T) {
+	c := &cluster{Namespace: "ns"}
+
+	// the features are disabled without gates
+	c.setFeatureGates(nil)
+	assert.False(t, c.featureEnabled(featureUpgradeRollback))
+	assert.False(t, c.balanceOSDsEnabled(cephv1.BalanceOSDsSpec{}))
+	assert.True(t, c.balanceOSDsEnabled(cephv1.BalanceOSDsSpec{Enabled: true}))
+
+	// the unknown gates are ignored
+	c.setFeatureGates(map[string]bool{featureBalanceOSDs: true, featureUpgradeRollback: false, "MemoryTargetTuning": true})
+	assert.True(t, c.featureEnabled(featureBalanceOSDs))
+	assert.True(t, c.balanceOSDsEnabled(cephv1.BalanceOSDsSpec{}))
+	assert.False(t, c.featureEnabled(featureUpgradeRollback))
+	assert.False(t, c.featureEnabled("MemoryTargetTuning"))
+
+	// a disabled gate does not disable the feature enabled in the spec
+	c.setFeatureGates(map[string]bool{featureBalanceOSDs: false})
+	assert.True(t, c.balanceOSDsEnabled(cephv1.BalanceOSDsSpec{Enabled: true}))
+}
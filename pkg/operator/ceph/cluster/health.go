@@ -0,0 +1,79 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"sort"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+// CephHealthSummary is a short summary of the health of the ceph daemons
+type CephHealthSummary struct {
+	// Status is the overall health of the cluster such as HEALTH_OK
+	Status       string   `json:"status"`
+	ActiveMgr    string   `json:"activeMgr"`
+	StandbyMgrs  []string `json:"standbyMgrs"`
+	MonsInQuorum int      `json:"monsInQuorum"`
+	NumOSDs      int      `json:"numOSDs"`
+	NumUpOSDs    int      `json:"numUpOSDs"`
+	NumInOSDs    int      `json:"numInOSDs"`
+	// HealthChecks are the codes of the failing health checks such as OSD_DOWN
+	HealthChecks []string `json:"healthChecks"`
+	// LastChecked is when the summary was retrieved from ceph
+	LastChecked string `json:"lastChecked"`
+}
+
+// HealthSummary returns the summary of the health of the cluster from the last check of the ceph status checker,
+// so frequent callers such as probes and dashboards do not query the mons. Nil until the status was checked.
+func (c *cluster) HealthSummary() *CephHealthSummary {
+	c.healthMux.Lock()
+	defer c.healthMux.Unlock()
+	return c.healthSummary
+}
+
+// updateHealthSummary stores the summary of the ceph status retrieved by the ceph status checker
+func (c *cluster) updateHealthSummary(status *client.CephStatus) {
+	summary := toHealthSummary(status, time.Now())
+	c.healthMux.Lock()
+	defer c.healthMux.Unlock()
+	c.healthSummary = summary
+}
+
+// toHealthSummary converts the ceph status to the health summary
+func toHealthSummary(status *client.CephStatus, checked time.Time) *CephHealthSummary {
+	summary := &CephHealthSummary{
+		Status:       status.Health.Status,
+		ActiveMgr:    status.MgrMap.ActiveName,
+		StandbyMgrs:  []string{},
+		MonsInQuorum: len(status.Quorum),
+		NumOSDs:      status.OsdMap.OsdMap.NumOsd,
+		NumUpOSDs:    status.OsdMap.OsdMap.NumUpOsd,
+		NumInOSDs:    status.OsdMap.OsdMap.NumInOsd,
+		HealthChecks: []string{},
+		LastChecked:  formatTime(checked.UTC()),
+	}
+	for _, standby := range status.MgrMap.Standbys {
+		summary.StandbyMgrs = append(summary.StandbyMgrs, standby.Name)
+	}
+	for code := range status.Health.Checks {
+		summary.HealthChecks = append(summary.HealthChecks, code)
+	}
+	sort.Strings(summary.HealthChecks)
+	return summary
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestHealthSummary(t *testing.T) {
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			if args[0] == "status" {
+				return `{"health":{"status":"HEALTH_WARN","checks":{"OSD_DOWN":{"severity":"HEALTH_WARN"},"MON_DOWN":{"severity":"HEALTH_WARN"}}},
+					"quorum":[0,1],
+					"osdmap":{"osdmap":{"num_osds":3,"num_up_osds":2,"num_in_osds":3}},
+					"mgrmap":{"active_name":"a","standbys":[{"gid":1,"name":"b"}]}}`, nil
+			}
+			return "", nil
+		},
+	}
+	c := testSpec()
+	c.Namespace = "ns"
+	c.context.Executor = executor
+	c.context.RookClientset = rookfake.NewSimpleClientset(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph", Namespace: "ns"}})
+	assert.Nil(t, c.HealthSummary())
+
+	// the status checker stores the summary of each check
+	checker := newCephStatusChecker(c.context, c.Namespace, "rook-ceph")
+	checker.onStatus = c.updateHealthSummary
+	checker.checkStatus()
+	summary := c.HealthSummary()
+	assert.NotNil(t, summary)
+	assert.Equal(t, "HEALTH_WARN", summary.Status)
+	assert.Equal(t, "a", summary.ActiveMgr)
+	assert.Equal(t, []string{"b"}, summary.StandbyMgrs)
+	assert.Equal(t, 2, summary.MonsInQuorum)
+	assert.Equal(t, 3, summary.NumOSDs)
+	assert.Equal(t, 2, summary.NumUpOSDs)
+	assert.Equal(t, 3, summary.NumInOSDs)
+	assert.Equal(t, []string{"MON_DOWN", "OSD_DOWN"}, summary.HealthChecks)
+	assert.NotEqual(t, "", summary.LastChecked)
+
+	// the summary of the last successful check is kept when the status cannot be retrieved
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+		return "", errors.New("mon timeout")
+	}
+	checker.checkStatus()
+	assert.Equal(t, summary, c.HealthSummary())
+}
@@ -0,0 +1,254 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/docker/distribution/reference"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+)
+
+const (
+	// the env var of the operator with the label of the ceph image that contains the ceph version. The version is
+	// detected with a job if it is not set.
+	versionImageLabelEnvVar = "ROOK_CEPH_VERSION_IMAGE_LABEL"
+
+	mediaTypeManifest     = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeManifestList = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaTypeOCIManifest  = "application/vnd.oci.image.manifest.v1+json"
+	mediaTypeOCIIndex     = "application/vnd.oci.image.index.v1+json"
+)
+
+var (
+	registryClient = &http.Client{Timeout: 30 * time.Second}
+
+	// the parameters of the bearer challenge of a registry, e.g. realm="https://auth.docker.io/token"
+	challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+type imageManifest struct {
+	MediaType string `json:"mediaType"`
+	Config    struct {
+		Digest string `json:"digest"`
+	} `json:"config"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+		} `json:"platform"`
+	} `json:"manifests"`
+}
+
+type imageConfig struct {
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"config"`
+}
+
+// versionFromImageLabel reads the ceph version from a label of the image in its registry, so the version is known
+// without running a job. It also returns whether the version is a development build or release candidate. Only
+// registries that allow anonymous pulls are supported. The image pull secrets and the registry mirrors configured
+// for the nodes are not used, the registry of the image is contacted directly by the operator.
+func versionFromImageLabel(ctx context.Context, image, label string) (*cephver.CephVersion, bool, error) {
+	named, err := reference.ParseNormalizedNamed(image)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse image %s. %+v", image, err)
+	}
+	registry := reference.Domain(named)
+	if registry == "docker.io" {
+		registry = "registry-1.docker.io"
+	}
+	r := &registryRepo{ctx: ctx, baseURL: fmt.Sprintf("https://%s/v2/%s", registry, reference.Path(named))}
+
+	ref := "latest"
+	if digested, ok := named.(reference.Digested); ok {
+		ref = digested.Digest().String()
+	} else if tagged, ok := named.(reference.Tagged); ok {
+		ref = tagged.Tag()
+	}
+
+	labels, err := r.imageLabels(ref)
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := labels[label]
+	if !ok {
+		return nil, false, fmt.Errorf("image %s has no label %s", image, label)
+	}
+	return parseVersionLabel(value)
+}
+
+// parseVersionLabel parses the value of the version label, which is either the output of `ceph --version` or only
+// the version, e.g. "14.2.4", "v14.2.4" or "-14.2.4"
+func parseVersionLabel(value string) (*cephver.CephVersion, bool, error) {
+	value = strings.TrimSpace(value)
+	if !strings.HasPrefix(value, "ceph version ") {
+		value = "ceph version " + strings.TrimLeft(value, "v-")
+	}
+	version, err := cephver.ExtractCephVersion(value)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to parse the ceph version label. %+v", err)
+	}
+	return version, cephver.IsPreRelease(value), nil
+}
+
+// registryRepo reads the metadata of a repository with the v2 API of its registry
+type registryRepo struct {
+	ctx     context.Context
+	baseURL string
+	token   string
+}
+
+// imageLabels returns the labels of the image with the tag or digest. For a multi-arch image the labels of the image
+// for the architecture of the operator are returned.
+func (r *registryRepo) imageLabels(ref string) (map[string]string, error) {
+	manifest := imageManifest{}
+	accept := strings.Join([]string{mediaTypeManifest, mediaTypeManifestList, mediaTypeOCIManifest, mediaTypeOCIIndex}, ", ")
+	if err := r.get("manifests/"+ref, accept, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to get the manifest of %s. %+v", ref, err)
+	}
+
+	if manifest.MediaType == mediaTypeManifestList || manifest.MediaType == mediaTypeOCIIndex {
+		digest := ""
+		for _, m := range manifest.Manifests {
+			if m.Platform.OS == "linux" && m.Platform.Architecture == runtime.GOARCH {
+				digest = m.Digest
+				break
+			}
+		}
+		if digest == "" {
+			return nil, fmt.Errorf("image %s has no manifest for linux/%s", ref, runtime.GOARCH)
+		}
+		manifest = imageManifest{}
+		if err := r.get("manifests/"+digest, strings.Join([]string{mediaTypeManifest, mediaTypeOCIManifest}, ", "), &manifest); err != nil {
+			return nil, fmt.Errorf("failed to get the manifest of %s for linux/%s. %+v", ref, runtime.GOARCH, err)
+		}
+	}
+	if manifest.Config.Digest == "" {
+		return nil, fmt.Errorf("the manifest of %s has no config", ref)
+	}
+
+	config := imageConfig{}
+	if err := r.get("blobs/"+manifest.Config.Digest, "", &config); err != nil {
+		return nil, fmt.Errorf("failed to get the config of %s. %+v", ref, err)
+	}
+	return config.Config.Labels, nil
+}
+
+// get decodes the json response of the registry. A bearer token is requested anonymously if the registry requires
+// one.
+func (r *registryRepo) get(path, accept string, result interface{}) error {
+	resp, err := r.do(r.baseURL+"/"+path, accept)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized && r.token == "" {
+		challenge := resp.Header.Get("Www-Authenticate")
+		resp.Body.Close()
+		if err := r.requestToken(challenge); err != nil {
+			return err
+		}
+		if resp, err = r.do(r.baseURL+"/"+path, accept); err != nil {
+			return err
+		}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("registry returned %s", resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("failed to parse the response of the registry. %+v", err)
+	}
+	return nil
+}
+
+func (r *registryRepo) do(target, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the registry request. %+v", err)
+	}
+	req = req.WithContext(r.ctx)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+	resp, err := registryClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query the registry. %+v", err)
+	}
+	return resp, nil
+}
+
+// requestToken requests an anonymous pull token from the auth service of the bearer challenge of the registry
+func (r *registryRepo) requestToken(challenge string) error {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return fmt.Errorf("registry requires an unsupported authentication %q", challenge)
+	}
+	params := map[string]string{}
+	for _, m := range challengeParamPattern.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	if params["realm"] == "" {
+		return fmt.Errorf("registry returned no token realm in %q", challenge)
+	}
+	query := url.Values{}
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+	tokenURL := params["realm"]
+	if len(query) > 0 {
+		tokenURL = tokenURL + "?" + query.Encode()
+	}
+
+	resp, err := r.do(tokenURL, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to get a registry token. %s", resp.Status)
+	}
+	token := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("failed to parse the registry token. %+v", err)
+	}
+	r.token = token.Token
+	if r.token == "" {
+		r.token = token.AccessToken
+	}
+	if r.token == "" {
+		return fmt.Errorf("registry returned an empty token")
+	}
+	return nil
+}
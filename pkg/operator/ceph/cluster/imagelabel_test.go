@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseVersionLabel(t *testing.T) {
+	for _, value := range []string{"14.2.4", "v14.2.4", "-14.2.4", "ceph version 14.2.4 (75f4de193b3ea58512f204623e6c5a16e6c1e1ba) nautilus (stable)"} {
+		version, preRelease, err := parseVersionLabel(value)
+		assert.Nil(t, err)
+		assert.Equal(t, 14, version.Major)
+		assert.Equal(t, 4, version.Extra)
+		assert.False(t, preRelease)
+	}
+
+	_, preRelease, err := parseVersionLabel("15.0.0-rc1")
+	assert.Nil(t, err)
+	assert.True(t, preRelease)
+
+	_, _, err = parseVersionLabel("nautilus")
+	assert.NotNil(t, err)
+}
+
+func TestVersionFromImageLabel(t *testing.T) {
+	labels := `{"CEPH_POINT_RELEASE":"-14.2.4"}`
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the registry requires a token
+		if r.URL.Path == "/token" {
+			assert.Equal(t, "repository:ceph/ceph:pull", r.URL.Query().Get("scope"))
+			fmt.Fprint(w, `{"token":"secret"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("Www-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:ceph/ceph:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/ceph/ceph/manifests/v14.2.4":
+			fmt.Fprintf(w, `{"mediaType":"%s","manifests":[{"digest":"sha256:other","platform":{"os":"linux","architecture":"s390x"}},{"digest":"sha256:arch","platform":{"os":"linux","architecture":"%s"}}]}`,
+				mediaTypeManifestList, runtime.GOARCH)
+		case "/v2/ceph/ceph/manifests/sha256:arch":
+			fmt.Fprintf(w, `{"mediaType":"%s","config":{"digest":"sha256:config"}}`, mediaTypeManifest)
+		case "/v2/ceph/ceph/blobs/sha256:config":
+			fmt.Fprintf(w, `{"config":{"Labels":%s}}`, labels)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	defer func(client *http.Client) { registryClient = client }(registryClient)
+	registryClient = server.Client()
+	image := strings.TrimPrefix(server.URL, "https://") + "/ceph/ceph:v14.2.4"
+
+	version, preRelease, err := versionFromImageLabel(context.Background(), image, "CEPH_POINT_RELEASE")
+	assert.Nil(t, err)
+	assert.Equal(t, "14.2.4 nautilus", version.String())
+	assert.False(t, preRelease)
+
+	// the version is detected with a job if the label is missing or the image is not found
+	_, _, err = versionFromImageLabel(context.Background(), image, "ceph-version")
+	assert.NotNil(t, err)
+	_, _, err = versionFromImageLabel(context.Background(), strings.TrimPrefix(server.URL, "https://")+"/ceph/ceph:v15", "CEPH_POINT_RELEASE")
+	assert.NotNil(t, err)
+
+	// the label cannot be parsed
+	labels = `{"CEPH_POINT_RELEASE":"latest"}`
+	_, _, err = versionFromImageLabel(context.Background(), image, "CEPH_POINT_RELEASE")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mgr"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const defaultReadinessTimeoutMinutes = 10
+
+var daemonReadinessInterval = 5 * time.Second
+
+// markInitialized records a successful orchestration of the cluster. Until the mon and mgr deployments of a new
+// cluster were found ready, each orchestration checks their readiness in the background and reports it with the
+// DaemonsNotReady condition. The cluster is only initialized once the deployments are ready, while the orchestration
+// status and the notification of the child controllers are not delayed by the check.
+func (c *cluster) markInitialized(ctx context.Context, spec cephv1.InitializationSpec) {
+	c.initMux.Lock()
+	defer c.initMux.Unlock()
+	c.orchestrated = true
+	if c.initCompleted || c.readinessCheckRunning {
+		return
+	}
+	c.readinessCheckRunning = true
+	go func() {
+		ready := c.checkDaemonsReady(ctx, spec)
+		c.initMux.Lock()
+		defer c.initMux.Unlock()
+		c.readinessCheckRunning = false
+		if ready {
+			c.initCompleted = true
+		}
+	}()
+}
+
+// checkDaemonsReady waits for the mon and mgr deployments to be ready and reports the result in the DaemonsNotReady
+// condition of the cluster. Returns whether the deployments are ready.
+func (c *cluster) checkDaemonsReady(ctx context.Context, spec cephv1.InitializationSpec) bool {
+	timeoutMinutes := spec.ReadinessTimeoutMinutes
+	if timeoutMinutes <= 0 {
+		timeoutMinutes = defaultReadinessTimeoutMinutes
+	}
+	err := c.waitForDeploymentsReady(ctx, time.Duration(timeoutMinutes)*time.Minute)
+	if err == nil && spec.StabilizationSeconds > 0 {
+		logger.Infof("waiting %ds for the daemons of cluster %s to stabilize", spec.StabilizationSeconds, c.Namespace)
+		select {
+		case <-time.After(time.Duration(spec.StabilizationSeconds) * time.Second):
+		case <-ctx.Done():
+			return false
+		}
+		// the daemons must still be ready after the stabilization period
+		var notReady []string
+		notReady, err = c.notReadyDeployments()
+		if err == nil && len(notReady) > 0 {
+			err = fmt.Errorf("deployments %s are not ready anymore after %ds", strings.Join(notReady, ", "), spec.StabilizationSeconds)
+		}
+	}
+
+	problem := ""
+	if err != nil {
+		problem = fmt.Sprintf("the daemons of cluster %s are not ready. %+v", c.Namespace, err)
+	}
+	c.reportCondition(cephv1.ClusterConditionDaemonsNotReady, problem, "DaemonsReady", "the mon and mgr deployments are ready")
+	return err == nil
+}
+
+// waitForDeploymentsReady waits until the mon and mgr deployments of the cluster are ready
+func (c *cluster) waitForDeploymentsReady(ctx context.Context, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		notReady, err := c.notReadyDeployments()
+		if err != nil {
+			return err
+		}
+		if len(notReady) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("deployments %s are not ready after %s", strings.Join(notReady, ", "), timeout)
+		}
+		logger.Infof("waiting for deployments %s of cluster %s to be ready", strings.Join(notReady, ", "), c.Namespace)
+		select {
+		case <-time.After(daemonReadinessInterval):
+		case <-ctx.Done():
+			return fmt.Errorf("canceled waiting for the deployments to be ready. %+v", ctx.Err())
+		}
+	}
+}
+
+// notReadyDeployments returns the names of the mon and mgr deployments of the cluster that have fewer ready pods than
+// desired. The deployments of the other daemons, e.g. OSDs on failed disks, do not delay the initialization.
+func (c *cluster) notReadyDeployments() ([]string, error) {
+	selector := fmt.Sprintf("%s in (%s,%s),%s=%s", k8sutil.AppAttr, mon.AppName, mgr.AppName, k8sutil.ClusterAttr, c.Namespace)
+	deployments, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the deployments. %+v", err)
+	}
+	notReady := []string{}
+	for _, d := range deployments.Items {
+		desired := int32(1)
+		if d.Spec.Replicas != nil {
+			desired = *d.Spec.Replicas
+		}
+		if d.Status.ReadyReplicas < desired {
+			notReady = append(notReady, d.Name)
+		}
+	}
+	return notReady, nil
+}
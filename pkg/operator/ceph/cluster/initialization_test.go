@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMarkInitialized(t *testing.T) {
+	daemonReadinessInterval = time.Millisecond
+	c := testSpec()
+	c.Namespace = "ns"
+	c.crdName = "my-cluster"
+	c.context.RookClientset = rookfake.NewSimpleClientset(&cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}})
+	daemonsNotReady := func() cephv1.ClusterCondition {
+		cephCluster, err := c.context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+		assert.Nil(t, err)
+		for _, condition := range cephCluster.Status.Conditions {
+			if condition.Type == cephv1.ClusterConditionDaemonsNotReady {
+				return condition
+			}
+		}
+		return cephv1.ClusterCondition{}
+	}
+	replicas := int32(1)
+	d := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mgr-a", Namespace: "ns", Labels: map[string]string{k8sutil.AppAttr: "rook-ceph-mgr", k8sutil.ClusterAttr: "ns"}},
+		Spec:       apps.DeploymentSpec{Replicas: &replicas},
+	}
+	d, err := c.context.Clientset.AppsV1().Deployments("ns").Create(d)
+	assert.Nil(t, err)
+
+	// the cluster is not initialized while the deployment is not ready, which is reported in the condition
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.markInitialized(ctx, cephv1.InitializationSpec{})
+	waitForReadinessCheck(t, c)
+	assert.True(t, c.orchestratedOnce())
+	assert.False(t, c.initialized())
+	assert.Equal(t, v1.ConditionTrue, daemonsNotReady().Status)
+	events, err := c.context.Clientset.CoreV1().Events("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events.Items))
+	assert.Equal(t, "DaemonsNotReady", events.Items[0].Reason)
+
+	// the deployments of other clusters and of the other daemons are ignored
+	notReady, err := c.notReadyDeployments()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"rook-ceph-mgr-a"}, notReady)
+	for name, labels := range map[string]map[string]string{
+		"rook-ceph-mgr-b": {k8sutil.AppAttr: "rook-ceph-mgr", k8sutil.ClusterAttr: "other"},
+		"rook-ceph-osd-0": {k8sutil.AppAttr: "rook-ceph-osd", k8sutil.ClusterAttr: "ns"},
+	} {
+		other := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: labels}}
+		_, err = c.context.Clientset.AppsV1().Deployments("ns").Create(other)
+		assert.Nil(t, err)
+	}
+
+	// the next orchestration checks the readiness again
+	d.Status.ReadyReplicas = 1
+	_, err = c.context.Clientset.AppsV1().Deployments("ns").Update(d)
+	assert.Nil(t, err)
+	notReady, err = c.notReadyDeployments()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(notReady))
+	c.markInitialized(context.Background(), cephv1.InitializationSpec{StabilizationSeconds: 1})
+	waitForReadinessCheck(t, c)
+	assert.True(t, c.initialized())
+	assert.Equal(t, v1.ConditionFalse, daemonsNotReady().Status)
+
+	// the readiness is not checked anymore once the cluster is initialized
+	d.Status.ReadyReplicas = 0
+	_, err = c.context.Clientset.AppsV1().Deployments("ns").Update(d)
+	assert.Nil(t, err)
+	c.markInitialized(context.Background(), cephv1.InitializationSpec{})
+	waitForReadinessCheck(t, c)
+	assert.True(t, c.initialized())
+	assert.Equal(t, v1.ConditionFalse, daemonsNotReady().Status)
+}
+
+func waitForReadinessCheck(t *testing.T, c *cluster) {
+	for i := 0; i < 500; i++ {
+		c.initMux.Lock()
+		running := c.readinessCheckRunning
+		c.initMux.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("the readiness check did not finish")
+}
@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// pendingOrchestrationsGauge is the number of spec changes of each cluster that are waiting for an
+	// orchestration. A value that never drops back to 0 indicates a stuck orchestration.
+	pendingOrchestrationsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "rook_ceph_cluster_pending_orchestrations",
+		Help: "Number of changes of the CephCluster spec that are waiting for an orchestration",
+	}, []string{"namespace"})
+
+	// orchestrationPhaseCounter counts the attempts to start each type of daemon during the orchestrations and
+	// whether they succeeded
+	orchestrationPhaseCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "rook_ceph_orchestration_phase_total",
+		Help: "Number of attempts to start the daemons of an orchestration phase by result",
+	}, []string{"namespace", "phase", "result"})
+
+	// queuedOrchestrationsGauge is the number of clusters waiting for the orchestrations of other clusters to
+	// complete when the number of concurrent orchestrations is limited
+	queuedOrchestrationsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "rook_ceph_queued_orchestrations",
+		Help: "Number of clusters waiting for a free slot to be orchestrated",
+	})
+)
+
+const (
+	phaseMon       = "mon"
+	phaseMgr       = "mgr"
+	phaseOSD       = "osd"
+	phaseRBDMirror = "rbdmirror"
+
+	phaseResultSuccess = "success"
+	phaseResultFailure = "failure"
+)
+
+var orchestrationPhases = []string{phaseMon, phaseMgr, phaseOSD, phaseRBDMirror}
+
+func init() {
+	// the registry is served by the metrics endpoint of the controller-runtime manager of the operator
+	metrics.Registry.MustRegister(pendingOrchestrationsGauge)
+	metrics.Registry.MustRegister(orchestrationPhaseCounter)
+	metrics.Registry.MustRegister(queuedOrchestrationsGauge)
+}
+
+// recordOrchestrationPhase counts an attempt of the phase, which failed if the error is not nil
+func (c *cluster) recordOrchestrationPhase(phase string, err error) {
+	result := phaseResultSuccess
+	if err != nil {
+		result = phaseResultFailure
+	}
+	orchestrationPhaseCounter.WithLabelValues(c.Namespace, phase, result).Inc()
+}
+
+// updatePendingOrchestrationsMetric sets the gauge of the cluster. The orchMux must be held by the caller so
+// the gauge is consistent with the orchestration status.
+func (c *cluster) updatePendingOrchestrationsMetric() {
+	pendingOrchestrationsGauge.WithLabelValues(c.Namespace).Set(float64(c.pendingOrchestrations))
+}
+
+// deletePendingOrchestrationsMetric removes the gauge and the phase counters of a cluster that was deleted
+func deletePendingOrchestrationsMetric(namespace string) {
+	pendingOrchestrationsGauge.DeleteLabelValues(namespace)
+	for _, phase := range orchestrationPhases {
+		orchestrationPhaseCounter.DeleteLabelValues(namespace, phase, phaseResultSuccess)
+		orchestrationPhaseCounter.DeleteLabelValues(namespace, phase, phaseResultFailure)
+	}
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func pendingOrchestrationsValue(t *testing.T, namespace string) float64 {
+	metric := &dto.Metric{}
+	err := pendingOrchestrationsGauge.WithLabelValues(namespace).Write(metric)
+	assert.Nil(t, err)
+	return metric.GetGauge().GetValue()
+}
+
+func TestPendingOrchestrationsMetric(t *testing.T) {
+	c := &cluster{Namespace: "pending-ns"}
+
+	// two changes while no orchestration is running
+	c.setOrchestrationNeeded()
+	c.setOrchestrationNeeded()
+	assert.Equal(t, 2, c.pendingOrchestrations)
+	assert.Equal(t, float64(2), pendingOrchestrationsValue(t, c.Namespace))
+
+	// the orchestration picks up all the pending changes
+	assert.True(t, c.checkSetOrchestrationStatus())
+	assert.Equal(t, float64(0), pendingOrchestrationsValue(t, c.Namespace))
+
+	// a change during the orchestration is pending until the next run
+	c.setOrchestrationNeeded()
+	assert.False(t, c.checkSetOrchestrationStatus())
+	c.unsetOrchestrationStatus()
+	assert.Equal(t, float64(1), pendingOrchestrationsValue(t, c.Namespace))
+	assert.True(t, c.checkSetOrchestrationStatus())
+	c.unsetOrchestrationStatus()
+	assert.Equal(t, float64(0), pendingOrchestrationsValue(t, c.Namespace))
+
+	deletePendingOrchestrationsMetric(c.Namespace)
+}
+
+func orchestrationPhaseValue(t *testing.T, namespace, phase, result string) float64 {
+	metric := &dto.Metric{}
+	err := orchestrationPhaseCounter.WithLabelValues(namespace, phase, result).Write(metric)
+	assert.Nil(t, err)
+	return metric.GetCounter().GetValue()
+}
+
+func TestOrchestrationPhaseMetric(t *testing.T) {
+	c := &cluster{Namespace: "phase-ns"}
+
+	c.recordOrchestrationPhase(phaseMon, nil)
+	c.recordOrchestrationPhase(phaseOSD, fmt.Errorf("failed"))
+	c.recordOrchestrationPhase(phaseOSD, nil)
+	assert.Equal(t, float64(1), orchestrationPhaseValue(t, c.Namespace, phaseMon, phaseResultSuccess))
+	assert.Equal(t, float64(0), orchestrationPhaseValue(t, c.Namespace, phaseMon, phaseResultFailure))
+	assert.Equal(t, float64(1), orchestrationPhaseValue(t, c.Namespace, phaseOSD, phaseResultFailure))
+	assert.Equal(t, float64(1), orchestrationPhaseValue(t, c.Namespace, phaseOSD, phaseResultSuccess))
+
+	// the counters of a deleted cluster start again from 0
+	deletePendingOrchestrationsMetric(c.Namespace)
+	assert.Equal(t, float64(0), orchestrationPhaseValue(t, c.Namespace, phaseOSD, phaseResultSuccess))
+	deletePendingOrchestrationsMetric(c.Namespace)
+}
@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+// the number of times the active mgr is queried to confirm that ceph reports a consistent active mgr
+const activeMgrQueries = 3
+
+var activeMgrQueryInterval = time.Second
+
+// activeMgr returns the ID of the active mgr, or an empty string if it is not known. During a failover ceph may
+// briefly report different active mgrs, or a mgr that is both active and standby. The active mgr is then unknown
+// so no decision is made on an ambiguous mgr map. An error is only returned if the orchestration was canceled.
+func (c *Cluster) activeMgr(ctx context.Context) (string, error) {
+	active := ""
+	for i := 0; i < activeMgrQueries; i++ {
+		if i > 0 {
+			select {
+			case <-time.After(activeMgrQueryInterval):
+			case <-ctx.Done():
+				return "", fmt.Errorf("canceled the query of the active mgr. %+v", ctx.Err())
+			}
+		}
+		status, err := client.Status(c.context, c.Namespace, false)
+		if err != nil {
+			logger.Warningf("failed to get the active mgr. %+v", err)
+			return "", nil
+		}
+		name := status.MgrMap.ActiveName
+		for _, standby := range status.MgrMap.Standbys {
+			if name != "" && standby.Name == name {
+				c.setAmbiguousActiveMgr(fmt.Sprintf("mgr %s is reported both active and standby", name))
+				return "", nil
+			}
+		}
+		if i > 0 && name != active {
+			c.setAmbiguousActiveMgr(fmt.Sprintf("the active mgr changed from %q to %q while it was queried", active, name))
+			return "", nil
+		}
+		active = name
+	}
+	return active, nil
+}
+
+func (c *Cluster) setAmbiguousActiveMgr(reason string) {
+	c.ambiguousActiveMgrMessage = fmt.Sprintf("the active mgr is ambiguous, probably during a failover: %s", reason)
+	logger.Warning(c.ambiguousActiveMgrMessage)
+}
+
+// AmbiguousActiveMgrMessage returns why the active mgr was ambiguous during the last start of the mgrs, or an empty
+// string if the active mgr was consistent or not needed
+func (c *Cluster) AmbiguousActiveMgrMessage() string {
+	return c.ambiguousActiveMgrMessage
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"fmt"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// validateMonAntiAffinity checks the anti-affinity of the mgr to the mons from the mgr spec
+func (c *Cluster) validateMonAntiAffinity() error {
+	switch c.MgrSpec.MonAntiAffinity {
+	case "", cephv1.MonAntiAffinityPreferred, cephv1.MonAntiAffinityRequired:
+		return nil
+	}
+	return fmt.Errorf("invalid mon anti-affinity %q, it must be %q or %q", c.MgrSpec.MonAntiAffinity, cephv1.MonAntiAffinityPreferred, cephv1.MonAntiAffinityRequired)
+}
+
+// countNodesWithoutMons returns the number of nodes where the mgr can be placed and no mon is running
+func (c *Cluster) countNodesWithoutMons() (int, error) {
+	nodes, err := c.context.Clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list nodes. %+v", err)
+	}
+	monPods, err := c.context.Clientset.CoreV1().Pods(c.Namespace).List(metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, mon.AppName)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list the mon pods. %+v", err)
+	}
+	monNodes := map[string]bool{}
+	for _, pod := range monPods.Items {
+		monNodes[pod.Spec.NodeName] = true
+	}
+
+	count := 0
+	for _, node := range nodes.Items {
+		if monNodes[node.Name] {
+			continue
+		}
+		valid, err := k8sutil.ValidNode(node, c.placement)
+		if err != nil {
+			logger.Warningf("failed to check if node %s is valid for the mgr. %+v", node.Name, err)
+			continue
+		}
+		if valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// checkMonAntiAffinity returns whether the mgr pods can be required to run on other nodes than the mons. If there
+// are not enough nodes without mons, the anti-affinity is only preferred so the mgr pods can still be scheduled.
+func checkMonAntiAffinity(mgrCount, nodeCount int) bool {
+	if nodeCount >= mgrCount {
+		logger.Infof("placing the %d mgrs on other nodes than the mons", mgrCount)
+		return true
+	}
+	logger.Warningf("only %d nodes without a mon are available for %d mgrs. the mgrs will avoid the nodes of the mons when possible, but may run next to a mon",
+		nodeCount, mgrCount)
+	return false
+}
+
+// applyMonAntiAffinity adds the anti-affinity to the mon pods to the mgr pod. ApplyToPodSpec of the placement must be
+// called first so the affinity is set.
+func (c *Cluster) applyMonAntiAffinity(spec *v1.PodSpec) {
+	if c.MgrSpec.MonAntiAffinity == "" {
+		return
+	}
+	monAntiAffinity := v1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				k8sutil.AppAttr: mon.AppName,
+			},
+		},
+		TopologyKey: v1.LabelHostname,
+	}
+	if spec.Affinity.PodAntiAffinity == nil {
+		spec.Affinity.PodAntiAffinity = &v1.PodAntiAffinity{}
+	} else {
+		// the anti-affinity of the placement is shared by the pods
+		spec.Affinity.PodAntiAffinity = spec.Affinity.PodAntiAffinity.DeepCopy()
+	}
+	paa := spec.Affinity.PodAntiAffinity
+
+	if c.MgrSpec.MonAntiAffinity == cephv1.MonAntiAffinityRequired && c.requireMonAntiAffinity {
+		paa.RequiredDuringSchedulingIgnoredDuringExecution =
+			append(paa.RequiredDuringSchedulingIgnoredDuringExecution, monAntiAffinity)
+	} else {
+		paa.PreferredDuringSchedulingIgnoredDuringExecution =
+			append(paa.PreferredDuringSchedulingIgnoredDuringExecution, v1.WeightedPodAffinityTerm{
+				Weight:          50,
+				PodAffinityTerm: monAntiAffinity,
+			})
+	}
+}
@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/config"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMonAntiAffinity(t *testing.T) {
+	clientset := testop.New(3)
+	clusterInfo := &cephconfig.ClusterInfo{FSID: "myfsid", CephVersion: cephver.Nautilus}
+	c := &Cluster{Namespace: "ns", Replicas: 1, clusterInfo: clusterInfo, context: &clusterd.Context{Clientset: clientset}}
+	mgrTestConfig := mgrConfig{
+		DaemonID:     "a",
+		ResourceName: "rook-ceph-mgr-a",
+		DataPathMap:  config.NewStatelessDaemonDataPathMap(config.MgrType, "a", "rook-ceph", "/var/lib/rook/"),
+	}
+
+	// no anti-affinity by default
+	assert.Nil(t, c.validateMonAntiAffinity())
+	d := c.makeDeployment(&mgrTestConfig)
+	assert.Nil(t, d.Spec.Template.Spec.Affinity.PodAntiAffinity)
+
+	c.MgrSpec.MonAntiAffinity = "always"
+	assert.NotNil(t, c.validateMonAntiAffinity())
+
+	// the mgr prefers other nodes than the mons
+	c.MgrSpec.MonAntiAffinity = cephv1.MonAntiAffinityPreferred
+	assert.Nil(t, c.validateMonAntiAffinity())
+	d = c.makeDeployment(&mgrTestConfig)
+	paa := d.Spec.Template.Spec.Affinity.PodAntiAffinity
+	assert.Equal(t, 0, len(paa.RequiredDuringSchedulingIgnoredDuringExecution))
+	assert.Equal(t, 1, len(paa.PreferredDuringSchedulingIgnoredDuringExecution))
+	term := paa.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm
+	assert.Equal(t, map[string]string{"app": "rook-ceph-mon"}, term.LabelSelector.MatchLabels)
+	assert.Equal(t, v1.LabelHostname, term.TopologyKey)
+
+	// the mons run on two of the three nodes, so one node is left for the mgr
+	for mon, node := range map[string]string{"a": "node0", "b": "node1"} {
+		pod := &v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mon-" + mon, Namespace: "ns", Labels: map[string]string{"app": "rook-ceph-mon"}},
+			Spec:       v1.PodSpec{NodeName: node},
+		}
+		_, err := clientset.CoreV1().Pods("ns").Create(pod)
+		assert.Nil(t, err)
+	}
+	nodes, err := c.countNodesWithoutMons()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, nodes)
+
+	// the anti-affinity is required when there are enough nodes
+	c.MgrSpec.MonAntiAffinity = cephv1.MonAntiAffinityRequired
+	c.requireMonAntiAffinity = checkMonAntiAffinity(1, nodes)
+	assert.True(t, c.requireMonAntiAffinity)
+	d = c.makeDeployment(&mgrTestConfig)
+	paa = d.Spec.Template.Spec.Affinity.PodAntiAffinity
+	assert.Equal(t, 1, len(paa.RequiredDuringSchedulingIgnoredDuringExecution))
+	assert.Equal(t, 0, len(paa.PreferredDuringSchedulingIgnoredDuringExecution))
+
+	// with too few nodes the anti-affinity is only preferred so the mgrs can still be scheduled
+	c.requireMonAntiAffinity = checkMonAntiAffinity(2, nodes)
+	assert.False(t, c.requireMonAntiAffinity)
+	d = c.makeDeployment(&mgrTestConfig)
+	paa = d.Spec.Template.Spec.Affinity.PodAntiAffinity
+	assert.Equal(t, 0, len(paa.RequiredDuringSchedulingIgnoredDuringExecution))
+	assert.Equal(t, 1, len(paa.PreferredDuringSchedulingIgnoredDuringExecution))
+}
@@ -18,6 +18,7 @@ package mgr
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/rook/rook/pkg/operator/ceph/config"
 	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
@@ -45,6 +46,10 @@ type mgrConfig struct {
 
 func (c *Cluster) dashboardPort() int {
 	if c.dashboard.Port == 0 {
+		// the port may be set with the settings of the dashboard module instead
+		if port := c.dashboardSettingPort(); port != 0 {
+			return port
+		}
 		// select default port
 		return dashboardPortHTTPS
 	}
@@ -52,6 +57,24 @@ func (c *Cluster) dashboardPort() int {
 	return c.dashboard.Port
 }
 
+// dashboardSettingPort returns the port of the dashboard from the settings of the dashboard module, the
+// ssl_server_port if the dashboard serves https or else the server_port, or 0 if the port is not set
+func (c *Cluster) dashboardSettingPort() int {
+	key := "server_port"
+	if c.dashboard.SSL == nil || *c.dashboard.SSL {
+		key = "ssl_server_port"
+	}
+	for _, module := range c.MgrSpec.Modules {
+		if module.Name != dashboardModuleName {
+			continue
+		}
+		if port, err := strconv.Atoi(module.Settings[key]); err == nil && port > 0 {
+			return port
+		}
+	}
+	return 0
+}
+
 func (c *Cluster) generateKeyring(m *mgrConfig) error {
 	user := fmt.Sprintf("mgr.%s", m.DaemonID)
 	/* TODO: the access string here does not match the access from the keyring template. should they match? */
@@ -26,6 +26,7 @@ import (
 	"syscall"
 	"time"
 
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"k8s.io/api/core/v1"
@@ -43,23 +44,35 @@ const (
 	passwordKeyName                = "password"
 	certAlreadyConfiguredErrorCode = 5
 	invalidArgErrorCode            = int(syscall.EINVAL)
+	notFoundErrorCode              = int(syscall.ENOENT)
 )
 
 var (
 	dashboardInitWaitTime = 5 * time.Second
+	// the keys where the dashboard stores its self-signed cert
+	dashboardCertKeys = []string{"mgr/dashboard/crt", "mgr/dashboard/key"}
 )
 
 func init() {
 	rand.Seed(time.Now().UnixNano())
 }
 
+// DashboardAvailabilityWarning returns a warning when the dashboard is enabled with a single mgr, since the
+// dashboard is down until the mgr is restarted when it fails. No warning is returned otherwise.
+func (c *Cluster) DashboardAvailabilityWarning() string {
+	if !c.dashboard.Enabled || c.Replicas >= 2 {
+		return ""
+	}
+	return fmt.Sprintf("the dashboard is enabled with %d mgr, so the dashboard is unavailable when the mgr fails. consider running two mgrs for a highly available dashboard", c.Replicas)
+}
+
 func (c *Cluster) configureDashboard(m *mgrConfig) error {
 	// enable or disable the dashboard module
 	if err := c.toggleDashboardModule(m); err != nil {
 		return err
 	}
 
-	dashboardService := c.makeDashboardService(appName, m.DashboardPort)
+	dashboardService := c.makeDashboardService(AppName, m.DashboardPort)
 	if c.dashboard.Enabled {
 		// expose the dashboard service
 		if _, err := c.context.Clientset.CoreV1().Services(c.Namespace).Create(dashboardService); err != nil {
@@ -110,6 +123,32 @@ func (c *Cluster) toggleDashboardModule(m *mgrConfig) error {
 		if err := client.MgrDisableModule(c.context, c.Namespace, dashboardModuleName); err != nil {
 			logger.Errorf("failed to disable mgr dashboard module. %+v", err)
 		}
+		if err := c.removeDashboardCredentials(); err != nil {
+			return fmt.Errorf("failed to remove the dashboard credentials. %+v", err)
+		}
+	}
+	return nil
+}
+
+// removeDashboardCredentials removes the password secret and the self-signed cert of a disabled dashboard. A
+// new password and cert are created if the dashboard is enabled again. The resources that are already gone
+// are skipped, so this is safe to run in every orchestration.
+func (c *Cluster) removeDashboardCredentials() error {
+	err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Delete(dashboardPasswordName, &metav1.DeleteOptions{})
+	if err == nil {
+		logger.Infof("removed the dashboard password secret %s", dashboardPasswordName)
+	} else if !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete the dashboard password secret. %+v", err)
+	}
+
+	for _, key := range dashboardCertKeys {
+		args := []string{"config-key", "rm", key}
+		if _, err := client.NewCephCommand(c.context, c.Namespace, args).Run(); err != nil {
+			if exitCode, parsed := c.exitCode(err); parsed && exitCode == notFoundErrorCode {
+				continue
+			}
+			return fmt.Errorf("failed to remove the dashboard cert %s. %+v", key, err)
+		}
 	}
 	return nil
 }
@@ -121,13 +160,15 @@ func (c *Cluster) configureDashboardModule(m *mgrConfig) error {
 		return err
 	}
 
-	// server port
+	// server port. the dashboard listens on the ssl_server_port with ssl, so both are set to the port of the service.
 	port := strconv.Itoa(m.DashboardPort)
-	changed, err := client.MgrSetConfig(c.context, c.Namespace, m.DaemonID, c.clusterInfo.CephVersion, "mgr/dashboard/server_port", port, false)
-	if err != nil {
-		return err
+	for _, key := range []string{"mgr/dashboard/server_port", "mgr/dashboard/ssl_server_port"} {
+		changed, err := client.MgrSetConfig(c.context, c.Namespace, m.DaemonID, c.clusterInfo.CephVersion, key, port, false)
+		if err != nil {
+			return err
+		}
+		hasChanged = hasChanged || changed
 	}
-	hasChanged = hasChanged || changed
 
 	// ssl support
 	var ssl string
@@ -136,12 +177,29 @@ func (c *Cluster) configureDashboardModule(m *mgrConfig) error {
 	} else {
 		ssl = strconv.FormatBool(*c.dashboard.SSL)
 	}
-	changed, err = client.MgrSetConfig(c.context, c.Namespace, m.DaemonID, c.clusterInfo.CephVersion, "mgr/dashboard/ssl", ssl, false)
+	changed, err := client.MgrSetConfig(c.context, c.Namespace, m.DaemonID, c.clusterInfo.CephVersion, "mgr/dashboard/ssl", ssl, false)
 	if err != nil {
 		return err
 	}
 	hasChanged = hasChanged || changed
 
+	// server address. binding to the pod IP is configured by an init container of the mgr pod. The address of the
+	// daemon takes precedence over the address of the module, so it is set too in case it still holds a pod IP.
+	if c.dashboard.BindAddress != "" && c.dashboard.BindAddress != cephv1.DashboardBindAddressPodIP {
+		changed, err = client.MgrSetConfig(c.context, c.Namespace, m.DaemonID, c.clusterInfo.CephVersion, "mgr/dashboard/server_addr", c.dashboard.BindAddress, false)
+		if err != nil {
+			return err
+		}
+		hasChanged = hasChanged || changed
+
+		key := fmt.Sprintf("mgr/dashboard/%s/server_addr", m.DaemonID)
+		changed, err = client.MgrSetConfig(c.context, c.Namespace, m.DaemonID, c.clusterInfo.CephVersion, key, c.dashboard.BindAddress, true)
+		if err != nil {
+			return err
+		}
+		hasChanged = hasChanged || changed
+	}
+
 	if hasChanged {
 		logger.Infof("dashboard config has changed")
 		return c.restartDashboard()
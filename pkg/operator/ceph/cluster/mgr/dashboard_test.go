@@ -67,11 +67,24 @@ func TestGetOrGeneratePassword(t *testing.T) {
 	assert.Equal(t, password, retrievedPassword)
 }
 
+func TestDashboardAvailabilityWarning(t *testing.T) {
+	c := &Cluster{Replicas: 1}
+	assert.Equal(t, "", c.DashboardAvailabilityWarning())
+
+	// a single mgr serves the dashboard
+	c.dashboard.Enabled = true
+	assert.Contains(t, c.DashboardAvailabilityWarning(), "consider running two mgrs")
+
+	c.Replicas = 2
+	assert.Equal(t, "", c.DashboardAvailabilityWarning())
+}
+
 func TestStartSecureDashboard(t *testing.T) {
 	enables := 0
 	disables := 0
 	moduleRetries := 0
 	exitCodeResponse := 0
+	certsRemoved := []string{}
 	executor := &exectest.MockExecutor{
 		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
 			logger.Infof("command: %s %v", command, args)
@@ -83,6 +96,9 @@ func TestStartSecureDashboard(t *testing.T) {
 					disables++
 				}
 			}
+			if args[0] == "config-key" && args[1] == "rm" {
+				certsRemoved = append(certsRemoved, args[2])
+			}
 			if args[0] == "dashboard" && args[1] == "create-self-signed-cert" {
 				if moduleRetries < 2 {
 					logger.Infof("simulating retry...")
@@ -139,4 +155,43 @@ func TestStartSecureDashboard(t *testing.T) {
 	assert.NotNil(t, err)
 	assert.True(t, errors.IsNotFound(err))
 	assert.Nil(t, svc)
+
+	// the password and the cert are removed
+	_, err = c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(dashboardPasswordName, metav1.GetOptions{})
+	assert.True(t, errors.IsNotFound(err))
+	assert.Equal(t, []string{"mgr/dashboard/crt", "mgr/dashboard/key"}, certsRemoved)
+
+	// disabling the dashboard again succeeds when the resources are already gone
+	err = c.configureDashboard(mgrConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, disables)
+}
+
+func TestConfigureDashboardModule(t *testing.T) {
+	configs := map[string]string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "config" && args[1] == "set" {
+				configs[args[3]] = args[4]
+			}
+			return "", nil
+		},
+	}
+	executor.MockExecuteCommandWithOutputFileTimeout = func(debug bool, timeout time.Duration, actionName string, command, outfileArg string, arg ...string) (string, error) {
+		return executor.MockExecuteCommandWithOutputFile(debug, actionName, command, outfileArg, arg...)
+	}
+	clusterInfo := &cephconfig.ClusterInfo{CephVersion: cephver.Nautilus}
+	c := &Cluster{clusterInfo: clusterInfo, context: &clusterd.Context{Clientset: test.New(3), Executor: executor}, Namespace: "myns",
+		dashboard: cephv1.DashboardSpec{Enabled: true, BindAddress: "10.0.0.1"}}
+	mgrConfig := &mgrConfig{DaemonID: "a", DashboardPort: dashboardPortHTTPS}
+
+	// the address of the daemon is set too, it would override the address of the module
+	err := c.configureDashboardModule(mgrConfig)
+	assert.Nil(t, err)
+	assert.Equal(t, "10.0.0.1", configs["mgr/dashboard/server_addr"])
+	assert.Equal(t, "10.0.0.1", configs["mgr/dashboard/a/server_addr"])
+
+	// the dashboard listens on the port of the service with or without ssl
+	assert.Equal(t, "8443", configs["mgr/dashboard/server_port"])
+	assert.Equal(t, "8443", configs["mgr/dashboard/ssl_server_port"])
 }
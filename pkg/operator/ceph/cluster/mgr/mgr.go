@@ -18,12 +18,16 @@ limitations under the License.
 package mgr
 
 import (
+	"context"
 	"fmt"
 	"path"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/coreos/pkg/capnslog"
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
@@ -34,6 +38,7 @@ import (
 	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
 	cephver "github.com/rook/rook/pkg/operator/ceph/version"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -43,8 +48,21 @@ var logger = capnslog.NewPackageLogger("github.com/rook/rook", "op-mgr")
 
 var prometheusRuleName = "prometheus-ceph-vVERSION-rules"
 
+// matches the prometheus rules of all ceph versions
+var prometheusRuleNamePattern = regexp.MustCompile("^(?:.+-)?" + strings.Replace(prometheusRuleName, "VERSION", `\d+`, 1) + "$")
+
+// the clusters for which the missing prometheus operator CRDs were already reported, by namespace. The mgr of a
+// cluster is recreated for each orchestration, so the state is kept by the package.
+var prometheusCRDsMissingLogged = map[string]bool{}
+var prometheusCRDsMux sync.Mutex
+
+// the prometheus rules are listed and deleted with the monitoring client, replaced in the tests
+var listPrometheusRules = k8sutil.ListPrometheusRules
+var deletePrometheusRule = k8sutil.DeletePrometheusRule
+
 const (
-	appName              = "rook-ceph-mgr"
+	// AppName is the "app" label on the mgr pods
+	AppName              = "rook-ceph-mgr"
 	serviceAccountName   = "rook-ceph-mgr"
 	prometheusModuleName = "prometheus"
 	metricsPort          = 9283
@@ -73,9 +91,33 @@ type Cluster struct {
 	exitCode        func(err error) (int, bool)
 	dataDirHostPath string
 	isUpgrade       bool
+
+	// The optional settings of the mgrs are set after New, their zero value keeps the default behavior.
+
+	// MgrSpec are the settings of the mgr daemons from the cluster CR
+	MgrSpec cephv1.MgrSpec
+	// PriorityClassName is the priority class of the mgr pods, no priority class if empty
+	PriorityClassName string
+	// GracePeriod is the termination grace period of the mgr pods, the default of kubernetes if nil
+	GracePeriod *int64
+	// CABundle are the CA certificates trusted by the mgr, e.g. for the dashboard SSO with an internal IdP
+	CABundle cephv1.CABundleSpec
+	// SchedulerName is the scheduler of the mgr pods, the default scheduler if empty
+	SchedulerName string
+	// SkipPrometheusRule is set when the prometheus rule cannot be deployed to the rules namespace
+	SkipPrometheusRule bool
+
+	// whether the mgrs are required to run on other nodes than the mons, only set when there are enough nodes
+	requireMonAntiAffinity bool
+	// the reason why the placement of the mgr does not match any node, empty if the mgr can be scheduled
+	unschedulableMessage string
+	// whether the placement was checked against the nodes by the last start of the mgrs
+	placementChecked bool
+	// the reason why the active mgr is not known, empty if ceph reported a consistent active mgr
+	ambiguousActiveMgrMessage string
 }
 
-// New creates an instance of the mgr
+// New creates an instance of the mgr. The optional settings are set on the exported fields of the returned Cluster.
 func New(
 	clusterInfo *cephconfig.ClusterInfo,
 	context *clusterd.Context,
@@ -113,24 +155,63 @@ func New(
 
 var updateDeploymentAndWait = mon.UpdateCephDeploymentAndWait
 
+// updateAndWaitForDeployment updates the mgr deployment and waits for its pod to be ready. If the pod does not become
+// ready, the reasons found in the status and the events of the pod are added to the error.
+func (c *Cluster) updateAndWaitForDeployment(d *apps.Deployment, daemonID string, cephVersion cephver.CephVersion) error {
+	err := updateDeploymentAndWait(c.context, d, c.Namespace, string(config.MgrType), daemonID, cephVersion, c.isUpgrade)
+	if err == nil {
+		return nil
+	}
+	if diagnosis := k8sutil.DiagnoseDeployment(c.context.Clientset, c.Namespace, d); diagnosis != "" {
+		return fmt.Errorf("%+v. %s", err, diagnosis)
+	}
+	return err
+}
+
 // Start begins the process of running a cluster of Ceph mgrs.
-func (c *Cluster) Start() error {
+func (c *Cluster) Start(ctx context.Context) error {
+	c.placementChecked = false
 	// Validate pod's memory if specified
 	err := opspec.CheckPodMemory(c.resources, cephMgrPodMinimumMemory)
 	if err != nil {
 		return fmt.Errorf("%v", err)
 	}
 
+	// the deployments are not created if their pods could never be scheduled
+	c.unschedulableMessage, err = c.checkPlacementMatchesNodes()
+	if err != nil {
+		logger.Warningf("failed to check if the placement of the mgr matches any node. %+v", err)
+	}
+	c.placementChecked = err == nil
+	if c.unschedulableMessage != "" {
+		return fmt.Errorf("%s", c.unschedulableMessage)
+	}
+
+	if err := c.validateMonAntiAffinity(); err != nil {
+		return err
+	}
+	c.requireMonAntiAffinity = false
+	if c.MgrSpec.MonAntiAffinity == cephv1.MonAntiAffinityRequired {
+		nodes, err := c.countNodesWithoutMons()
+		if err != nil {
+			return fmt.Errorf("failed to count the nodes without mons for the mgrs. %+v", err)
+		}
+		c.requireMonAntiAffinity = checkMonAntiAffinity(c.Replicas, nodes)
+	}
+
 	logger.Infof("start running mgr")
 
-	for i := 0; i < c.Replicas; i++ {
-		if i >= 2 {
-			logger.Errorf("cannot have more than 2 mgrs")
-			break
+	c.ambiguousActiveMgrMessage = ""
+	daemonIDs, err := c.mgrDaemonIDs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the mgrs to start. %+v", err)
+	}
+	for _, daemonID := range daemonIDs {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("canceled the start of the mgrs. %+v", err)
 		}
 
-		daemonID := k8sutil.IndexToName(i)
-		resourceName := fmt.Sprintf("%s-%s", appName, daemonID)
+		resourceName := fmt.Sprintf("%s-%s", AppName, daemonID)
 		mgrConfig := &mgrConfig{
 			DaemonID:      daemonID,
 			ResourceName:  resourceName,
@@ -138,6 +219,28 @@ func (c *Cluster) Start() error {
 			DataPathMap:   config.NewStatelessDaemonDataPathMap(config.MgrType, daemonID, c.Namespace, c.dataDirHostPath),
 		}
 
+		if err := c.validateExtraVolumes(mgrConfig); err != nil {
+			return fmt.Errorf("invalid volumes for %s. %+v", resourceName, err)
+		}
+		if err := c.validateExtraEnv(); err != nil {
+			return fmt.Errorf("invalid env vars for %s. %+v", resourceName, err)
+		}
+		if err := c.validateSidecars(mgrConfig); err != nil {
+			return fmt.Errorf("invalid sidecars for %s. %+v", resourceName, err)
+		}
+		if err := c.validateModules(mgrConfig); err != nil {
+			return fmt.Errorf("invalid mgr modules for %s. %+v", resourceName, err)
+		}
+		if err := c.validateSecurityContext(); err != nil {
+			return fmt.Errorf("invalid security context for %s. %+v", resourceName, err)
+		}
+		if err := c.validateHostNamespaces(); err != nil {
+			return fmt.Errorf("invalid host namespaces for %s. %+v", resourceName, err)
+		}
+		if err := c.validateUpdateStrategy(); err != nil {
+			return fmt.Errorf("invalid update strategy for %s. %+v", resourceName, err)
+		}
+
 		// generate keyring specific to this mgr daemon saved to k8s secret
 		if err := c.generateKeyring(mgrConfig); err != nil {
 			return fmt.Errorf("failed to generate keyring for %s. %+v", resourceName, err)
@@ -173,7 +276,7 @@ func (c *Cluster) Start() error {
 				}
 			}
 
-			if err := updateDeploymentAndWait(c.context, d, c.Namespace, daemon, mgrConfig.DaemonID, cephVersionToUse, c.isUpgrade); err != nil {
+			if err := c.updateAndWaitForDeployment(d, mgrConfig.DaemonID, cephVersionToUse); err != nil {
 				return fmt.Errorf("failed to update mgr deployment %s. %+v", resourceName, err)
 			}
 		}
@@ -190,21 +293,36 @@ func (c *Cluster) Start() error {
 			logger.Errorf("failed to enable mgr dashboard. %+v", err)
 		}
 
+		if err := c.enableModules(); err != nil {
+			logger.Errorf("failed to enable mgr modules. %+v", err)
+		} else if err := c.applyModuleSettings(); err != nil {
+			logger.Errorf("failed to apply the settings of the mgr modules. %+v", err)
+		}
+
+	}
+
+	// remove the mgrs after the scale down
+	if err := c.removeExtraMgrs(ctx, daemonIDs); err != nil {
+		return fmt.Errorf("failed to remove the extra mgrs. %+v", err)
 	}
 
 	// create the metrics service
-	service := c.makeMetricsService(appName)
+	service := c.makeMetricsService(AppName)
 	if _, err := c.context.Clientset.CoreV1().Services(c.Namespace).Create(service); err != nil {
 		if !errors.IsAlreadyExists(err) {
 			return fmt.Errorf("failed to create mgr service. %+v", err)
 		}
-		logger.Infof("mgr metrics service already exists")
+		logger.Infof("mgr metrics service already exists. updating if needed")
+		// the ports of the mgr modules may have changed
+		if service, err = k8sutil.UpdateService(c.context.Clientset, c.Namespace, service); err != nil {
+			return fmt.Errorf("failed to update mgr service. %+v", err)
+		}
 	} else {
 		logger.Infof("mgr metrics service started")
 	}
 
 	// enable monitoring if `monitoring: enabled: true`
-	if c.monitoringSpec.Enabled {
+	if c.monitoringSpec.Enabled && c.prometheusCRDsAvailable() {
 		if c.clusterInfo.CephVersion.IsAtLeastNautilus() {
 			logger.Infof("starting monitoring deployment")
 			// servicemonitor takes some metadata from the service for easy mapping
@@ -219,7 +337,9 @@ func (c *Cluster) Start() error {
 			if namespace == "" {
 				namespace = c.Namespace
 			}
-			if err := c.deployPrometheusRule(prometheusRuleName, namespace); err != nil {
+			if c.SkipPrometheusRule {
+				logger.Warningf("not deploying the prometheus rule to namespace %s", namespace)
+			} else if err := c.deployPrometheusRule(prometheusRuleName, namespace); err != nil {
 				logger.Errorf("failed to deploy prometheus rule. %+v", err)
 			} else {
 				logger.Infof("prometheusRule deployed")
@@ -240,6 +360,31 @@ func (c *Cluster) enablePrometheusModule(clusterName string) error {
 	return nil
 }
 
+// prometheusCRDsAvailable checks whether the prometheus operator CRDs are installed. When they are missing
+// the warning is only logged once instead of failing to create the monitoring resources on every orchestration.
+func (c *Cluster) prometheusCRDsAvailable() bool {
+	available, err := k8sutil.PrometheusCRDsAvailable(c.context.Clientset)
+	if err != nil {
+		logger.Errorf("failed to detect the prometheus operator CRDs. %+v", err)
+		return false
+	}
+
+	prometheusCRDsMux.Lock()
+	defer prometheusCRDsMux.Unlock()
+	if !available {
+		if !prometheusCRDsMissingLogged[c.Namespace] {
+			logger.Warningf("monitoring is enabled on cluster %s but the ServiceMonitor and PrometheusRule CRDs are not installed. skipping the monitoring setup until the prometheus operator is installed", c.Namespace)
+			prometheusCRDsMissingLogged[c.Namespace] = true
+		}
+		return false
+	}
+	if prometheusCRDsMissingLogged[c.Namespace] {
+		logger.Infof("the prometheus operator CRDs are installed, setting up monitoring on cluster %s", c.Namespace)
+		delete(prometheusCRDsMissingLogged, c.Namespace)
+	}
+	return true
+}
+
 // add a servicemonitor that allows prometheus to scrape from the monitoring endpoint of the cluster
 func (c *Cluster) enableServiceMonitor(service *v1.Service) error {
 	name := service.GetName()
@@ -253,6 +398,7 @@ func (c *Cluster) enableServiceMonitor(service *v1.Service) error {
 	k8sutil.SetOwnerRef(&serviceMonitor.ObjectMeta, &c.ownerRef)
 	serviceMonitor.Spec.NamespaceSelector.MatchNames = []string{namespace}
 	serviceMonitor.Spec.Selector.MatchLabels = service.GetLabels()
+	serviceMonitor.SetLabels(mergeLabels(serviceMonitor.GetLabels(), c.monitoringSpec.ServiceMonitorLabels, c.monitoringLabels()))
 	if _, err := k8sutil.CreateOrUpdateServiceMonitor(serviceMonitor); err != nil {
 		return fmt.Errorf("service monitor could not be enabled. %+v", err)
 	}
@@ -269,12 +415,82 @@ func (c *Cluster) deployPrometheusRule(name, namespace string) error {
 	if err != nil {
 		return fmt.Errorf("prometheus rule could not be deployed. %+v", err)
 	}
+	// the rules namespace may be shared by several clusters, which each need their own rule
+	if namespace != c.Namespace {
+		name = fmt.Sprintf("%s-%s", c.Namespace, name)
+	}
 	prometheusRule.SetName(name)
 	prometheusRule.SetNamespace(namespace)
 	owners := append(prometheusRule.GetOwnerReferences(), c.ownerRef)
 	k8sutil.SetOwnerRefs(&prometheusRule.ObjectMeta, owners)
+	// the cluster label is used to find the rules of this cluster when other versions need to be removed
+	prometheusRule.SetLabels(mergeLabels(prometheusRule.GetLabels(), c.monitoringSpec.RuleLabels, c.monitoringLabels()))
 	if _, err := k8sutil.CreateOrUpdatePrometheusRule(prometheusRule); err != nil {
 		return fmt.Errorf("prometheus rule could not be deployed. %+v", err)
 	}
+
+	// the rules of other ceph versions would fire the same alerts again, for example after an upgrade
+	if err := c.removeStalePrometheusRules(name, namespace); err != nil {
+		logger.Warningf("failed to remove stale prometheus rules. %+v", err)
+	}
 	return nil
 }
+
+// removeStalePrometheusRules deletes the prometheusRules of the cluster that were deployed for other
+// ceph major versions than the current rule, or with a name that is not scoped to the cluster. The rules deployed
+// by older versions of the operator do not have the cluster label, so all the rules of the namespace are checked.
+func (c *Cluster) removeStalePrometheusRules(currentName, namespace string) error {
+	rules, err := listPrometheusRules(namespace, "")
+	if err != nil {
+		return err
+	}
+	for _, name := range c.stalePrometheusRules(rules, currentName) {
+		logger.Infof("removing stale prometheus rule %s", name)
+		if err := deletePrometheusRule(namespace, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stalePrometheusRules returns the names of the versioned rules owned by the cluster other than the current rule.
+// The rules of the other clusters in a shared rules namespace are owned by the other clusters.
+func (c *Cluster) stalePrometheusRules(rules []*monitoringv1.PrometheusRule, currentName string) []string {
+	stale := []string{}
+	for _, rule := range rules {
+		if rule.GetName() == currentName || !prometheusRuleNamePattern.MatchString(rule.GetName()) {
+			continue
+		}
+		owned := false
+		for _, owner := range rule.GetOwnerReferences() {
+			if owner.UID == c.ownerRef.UID {
+				owned = true
+				break
+			}
+		}
+		if owned {
+			stale = append(stale, rule.GetName())
+		}
+	}
+	return stale
+}
+
+// monitoringLabels returns the labels of the operator on the monitoring objects of the cluster
+func (c *Cluster) monitoringLabels() map[string]string {
+	return map[string]string{k8sutil.AppAttr: AppName, k8sutil.ClusterAttr: c.Namespace}
+}
+
+// mergeLabels returns the labels with the extra labels of the user added, overriding any existing key. The labels of
+// the operator are added last, so the user cannot override them.
+func mergeLabels(labels, extra, operator map[string]string) map[string]string {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	for k, v := range extra {
+		labels[k] = v
+	}
+	for k, v := range operator {
+		labels[k] = v
+	}
+	return labels
+}
@@ -17,15 +17,19 @@ limitations under the License.
 package mgr
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"testing"
 
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
 	testopk8s "github.com/rook/rook/pkg/operator/k8sutil/test"
 	testop "github.com/rook/rook/pkg/operator/test"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
@@ -34,9 +38,11 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 func TestStartMGR(t *testing.T) {
+	ctx := context.Background()
 	var deploymentsUpdated *[]*apps.Deployment
 	updateDeploymentAndWait, deploymentsUpdated = testopk8s.UpdateDeploymentAndWaitStub()
 
@@ -72,7 +78,7 @@ func TestStartMGR(t *testing.T) {
 	defer os.RemoveAll(c.dataDir)
 
 	// start a basic service
-	err := c.Start()
+	err := c.Start(ctx)
 	assert.Nil(t, err)
 	validateStart(t, c)
 	assert.ElementsMatch(t, []string{}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
@@ -80,7 +86,7 @@ func TestStartMGR(t *testing.T) {
 
 	c.dashboard.UrlPrefix = "/test"
 	c.dashboard.Port = 12345
-	err = c.Start()
+	err = c.Start(ctx)
 	assert.Nil(t, err)
 	validateStart(t, c)
 	assert.ElementsMatch(t, []string{"rook-ceph-mgr-a"}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
@@ -89,7 +95,7 @@ func TestStartMGR(t *testing.T) {
 	// starting again with more replicas
 	c.Replicas = 3
 	c.dashboard.Enabled = false
-	err = c.Start()
+	err = c.Start(ctx)
 	assert.Nil(t, err)
 	validateStart(t, c)
 	assert.ElementsMatch(t, []string{"rook-ceph-mgr-a"}, testopk8s.DeploymentNamesUpdated(deploymentsUpdated))
@@ -125,3 +131,126 @@ func validateStart(t *testing.T, c *Cluster) {
 		assert.True(t, errors.IsNotFound(err))
 	}
 }
+
+func TestUpdateAndWaitForDeployment(t *testing.T) {
+	defer func() { updateDeploymentAndWait = mon.UpdateCephDeploymentAndWait }()
+	clientset := testop.New(1)
+	c := &Cluster{Namespace: "ns", context: &clusterd.Context{Clientset: clientset}}
+	labels := map[string]string{"app": AppName, "mgr": "a"}
+	d := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mgr-a", Namespace: "ns"},
+		Spec:       apps.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+	}
+
+	updateDeploymentAndWait = func(context *clusterd.Context, deployment *apps.Deployment, namespace, daemonType, daemonName string, cephVersion cephver.CephVersion, isUpgrade bool) error {
+		return nil
+	}
+	assert.Nil(t, c.updateAndWaitForDeployment(d, "a", cephver.Nautilus))
+
+	// the reason why the mgr is not ready is added to the error
+	updateDeploymentAndWait = func(context *clusterd.Context, deployment *apps.Deployment, namespace, daemonType, daemonName string, cephVersion cephver.CephVersion, isUpgrade bool) error {
+		return fmt.Errorf("gave up waiting for deployment %s to update", deployment.Name)
+	}
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mgr-a-123", Namespace: "ns", Labels: labels},
+		Status: v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{
+			{Name: "mgr", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ErrImagePull"}}},
+		}},
+	}
+	_, err := clientset.CoreV1().Pods("ns").Create(pod)
+	assert.Nil(t, err)
+	err = c.updateAndWaitForDeployment(d, "a", cephver.Nautilus)
+	assert.NotNil(t, err)
+	assert.Equal(t, "gave up waiting for deployment rook-ceph-mgr-a to update. pod rook-ceph-mgr-a-123: container mgr is waiting with ErrImagePull", err.Error())
+}
+
+func TestMergeLabels(t *testing.T) {
+	// no existing labels
+	labels := mergeLabels(nil, map[string]string{"release": "prometheus"}, nil)
+	assert.Equal(t, map[string]string{"release": "prometheus"}, labels)
+
+	// extra labels are added and override existing ones
+	labels = mergeLabels(map[string]string{"prometheus": "rook-prometheus", "role": "alert-rules"},
+		map[string]string{"role": "rules", "release": "prometheus"}, nil)
+	assert.Equal(t, map[string]string{"prometheus": "rook-prometheus", "role": "rules", "release": "prometheus"}, labels)
+
+	// no extra labels
+	labels = mergeLabels(map[string]string{"team": "rook"}, nil, nil)
+	assert.Equal(t, map[string]string{"team": "rook"}, labels)
+
+	// the labels of the operator cannot be overridden
+	c := &Cluster{Namespace: "ns"}
+	labels = mergeLabels(map[string]string{"team": "rook"}, map[string]string{"app": "other", "rook_cluster": "other", "release": "prometheus"}, c.monitoringLabels())
+	assert.Equal(t, map[string]string{"team": "rook", "app": "rook-ceph-mgr", "rook_cluster": "ns", "release": "prometheus"}, labels)
+}
+
+func TestStalePrometheusRules(t *testing.T) {
+	c := &Cluster{Namespace: "ns", ownerRef: metav1.OwnerReference{UID: "cluster-uid"}}
+	rule := func(name string, ownerUID string) *monitoringv1.PrometheusRule {
+		return &monitoringv1.PrometheusRule{ObjectMeta: metav1.ObjectMeta{
+			Name:            name,
+			OwnerReferences: []metav1.OwnerReference{{UID: types.UID(ownerUID)}},
+		}}
+	}
+	rules := []*monitoringv1.PrometheusRule{
+		rule("prometheus-ceph-v14-rules", "cluster-uid"),
+		rule("prometheus-ceph-v15-rules", "cluster-uid"),
+		// rules of another cluster sharing the rules namespace
+		rule("prometheus-ceph-v14-rules-other", "cluster-uid"),
+		rule("prometheus-ceph-v13-rules", "other-uid"),
+		// unrelated rules
+		rule("my-rules", "cluster-uid"),
+	}
+
+	// after the upgrade from v14 to v15 only the v14 rules of the cluster are stale
+	stale := c.stalePrometheusRules(rules, "prometheus-ceph-v15-rules")
+	assert.Equal(t, []string{"prometheus-ceph-v14-rules"}, stale)
+
+	// nothing is stale when only the current rule exists
+	stale = c.stalePrometheusRules(rules[1:2], "prometheus-ceph-v15-rules")
+	assert.Equal(t, 0, len(stale))
+
+	// the rules deployed before the names were scoped to the cluster are stale in a shared rules namespace
+	rules = append(rules, rule("ns-prometheus-ceph-v15-rules", "cluster-uid"), rule("ns2-prometheus-ceph-v15-rules", "other-uid"))
+	stale = c.stalePrometheusRules(rules, "ns-prometheus-ceph-v15-rules")
+	assert.Equal(t, []string{"prometheus-ceph-v14-rules", "prometheus-ceph-v15-rules"}, stale)
+}
+
+func TestRemoveStalePrometheusRules(t *testing.T) {
+	defer func(list func(string, string) ([]*monitoringv1.PrometheusRule, error)) { listPrometheusRules = list }(listPrometheusRules)
+	defer func(del func(string, string) error) { deletePrometheusRule = del }(deletePrometheusRule)
+
+	c := &Cluster{Namespace: "ns", ownerRef: metav1.OwnerReference{UID: "cluster-uid"}}
+	owners := []metav1.OwnerReference{{UID: "cluster-uid"}}
+	listed := ""
+	listPrometheusRules = func(namespace, labelSelector string) ([]*monitoringv1.PrometheusRule, error) {
+		listed = namespace
+		// the rules deployed by older versions of the operator have no cluster label
+		assert.Equal(t, "", labelSelector)
+		return []*monitoringv1.PrometheusRule{
+			{ObjectMeta: metav1.ObjectMeta{Name: "prometheus-ceph-v14-rules", OwnerReferences: owners}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "ns-prometheus-ceph-v15-rules", OwnerReferences: owners}},
+		}, nil
+	}
+	deleted := []string{}
+	deletePrometheusRule = func(namespace, name string) error {
+		deleted = append(deleted, namespace+"/"+name)
+		return nil
+	}
+
+	assert.Nil(t, c.removeStalePrometheusRules("ns-prometheus-ceph-v15-rules", "monitoring"))
+	assert.Equal(t, "monitoring", listed)
+	assert.Equal(t, []string{"monitoring/prometheus-ceph-v14-rules"}, deleted)
+
+	// the deletion stops at the first error
+	deletePrometheusRule = func(namespace, name string) error {
+		return fmt.Errorf("mock failure")
+	}
+	assert.NotNil(t, c.removeStalePrometheusRules("ns-prometheus-ceph-v15-rules", "monitoring"))
+
+	// no rule is deleted when the rules cannot be listed
+	listPrometheusRules = func(namespace, labelSelector string) ([]*monitoringv1.PrometheusRule, error) {
+		return nil, fmt.Errorf("mock failure")
+	}
+	assert.NotNil(t, c.removeStalePrometheusRules("ns-prometheus-ceph-v15-rules", "monitoring"))
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// the port of the mgr daemon
+	mgrDaemonPort = 6800
+)
+
+// the ports the mgr modules listen on by default
+var defaultModulePorts = map[string]int{
+	"restful": 8003,
+}
+
+// the settings of the dashboard module that are configured from the dashboard spec, so they cannot be overridden
+// by the settings of the module
+var dashboardSpecSettings = map[string]string{
+	"ssl":         "dashboard.ssl",
+	"url_prefix":  "dashboard.urlPrefix",
+	"server_addr": "dashboard.bindAddress",
+}
+
+// the minimum resource limits recommended for the mgr when the resource-heavy modules are enabled. The modules run
+// in the mgr process, so their resources can only be given to the whole mgr.
+var moduleRecommendedLimits = map[string]v1.ResourceList{
+	"balancer": {
+		v1.ResourceCPU:    resource.MustParse("500m"),
+		v1.ResourceMemory: resource.MustParse("1Gi"),
+	},
+	"pg_autoscaler": {
+		v1.ResourceCPU:    resource.MustParse("500m"),
+		v1.ResourceMemory: resource.MustParse("1Gi"),
+	},
+}
+
+// modulePort returns the port of the module, or 0 if the module has no port to expose
+func modulePort(module cephv1.MgrModuleSpec) int {
+	if module.Port != 0 {
+		return module.Port
+	}
+	return defaultModulePorts[module.Name]
+}
+
+// moduleContainerPorts returns the container ports of the modules from the mgr spec
+func (c *Cluster) moduleContainerPorts() []v1.ContainerPort {
+	ports := []v1.ContainerPort{}
+	for _, module := range c.MgrSpec.Modules {
+		if value, ok := module.Settings["server_port"]; ok && module.Port != 0 && value != strconv.Itoa(module.Port) {
+			return fmt.Errorf("setting server_port %q of mgr module %s conflicts with its port %d", value, module.Name, module.Port)
+		}
+		port := modulePort(module)
+		if port == 0 {
+			continue
+		}
+		ports = append(ports, v1.ContainerPort{
+			Name:          module.Name,
+			ContainerPort: int32(port),
+			Protocol:      v1.ProtocolTCP,
+		})
+	}
+	return ports
+}
+
+// moduleServicePorts returns the service ports of the modules from the mgr spec
+func (c *Cluster) moduleServicePorts() []v1.ServicePort {
+	ports := []v1.ServicePort{}
+	for _, module := range c.MgrSpec.Modules {
+		port := modulePort(module)
+		if port == 0 {
+			continue
+		}
+		ports = append(ports, v1.ServicePort{
+			Name:     module.Name,
+			Port:     int32(port),
+			Protocol: v1.ProtocolTCP,
+		})
+	}
+	return ports
+}
+
+// enableModules enables the modules from the mgr spec
+func (c *Cluster) enableModules() error {
+	for _, module := range c.MgrSpec.Modules {
+		// the dashboard is enabled with the dashboard spec, the module only carries its settings
+		if module.Name == dashboardModuleName {
+			continue
+		}
+		if err := client.MgrEnableModule(c.context, c.Namespace, module.Name, false); err != nil {
+			return fmt.Errorf("failed to enable mgr module %s. %+v", module.Name, err)
+		}
+		logger.Infof("mgr module %s enabled", module.Name)
+	}
+	return nil
+}
+
+// validateModules checks that the modules from the mgr spec are not configured by Rook and that their ports
+// do not collide with each other or with the ports of the mgr. The dashboard module may only set the settings that
+// are not configured from the dashboard spec.
+func (c *Cluster) validateModules(mgrConfig *mgrConfig) error {
+	usedPorts := map[int]string{
+		mgrDaemonPort:           "mgr",
+		metricsPort:             prometheusModuleName,
+		mgrConfig.DashboardPort: dashboardModuleName,
+	}
+	for _, module := range c.MgrSpec.Modules {
+		if module.Name == "" {
+			return fmt.Errorf("the name of a mgr module is empty")
+		}
+		if module.Name == prometheusModuleName {
+			return fmt.Errorf("mgr module %s is configured by rook", module.Name)
+		}
+		for key := range module.Settings {
+			if key == "" || strings.ContainsAny(key, " /") {
+				return fmt.Errorf("invalid setting %q of mgr module %s", key, module.Name)
+			}
+		}
+		if module.Name == dashboardModuleName {
+			if err := c.validateDashboardSettings(module); err != nil {
+				return err
+			}
+			continue
+		}
+		port := modulePort(module)
+		if port == 0 {
+			continue
+		}
+		if port < 0 || port > 65535 {
+			return fmt.Errorf("invalid port %d for mgr module %s", port, module.Name)
+		}
+		if errs := validation.IsValidPortName(module.Name); len(errs) > 0 {
+			return fmt.Errorf("the port of mgr module %s cannot be exposed with the module name. %s", module.Name, strings.Join(errs, ", "))
+		}
+		if other, ok := usedPorts[port]; ok {
+			return fmt.Errorf("port %d of mgr module %s is already used by %s", port, module.Name, other)
+		}
+		usedPorts[port] = module.Name
+	}
+	return nil
+}
+
+// validateDashboardSettings checks that the settings of the dashboard module do not conflict with the dashboard spec
+func (c *Cluster) validateDashboardSettings(module cephv1.MgrModuleSpec) error {
+	if module.Port != 0 {
+		return fmt.Errorf("the port of mgr module %s is set with dashboard.port or the server_port and ssl_server_port settings", module.Name)
+	}
+	for key := range module.Settings {
+		if field, ok := dashboardSpecSettings[key]; ok {
+			return fmt.Errorf("setting %s of mgr module %s is configured with %s", key, module.Name, field)
+		}
+	}
+	for _, key := range []string{"server_port", "ssl_server_port"} {
+		value, ok := module.Settings[key]
+		if !ok {
+			continue
+		}
+		port, err := strconv.Atoi(value)
+		if err != nil || port <= 0 || port > 65535 {
+			return fmt.Errorf("invalid setting %s %q of mgr module %s", key, value, module.Name)
+		}
+		if c.dashboard.Port != 0 && port != c.dashboard.Port {
+			return fmt.Errorf("setting %s %d of mgr module %s conflicts with dashboard.port %d", key, port, module.Name, c.dashboard.Port)
+		}
+	}
+	return nil
+}
+
+// ModuleResourceWarnings returns a warning for each resource-heavy module from the mgr spec that is enabled while the
+// resource limit of the mgr is below the recommended minimum for the module. No warning is returned for the
+// resources without a limit.
+func (c *Cluster) ModuleResourceWarnings() []string {
+	warnings := []string{}
+	for _, module := range c.MgrSpec.Modules {
+		recommended, ok := moduleRecommendedLimits[module.Name]
+		if !ok {
+			continue
+		}
+		for _, name := range []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory} {
+			limit, ok := c.resources.Limits[name]
+			minimum, recommendedOK := recommended[name]
+			if !ok || limit.IsZero() || !recommendedOK || limit.Cmp(minimum) >= 0 {
+				continue
+			}
+			warnings = append(warnings, fmt.Sprintf("mgr module %s is enabled with a %s limit of %s, consider increasing it to at least %s",
+				module.Name, name, limit.String(), minimum.String()))
+		}
+	}
+	return warnings
+}
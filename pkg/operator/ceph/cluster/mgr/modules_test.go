@@ -0,0 +1,165 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package mgr
+
+import (
+	"fmt"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestModulePorts(t *testing.T) {
+	c := &Cluster{MgrSpec: cephv1.MgrSpec{Modules: []cephv1.MgrModuleSpec{
+		{Name: "restful"},
+		{Name: "telemetry"},
+		{Name: "mymodule", Port: 9000},
+	}}}
+
+	ports := c.moduleContainerPorts()
+	assert.Equal(t, 2, len(ports))
+	assert.Equal(t, "restful", ports[0].Name)
+	assert.Equal(t, int32(8003), ports[0].ContainerPort)
+	assert.Equal(t, "mymodule", ports[1].Name)
+	assert.Equal(t, int32(9000), ports[1].ContainerPort)
+
+	servicePorts := c.moduleServicePorts()
+	assert.Equal(t, 2, len(servicePorts))
+	assert.Equal(t, int32(8003), servicePorts[0].Port)
+	assert.Equal(t, int32(9000), servicePorts[1].Port)
+
+	// the default port can be overridden
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "restful", Port: 8004}}
+	ports = c.moduleContainerPorts()
+	assert.Equal(t, 1, len(ports))
+	assert.Equal(t, int32(8004), ports[0].ContainerPort)
+}
+
+func TestValidateModules(t *testing.T) {
+	m := &mgrConfig{DashboardPort: 8443}
+	c := &Cluster{}
+	assert.Nil(t, c.validateModules(m))
+
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "restful"}, {Name: "telemetry"}}
+	assert.Nil(t, c.validateModules(m))
+
+	// the settings of the modules
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "balancer", Settings: map[string]string{"mode": "upmap"}}}
+	assert.Nil(t, c.validateModules(m))
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "balancer", Settings: map[string]string{"mgr/balancer/mode": "upmap"}}}
+	assert.NotNil(t, c.validateModules(m))
+
+	// modules configured by rook
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "prometheus"}}
+	assert.NotNil(t, c.validateModules(m))
+
+	// the dashboard only sets the settings that are not configured from the dashboard spec
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "dashboard", Settings: map[string]string{"ssl_server_port": "9443", "standby_behaviour": "error"}}}
+	assert.Nil(t, c.validateModules(m))
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "dashboard", Settings: map[string]string{"url_prefix": "/ceph"}}}
+	assert.NotNil(t, c.validateModules(m))
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "dashboard", Port: 9443}}
+	assert.NotNil(t, c.validateModules(m))
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "dashboard", Settings: map[string]string{"server_port": "http"}}}
+	assert.NotNil(t, c.validateModules(m))
+	c.dashboard.Port = 8443
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "dashboard", Settings: map[string]string{"ssl_server_port": "9443"}}}
+	assert.NotNil(t, c.validateModules(m))
+	c.dashboard.Port = 0
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: ""}}
+	assert.NotNil(t, c.validateModules(m))
+
+	// colliding ports
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "restful", Port: 8443}}
+	assert.NotNil(t, c.validateModules(m))
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "restful"}, {Name: "mymodule", Port: 8003}}
+	assert.NotNil(t, c.validateModules(m))
+
+	// the server port setting must match the port of the module
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "mymodule", Port: 9000, Settings: map[string]string{"server_port": "9000"}}}
+	assert.Nil(t, c.validateModules(m))
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "mymodule", Port: 9000, Settings: map[string]string{"server_port": "9001"}}}
+	assert.NotNil(t, c.validateModules(m))
+
+	// invalid ports
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "restful", Port: 70000}}
+	assert.NotNil(t, c.validateModules(m))
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "a_module_with_a_long_name", Port: 9000}}
+	assert.NotNil(t, c.validateModules(m))
+}
+
+func TestDashboardSettingPort(t *testing.T) {
+	c := &Cluster{}
+	assert.Equal(t, dashboardPortHTTPS, c.dashboardPort())
+
+	// the port of the served protocol is taken from the settings
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "dashboard", Settings: map[string]string{"server_port": "8080", "ssl_server_port": "9443"}}}
+	assert.Equal(t, 9443, c.dashboardPort())
+	ssl := false
+	c.dashboard.SSL = &ssl
+	assert.Equal(t, 8080, c.dashboardPort())
+
+	// the port of the dashboard spec takes precedence
+	c.dashboard.Port = 7000
+	assert.Equal(t, 7000, c.dashboardPort())
+}
+
+func TestEnableModules(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	enabled := []string{}
+	executor.MockExecuteCommandWithOutputFile = func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+		if args[0] == "mgr" && args[1] == "module" && args[2] == "enable" {
+			enabled = append(enabled, args[3])
+			return "", nil
+		}
+		return "", fmt.Errorf("unexpected ceph command '%v'", args)
+	}
+	c := &Cluster{
+		context: &clusterd.Context{Executor: executor},
+		MgrSpec: cephv1.MgrSpec{Modules: []cephv1.MgrModuleSpec{{Name: "restful"}, {Name: "dashboard"}, {Name: "telemetry"}}},
+	}
+
+	// the dashboard is enabled with the dashboard spec
+	assert.Nil(t, c.enableModules())
+	assert.Equal(t, []string{"restful", "telemetry"}, enabled)
+}
+
+func TestModuleResourceWarnings(t *testing.T) {
+	c := &Cluster{MgrSpec: cephv1.MgrSpec{Modules: []cephv1.MgrModuleSpec{{Name: "pg_autoscaler"}, {Name: "telemetry"}}}}
+
+	// no limits
+	assert.Equal(t, 0, len(c.ModuleResourceWarnings()))
+
+	// limits above the recommendation
+	c.resources.Limits = v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("2Gi")}
+	assert.Equal(t, 0, len(c.ModuleResourceWarnings()))
+
+	// the memory limit is below the recommendation
+	c.resources.Limits = v1.ResourceList{v1.ResourceCPU: resource.MustParse("1"), v1.ResourceMemory: resource.MustParse("512Mi")}
+	warnings := c.ModuleResourceWarnings()
+	assert.Equal(t, 1, len(warnings))
+	assert.Equal(t, "mgr module pg_autoscaler is enabled with a memory limit of 512Mi, consider increasing it to at least 1Gi", warnings[0])
+
+	// both limits are below the recommendation for each heavy module
+	c.MgrSpec.Modules = append(c.MgrSpec.Modules, cephv1.MgrModuleSpec{Name: "balancer"})
+	c.resources.Limits = v1.ResourceList{v1.ResourceCPU: resource.MustParse("100m"), v1.ResourceMemory: resource.MustParse("512Mi")}
+	assert.Equal(t, 4, len(c.ModuleResourceWarnings()))
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/rook/rook/pkg/operator/ceph/config"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// the configmap with the module settings that were applied by the operator, so the settings removed from the
+	// spec can be removed from the mgr
+	moduleSettingsConfigMapName = "rook-ceph-mgr-module-settings"
+	moduleSettingsKey           = "settings"
+)
+
+// moduleSettings returns the settings of the modules from the mgr spec by the name of their option in the mon
+// configuration database, e.g. mgr/balancer/mode. The port of a module is set with its server_port, so the module
+// listens on the port exposed by the pod and the service.
+func (c *Cluster) moduleSettings() map[string]string {
+	settings := map[string]string{}
+	for _, module := range c.MgrSpec.Modules {
+		if module.Port != 0 {
+			settings[fmt.Sprintf("mgr/%s/server_port", module.Name)] = strconv.Itoa(module.Port)
+		}
+		for key, value := range module.Settings {
+			settings[fmt.Sprintf("mgr/%s/%s", module.Name, key)] = value
+		}
+	}
+	return settings
+}
+
+// applyModuleSettings sets the settings of the modules from the mgr spec and removes the settings that were applied
+// by an earlier orchestration but are no longer in the spec. The settings that were not applied by the operator are
+// left alone.
+func (c *Cluster) applyModuleSettings() error {
+	applied, err := c.appliedModuleSettings()
+	if err != nil {
+		return err
+	}
+	settings := c.moduleSettings()
+	monStore := config.GetMonStore(c.context, c.Namespace)
+	for _, option := range sortedKeys(settings) {
+		if err := monStore.Set("mgr", option, settings[option]); err != nil {
+			return fmt.Errorf("failed to set mgr module setting %s. %+v", option, err)
+		}
+	}
+	for _, option := range applied {
+		if _, ok := settings[option]; ok {
+			continue
+		}
+		logger.Infof("removing mgr module setting %s since it was removed from the spec", option)
+		if err := monStore.Delete("mgr", option); err != nil {
+			return fmt.Errorf("failed to remove mgr module setting %s. %+v", option, err)
+		}
+	}
+	return c.saveAppliedModuleSettings(sortedKeys(settings))
+}
+
+// appliedModuleSettings returns the options of the module settings that were applied by the operator
+func (c *Cluster) appliedModuleSettings() ([]string, error) {
+	cm, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Get(moduleSettingsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return []string{}, nil
+		}
+		return nil, fmt.Errorf("failed to get configmap %s. %+v", moduleSettingsConfigMapName, err)
+	}
+	applied := []string{}
+	if err := json.Unmarshal([]byte(cm.Data[moduleSettingsKey]), &applied); err != nil {
+		logger.Warningf("invalid mgr module settings in configmap %s, the removed settings are not cleaned up. %+v", moduleSettingsConfigMapName, err)
+		return []string{}, nil
+	}
+	return applied, nil
+}
+
+// saveAppliedModuleSettings stores the options of the module settings that were applied by the operator
+func (c *Cluster) saveAppliedModuleSettings(options []string) error {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the mgr module settings. %+v", err)
+	}
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      moduleSettingsConfigMapName,
+			Namespace: c.Namespace,
+		},
+		Data: map[string]string{moduleSettingsKey: string(data)},
+	}
+	k8sutil.SetOwnerRef(&cm.ObjectMeta, &c.ownerRef)
+	if _, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Create(cm); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create configmap %s. %+v", moduleSettingsConfigMapName, err)
+		}
+		if _, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Update(cm); err != nil {
+			return fmt.Errorf("failed to update configmap %s. %+v", moduleSettingsConfigMapName, err)
+		}
+	}
+	return nil
+}
+
+func sortedKeys(settings map[string]string) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,76 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"strings"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyModuleSettings(t *testing.T) {
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			if args[0] == "config" {
+				commands = append(commands, strings.Join(args[:4], " "))
+			}
+			return "", nil
+		},
+	}
+	clientset := testop.New(1)
+	c := &Cluster{Namespace: "ns", context: &clusterd.Context{Clientset: clientset, Executor: executor}}
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{
+		{Name: "balancer", Settings: map[string]string{"mode": "upmap", "sleep_interval": "120"}},
+		{Name: "restful"},
+	}
+
+	// the settings are applied
+	assert.Nil(t, c.applyModuleSettings())
+	assert.Equal(t, []string{"config set mgr mgr/balancer/mode", "config set mgr mgr/balancer/sleep_interval"}, commands)
+	cm, err := clientset.CoreV1().ConfigMaps("ns").Get(moduleSettingsConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, `["mgr/balancer/mode","mgr/balancer/sleep_interval"]`, cm.Data[moduleSettingsKey])
+
+	// the setting removed from the spec is removed from the mgr
+	commands = []string{}
+	c.MgrSpec.Modules[0].Settings = map[string]string{"mode": "crush-compat"}
+	assert.Nil(t, c.applyModuleSettings())
+	assert.Equal(t, []string{"config set mgr mgr/balancer/mode", "config rm mgr mgr/balancer/sleep_interval"}, commands)
+	cm, err = clientset.CoreV1().ConfigMaps("ns").Get(moduleSettingsConfigMapName, metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, `["mgr/balancer/mode"]`, cm.Data[moduleSettingsKey])
+
+	// the settings are removed with the module, afterwards there is nothing left to remove
+	commands = []string{}
+	c.MgrSpec.Modules = nil
+	assert.Nil(t, c.applyModuleSettings())
+	assert.Equal(t, []string{"config rm mgr mgr/balancer/mode"}, commands)
+	commands = []string{}
+	assert.Nil(t, c.applyModuleSettings())
+	assert.Equal(t, []string{}, commands)
+
+	// the port of a module is set as its server port
+	c.MgrSpec.Modules = []cephv1.MgrModuleSpec{{Name: "mymodule", Port: 9000}}
+	assert.Equal(t, map[string]string{"mgr/mymodule/server_port": "9000"}, c.moduleSettings())
+}
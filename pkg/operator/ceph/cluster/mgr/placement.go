@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// checkPlacementMatchesNodes returns a message if the node affinity and tolerations of the mgr placement do not match
+// any node, e.g. because of a typo in a node label. No message is returned if there are no nodes to check against.
+func (c *Cluster) checkPlacementMatchesNodes() (string, error) {
+	nodes, err := c.context.Clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list nodes. %+v", err)
+	}
+	if len(nodes.Items) == 0 {
+		return "", nil
+	}
+	for _, node := range nodes.Items {
+		matches, err := k8sutil.NodeMeetsPlacementTerms(node, c.placement, false)
+		if err != nil {
+			logger.Warningf("failed to check if node %s matches the placement of the mgr. %+v", node.Name, err)
+			continue
+		}
+		if matches {
+			return "", nil
+		}
+	}
+	return fmt.Sprintf("the placement of the mgr does not match any of the %d nodes, so the mgr pods could not be scheduled. check the node affinity and tolerations of the mgr placement", len(nodes.Items)), nil
+}
+
+// UnschedulableMessage returns the reason why the placement of the mgr does not match any node, or an empty string
+// if the mgr can be scheduled
+func (c *Cluster) UnschedulableMessage() string {
+	return c.unschedulableMessage
+}
+
+// PlacementChecked returns whether the last start of the mgrs checked the placement against the nodes. The
+// placement is not checked if the mgrs failed to start before, or if the nodes could not be listed.
+func (c *Cluster) PlacementChecked() bool {
+	return c.placementChecked
+}
@@ -0,0 +1,77 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"context"
+	"testing"
+
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckPlacementMatchesNodes(t *testing.T) {
+	clientset := testop.New(3)
+	c := &Cluster{Namespace: "ns", context: &clusterd.Context{Clientset: clientset}}
+
+	// any node matches without a placement
+	message, err := c.checkPlacementMatchesNodes()
+	assert.Nil(t, err)
+	assert.Equal(t, "", message)
+
+	// one node has the label of the affinity
+	node, err := clientset.CoreV1().Nodes().Get("node1", metav1.GetOptions{})
+	assert.Nil(t, err)
+	node.Labels = map[string]string{"role": "storage-mgr"}
+	_, err = clientset.CoreV1().Nodes().Update(node)
+	assert.Nil(t, err)
+	affinity := func(value string) *v1.NodeAffinity {
+		return &v1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{
+				NodeSelectorTerms: []v1.NodeSelectorTerm{{
+					MatchExpressions: []v1.NodeSelectorRequirement{{Key: "role", Operator: v1.NodeSelectorOpIn, Values: []string{value}}},
+				}},
+			},
+		}
+	}
+	c.placement = rookalpha.Placement{NodeAffinity: affinity("storage-mgr")}
+	message, err = c.checkPlacementMatchesNodes()
+	assert.Nil(t, err)
+	assert.Equal(t, "", message)
+
+	// a typo in the label matches no node, so the deployments are not created
+	c.placement = rookalpha.Placement{NodeAffinity: affinity("storage-mrg")}
+	message, err = c.checkPlacementMatchesNodes()
+	assert.Nil(t, err)
+	assert.Contains(t, message, "does not match any of the 3 nodes")
+	assert.NotNil(t, c.Start(context.Background()))
+	assert.Equal(t, message, c.UnschedulableMessage())
+	assert.True(t, c.PlacementChecked())
+	deployments, err := clientset.AppsV1().Deployments("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deployments.Items))
+
+	// the placement cannot be checked without nodes
+	c.context.Clientset = testop.New(0)
+	message, err = c.checkPlacementMatchesNodes()
+	assert.Nil(t, err)
+	assert.Equal(t, "", message)
+}
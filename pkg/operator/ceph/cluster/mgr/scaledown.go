@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the maximum number of mgrs, one active and one standby
+const maxMgrReplicas = 2
+
+// mgrDaemonIDs returns the IDs of the mgrs to start. When the mgrs are scaled down, the active mgr is kept so that
+// only standby mgrs are removed.
+func (c *Cluster) mgrDaemonIDs(ctx context.Context) ([]string, error) {
+	replicas := c.Replicas
+	if replicas > maxMgrReplicas {
+		logger.Errorf("cannot have more than %d mgrs", maxMgrReplicas)
+		replicas = maxMgrReplicas
+	}
+	daemonIDs := []string{}
+	for i := 0; i < replicas; i++ {
+		daemonIDs = append(daemonIDs, k8sutil.IndexToName(i))
+	}
+
+	if replicas == 0 {
+		return daemonIDs, nil
+	}
+	extra, err := c.extraMgrs(daemonIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(extra) == 0 {
+		return daemonIDs, nil
+	}
+	active, err := c.activeMgr(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range extra {
+		if id == active {
+			logger.Infof("keeping the active mgr %s while scaling down to %d mgrs", active, replicas)
+			return append([]string{active}, daemonIDs[:replicas-1]...), nil
+		}
+	}
+	return daemonIDs, nil
+}
+
+// removeExtraMgrs removes the deployments of the mgrs that are not kept, the standby mgrs first. If an extra mgr is
+// the active mgr, it is failed over to a kept mgr before it is removed.
+func (c *Cluster) removeExtraMgrs(ctx context.Context, keep []string) error {
+	if len(keep) == 0 {
+		// there is no mgr to fail over to
+		return nil
+	}
+	extra, err := c.extraMgrs(keep)
+	if err != nil {
+		return err
+	}
+	if len(extra) == 0 {
+		return nil
+	}
+	active, err := c.activeMgr(ctx)
+	if err != nil {
+		return err
+	}
+	if c.ambiguousActiveMgrMessage != "" {
+		// an extra mgr might be the active mgr, so it is not removed without a failover
+		logger.Warningf("not removing the extra mgrs %v until the active mgr is known", extra)
+		return nil
+	}
+	sort.SliceStable(extra, func(i, j int) bool {
+		return extra[i] != active && extra[j] == active
+	})
+	for _, id := range extra {
+		if id == active {
+			logger.Infof("failing over the active mgr %s before removing it", id)
+			if err := client.MgrFail(c.context, c.Namespace, id); err != nil {
+				return err
+			}
+		}
+		name := fmt.Sprintf("%s-%s", AppName, id)
+		logger.Infof("removing mgr %s after the scale down to %d mgrs", name, len(keep))
+		if err := k8sutil.DeleteDeployment(c.context.Clientset, c.Namespace, name); err != nil {
+			return fmt.Errorf("failed to remove mgr %s. %+v", name, err)
+		}
+	}
+	return nil
+}
+
+// extraMgrs returns the IDs of the mgr deployments that are not kept, in the order of the IDs
+func (c *Cluster) extraMgrs(keep []string) ([]string, error) {
+	deployments, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).List(metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, AppName)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the mgr deployments. %+v", err)
+	}
+	kept := map[string]bool{}
+	for _, id := range keep {
+		kept[id] = true
+	}
+	extra := []string{}
+	for _, d := range deployments.Items {
+		id, ok := d.Labels["mgr"]
+		if !ok {
+			id = strings.TrimPrefix(d.Name, AppName+"-")
+		}
+		if !kept[id] {
+			extra = append(extra, id)
+		}
+	}
+	sort.Strings(extra)
+	return extra, nil
+}
@@ -0,0 +1,162 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func createMgrDeployment(t *testing.T, context *clusterd.Context, id string) {
+	d := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%s", AppName, id),
+			Namespace: "ns",
+			Labels:    map[string]string{"app": AppName, "mgr": id},
+		},
+	}
+	_, err := context.Clientset.AppsV1().Deployments("ns").Create(d)
+	assert.Nil(t, err)
+}
+
+func mgrDeploymentExists(context *clusterd.Context, id string) bool {
+	_, err := context.Clientset.AppsV1().Deployments("ns").Get(fmt.Sprintf("%s-%s", AppName, id), metav1.GetOptions{})
+	return err == nil
+}
+
+func TestScaleDownMgrs(t *testing.T) {
+	defer func(interval time.Duration) { activeMgrQueryInterval = interval }(activeMgrQueryInterval)
+	activeMgrQueryInterval = 0
+	ctx := context.TODO()
+	active := "b"
+	statusCalls := 0
+	failed := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "status" {
+				statusCalls++
+				return fmt.Sprintf(`{"mgrmap":{"active_name":"%s","available":true}}`, active), nil
+			}
+			if args[0] == "mgr" && args[1] == "fail" {
+				failed = append(failed, args[2])
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor, Clientset: testop.New(1)}
+	c := &Cluster{Namespace: "ns", Replicas: 2, context: context}
+	createMgrDeployment(t, context, "a")
+	createMgrDeployment(t, context, "b")
+
+	// the active mgr is not looked up without a scale down
+	ids, err := c.mgrDaemonIDs(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, ids)
+	assert.Nil(t, c.removeExtraMgrs(ctx, ids))
+	assert.Equal(t, 0, statusCalls)
+
+	// rook-ceph-mgr-b is active, so the standby rook-ceph-mgr-a is removed
+	c.Replicas = 1
+	ids, err = c.mgrDaemonIDs(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"b"}, ids)
+	assert.Nil(t, c.removeExtraMgrs(ctx, ids))
+	assert.False(t, mgrDeploymentExists(context, "a"))
+	assert.True(t, mgrDeploymentExists(context, "b"))
+	assert.Equal(t, 0, len(failed))
+
+	// the standby is kept when the active mgr is unknown
+	createMgrDeployment(t, context, "a")
+	active = ""
+	ids, err = c.mgrDaemonIDs(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a"}, ids)
+
+	// the extra mgr became active in the meantime, so it is failed over before it is removed
+	active = "b"
+	assert.Nil(t, c.removeExtraMgrs(ctx, []string{"a"}))
+	assert.Equal(t, []string{"b"}, failed)
+	assert.True(t, mgrDeploymentExists(context, "a"))
+	assert.False(t, mgrDeploymentExists(context, "b"))
+}
+
+func TestAmbiguousActiveMgr(t *testing.T) {
+	defer func(interval time.Duration) { activeMgrQueryInterval = interval }(activeMgrQueryInterval)
+	activeMgrQueryInterval = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mgrMaps := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "status" {
+				mgrMap := mgrMaps[0]
+				if len(mgrMaps) > 1 {
+					mgrMaps = mgrMaps[1:]
+				}
+				return fmt.Sprintf(`{"mgrmap":%s}`, mgrMap), nil
+			}
+			if args[0] == "mgr" && args[1] == "fail" {
+				assert.Fail(t, "the mgr must not be failed over")
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor, Clientset: testop.New(1)}
+	c := &Cluster{Namespace: "ns", Replicas: 1, context: context}
+	createMgrDeployment(t, context, "a")
+	createMgrDeployment(t, context, "b")
+
+	// the same mgr is active in all the queries
+	mgrMaps = []string{`{"active_name":"b","standbys":[{"name":"a"}]}`}
+	active, err := c.activeMgr(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "b", active)
+	assert.Equal(t, "", c.AmbiguousActiveMgrMessage())
+
+	// the active mgr changed between the queries, so the extra mgr is not removed
+	mgrMaps = []string{`{"active_name":"a"}`, `{"active_name":"b"}`}
+	active, err = c.activeMgr(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "", active)
+	assert.Contains(t, c.AmbiguousActiveMgrMessage(), `changed from "a" to "b"`)
+	assert.Nil(t, c.removeExtraMgrs(ctx, []string{"a"}))
+	assert.True(t, mgrDeploymentExists(context, "b"))
+
+	// a mgr is both active and standby
+	c.ambiguousActiveMgrMessage = ""
+	mgrMaps = []string{`{"active_name":"b","standbys":[{"name":"b"}]}`}
+	active, err = c.activeMgr(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "", active)
+	assert.Contains(t, c.AmbiguousActiveMgrMessage(), "mgr b is reported both active and standby")
+
+	// the queries stop when the orchestration is canceled
+	activeMgrQueryInterval = time.Hour
+	cancel()
+	mgrMaps = []string{`{"active_name":"b","standbys":[{"name":"a"}]}`}
+	_, err = c.activeMgr(ctx)
+	assert.NotNil(t, err)
+}
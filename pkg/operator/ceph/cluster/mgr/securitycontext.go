@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mgr
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	v1 "k8s.io/api/core/v1"
+)
+
+// the capabilities the mgr needs to switch from root to the ceph user
+var setUserCapabilities = []v1.Capability{"SETUID", "SETGID"}
+
+// containerSecurityContext returns the security context of the mgr containers, which is the security context from
+// the mgr spec merged over the security context required by Rook
+func (c *Cluster) containerSecurityContext() *v1.SecurityContext {
+	return mergeSecurityContext(mon.PodSecurityContext(), c.MgrSpec.SecurityContext)
+}
+
+// mergeSecurityContext overrides the fields of the security context that are set in the override
+func mergeSecurityContext(base, override *v1.SecurityContext) *v1.SecurityContext {
+	merged := base.DeepCopy()
+	if override == nil {
+		return merged
+	}
+	if merged == nil {
+		merged = &v1.SecurityContext{}
+	}
+	override = override.DeepCopy()
+	if override.Capabilities != nil {
+		merged.Capabilities = override.Capabilities
+	}
+	if override.Privileged != nil {
+		merged.Privileged = override.Privileged
+	}
+	if override.SELinuxOptions != nil {
+		merged.SELinuxOptions = override.SELinuxOptions
+	}
+	if override.RunAsUser != nil {
+		merged.RunAsUser = override.RunAsUser
+	}
+	if override.RunAsGroup != nil {
+		merged.RunAsGroup = override.RunAsGroup
+	}
+	if override.RunAsNonRoot != nil {
+		merged.RunAsNonRoot = override.RunAsNonRoot
+	}
+	if override.ReadOnlyRootFilesystem != nil {
+		merged.ReadOnlyRootFilesystem = override.ReadOnlyRootFilesystem
+	}
+	if override.AllowPrivilegeEscalation != nil {
+		merged.AllowPrivilegeEscalation = override.AllowPrivilegeEscalation
+	}
+	if override.ProcMount != nil {
+		merged.ProcMount = override.ProcMount
+	}
+	return merged
+}
+
+// validateSecurityContext checks that the security contexts from the mgr spec do not remove the privileges the mgr
+// needs to start
+func (c *Cluster) validateSecurityContext() error {
+	required := mon.PodSecurityContext()
+	sc := c.containerSecurityContext()
+	if required.Privileged != nil && *required.Privileged && (sc.Privileged == nil || !*sc.Privileged) {
+		return fmt.Errorf("the mgr containers must be privileged since ROOK_HOSTPATH_REQUIRES_PRIVILEGED is set")
+	}
+
+	runAsUser, runAsNonRoot := c.runAsSettings(sc)
+	if runAsUser != nil && *runAsUser != 0 {
+		return nil
+	}
+	// the ceph image runs as root and the mgr switches to the ceph user after it started
+	if runAsNonRoot != nil && *runAsNonRoot {
+		return fmt.Errorf("runAsNonRoot requires a runAsUser other than root since the ceph image runs as root")
+	}
+	if sc.Capabilities != nil {
+		for _, capability := range setUserCapabilities {
+			if capabilityDropped(sc.Capabilities, capability) {
+				return fmt.Errorf("the mgr needs the %s capability to switch from root to the ceph user. add it to the capabilities or set runAsUser", capability)
+			}
+		}
+	}
+	return nil
+}
+
+// runAsSettings returns the user and the runAsNonRoot setting of the mgr containers. The container settings take
+// precedence over the pod settings.
+func (c *Cluster) runAsSettings(sc *v1.SecurityContext) (*int64, *bool) {
+	runAsUser := sc.RunAsUser
+	runAsNonRoot := sc.RunAsNonRoot
+	if pod := c.MgrSpec.PodSecurityContext; pod != nil {
+		if runAsUser == nil {
+			runAsUser = pod.RunAsUser
+		}
+		if runAsNonRoot == nil {
+			runAsNonRoot = pod.RunAsNonRoot
+		}
+	}
+	return runAsUser, runAsNonRoot
+}
+
+// validateHostNamespaces checks that the host namespaces from the mgr spec are compatible with the security context of
+// the mgr containers. The processes of the mgr pod can see and signal the processes of the host with hostPID, which
+// defeats a security context that restricts the mgr to a non-root user or drops capabilities. Otherwise sharing the
+// host namespaces is allowed with a warning.
+func (c *Cluster) validateHostNamespaces() error {
+	if !c.MgrSpec.HostPID && !c.MgrSpec.HostIPC {
+		return nil
+	}
+	sc := c.containerSecurityContext()
+	if _, runAsNonRoot := c.runAsSettings(sc); runAsNonRoot != nil && *runAsNonRoot {
+		return fmt.Errorf("hostPID and hostIPC cannot be set on the mgr when runAsNonRoot restricts its security context")
+	}
+	if sc.Capabilities != nil && len(sc.Capabilities.Drop) > 0 {
+		return fmt.Errorf("hostPID and hostIPC cannot be set on the mgr when its security context drops the capabilities %v", sc.Capabilities.Drop)
+	}
+	logger.Warningf("the mgr pods share the host namespaces (hostPID: %t, hostIPC: %t), so they can access the processes of the host",
+		c.MgrSpec.HostPID, c.MgrSpec.HostIPC)
+	return nil
+}
+
+// capabilityDropped returns whether the capability is dropped and not added back
+func capabilityDropped(capabilities *v1.Capabilities, capability v1.Capability) bool {
+	for _, added := range capabilities.Add {
+		if added == capability || added == "ALL" {
+			return false
+		}
+	}
+	for _, dropped := range capabilities.Drop {
+		if dropped == capability || dropped == "ALL" {
+			return true
+		}
+	}
+	return false
+}
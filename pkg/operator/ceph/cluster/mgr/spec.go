@@ -24,7 +24,6 @@ import (
 
 	rookcephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/daemon/ceph/client"
-	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
 	"github.com/rook/rook/pkg/operator/ceph/config"
 	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
 	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
@@ -39,6 +38,8 @@ import (
 
 const (
 	podIPEnvVar = "ROOK_POD_IP"
+	// the name of the volume with the data dir of the mgr
+	daemonDataVolumeName = "ceph-daemon-data"
 )
 
 func (c *Cluster) makeDeployment(mgrConfig *mgrConfig) *apps.Deployment {
@@ -56,6 +57,11 @@ func (c *Cluster) makeDeployment(mgrConfig *mgrConfig) *apps.Deployment {
 			RestartPolicy:      v1.RestartPolicyAlways,
 			Volumes:            opspec.DaemonVolumes(mgrConfig.DataPathMap, mgrConfig.ResourceName),
 			HostNetwork:        c.Network.IsHost(),
+			HostPID:            c.MgrSpec.HostPID,
+			HostIPC:            c.MgrSpec.HostIPC,
+			PriorityClassName:  c.PriorityClassName,
+			// the default grace period of kubernetes applies when none is set
+			TerminationGracePeriodSeconds: c.GracePeriod,
 		},
 	}
 
@@ -72,13 +78,39 @@ func (c *Cluster) makeDeployment(mgrConfig *mgrConfig) *apps.Deployment {
 		// ceph config set commands want admin keyring
 		podSpec.Spec.Volumes = append(podSpec.Spec.Volumes,
 			keyring.Volume().Admin())
+	} else if c.dashboard.BindAddress == rookcephv1.DashboardBindAddressPodIP {
+		// the pod IP is only known once the pod is scheduled, so the dashboard bind address
+		// is set by an init container in the same way as the http bind fix above
+		podSpec.Spec.InitContainers = []v1.Container{
+			c.makeSetServerAddrInitContainer(mgrConfig, "dashboard"),
+		}
+		podSpec.Spec.Volumes = append(podSpec.Spec.Volumes,
+			keyring.Volume().Admin())
 	}
 
-	if c.Network.IsHost() {
-		podSpec.Spec.DNSPolicy = v1.DNSClusterFirstWithHostNet
+	// extra volumes requested by the user, e.g. for mgr modules
+	podSpec.Spec.Volumes = append(podSpec.Spec.Volumes, c.MgrSpec.Volumes...)
+	podSpec.Spec.Containers[0].VolumeMounts = append(podSpec.Spec.Containers[0].VolumeMounts, c.MgrSpec.VolumeMounts...)
+	// extra env for the mgr modules, the env vars set by rook take precedence over the keys from envFrom
+	podSpec.Spec.Containers[0].Env = append(podSpec.Spec.Containers[0].Env, c.MgrSpec.Env...)
+	podSpec.Spec.Containers[0].EnvFrom = append(podSpec.Spec.Containers[0].EnvFrom, c.MgrSpec.EnvFrom...)
+	// the security contexts from the mgr spec, e.g. for the restricted pod security standard
+	podSpec.Spec.SecurityContext = c.MgrSpec.PodSecurityContext.DeepCopy()
+	if c.MgrSpec.SecurityContext != nil {
+		for i := range podSpec.Spec.InitContainers {
+			podSpec.Spec.InitContainers[i].SecurityContext = c.containerSecurityContext()
+		}
 	}
+	c.CABundle.ApplyToPodSpec(&podSpec.Spec)
+	podSpec.Spec.SchedulerName = c.SchedulerName
+	c.cephVersion.ApplyImagePullPolicyToPodSpec(&podSpec.Spec)
+	// sidecar containers requested by the user, e.g. log shippers
+	podSpec.Spec.Containers = append(podSpec.Spec.Containers, c.MgrSpec.Sidecars...)
+
+	c.Network.ApplyDNSToPodSpec(&podSpec.Spec)
 	c.annotations.ApplyToObjectMeta(&podSpec.ObjectMeta)
 	c.placement.ApplyToPodSpec(&podSpec.Spec)
+	c.applyMonAntiAffinity(&podSpec.Spec)
 
 	replicas := int32(1)
 	if len(c.annotations) == 0 {
@@ -100,12 +132,11 @@ func (c *Cluster) makeDeployment(mgrConfig *mgrConfig) *apps.Deployment {
 			},
 			Template: podSpec,
 			Replicas: &replicas,
-			Strategy: apps.DeploymentStrategy{
-				Type: apps.RecreateDeploymentStrategyType,
-			},
+			Strategy: c.updateStrategy(),
 		},
 	}
 	k8sutil.AddRookVersionLabelToDeployment(d)
+	k8sutil.AddRookVersionAnnotationToObjectMeta(&d.ObjectMeta)
 	opspec.AddCephVersionLabelToDeployment(c.clusterInfo.CephVersion, d)
 	k8sutil.SetOwnerRef(&d.ObjectMeta, &c.ownerRef)
 	return d
@@ -138,7 +169,12 @@ func (c *Cluster) needHttpBindFix() bool {
 // are removed (see the init container factory method). Once the minimum
 // supported version of Rook contains the fix, all of this can be removed.
 func (c *Cluster) clearHttpBindFix(mgrConfig *mgrConfig) {
-	for _, module := range []string{"dashboard", "prometheus"} {
+	modules := []string{"prometheus"}
+	// a user-defined dashboard bind address must not be cleared
+	if c.dashboard.BindAddress == "" {
+		modules = append(modules, "dashboard")
+	}
+	for _, module := range modules {
 		// there are two forms of the configuration key that might exist which
 		// depends not on the current version, but on the version that may be
 		// the version being upgraded from.
@@ -239,7 +275,7 @@ func (c *Cluster) makeMgrDaemonContainer(mgrConfig *mgrConfig) v1.Container {
 		Ports: []v1.ContainerPort{
 			{
 				Name:          "mgr",
-				ContainerPort: int32(6800),
+				ContainerPort: int32(mgrDaemonPort),
 				Protocol:      v1.ProtocolTCP,
 			},
 			{
@@ -268,9 +304,12 @@ func (c *Cluster) makeMgrDaemonContainer(mgrConfig *mgrConfig) v1.Container {
 			InitialDelaySeconds: 60,
 		},
 		Lifecycle:       opspec.PodLifeCycle(""),
-		SecurityContext: mon.PodSecurityContext(),
+		SecurityContext: c.containerSecurityContext(),
 	}
 
+	// expose the ports of the mgr modules enabled in the mgr spec
+	container.Ports = append(container.Ports, c.moduleContainerPorts()...)
+
 	// If host networking is enabled, we don't need a bind addr that is different from the public addr
 	if !c.Network.IsHost() {
 		// Opposite of the above, --public-bind-addr will *not* still advertise on the previous
@@ -283,7 +322,7 @@ func (c *Cluster) makeMgrDaemonContainer(mgrConfig *mgrConfig) v1.Container {
 }
 
 func (c *Cluster) makeMetricsService(name string) *v1.Service {
-	labels := opspec.AppLabels(appName, c.Namespace)
+	labels := opspec.AppLabels(AppName, c.Namespace)
 	svc := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
@@ -302,13 +341,15 @@ func (c *Cluster) makeMetricsService(name string) *v1.Service {
 			},
 		},
 	}
+	svc.Spec.Ports = append(svc.Spec.Ports, c.moduleServicePorts()...)
 
+	k8sutil.AddRookVersionAnnotationToObjectMeta(&svc.ObjectMeta)
 	k8sutil.SetOwnerRef(&svc.ObjectMeta, &c.ownerRef)
 	return svc
 }
 
 func (c *Cluster) makeDashboardService(name string, port int) *v1.Service {
-	labels := opspec.AppLabels(appName, c.Namespace)
+	labels := opspec.AppLabels(AppName, c.Namespace)
 	svc := &v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      fmt.Sprintf("%s-dashboard", name),
@@ -332,7 +373,7 @@ func (c *Cluster) makeDashboardService(name string, port int) *v1.Service {
 }
 
 func (c *Cluster) getPodLabels(daemonName string) map[string]string {
-	labels := opspec.PodLabels(appName, c.Namespace, "mgr", daemonName)
+	labels := opspec.PodLabels(AppName, c.Namespace, "mgr", daemonName)
 	// leave "instance" key for legacy usage
 	labels["instance"] = daemonName
 	return labels
@@ -349,3 +390,113 @@ func (c *Cluster) cephMgrOrchestratorModuleEnvs() []v1.EnvVar {
 	}
 	return envVars
 }
+
+// validateExtraVolumes checks that the extra volumes and mounts from the mgr spec do not collide with
+// the volumes and mounts created by Rook for the mgr pod
+func (c *Cluster) validateExtraVolumes(mgrConfig *mgrConfig) error {
+	rookVolumes := append(opspec.DaemonVolumes(mgrConfig.DataPathMap, mgrConfig.ResourceName), keyring.Volume().Admin())
+	for _, volume := range c.MgrSpec.Volumes {
+		for _, rookVolume := range rookVolumes {
+			if volume.Name == rookVolume.Name {
+				return fmt.Errorf("volume name %s is reserved by rook", volume.Name)
+			}
+		}
+	}
+
+	rookMounts := append(opspec.DaemonVolumeMounts(mgrConfig.DataPathMap, mgrConfig.ResourceName), keyring.VolumeMount().Admin())
+	for _, mount := range c.MgrSpec.VolumeMounts {
+		for _, rookMount := range rookMounts {
+			if mount.MountPath == rookMount.MountPath {
+				return fmt.Errorf("mount path %s of volume %s is reserved by rook", mount.MountPath, mount.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateExtraEnv checks that the extra env vars from the mgr spec do not override the env vars set by Rook
+func (c *Cluster) validateExtraEnv() error {
+	rookEnv := append(opspec.DaemonEnvVars(c.cephVersion.Image), c.cephMgrOrchestratorModuleEnvs()...)
+	for _, env := range c.MgrSpec.Env {
+		for _, rookVar := range rookEnv {
+			if env.Name == rookVar.Name {
+				return fmt.Errorf("env var %s is reserved by rook", env.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// updateStrategy returns the strategy of the mgr deployments from the mgr spec. The default is to recreate the pod,
+// so two pods never run the same mgr.
+func (c *Cluster) updateStrategy() apps.DeploymentStrategy {
+	if c.MgrSpec.UpdateStrategy == nil {
+		return apps.DeploymentStrategy{Type: apps.RecreateDeploymentStrategyType}
+	}
+	return *c.MgrSpec.UpdateStrategy.DeepCopy()
+}
+
+// validateUpdateStrategy checks the type of the strategy from the mgr spec and that the rolling update settings are
+// only set for rolling updates
+func (c *Cluster) validateUpdateStrategy() error {
+	strategy := c.MgrSpec.UpdateStrategy
+	if strategy == nil {
+		return nil
+	}
+	switch strategy.Type {
+	case apps.RecreateDeploymentStrategyType:
+		if strategy.RollingUpdate != nil {
+			return fmt.Errorf("rollingUpdate cannot be set with the %s update strategy", strategy.Type)
+		}
+	case apps.RollingUpdateDeploymentStrategyType:
+		if strategy.RollingUpdate == nil {
+			return nil
+		}
+		surge := strategy.RollingUpdate.MaxSurge
+		unavailable := strategy.RollingUpdate.MaxUnavailable
+		if surge != nil && unavailable != nil && isZero(*surge) && isZero(*unavailable) {
+			return fmt.Errorf("maxSurge and maxUnavailable of the rolling update cannot both be 0")
+		}
+		if surge == nil || !isZero(*surge) {
+			logger.Warningf("the rolling update of the mgr with a maxSurge starts the new mgr pod before the old pod is stopped, so both pods briefly run the same mgr")
+		}
+	default:
+		return fmt.Errorf("invalid update strategy %q, it must be %q or %q", strategy.Type, apps.RecreateDeploymentStrategyType, apps.RollingUpdateDeploymentStrategyType)
+	}
+	return nil
+}
+
+// isZero returns whether the number or percentage is 0
+func isZero(value intstr.IntOrString) bool {
+	if value.Type == intstr.Int {
+		return value.IntValue() == 0
+	}
+	return strings.TrimSuffix(value.StrVal, "%") == "0"
+}
+
+// validateSidecars checks that the sidecars from the mgr spec do not collide with the containers created by Rook
+// and only mount the data dir of the mgr if it is explicitly allowed
+func (c *Cluster) validateSidecars(mgrConfig *mgrConfig) error {
+	rookContainers := []v1.Container{
+		c.makeMgrDaemonContainer(mgrConfig),
+		c.makeCopyKeyringInitContainer(mgrConfig),
+		c.makeSetServerAddrInitContainer(mgrConfig, "dashboard"),
+		c.makeSetServerAddrInitContainer(mgrConfig, "prometheus"),
+	}
+	for _, sidecar := range c.MgrSpec.Sidecars {
+		for _, rookContainer := range rookContainers {
+			if sidecar.Name == rookContainer.Name {
+				return fmt.Errorf("sidecar name %s is reserved by rook", sidecar.Name)
+			}
+		}
+		if c.MgrSpec.AllowSidecarDataDirAccess {
+			continue
+		}
+		for _, mount := range sidecar.VolumeMounts {
+			if mount.Name == daemonDataVolumeName {
+				return fmt.Errorf("sidecar %s mounts the mgr data dir, which requires allowSidecarDataDirAccess", sidecar.Name)
+			}
+		}
+	}
+	return nil
+}
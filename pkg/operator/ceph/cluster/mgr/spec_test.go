@@ -17,6 +17,7 @@ limitations under the License.
 package mgr
 
 import (
+	"os"
 	"testing"
 
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
@@ -24,17 +25,23 @@ import (
 	"github.com/rook/rook/pkg/clusterd"
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	"github.com/rook/rook/pkg/operator/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
 	cephtest "github.com/rook/rook/pkg/operator/ceph/test"
 	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/rook/rook/pkg/operator/k8sutil"
 	optest "github.com/rook/rook/pkg/operator/test"
+	rookversion "github.com/rook/rook/pkg/version"
 	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 func TestPodSpec(t *testing.T) {
 	clusterInfo := &cephconfig.ClusterInfo{FSID: "myfsid"}
+	gracePeriod := int64(120)
 	c := New(
 		clusterInfo,
 		&clusterd.Context{Clientset: optest.New(1)},
@@ -60,6 +67,8 @@ func TestPodSpec(t *testing.T) {
 		"/var/lib/rook/",
 		false,
 	)
+	c.PriorityClassName = "my-priority-class"
+	c.GracePeriod = &gracePeriod
 
 	mgrTestConfig := mgrConfig{
 		DaemonID:      "a",
@@ -72,16 +81,18 @@ func TestPodSpec(t *testing.T) {
 
 	// Deployment should have Ceph labels
 	cephtest.AssertLabelsContainCephRequirements(t, d.ObjectMeta.Labels,
-		config.MgrType, "a", appName, "ns")
+		config.MgrType, "a", AppName, "ns")
 
 	podTemplate := cephtest.NewPodTemplateSpecTester(t, &d.Spec.Template)
 	podTemplate.Spec().Containers().RequireAdditionalEnvVars(
 		"ROOK_OPERATOR_NAMESPACE", "ROOK_CEPH_CLUSTER_CRD_VERSION", "ROOK_VERSION",
 		"ROOK_CEPH_CLUSTER_CRD_NAME")
-	podTemplate.RunFullSuite(config.MgrType, "a", appName, "ns", "ceph/ceph:myceph",
+	podTemplate.RunFullSuite(config.MgrType, "a", AppName, "ns", "ceph/ceph:myceph",
 		"200", "100", "500", "250" /* resources */)
 	assert.Equal(t, 2, len(d.Spec.Template.Annotations))
-
+	assert.Equal(t, rookversion.Version, d.Annotations[k8sutil.RookVersionAnnotationKey])
+	assert.Equal(t, "my-priority-class", d.Spec.Template.Spec.PriorityClassName)
+	assert.Equal(t, int64(120), *d.Spec.Template.Spec.TerminationGracePeriodSeconds)
 }
 
 func TestServiceSpec(t *testing.T) {
@@ -107,6 +118,7 @@ func TestServiceSpec(t *testing.T) {
 	assert.NotNil(t, s)
 	assert.Equal(t, "rook-mgr", s.Name)
 	assert.Equal(t, 1, len(s.Spec.Ports))
+	assert.Equal(t, rookversion.Version, s.Annotations[k8sutil.RookVersionAnnotationKey])
 }
 
 func TestHostNetwork(t *testing.T) {
@@ -202,3 +214,340 @@ func TestHttpBindFix(t *testing.T) {
 			len(d.Spec.Template.Spec.InitContainers))
 	}
 }
+
+func TestDashboardBindAddress(t *testing.T) {
+	// the http bind fix of the older versions would add its own init containers
+	clusterInfo := &cephconfig.ClusterInfo{FSID: "myfsid", CephVersion: cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}}
+	c := New(
+		clusterInfo,
+		&clusterd.Context{Clientset: optest.New(1)},
+		"ns",
+		"myversion",
+		cephv1.CephVersionSpec{},
+		rookalpha.Placement{},
+		rookalpha.Annotations{},
+		cephv1.NetworkSpec{},
+		cephv1.DashboardSpec{},
+		cephv1.MonitoringSpec{},
+		v1.ResourceRequirements{},
+		metav1.OwnerReference{},
+		"/var/lib/rook/",
+		false,
+	)
+
+	mgrTestConfig := mgrConfig{
+		DaemonID:      "a",
+		ResourceName:  "mgr-a",
+		DashboardPort: 1234,
+		DataPathMap:   config.NewStatelessDaemonDataPathMap(config.MgrType, "a", "rook-ceph", "/var/lib/rook/"),
+	}
+
+	// by default the dashboard binds to all interfaces
+	d := c.makeDeployment(&mgrTestConfig)
+	assert.Equal(t, 0, len(d.Spec.Template.Spec.InitContainers))
+
+	// an explicit address is applied with the mgr config, not in the pod spec
+	c.dashboard.BindAddress = "10.0.0.1"
+	d = c.makeDeployment(&mgrTestConfig)
+	assert.Equal(t, 0, len(d.Spec.Template.Spec.InitContainers))
+
+	// binding to the pod IP requires an init container
+	c.dashboard.BindAddress = cephv1.DashboardBindAddressPodIP
+	d = c.makeDeployment(&mgrTestConfig)
+	assert.Equal(t, 1, len(d.Spec.Template.Spec.InitContainers))
+	assert.Equal(t, "init-set-dashboard-server-addr", d.Spec.Template.Spec.InitContainers[0].Name)
+}
+
+// newTestCluster returns a mgr cluster with the given settings of the mgrs and the config of mgr "a"
+func newTestCluster(cephVersion cephver.CephVersion, mgrSpec cephv1.MgrSpec) (*Cluster, mgrConfig) {
+	clusterInfo := &cephconfig.ClusterInfo{FSID: "myfsid", CephVersion: cephVersion}
+	c := New(
+		clusterInfo,
+		&clusterd.Context{Clientset: optest.New(1)},
+		"ns",
+		"myversion",
+		cephv1.CephVersionSpec{},
+		rookalpha.Placement{},
+		rookalpha.Annotations{},
+		cephv1.NetworkSpec{},
+		cephv1.DashboardSpec{},
+		cephv1.MonitoringSpec{},
+		v1.ResourceRequirements{},
+		metav1.OwnerReference{},
+		"/var/lib/rook/",
+		false,
+	)
+	c.MgrSpec = mgrSpec
+
+	mgrTestConfig := mgrConfig{
+		DaemonID:      "a",
+		ResourceName:  "rook-ceph-mgr-a",
+		DashboardPort: 1234,
+		DataPathMap:   config.NewStatelessDaemonDataPathMap(config.MgrType, "a", "rook-ceph", "/var/lib/rook/"),
+	}
+	return c, mgrTestConfig
+}
+
+func TestExtraVolumes(t *testing.T) {
+	mgrSpec := cephv1.MgrSpec{
+		Volumes: []v1.Volume{
+			{Name: "module-certs", VolumeSource: v1.VolumeSource{Secret: &v1.SecretVolumeSource{SecretName: "certs"}}},
+		},
+		VolumeMounts: []v1.VolumeMount{
+			{Name: "module-certs", MountPath: "/etc/module-certs"},
+		},
+	}
+	c, mgrTestConfig := newTestCluster(cephver.Nautilus, mgrSpec)
+	assert.Nil(t, c.validateExtraVolumes(&mgrTestConfig))
+
+	d := c.makeDeployment(&mgrTestConfig)
+	volumes := d.Spec.Template.Spec.Volumes
+	assert.Equal(t, "module-certs", volumes[len(volumes)-1].Name)
+	mounts := d.Spec.Template.Spec.Containers[0].VolumeMounts
+	assert.Equal(t, "/etc/module-certs", mounts[len(mounts)-1].MountPath)
+
+	// the volume name collides with the keyring of the mgr
+	c.MgrSpec.Volumes[0].Name = keyring.Volume().Resource(mgrTestConfig.ResourceName).Name
+	assert.NotNil(t, c.validateExtraVolumes(&mgrTestConfig))
+
+	// the mount path collides with the admin keyring
+	c.MgrSpec.Volumes[0].Name = "module-certs"
+	c.MgrSpec.VolumeMounts[0].MountPath = keyring.VolumeMount().Admin().MountPath
+	assert.NotNil(t, c.validateExtraVolumes(&mgrTestConfig))
+}
+
+func TestExtraEnv(t *testing.T) {
+	mgrSpec := cephv1.MgrSpec{
+		Env: []v1.EnvVar{{Name: "MODULE_SETTING", Value: "value"}},
+		EnvFrom: []v1.EnvFromSource{
+			{ConfigMapRef: &v1.ConfigMapEnvSource{LocalObjectReference: v1.LocalObjectReference{Name: "module-config"}}},
+		},
+	}
+	c, mgrTestConfig := newTestCluster(cephver.Nautilus, mgrSpec)
+	assert.Nil(t, c.validateExtraEnv())
+
+	d := c.makeDeployment(&mgrTestConfig)
+	container := d.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "MODULE_SETTING", container.Env[len(container.Env)-1].Name)
+	assert.Equal(t, 1, len(container.EnvFrom))
+	assert.Equal(t, "module-config", container.EnvFrom[0].ConfigMapRef.Name)
+
+	// the env vars set by rook cannot be overridden
+	c.MgrSpec.Env = append(c.MgrSpec.Env, v1.EnvVar{Name: "ROOK_VERSION", Value: "other"})
+	assert.NotNil(t, c.validateExtraEnv())
+}
+
+func TestSidecars(t *testing.T) {
+	mgrSpec := cephv1.MgrSpec{
+		Sidecars: []v1.Container{
+			{Name: "log-shipper", Image: "fluent/fluent-bit"},
+		},
+	}
+	c, mgrTestConfig := newTestCluster(cephver.Nautilus, mgrSpec)
+	assert.Nil(t, c.validateSidecars(&mgrTestConfig))
+
+	d := c.makeDeployment(&mgrTestConfig)
+	containers := d.Spec.Template.Spec.Containers
+	assert.Equal(t, 2, len(containers))
+	assert.Equal(t, "mgr", containers[0].Name)
+	assert.Equal(t, "log-shipper", containers[1].Name)
+
+	// the name collides with a rook container
+	c.MgrSpec.Sidecars[0].Name = "mgr"
+	assert.NotNil(t, c.validateSidecars(&mgrTestConfig))
+	c.MgrSpec.Sidecars[0].Name = "init-set-dashboard-server-addr"
+	assert.NotNil(t, c.validateSidecars(&mgrTestConfig))
+
+	// the data dir is only mounted when allowed
+	c.MgrSpec.Sidecars[0].Name = "log-shipper"
+	c.MgrSpec.Sidecars[0].VolumeMounts = []v1.VolumeMount{{Name: daemonDataVolumeName, MountPath: "/data"}}
+	assert.NotNil(t, c.validateSidecars(&mgrTestConfig))
+	c.MgrSpec.AllowSidecarDataDirAccess = true
+	assert.Nil(t, c.validateSidecars(&mgrTestConfig))
+}
+
+func TestCABundle(t *testing.T) {
+	mgrSpec := cephv1.MgrSpec{
+		Sidecars: []v1.Container{
+			{Name: "log-shipper", Image: "fluent/fluent-bit"},
+		},
+	}
+	c, mgrTestConfig := newTestCluster(cephver.Nautilus, mgrSpec)
+	c.CABundle = cephv1.CABundleSpec{ConfigMapName: "corporate-ca"}
+
+	// the mgr trusts the bundle, the sidecars are left alone
+	d := c.makeDeployment(&mgrTestConfig)
+	spec := d.Spec.Template.Spec
+	found := false
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil && volume.ConfigMap.Name == "corporate-ca" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+	mgrEnv := map[string]string{}
+	for _, env := range spec.Containers[0].Env {
+		mgrEnv[env.Name] = env.Value
+	}
+	assert.Equal(t, "/etc/rook/ca-bundle/ca-bundle.crt", mgrEnv["REQUESTS_CA_BUNDLE"])
+	assert.Equal(t, 0, len(spec.Containers[1].Env))
+}
+
+func TestSchedulerName(t *testing.T) {
+	c, mgrTestConfig := newTestCluster(cephver.Nautilus, cephv1.MgrSpec{})
+
+	// the default scheduler places the mgr
+	d := c.makeDeployment(&mgrTestConfig)
+	assert.Equal(t, "", d.Spec.Template.Spec.SchedulerName)
+
+	c.SchedulerName = "topology-scheduler"
+	d = c.makeDeployment(&mgrTestConfig)
+	assert.Equal(t, "topology-scheduler", d.Spec.Template.Spec.SchedulerName)
+}
+
+func TestSecurityContext(t *testing.T) {
+	mgrSpec := cephv1.MgrSpec{
+		Sidecars: []v1.Container{
+			{Name: "log-shipper", Image: "fluent/fluent-bit"},
+		},
+	}
+	c, mgrTestConfig := newTestCluster(cephver.Mimic, mgrSpec)
+
+	// the security context of rook without overrides
+	assert.Nil(t, c.validateSecurityContext())
+	d := c.makeDeployment(&mgrTestConfig)
+	spec := d.Spec.Template.Spec
+	assert.Nil(t, spec.SecurityContext)
+	assert.False(t, *spec.Containers[0].SecurityContext.Privileged)
+	assert.Nil(t, spec.Containers[0].SecurityContext.RunAsUser)
+	assert.Nil(t, spec.InitContainers[0].SecurityContext)
+
+	// the overrides are merged over the security context of rook, the sidecars are left alone
+	user := int64(167)
+	nonRoot := true
+	noEscalation := false
+	fsGroup := int64(167)
+	c.MgrSpec.PodSecurityContext = &v1.PodSecurityContext{FSGroup: &fsGroup}
+	c.MgrSpec.SecurityContext = &v1.SecurityContext{
+		RunAsUser:                &user,
+		RunAsNonRoot:             &nonRoot,
+		AllowPrivilegeEscalation: &noEscalation,
+		Capabilities:             &v1.Capabilities{Drop: []v1.Capability{"ALL"}},
+	}
+	assert.Nil(t, c.validateSecurityContext())
+	d = c.makeDeployment(&mgrTestConfig)
+	spec = d.Spec.Template.Spec
+	assert.Equal(t, fsGroup, *spec.SecurityContext.FSGroup)
+	for _, container := range []v1.Container{spec.Containers[0], spec.InitContainers[0], spec.InitContainers[1]} {
+		assert.False(t, *container.SecurityContext.Privileged)
+		assert.Equal(t, user, *container.SecurityContext.RunAsUser)
+		assert.True(t, *container.SecurityContext.RunAsNonRoot)
+		assert.False(t, *container.SecurityContext.AllowPrivilegeEscalation)
+		assert.Equal(t, []v1.Capability{"ALL"}, container.SecurityContext.Capabilities.Drop)
+	}
+	assert.Equal(t, "log-shipper", spec.Containers[1].Name)
+	assert.Nil(t, spec.Containers[1].SecurityContext)
+
+	// the runAsUser of the pod applies to the containers
+	c.MgrSpec.SecurityContext.RunAsUser = nil
+	assert.NotNil(t, c.validateSecurityContext())
+	c.MgrSpec.PodSecurityContext.RunAsUser = &user
+	assert.Nil(t, c.validateSecurityContext())
+
+	// the mgr starting as root needs to switch to the ceph user
+	c.MgrSpec.PodSecurityContext = nil
+	c.MgrSpec.SecurityContext.RunAsNonRoot = nil
+	assert.NotNil(t, c.validateSecurityContext())
+	c.MgrSpec.SecurityContext.Capabilities.Add = []v1.Capability{"SETUID", "SETGID"}
+	assert.Nil(t, c.validateSecurityContext())
+	c.MgrSpec.SecurityContext.Capabilities = &v1.Capabilities{Drop: []v1.Capability{"SETGID"}}
+	assert.NotNil(t, c.validateSecurityContext())
+
+	// the privileged containers required by rook cannot be unprivileged
+	os.Setenv("ROOK_HOSTPATH_REQUIRES_PRIVILEGED", "true")
+	defer os.Unsetenv("ROOK_HOSTPATH_REQUIRES_PRIVILEGED")
+	unprivileged := false
+	c.MgrSpec.SecurityContext = &v1.SecurityContext{Privileged: &unprivileged}
+	assert.NotNil(t, c.validateSecurityContext())
+	c.MgrSpec.SecurityContext = &v1.SecurityContext{}
+	assert.Nil(t, c.validateSecurityContext())
+	assert.True(t, *c.containerSecurityContext().Privileged)
+}
+
+func TestUpdateStrategy(t *testing.T) {
+	c, mgrTestConfig := newTestCluster(cephver.Nautilus, cephv1.MgrSpec{})
+
+	// the pod is recreated by default
+	assert.Nil(t, c.validateUpdateStrategy())
+	d := c.makeDeployment(&mgrTestConfig)
+	assert.Equal(t, apps.RecreateDeploymentStrategyType, d.Spec.Strategy.Type)
+	assert.Nil(t, d.Spec.Strategy.RollingUpdate)
+
+	surge := intstr.FromInt(0)
+	unavailable := intstr.FromString("100%")
+	c.MgrSpec.UpdateStrategy = &apps.DeploymentStrategy{
+		Type:          apps.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &apps.RollingUpdateDeployment{MaxSurge: &surge, MaxUnavailable: &unavailable},
+	}
+	assert.Nil(t, c.validateUpdateStrategy())
+	d = c.makeDeployment(&mgrTestConfig)
+	assert.Equal(t, apps.RollingUpdateDeploymentStrategyType, d.Spec.Strategy.Type)
+	assert.Equal(t, surge, *d.Spec.Strategy.RollingUpdate.MaxSurge)
+	assert.Equal(t, unavailable, *d.Spec.Strategy.RollingUpdate.MaxUnavailable)
+
+	// the rolling update must be able to replace the pod
+	unavailable = intstr.FromString("0%")
+	c.MgrSpec.UpdateStrategy.RollingUpdate.MaxUnavailable = &unavailable
+	assert.NotNil(t, c.validateUpdateStrategy())
+
+	// the rolling update settings are only allowed for rolling updates
+	c.MgrSpec.UpdateStrategy.Type = apps.RecreateDeploymentStrategyType
+	assert.NotNil(t, c.validateUpdateStrategy())
+	c.MgrSpec.UpdateStrategy = &apps.DeploymentStrategy{Type: "BlueGreen"}
+	assert.NotNil(t, c.validateUpdateStrategy())
+}
+
+func TestHostNamespaces(t *testing.T) {
+	clusterInfo := &cephconfig.ClusterInfo{FSID: "myfsid", CephVersion: cephver.Nautilus}
+	c := &Cluster{Namespace: "ns", clusterInfo: clusterInfo, context: &clusterd.Context{Clientset: optest.New(1)}}
+	mgrTestConfig := mgrConfig{
+		DaemonID:     "a",
+		ResourceName: "rook-ceph-mgr-a",
+		DataPathMap:  config.NewStatelessDaemonDataPathMap(config.MgrType, "a", "rook-ceph", "/var/lib/rook/"),
+	}
+
+	// the host namespaces are not shared by default
+	d := c.makeDeployment(&mgrTestConfig)
+	assert.False(t, d.Spec.Template.Spec.HostPID)
+	assert.False(t, d.Spec.Template.Spec.HostIPC)
+
+	c.MgrSpec.HostPID = true
+	c.MgrSpec.HostIPC = true
+	d = c.makeDeployment(&mgrTestConfig)
+	assert.True(t, d.Spec.Template.Spec.HostPID)
+	assert.True(t, d.Spec.Template.Spec.HostIPC)
+
+	// the host namespaces are independent of the security context of the containers
+	escalation := false
+	c.MgrSpec.SecurityContext = &v1.SecurityContext{AllowPrivilegeEscalation: &escalation}
+	d = c.makeDeployment(&mgrTestConfig)
+	assert.True(t, d.Spec.Template.Spec.HostPID)
+	assert.Equal(t, &escalation, d.Spec.Template.Spec.Containers[0].SecurityContext.AllowPrivilegeEscalation)
+	assert.Nil(t, c.validateHostNamespaces())
+
+	// a restricted security context rejects the host namespaces
+	nonRoot := true
+	c.MgrSpec.SecurityContext = &v1.SecurityContext{RunAsNonRoot: &nonRoot}
+	assert.NotNil(t, c.validateHostNamespaces())
+	c.MgrSpec.SecurityContext = nil
+	c.MgrSpec.PodSecurityContext = &v1.PodSecurityContext{RunAsNonRoot: &nonRoot}
+	assert.NotNil(t, c.validateHostNamespaces())
+	c.MgrSpec.PodSecurityContext = nil
+	c.MgrSpec.SecurityContext = &v1.SecurityContext{Capabilities: &v1.Capabilities{Drop: []v1.Capability{"ALL"}}}
+	assert.NotNil(t, c.validateHostNamespaces())
+
+	// the restricted security context is allowed without the host namespaces
+	c.MgrSpec.HostPID = false
+	c.MgrSpec.HostIPC = false
+	assert.Nil(t, c.validateHostNamespaces())
+}
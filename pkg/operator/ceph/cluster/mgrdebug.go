@@ -0,0 +1,65 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/ceph/config"
+)
+
+const mgrDebugOption = "debug_mgr"
+
+// mgrDebugLevel returns the mgr debug level requested by the annotation of the CephCluster CR
+func mgrDebugLevel(cephCluster *cephv1.CephCluster) string {
+	return cephCluster.GetAnnotations()[mgrDebugLevelAnnotation]
+}
+
+// requestMgrDebugLevel sets the mgr debug level requested by the annotation, applied by the next applyMgrDebugLevel
+func (c *cluster) requestMgrDebugLevel(level string) {
+	c.mgrDebugMux.Lock()
+	defer c.mgrDebugMux.Unlock()
+	c.requestedMgrDebugLevel = level
+}
+
+// applyMgrDebugLevel sets the requested debug level of the mgr daemons in the mon config database if it changed. An
+// empty level removes the setting so the default level applies again. The applied level is reported in the status.
+func (c *cluster) applyMgrDebugLevel() error {
+	c.mgrDebugMux.Lock()
+	defer c.mgrDebugMux.Unlock()
+	level := c.requestedMgrDebugLevel
+	if level == c.mgrDebugLevel {
+		return nil
+	}
+
+	monStore := config.GetMonStore(c.context, c.Namespace)
+	if level == "" {
+		logger.Infof("resetting the debug level of the mgrs of cluster %s", c.Namespace)
+		if err := monStore.Delete("mgr", mgrDebugOption); err != nil {
+			return fmt.Errorf("failed to reset the mgr debug level. %+v", err)
+		}
+	} else {
+		logger.Infof("setting the debug level of the mgrs of cluster %s to %s", c.Namespace, level)
+		if err := monStore.Set("mgr", mgrDebugOption, level); err != nil {
+			return fmt.Errorf("failed to set the mgr debug level to %s. %+v", level, err)
+		}
+	}
+	c.mgrDebugLevel = level
+	c.updateMgrDebugLevelStatus(level)
+	return nil
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyMgrDebugLevel(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "my-cluster",
+			Namespace:   "ns",
+			Annotations: map[string]string{mgrDebugLevelAnnotation: "20"},
+		},
+	}
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			commands = append(commands, strings.Join(args[:4], " "))
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Executor: executor, RookClientset: rookfake.NewSimpleClientset(cephCluster)}
+	c := &cluster{Namespace: "ns", crdName: "my-cluster", context: context}
+
+	// the level from the annotation is set
+	c.requestMgrDebugLevel(mgrDebugLevel(cephCluster))
+	assert.Nil(t, c.applyMgrDebugLevel())
+	assert.Equal(t, []string{"config set mgr debug_mgr"}, commands)
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "20", updated.Status.MgrDebugLevel)
+
+	// the same level is not applied again
+	c.requestMgrDebugLevel("20")
+	assert.Nil(t, c.applyMgrDebugLevel())
+	assert.Equal(t, 1, len(commands))
+
+	// the level is reset when the annotation is removed
+	c.requestMgrDebugLevel("")
+	assert.Nil(t, c.applyMgrDebugLevel())
+	assert.Equal(t, "config rm mgr debug_mgr", commands[1])
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", updated.Status.MgrDebugLevel)
+}
@@ -42,6 +42,9 @@ const (
 	// All mons share the same keyring
 	keyringStoreName = "rook-ceph-mons"
 
+	// the secret with the admin key for the csi driver
+	csiSecretName = "rook-ceph-csi"
+
 	// The final string field is for the admin keyring
 	keyringTemplate = `
 [mon.]
@@ -70,6 +73,9 @@ func dataDirRelativeHostPath(monName string) string {
 	return path.Join(monHostDir, "data")
 }
 
+// ErrClusterInfoNotFound is returned when loading the cluster info of a cluster that was not created yet
+var ErrClusterInfoNotFound = fmt.Errorf("not expected to create new cluster info and did not find existing secret")
+
 // LoadClusterInfo constructs or loads a clusterinfo and returns it along with the maxMonID
 func LoadClusterInfo(context *clusterd.Context, namespace string) (*cephconfig.ClusterInfo, int, *Mapping, error) {
 	return CreateOrLoadClusterInfo(context, namespace, nil)
@@ -89,7 +95,7 @@ func CreateOrLoadClusterInfo(context *clusterd.Context, namespace string, ownerR
 			return nil, maxMonID, monMapping, fmt.Errorf("failed to get mon secrets. %+v", err)
 		}
 		if ownerRef == nil {
-			return nil, maxMonID, monMapping, fmt.Errorf("not expected to create new cluster info and did not find existing secret")
+			return nil, maxMonID, monMapping, ErrClusterInfoNotFound
 		}
 
 		clusterInfo, err = createNamedClusterInfo(context, namespace)
@@ -192,7 +198,7 @@ func createClusterAccessSecret(clientset kubernetes.Interface, namespace string,
 	}
 	csiSecret := &v1.Secret{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "rook-ceph-csi",
+			Name:      csiSecretName,
 			Namespace: namespace,
 		},
 		Data: csiSecrets,
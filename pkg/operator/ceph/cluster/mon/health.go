@@ -17,6 +17,7 @@ limitations under the License.
 package mon
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -169,7 +170,7 @@ func (c *Cluster) checkHealth() error {
 	// create/start new mons when there are fewer mons than the desired count in the CRD
 	if len(status.MonMap.Mons) < desiredMonCount {
 		logger.Infof("adding mons. currently %d mons are in quorum and the desired count is %d.", len(status.MonMap.Mons), desiredMonCount)
-		return c.startMons(desiredMonCount)
+		return c.startMons(context.Background(), desiredMonCount)
 	}
 
 	// remove extra mons if the desired count has decreased in the CRD and all the mons are currently healthy
@@ -0,0 +1,208 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/config/keyring"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the key in the mon secret of the new admin key while it is rotated, until the secrets are switched to it
+const pendingAdminSecretName = "pending-admin-secret"
+
+// RotateAdminKey replaces the key of client.admin with a new key. The new key is stored as the pending key in the mon
+// secret before it is imported in ceph with the old key. The operator then switches its own connection to the new key
+// and the secrets derived from the key are updated. If the rotation is interrupted after the import, the pending key
+// is found by the next orchestration, which finishes the rotation.
+func (c *Cluster) RotateAdminKey() error {
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+
+	logger.Infof("rotating the admin key of cluster %s", c.Namespace)
+	newKey, err := c.context.Executor.ExecuteCommandWithOutput(false, "gen admin key", "ceph-authtool", "--gen-print-key")
+	if err != nil {
+		return fmt.Errorf("failed to generate a new admin key. %+v", err)
+	}
+	newKey = strings.TrimSpace(newKey)
+	if newKey == "" {
+		return fmt.Errorf("failed to generate a new admin key. the key is empty")
+	}
+
+	// the new key must be stored before ceph accepts it, so it is not lost if the operator stops after the import
+	if err := c.setPendingAdminKey(newKey); err != nil {
+		return err
+	}
+
+	// import the new key while the operator is still connected with the old key
+	newInfo := *c.ClusterInfo
+	newInfo.AdminSecret = newKey
+	dir := path.Join(c.context.ConfigDir, c.ClusterInfo.Name)
+	if err := os.MkdirAll(dir, 0744); err != nil {
+		return fmt.Errorf("failed to create dir %s. %+v", dir, err)
+	}
+	keyringPath := path.Join(dir, "client.admin.rotated.keyring")
+	if err := ioutil.WriteFile(keyringPath, []byte(cephconfig.AdminKeyring(&newInfo)), 0600); err != nil {
+		return fmt.Errorf("failed to write the new admin keyring. %+v", err)
+	}
+	defer os.Remove(keyringPath)
+	if err := client.AuthImport(c.context, c.ClusterInfo.Name, keyringPath); err != nil {
+		// ceph may still have accepted the key, e.g. if the command timed out after the import
+		if reconcileErr := c.reconcilePendingAdminKey(); reconcileErr != nil {
+			logger.Errorf("failed to reconcile the admin key after the failed import. %+v", reconcileErr)
+		}
+		return fmt.Errorf("failed to import the new admin key. %+v", err)
+	}
+
+	if err := c.switchAdminKey(newKey); err != nil {
+		return err
+	}
+	logger.Infof("rotated the admin key of cluster %s", c.Namespace)
+	return nil
+}
+
+// switchAdminKey switches the connection of the operator and the secrets to the admin key that ceph accepts. The
+// pending key is cleared together with the update of the admin key in the mon secret.
+func (c *Cluster) switchAdminKey(key string) error {
+	// ceph only accepts the new key from now on, so the operator must connect with it before anything else
+	c.ClusterInfo.AdminSecret = key
+	if err := WriteConnectionConfig(c.context, c.ClusterInfo); err != nil {
+		return fmt.Errorf("failed to write the connection config with the new admin key. %+v", err)
+	}
+
+	if err := c.updateAdminKeySecrets(); err != nil {
+		return fmt.Errorf("failed to update the secrets with the new admin key. %+v", err)
+	}
+	return nil
+}
+
+// reconcilePendingAdminKey finishes a rotation of the admin key that was interrupted. Ceph accepts either the pending
+// key, if it was imported, or the old key. The pending key is tried first, then the old key. If ceph accepts neither,
+// e.g. since the mons are not running, the pending key is kept so the next orchestration tries again.
+func (c *Cluster) reconcilePendingAdminKey() error {
+	pending, err := c.pendingAdminKey()
+	if err != nil {
+		return err
+	}
+	if pending == "" {
+		return nil
+	}
+	oldKey := c.ClusterInfo.AdminSecret
+	logger.Infof("found a pending admin key of cluster %s, checking which key ceph accepts", c.Namespace)
+
+	if c.adminKeyAccepted(pending) {
+		logger.Infof("ceph accepts the pending admin key of cluster %s, finishing the rotation", c.Namespace)
+		return c.switchAdminKey(pending)
+	}
+	if c.adminKeyAccepted(oldKey) {
+		logger.Infof("ceph did not accept the pending admin key of cluster %s, keeping the current key", c.Namespace)
+		return c.setPendingAdminKey("")
+	}
+
+	logger.Warningf("ceph accepts neither the pending nor the current admin key of cluster %s. retrying in the next orchestration", c.Namespace)
+	c.ClusterInfo.AdminSecret = oldKey
+	if err := WriteConnectionConfig(c.context, c.ClusterInfo); err != nil {
+		return fmt.Errorf("failed to write the connection config with the current admin key. %+v", err)
+	}
+	return nil
+}
+
+// adminKeyAccepted returns whether ceph accepts the admin key. The connection config of the operator is written with
+// the key.
+func (c *Cluster) adminKeyAccepted(key string) bool {
+	c.ClusterInfo.AdminSecret = key
+	if err := WriteConnectionConfig(c.context, c.ClusterInfo); err != nil {
+		logger.Warningf("failed to write the connection config to check the admin key. %+v", err)
+		return false
+	}
+	if _, err := client.Status(c.context, c.ClusterInfo.Name, false); err != nil {
+		logger.Debugf("the admin key was not accepted. %+v", err)
+		return false
+	}
+	return true
+}
+
+// pendingAdminKey returns the admin key of a rotation that was not finished, or an empty string
+func (c *Cluster) pendingAdminKey() (string, error) {
+	secret, err := c.context.Clientset.CoreV1().Secrets(c.Namespace).Get(AppName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get mon secrets. %+v", err)
+	}
+	return string(secret.Data[pendingAdminSecretName]), nil
+}
+
+// setPendingAdminKey stores the pending admin key in the mon secret. An empty key removes it.
+func (c *Cluster) setPendingAdminKey(key string) error {
+	secrets := c.context.Clientset.CoreV1().Secrets(c.Namespace)
+	secret, err := secrets.Get(AppName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get mon secrets. %+v", err)
+	}
+	if key == "" {
+		delete(secret.Data, pendingAdminSecretName)
+	} else {
+		secret.Data[pendingAdminSecretName] = []byte(key)
+	}
+	if _, err := secrets.Update(secret); err != nil {
+		return fmt.Errorf("failed to store the pending admin key. %+v", err)
+	}
+	return nil
+}
+
+// updateAdminKeySecrets stores the admin key from the cluster info in all the secrets that contain it
+func (c *Cluster) updateAdminKeySecrets() error {
+	secrets := c.context.Clientset.CoreV1().Secrets(c.Namespace)
+	secret, err := secrets.Get(AppName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get mon secrets. %+v", err)
+	}
+	secret.Data[adminSecretName] = []byte(c.ClusterInfo.AdminSecret)
+	delete(secret.Data, pendingAdminSecretName)
+	if _, err := secrets.Update(secret); err != nil {
+		return fmt.Errorf("failed to update mon secrets. %+v", err)
+	}
+
+	csiSecret, err := secrets.Get(csiSecretName, metav1.GetOptions{})
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to get csi secret. %+v", err)
+		}
+	} else {
+		csiSecret.Data["adminKey"] = []byte(c.ClusterInfo.AdminSecret)
+		csiSecret.Data["userKey"] = []byte(c.ClusterInfo.AdminSecret)
+		if _, err := secrets.Update(csiSecret); err != nil {
+			return fmt.Errorf("failed to update csi secret. %+v", err)
+		}
+	}
+
+	k := keyring.GetSecretStore(c.context, c.Namespace, &c.ownerRef)
+	if err := k.CreateOrUpdate(keyringStoreName, c.genMonSharedKeyring()); err != nil {
+		return fmt.Errorf("failed to update mon keyring secret. %+v", err)
+	}
+	if err := k.Admin().CreateOrUpdate(c.ClusterInfo); err != nil {
+		return fmt.Errorf("failed to update admin keyring secret. %+v", err)
+	}
+	return nil
+}
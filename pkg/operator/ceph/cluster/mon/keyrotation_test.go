@@ -0,0 +1,121 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package mon
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRotateAdminKey(t *testing.T) {
+	configDir, _ := ioutil.TempDir("", "")
+	defer os.RemoveAll(configDir)
+
+	importedKeyring := ""
+	importErr := errors.New("import failed")
+	// the admin key that ceph accepts, checked against the keyring of the connection of the operator
+	acceptedKey := "adminsecret"
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutput: func(debug bool, actionName string, command string, args ...string) (string, error) {
+			if command == "ceph-authtool" && args[0] == "--gen-print-key" {
+				return "newadminsecret\n", nil
+			}
+			return "", nil
+		},
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName string, command string, outFileArg string, args ...string) (string, error) {
+			if args[0] == "auth" && args[1] == "import" {
+				contents, _ := ioutil.ReadFile(args[3])
+				importedKeyring = string(contents)
+				return "", importErr
+			}
+			if args[0] == "status" {
+				for _, arg := range args {
+					if strings.HasPrefix(arg, "--keyring=") {
+						contents, _ := ioutil.ReadFile(strings.TrimPrefix(arg, "--keyring="))
+						if strings.Contains(string(contents), "key = "+acceptedKey+"\n") {
+							return "{}", nil
+						}
+					}
+				}
+				return "", errors.New("permission denied")
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{Clientset: test.New(1), Executor: executor, ConfigDir: configDir}
+	c := newCluster(context, "ns", cephv1.NetworkSpec{}, false, v1.ResourceRequirements{})
+	c.ClusterInfo = test.CreateConfigDir(1)
+	assert.Nil(t, createClusterAccessSecret(context.Clientset, "ns", c.ClusterInfo, &c.ownerRef))
+	monSecret := func() *v1.Secret {
+		secret, err := context.Clientset.CoreV1().Secrets("ns").Get(AppName, metav1.GetOptions{})
+		assert.Nil(t, err)
+		return secret
+	}
+
+	// the key is not changed if ceph did not accept the new key
+	assert.NotNil(t, c.RotateAdminKey())
+	assert.True(t, strings.Contains(importedKeyring, "newadminsecret"))
+	assert.Equal(t, "adminsecret", c.ClusterInfo.AdminSecret)
+	assert.Equal(t, "adminsecret", string(monSecret().Data[adminSecretName]))
+	_, pending := monSecret().Data[pendingAdminSecretName]
+	assert.False(t, pending)
+
+	// the new key is used by the operator and stored in the secrets
+	importErr = nil
+	assert.Nil(t, c.RotateAdminKey())
+	assert.Equal(t, "newadminsecret", c.ClusterInfo.AdminSecret)
+	assert.Equal(t, "newadminsecret", string(monSecret().Data[adminSecretName]))
+	_, pending = monSecret().Data[pendingAdminSecretName]
+	assert.False(t, pending)
+	csiSecret, _ := context.Clientset.CoreV1().Secrets("ns").Get(csiSecretName, metav1.GetOptions{})
+	assert.Equal(t, "newadminsecret", string(csiSecret.Data["adminKey"]))
+	assert.Equal(t, "newadminsecret", string(csiSecret.Data["userKey"]))
+
+	// the rotation was interrupted after ceph accepted the pending key, so the rotation is finished
+	assert.Nil(t, c.setPendingAdminKey("pendingsecret"))
+	acceptedKey = "pendingsecret"
+	assert.Nil(t, c.reconcilePendingAdminKey())
+	assert.Equal(t, "pendingsecret", c.ClusterInfo.AdminSecret)
+	assert.Equal(t, "pendingsecret", string(monSecret().Data[adminSecretName]))
+	_, pending = monSecret().Data[pendingAdminSecretName]
+	assert.False(t, pending)
+
+	// the pending key is kept while ceph cannot be reached, and the current key is used
+	assert.Nil(t, c.setPendingAdminKey("othersecret"))
+	acceptedKey = "unreachable"
+	assert.Nil(t, c.reconcilePendingAdminKey())
+	assert.Equal(t, "pendingsecret", c.ClusterInfo.AdminSecret)
+	assert.Equal(t, "othersecret", string(monSecret().Data[pendingAdminSecretName]))
+
+	// the rotation was interrupted before ceph accepted the pending key, so the pending key is dropped
+	acceptedKey = "pendingsecret"
+	assert.Nil(t, c.reconcilePendingAdminKey())
+	assert.Equal(t, "pendingsecret", c.ClusterInfo.AdminSecret)
+	_, pending = monSecret().Data[pendingAdminSecretName]
+	assert.False(t, pending)
+}
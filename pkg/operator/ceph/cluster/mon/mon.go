@@ -20,8 +20,10 @@ limitations under the License.
 package mon
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -89,6 +91,10 @@ const (
 	// pods and waiting for kubernetes scheduling to complete.
 	canaryRetries           = 180
 	canaryRetryDelaySeconds = 5
+
+	// the time to wait for a majority of the mons to be in quorum before starting the other daemons
+	quorumMajorityTimeout       = 5 * time.Minute
+	quorumMajorityCheckInterval = 5 * time.Second
 )
 
 var (
@@ -119,6 +125,19 @@ type Cluster struct {
 	ownerRef            metav1.OwnerReference
 	csiConfigMutex      *sync.Mutex
 	isUpgrade           bool
+	// whether the mons are required to be in separate zones, only set when there are enough zones for all the mons
+	requireZoneSpread bool
+	// a copy of the cluster info taken when the orchestration lock is released, guarded by infoMux, so the cluster
+	// info can be read without waiting for a running orchestration
+	info    *infoSnapshot
+	infoMux sync.RWMutex
+}
+
+// infoSnapshot is a copy of the parts of the cluster info that are read outside of the orchestration lock
+type infoSnapshot struct {
+	fsid        string
+	cephVersion cephver.CephVersion
+	monitors    []cephconfig.MonInfo
 }
 
 // monConfig for a single monitor
@@ -175,8 +194,9 @@ func New(context *clusterd.Context, namespace, dataDirHostPath string, network c
 	}
 }
 
-// Start begins the process of running a cluster of Ceph mons.
-func (c *Cluster) Start(clusterInfo *cephconfig.ClusterInfo, rookVersion string, cephVersion cephver.CephVersion, spec cephv1.ClusterSpec, isUpgrade bool) (*cephconfig.ClusterInfo, error) {
+// Start begins the process of running a cluster of Ceph mons. The mons that are not created yet are not started
+// after the context is canceled.
+func (c *Cluster) Start(ctx context.Context, clusterInfo *cephconfig.ClusterInfo, rookVersion string, cephVersion cephver.CephVersion, spec cephv1.ClusterSpec, isUpgrade bool) (*cephconfig.ClusterInfo, error) {
 
 	// Only one goroutine can orchestrate the mons at a time
 	c.acquireOrchestrationLock()
@@ -198,6 +218,15 @@ func (c *Cluster) Start(clusterInfo *cephconfig.ClusterInfo, rookVersion string,
 		return nil, fmt.Errorf("%v", err)
 	}
 
+	c.requireZoneSpread = false
+	if c.spec.Mon.ZoneSpread {
+		zones, err := c.countMonZones()
+		if err != nil {
+			return nil, fmt.Errorf("failed to count the zones for the mons. %+v", err)
+		}
+		c.requireZoneSpread = checkZoneSpread(c.spec.Mon.Count, zones, c.zoneTopologyKey())
+	}
+
 	logger.Infof("start running mons")
 
 	logger.Debugf("establishing ceph cluster info")
@@ -208,10 +237,57 @@ func (c *Cluster) Start(clusterInfo *cephconfig.ClusterInfo, rookVersion string,
 	logger.Infof("targeting the mon count %d", c.spec.Mon.Count)
 
 	// create the mons for a new cluster or ensure mons are running in an existing cluster
-	return c.ClusterInfo, c.startMons(c.spec.Mon.Count)
+	return c.ClusterInfo, c.startMons(ctx, c.spec.Mon.Count)
+}
+
+// zoneTopologyKey is the node label used to spread the mons across zones
+func (c *Cluster) zoneTopologyKey() string {
+	if c.spec.Mon.ZoneTopologyKey != "" {
+		return c.spec.Mon.ZoneTopologyKey
+	}
+	return v1.LabelZoneFailureDomain
+}
+
+// countMonZones returns the number of distinct zones of the nodes where mons can be placed
+func (c *Cluster) countMonZones() (int, error) {
+	nodes, err := c.context.Clientset.CoreV1().Nodes().List(metav1.ListOptions{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list nodes. %+v", err)
+	}
+
+	placement := cephv1.GetMonPlacement(c.spec.Placement)
+	key := c.zoneTopologyKey()
+	zones := map[string]bool{}
+	for _, node := range nodes.Items {
+		zone, ok := node.Labels[key]
+		if !ok {
+			continue
+		}
+		valid, err := k8sutil.ValidNode(node, placement)
+		if err != nil {
+			logger.Warningf("failed to check if node %s is valid for mons. %+v", node.Name, err)
+			continue
+		}
+		if valid {
+			zones[zone] = true
+		}
+	}
+	return len(zones), nil
 }
 
-func (c *Cluster) startMons(targetCount int) error {
+// checkZoneSpread returns whether each mon can be required to run in a different zone. If there are not
+// enough zones, the mons are spread as much as possible but a zone failure may break the quorum.
+func checkZoneSpread(monCount, zoneCount int, topologyKey string) bool {
+	if zoneCount >= monCount {
+		logger.Infof("placing the %d mons in separate zones (topology key %s)", monCount, topologyKey)
+		return true
+	}
+	logger.Warningf("only %d zones are available for %d mons with the topology key %s. the mons will be spread across the zones as much as possible, but the quorum may not survive the failure of a zone",
+		zoneCount, monCount, topologyKey)
+	return false
+}
+
+func (c *Cluster) startMons(ctx context.Context, targetCount int) error {
 	// init the mon config
 	existingCount, mons := c.initMonConfig(targetCount)
 
@@ -239,6 +315,9 @@ func (c *Cluster) startMons(targetCount int) error {
 	if existingCount < len(mons) {
 		// Start the new mons one at a time
 		for i := existingCount; i < targetCount; i++ {
+			if err := ctx.Err(); err != nil {
+				return fmt.Errorf("canceled the start of the mons. %+v", err)
+			}
 			if err := c.ensureMonsRunning(mons, i, targetCount, true); err != nil {
 				return err
 			}
@@ -356,6 +435,11 @@ func (c *Cluster) initClusterInfo(cephVersion cephver.CephVersion) error {
 		return fmt.Errorf("failed to get cluster info. %+v", err)
 	}
 
+	// finish a rotation of the admin key that was interrupted, e.g. by a restart of the operator
+	if err := c.reconcilePendingAdminKey(); err != nil {
+		return err
+	}
+
 	// save cluster monitor config
 	if err = c.saveMonConfig(); err != nil {
 		return fmt.Errorf("failed to save mons. %+v", err)
@@ -976,6 +1060,42 @@ func waitForQuorumWithMons(context *clusterd.Context, clusterName string, mons [
 	return nil
 }
 
+// WaitForQuorumMajority waits until a majority of the mons in the mon map are in quorum. The mons may have
+// been started while some of them are still syncing, in which case the other daemons would fail to connect.
+func (c *Cluster) WaitForQuorumMajority() error {
+	if !c.waitForStart {
+		return nil
+	}
+	return waitForQuorumMajority(c.context, c.ClusterInfo.Name, quorumMajorityCheckInterval, quorumMajorityTimeout)
+}
+
+func waitForQuorumMajority(context *clusterd.Context, clusterName string, interval, timeout time.Duration) error {
+	logger.Infof("waiting for a majority of the mons to be in quorum")
+	deadline := time.Now().Add(timeout)
+	for {
+		quorumStatus, err := client.GetMonQuorumStatus(context, clusterName, false)
+		if err != nil {
+			logger.Debugf("failed to get quorum_status. %+v", err)
+		} else if hasQuorumMajority(quorumStatus) {
+			logQuorumMembers(quorumStatus)
+			return nil
+		} else {
+			logger.Infof("%d of %d mons are in quorum", len(quorumStatus.Quorum), len(quorumStatus.MonMap.Mons))
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for a majority of the mons to be in quorum", timeout.String())
+		}
+		<-time.After(interval)
+	}
+}
+
+// hasQuorumMajority returns whether more than half of the mons in the mon map are in quorum
+func hasQuorumMajority(quorumStatus client.MonStatusResponse) bool {
+	monCount := len(quorumStatus.MonMap.Mons)
+	return monCount > 0 && len(quorumStatus.Quorum) > monCount/2
+}
+
 func logQuorumMembers(monStatusResp client.MonStatusResponse) {
 	var monsInQuorum []string
 	for _, m := range monStatusResp.MonMap.Mons {
@@ -1020,6 +1140,59 @@ func (c *Cluster) acquireOrchestrationLock() {
 }
 
 func (c *Cluster) releaseOrchestrationLock() {
+	c.publishInfoSnapshot()
 	c.orchestrationMutex.Unlock()
 	logger.Debugf("Released lock for mon orchestration")
 }
+
+// publishInfoSnapshot copies the cluster info for the readers outside of the orchestration lock. The orchestration
+// and the health checks of the mons change the cluster info while they hold the lock, so the copy is taken before
+// the lock is released.
+func (c *Cluster) publishInfoSnapshot() {
+	var snapshot *infoSnapshot
+	if c.ClusterInfo != nil {
+		snapshot = &infoSnapshot{
+			fsid:        c.ClusterInfo.FSID,
+			cephVersion: c.ClusterInfo.CephVersion,
+			monitors:    make([]cephconfig.MonInfo, 0, len(c.ClusterInfo.Monitors)),
+		}
+		for _, mon := range c.ClusterInfo.Monitors {
+			snapshot.monitors = append(snapshot.monitors, *mon)
+		}
+		sort.Slice(snapshot.monitors, func(i, j int) bool { return snapshot.monitors[i].Name < snapshot.monitors[j].Name })
+	}
+	c.infoMux.Lock()
+	c.info = snapshot
+	c.infoMux.Unlock()
+}
+
+// RefreshInfoSnapshot updates the copy of the cluster info returned by InfoSnapshot, e.g. after the cluster info was
+// set without an orchestration of the mons. It waits for a running orchestration.
+func (c *Cluster) RefreshInfoSnapshot() {
+	c.acquireOrchestrationLock()
+	c.releaseOrchestrationLock()
+}
+
+// Identity returns the fsid and the ceph version of the cluster info as of the last time the orchestration lock was
+// released, without copying the monitors. It does not wait for a running orchestration.
+func (c *Cluster) Identity() (string, cephver.CephVersion) {
+	c.infoMux.RLock()
+	defer c.infoMux.RUnlock()
+	if c.info == nil {
+		return "", cephver.CephVersion{}
+	}
+	return c.info.fsid, c.info.cephVersion
+}
+
+// InfoSnapshot returns the fsid, the ceph version and a copy of the monitors of the cluster info as of the last
+// time the orchestration lock was released. It does not wait for a running orchestration.
+func (c *Cluster) InfoSnapshot() (string, cephver.CephVersion, []cephconfig.MonInfo) {
+	c.infoMux.RLock()
+	defer c.infoMux.RUnlock()
+	if c.info == nil {
+		return "", cephver.CephVersion{}, nil
+	}
+	monitors := make([]cephconfig.MonInfo, len(c.info.monitors))
+	copy(monitors, c.info.monitors)
+	return c.info.fsid, c.info.cephVersion, monitors
+}
@@ -17,6 +17,8 @@ limitations under the License.
 package mon
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -142,25 +144,27 @@ func TestResourceName(t *testing.T) {
 }
 
 func TestStartMonPods(t *testing.T) {
+	ctx := context.Background()
 
 	namespace := "ns"
 	context := newTestStartCluster(namespace)
 	c := newCluster(context, namespace, cephv1.NetworkSpec{}, true, v1.ResourceRequirements{})
 
 	// start a basic cluster
-	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	_, err := c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Nil(t, err)
 
 	validateStart(t, c)
 
 	// starting again should be a no-op, but still results in an error
-	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	_, err = c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Nil(t, err)
 
 	validateStart(t, c)
 }
 
 func TestOperatorRestart(t *testing.T) {
+	ctx := context.Background()
 
 	namespace := "ns"
 	context := newTestStartCluster(namespace)
@@ -168,7 +172,7 @@ func TestOperatorRestart(t *testing.T) {
 	c.ClusterInfo = test.CreateConfigDir(1)
 
 	// start a basic cluster
-	info, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	info, err := c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Nil(t, err)
 	assert.True(t, info.IsInitialized())
 
@@ -177,7 +181,7 @@ func TestOperatorRestart(t *testing.T) {
 	c = newCluster(context, namespace, cephv1.NetworkSpec{}, true, v1.ResourceRequirements{})
 
 	// starting again should be a no-op, but will not result in an error
-	info, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	info, err = c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Nil(t, err)
 	assert.True(t, info.IsInitialized())
 
@@ -186,6 +190,7 @@ func TestOperatorRestart(t *testing.T) {
 
 // safety check that if hostNetwork is used no changes occur on an operator restart
 func TestOperatorRestartHostNetwork(t *testing.T) {
+	ctx := context.Background()
 
 	namespace := "ns"
 	context := newTestStartCluster(namespace)
@@ -195,7 +200,7 @@ func TestOperatorRestartHostNetwork(t *testing.T) {
 	c.ClusterInfo = test.CreateConfigDir(1)
 
 	// start a basic cluster
-	info, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	info, err := c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Nil(t, err)
 	assert.True(t, info.IsInitialized())
 
@@ -205,7 +210,7 @@ func TestOperatorRestartHostNetwork(t *testing.T) {
 	c = newCluster(context, namespace, cephv1.NetworkSpec{HostNetwork: true}, false, v1.ResourceRequirements{})
 
 	// starting again should be a no-op, but still results in an error
-	info, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	info, err = c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Nil(t, err)
 	assert.True(t, info.IsInitialized(), info)
 
@@ -342,3 +347,80 @@ func TestMonFoundInQuorum(t *testing.T) {
 	assert.True(t, monFoundInQuorum("c", response))
 	assert.False(t, monFoundInQuorum("d", response))
 }
+
+func TestWaitForQuorumMajority(t *testing.T) {
+	namespace := "ns"
+	quorumChecks := 0
+	quorumResponse := func() (string, error) {
+		quorumChecks++
+		resp := client.MonStatusResponse{Quorum: []int{0}}
+		resp.MonMap.Mons = []client.MonMapEntry{{Name: "a", Rank: 0}, {Name: "b", Rank: 1}, {Name: "c", Rank: 2}}
+		if quorumChecks == 1 {
+			return "", fmt.Errorf("test error")
+		}
+		if quorumChecks > 2 {
+			// the second mon joined quorum
+			resp.Quorum = []int{0, 1}
+		}
+		serialized, _ := json.Marshal(resp)
+		return string(serialized), nil
+	}
+	context := newTestStartClusterWithQuorumResponse(namespace, quorumResponse)
+	err := waitForQuorumMajority(context, namespace, time.Millisecond, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, quorumChecks)
+
+	// times out if the majority is never reached
+	quorumChecks = 0
+	quorumResponse = func() (string, error) {
+		return clienttest.MonInQuorumResponseMany(2), nil
+	}
+	context = newTestStartClusterWithQuorumResponse(namespace, quorumResponse)
+	err = waitForQuorumMajority(context, namespace, time.Millisecond, 10*time.Millisecond)
+	assert.NotNil(t, err)
+}
+
+func TestHasQuorumMajority(t *testing.T) {
+	response := client.MonStatusResponse{}
+	assert.False(t, hasQuorumMajority(response))
+
+	response.MonMap.Mons = []client.MonMapEntry{{Name: "a", Rank: 0}, {Name: "b", Rank: 1}, {Name: "c", Rank: 2}}
+	response.Quorum = []int{0}
+	assert.False(t, hasQuorumMajority(response))
+	response.Quorum = []int{0, 2}
+	assert.True(t, hasQuorumMajority(response))
+	response.Quorum = []int{0, 1, 2}
+	assert.True(t, hasQuorumMajority(response))
+}
+
+func TestInfoSnapshot(t *testing.T) {
+	c := &Cluster{}
+	fsid, _, monitors := c.InfoSnapshot()
+	assert.Equal(t, "", fsid)
+	assert.Nil(t, monitors)
+
+	// the snapshot is taken when the orchestration lock is released
+	c.acquireOrchestrationLock()
+	c.ClusterInfo = &cephconfig.ClusterInfo{
+		FSID:        "myfsid",
+		CephVersion: cephver.Nautilus,
+		Monitors: map[string]*cephconfig.MonInfo{
+			"b": {Name: "b", Endpoint: "1.2.3.5:6789"},
+			"a": {Name: "a", Endpoint: "1.2.3.4:6789"},
+		},
+	}
+	fsid, _, _ = c.InfoSnapshot()
+	assert.Equal(t, "", fsid)
+	c.releaseOrchestrationLock()
+
+	// the snapshot is read while an orchestration holds the lock
+	c.acquireOrchestrationLock()
+	defer c.releaseOrchestrationLock()
+	fsid, cephVersion, monitors := c.InfoSnapshot()
+	assert.Equal(t, "myfsid", fsid)
+	assert.Equal(t, cephver.Nautilus, cephVersion)
+	assert.Equal(t, []cephconfig.MonInfo{{Name: "a", Endpoint: "1.2.3.4:6789"}, {Name: "b", Endpoint: "1.2.3.5:6789"}}, monitors)
+	fsid, cephVersion = c.Identity()
+	assert.Equal(t, "myfsid", fsid)
+	assert.Equal(t, cephver.Nautilus, cephVersion)
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package mon
 
 import (
+	"context"
 	"strings"
 	"sync"
 	"testing"
@@ -71,6 +72,7 @@ func TestNodeAffinity(t *testing.T) {
 
 // this tests can 3 mons with hostnetworking on the same host is rejected
 func TestHostNetworkSameNode(t *testing.T) {
+	ctx := context.Background()
 	namespace := "ns"
 	context := newTestStartCluster(namespace)
 
@@ -79,11 +81,12 @@ func TestHostNetworkSameNode(t *testing.T) {
 	c.ClusterInfo = test.CreateConfigDir(1)
 
 	// start a basic cluster
-	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	_, err := c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Error(t, err)
 }
 
 func TestPodMemory(t *testing.T) {
+	ctx := context.Background()
 	namespace := "ns"
 	context := newTestStartCluster(namespace)
 
@@ -97,7 +100,7 @@ func TestPodMemory(t *testing.T) {
 	c := newCluster(context, namespace, cephv1.NetworkSpec{}, true, r)
 	c.ClusterInfo = test.CreateConfigDir(1)
 	// start a basic cluster
-	_, err := c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	_, err := c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Error(t, err)
 
 	// Test REQUEST == LIMIT
@@ -113,7 +116,7 @@ func TestPodMemory(t *testing.T) {
 	c = newCluster(context, namespace, cephv1.NetworkSpec{}, true, r)
 	c.ClusterInfo = test.CreateConfigDir(1)
 	// start a basic cluster
-	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	_, err = c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Error(t, err)
 
 	// Test LIMIT != REQUEST but obviously LIMIT > REQUEST
@@ -129,7 +132,7 @@ func TestPodMemory(t *testing.T) {
 	c = newCluster(context, namespace, cephv1.NetworkSpec{}, true, r)
 	c.ClusterInfo = test.CreateConfigDir(1)
 	// start a basic cluster
-	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	_, err = c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Error(t, err)
 
 	// Test valid case where pod resource is set approprietly
@@ -145,7 +148,7 @@ func TestPodMemory(t *testing.T) {
 	c = newCluster(context, namespace, cephv1.NetworkSpec{}, true, r)
 	c.ClusterInfo = test.CreateConfigDir(1)
 	// start a basic cluster
-	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	_, err = c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Nil(t, err)
 
 	// Test no resources were specified on the pod
@@ -153,7 +156,7 @@ func TestPodMemory(t *testing.T) {
 	c = newCluster(context, namespace, cephv1.NetworkSpec{}, true, r)
 	c.ClusterInfo = test.CreateConfigDir(1)
 	// start a basic cluster
-	_, err = c.Start(c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
+	_, err = c.Start(ctx, c.ClusterInfo, c.rookVersion, cephver.Mimic, c.spec, false)
 	assert.Nil(t, err)
 
 }
@@ -175,6 +175,23 @@ func (c *Cluster) setPodPlacement(pod *v1.PodSpec, p rook.Placement, nodeSelecto
 				PodAffinityTerm: monAntiAffinity,
 			})
 	}
+
+	// spread the mons across zones. the rule is only required when there are enough zones for all the mons,
+	// otherwise the mons could not all be scheduled.
+	if c.spec.Mon.ZoneSpread {
+		zoneAntiAffinity := monAntiAffinity
+		zoneAntiAffinity.TopologyKey = c.zoneTopologyKey()
+		if c.requireZoneSpread {
+			paa.RequiredDuringSchedulingIgnoredDuringExecution =
+				append(paa.RequiredDuringSchedulingIgnoredDuringExecution, zoneAntiAffinity)
+		} else {
+			paa.PreferredDuringSchedulingIgnoredDuringExecution =
+				append(paa.PreferredDuringSchedulingIgnoredDuringExecution, v1.WeightedPodAffinityTerm{
+					Weight:          100,
+					PodAffinityTerm: zoneAntiAffinity,
+				})
+		}
+	}
 }
 
 func (c *Cluster) makeMonPod(monConfig *monConfig) *v1.Pod {
@@ -196,6 +213,7 @@ func (c *Cluster) makeMonPod(monConfig *monConfig) *v1.Pod {
 	if c.Network.IsHost() {
 		podSpec.DNSPolicy = v1.DNSClusterFirstWithHostNet
 	}
+	c.spec.CephVersion.ApplyImagePullPolicyToPodSpec(&podSpec)
 
 	pod := &v1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
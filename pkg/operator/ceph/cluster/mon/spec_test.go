@@ -17,6 +17,7 @@ limitations under the License.
 package mon
 
 import (
+	"fmt"
 	"sync"
 	"testing"
 
@@ -220,3 +221,44 @@ func TestPodSpecPlacement(t *testing.T) {
 	p = makePlacement()
 	testPodSpecPlacement(t, false, false, 2, 1, &p)
 }
+
+func TestPodSpecZoneSpread(t *testing.T) {
+	clientset := testop.New(3)
+	for i, zone := range []string{"a", "b", "b"} {
+		node, _ := clientset.CoreV1().Nodes().Get(fmt.Sprintf("node%d", i), metav1.GetOptions{})
+		node.Labels = map[string]string{"topology.example.com/zone": zone}
+		clientset.CoreV1().Nodes().Update(node)
+	}
+	c := New(&clusterd.Context{Clientset: clientset, ConfigDir: "/var/lib/rook"}, "ns", "/var/lib/rook", cephv1.NetworkSpec{}, metav1.OwnerReference{}, &sync.Mutex{}, false)
+	setCommonMonProperties(c, 0, cephv1.MonSpec{Count: 3}, "rook/rook:myversion")
+	c.spec.Mon.ZoneSpread = true
+	c.spec.Mon.ZoneTopologyKey = "topology.example.com/zone"
+
+	zones, err := c.countMonZones()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, zones)
+	assert.False(t, checkZoneSpread(3, zones, c.zoneTopologyKey()))
+	assert.True(t, checkZoneSpread(2, zones, c.zoneTopologyKey()))
+
+	// not enough zones, the zone anti-affinity is only preferred
+	d := c.makeDeployment(testGenMonConfig("a"))
+	c.setPodPlacement(&d.Spec.Template.Spec, cephv1.GetMonPlacement(c.spec.Placement), nil)
+	paa := d.Spec.Template.Spec.Affinity.PodAntiAffinity
+	assert.Equal(t, 1, len(paa.RequiredDuringSchedulingIgnoredDuringExecution))
+	assert.Equal(t, v1.LabelHostname, paa.RequiredDuringSchedulingIgnoredDuringExecution[0].TopologyKey)
+	assert.Equal(t, 1, len(paa.PreferredDuringSchedulingIgnoredDuringExecution))
+	assert.Equal(t, "topology.example.com/zone", paa.PreferredDuringSchedulingIgnoredDuringExecution[0].PodAffinityTerm.TopologyKey)
+
+	// enough zones, the zone anti-affinity is required
+	c.requireZoneSpread = true
+	d = c.makeDeployment(testGenMonConfig("a"))
+	c.setPodPlacement(&d.Spec.Template.Spec, cephv1.GetMonPlacement(c.spec.Placement), nil)
+	paa = d.Spec.Template.Spec.Affinity.PodAntiAffinity
+	assert.Equal(t, 2, len(paa.RequiredDuringSchedulingIgnoredDuringExecution))
+	assert.Equal(t, "topology.example.com/zone", paa.RequiredDuringSchedulingIgnoredDuringExecution[1].TopologyKey)
+	assert.Equal(t, 0, len(paa.PreferredDuringSchedulingIgnoredDuringExecution))
+
+	// the default topology key
+	c.spec.Mon.ZoneTopologyKey = ""
+	assert.Equal(t, v1.LabelZoneFailureDomain, c.zoneTopologyKey())
+}
@@ -0,0 +1,151 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultMonAutoScaleMinCount = 3
+	defaultMonAutoScaleMaxCount = 5
+	// the number of storage nodes from which 5 mons are recommended instead of 3, which leaves spare nodes for the
+	// failover of the mons
+	monAutoScaleLargeClusterNodes = 7
+)
+
+// validateMonAutoScale checks the mode and the bounds of the mon scaling. The bounds are not checked when the mons
+// are not scaled automatically.
+func validateMonAutoScale(spec cephv1.MonAutoScaleSpec) error {
+	switch spec.Mode {
+	case "":
+		return nil
+	case cephv1.MonAutoScaleRecommend, cephv1.MonAutoScaleApply:
+	default:
+		return fmt.Errorf("invalid mon autoScale mode %q, it must be %q or %q", spec.Mode, cephv1.MonAutoScaleRecommend, cephv1.MonAutoScaleApply)
+	}
+	minCount, maxCount := monAutoScaleBounds(spec)
+	if minCount%2 == 0 || maxCount%2 == 0 {
+		return fmt.Errorf("the mon autoScale minCount %d and maxCount %d must be odd", minCount, maxCount)
+	}
+	if minCount > maxCount {
+		return fmt.Errorf("the mon autoScale minCount %d is larger than the maxCount %d", minCount, maxCount)
+	}
+	return nil
+}
+
+func monAutoScaleBounds(spec cephv1.MonAutoScaleSpec) (int, int) {
+	minCount := spec.MinCount
+	if minCount <= 0 {
+		minCount = defaultMonAutoScaleMinCount
+	}
+	maxCount := spec.MaxCount
+	if maxCount <= 0 {
+		maxCount = defaultMonAutoScaleMaxCount
+	}
+	return minCount, maxCount
+}
+
+// recommendedMonCount returns the mon count for the number of storage nodes: 3 mons for the small clusters and 5
+// mons for the large clusters, within the bounds of the spec. When a node cannot run several mons, the count does
+// not exceed the number of nodes. The count is always odd.
+func recommendedMonCount(spec cephv1.MonAutoScaleSpec, nodes int, allowMultiplePerNode bool) int {
+	count := 3
+	if nodes >= monAutoScaleLargeClusterNodes {
+		count = 5
+	}
+	minCount, maxCount := monAutoScaleBounds(spec)
+	if count < minCount {
+		count = minCount
+	}
+	if count > maxCount {
+		count = maxCount
+	}
+	if !allowMultiplePerNode && nodes > 0 && count > nodes {
+		count = nodes
+	}
+	if count%2 == 0 {
+		count--
+	}
+	return count
+}
+
+// monCount returns the number of mons the orchestration starts. When the mons are scaled automatically, the
+// recommended count is recorded in the status and replaces the count of the spec in the "apply" mode.
+func (c *cluster) monCount(spec *cephv1.ClusterSpec) int {
+	autoScale := spec.Mon.AutoScale
+	if autoScale.Mode == "" {
+		return spec.Mon.Count
+	}
+	nodes, err := c.storageNodeCount(spec)
+	if err != nil {
+		logger.Warningf("failed to count the storage nodes of cluster %s, keeping the mon count of the spec. %+v", c.Namespace, err)
+		return spec.Mon.Count
+	}
+	count := recommendedMonCount(autoScale, nodes, spec.Mon.AllowMultiplePerNode)
+	c.updateRecommendedMonCount(count)
+	if autoScale.Mode != cephv1.MonAutoScaleApply {
+		if count != spec.Mon.Count {
+			logger.Infof("%d mons are recommended for the %d storage nodes of cluster %s, the spec has %d mons", count, nodes, c.Namespace, spec.Mon.Count)
+		}
+		return spec.Mon.Count
+	}
+	if count != spec.Mon.Count {
+		logger.Infof("scaling cluster %s to %d mons for its %d storage nodes instead of the %d mons of the spec", c.Namespace, count, nodes, spec.Mon.Count)
+	}
+	return count
+}
+
+// storageNodeCount returns the number of the nodes of the storage spec that can run OSDs
+func (c *cluster) storageNodeCount(spec *cephv1.ClusterSpec) (int, error) {
+	storage := spec.Storage
+	if storage.UseAllNodes {
+		hostnames, err := k8sutil.GetNodeHostNames(c.context.Clientset)
+		if err != nil {
+			return 0, fmt.Errorf("failed to get the node hostnames. %+v", err)
+		}
+		storage.Nodes = nil
+		for name, hostname := range hostnames {
+			if hostname == "" {
+				hostname = name
+			}
+			storage.Nodes = append(storage.Nodes, rookalpha.Node{Name: hostname})
+		}
+	}
+	return len(k8sutil.GetValidNodes(storage, c.context.Clientset, cephv1.GetOSDPlacement(spec.Placement))), nil
+}
+
+// updateRecommendedMonCount records the recommended mon count in the status of the CephCluster CR
+func (c *cluster) updateRecommendedMonCount(count int) {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to update the recommended mon count. %+v", c.Namespace, err)
+		return
+	}
+	if cephCluster.Status.RecommendedMonCount == count {
+		return
+	}
+	cephCluster.Status.RecommendedMonCount = count
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to update the recommended mon count of cluster %s. %+v", c.Namespace, err)
+	}
+}
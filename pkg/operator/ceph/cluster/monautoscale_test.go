@@ -0,0 +1,89 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecommendedMonCount(t *testing.T) {
+	spec := cephv1.MonAutoScaleSpec{Mode: cephv1.MonAutoScaleApply}
+	assert.Equal(t, 3, recommendedMonCount(spec, 0, false))
+	assert.Equal(t, 3, recommendedMonCount(spec, 6, false))
+	assert.Equal(t, 5, recommendedMonCount(spec, 7, false))
+	assert.Equal(t, 5, recommendedMonCount(spec, 100, false))
+
+	// the count stays within the bounds and odd
+	assert.Equal(t, 3, recommendedMonCount(cephv1.MonAutoScaleSpec{MaxCount: 4}, 100, false))
+	assert.Equal(t, 7, recommendedMonCount(cephv1.MonAutoScaleSpec{MinCount: 7, MaxCount: 9}, 3, true))
+	assert.Equal(t, 1, recommendedMonCount(cephv1.MonAutoScaleSpec{MinCount: 1, MaxCount: 1}, 100, false))
+
+	// the count does not exceed the nodes if there is a single mon per node
+	assert.Equal(t, 3, recommendedMonCount(cephv1.MonAutoScaleSpec{MinCount: 7, MaxCount: 9}, 3, false))
+	assert.Equal(t, 1, recommendedMonCount(cephv1.MonAutoScaleSpec{MinCount: 3}, 2, false))
+	assert.Equal(t, 3, recommendedMonCount(cephv1.MonAutoScaleSpec{MinCount: 3}, 2, true))
+
+	assert.Nil(t, validateMonAutoScale(cephv1.MonAutoScaleSpec{}))
+	// the bounds are ignored when the mons are not scaled automatically
+	assert.Nil(t, validateMonAutoScale(cephv1.MonAutoScaleSpec{MinCount: 4}))
+	assert.Nil(t, validateMonAutoScale(spec))
+	assert.NotNil(t, validateMonAutoScale(cephv1.MonAutoScaleSpec{Mode: "always"}))
+	assert.NotNil(t, validateMonAutoScale(cephv1.MonAutoScaleSpec{Mode: cephv1.MonAutoScaleApply, MinCount: 4}))
+	assert.NotNil(t, validateMonAutoScale(cephv1.MonAutoScaleSpec{Mode: cephv1.MonAutoScaleApply, MinCount: 5, MaxCount: 3}))
+}
+
+func TestMonCount(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(8),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+	spec := &cephv1.ClusterSpec{
+		Mon:     cephv1.MonSpec{Count: 3},
+		Storage: rookalpha.StorageScopeSpec{UseAllNodes: true},
+	}
+
+	// the count of the spec is used by default
+	assert.Equal(t, 3, c.monCount(spec))
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, updated.Status.RecommendedMonCount)
+
+	// the recommended count is only recorded
+	spec.Mon.AutoScale.Mode = cephv1.MonAutoScaleRecommend
+	assert.Equal(t, 3, c.monCount(spec))
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 5, updated.Status.RecommendedMonCount)
+
+	// the recommended count is applied
+	spec.Mon.AutoScale.Mode = cephv1.MonAutoScaleApply
+	assert.Equal(t, 5, c.monCount(spec))
+
+	// only the nodes of the storage spec are counted
+	spec.Storage = rookalpha.StorageScopeSpec{Nodes: []rookalpha.Node{{Name: "node0"}, {Name: "node1"}, {Name: "node2"}}}
+	assert.Equal(t, 3, c.monCount(spec))
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the permissions the mgr needs in the rules namespace to deploy the prometheus rule and remove the stale rules
+var prometheusRulePermissions = []rbacPermission{
+	{verb: "create", group: "monitoring.coreos.com", resource: "prometheusrules"},
+	{verb: "update", group: "monitoring.coreos.com", resource: "prometheusrules"},
+	{verb: "list", group: "monitoring.coreos.com", resource: "prometheusrules"},
+	{verb: "delete", group: "monitoring.coreos.com", resource: "prometheusrules"},
+}
+
+// checkMonitoringRulesNamespace checks that the rules namespace of the monitoring spec exists and that the operator
+// can write the prometheus rules to it. It returns whether the prometheus rule can be deployed, the rule is
+// deployed when the permissions cannot be reviewed.
+func (c *cluster) checkMonitoringRulesNamespace(spec cephv1.MonitoringSpec) bool {
+	if !spec.Enabled {
+		c.reportCondition(cephv1.ClusterConditionMonitoringRulesNamespaceUnavailable, "", "MonitoringDisabled", "monitoring is disabled")
+		return true
+	}
+
+	namespace := spec.RulesNamespace
+	if namespace == "" {
+		namespace = c.Namespace
+	}
+	message, err := c.rulesNamespaceUnavailable(namespace)
+	if err != nil {
+		logger.Warningf("failed to check the monitoring rules namespace %s. %+v", namespace, err)
+		return true
+	}
+	c.reportCondition(cephv1.ClusterConditionMonitoringRulesNamespaceUnavailable, message,
+		"MonitoringRulesNamespaceAvailable", "the prometheus rules can be deployed to the rules namespace")
+	return message == ""
+}
+
+// rulesNamespaceUnavailable returns why the prometheus rules cannot be deployed to the namespace, or an empty string
+// if they can be deployed
+func (c *cluster) rulesNamespaceUnavailable(namespace string) (string, error) {
+	// the existence is not checked if the rbac of the operator does not allow to get the namespaces
+	if _, err := c.context.Clientset.CoreV1().Namespaces().Get(namespace, metav1.GetOptions{}); err != nil && !errors.IsForbidden(err) {
+		if !errors.IsNotFound(err) {
+			return "", fmt.Errorf("failed to get namespace %s. %+v", namespace, err)
+		}
+		return fmt.Sprintf("the monitoring rules namespace %s does not exist, the prometheus rules are not deployed", namespace), nil
+	}
+	missing, err := c.missingPermissions(namespace, prometheusRulePermissions)
+	if err != nil {
+		return "", err
+	}
+	if len(missing) > 0 {
+		return fmt.Sprintf("the service account of the operator is not allowed to %s in the monitoring rules namespace %s, the prometheus rules are not deployed",
+			strings.Join(missing, ", "), namespace), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,81 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	authv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestCheckMonitoringRulesNamespace(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	clientset := testop.New(1)
+	context := &clusterd.Context{
+		Clientset:     clientset,
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+
+	allowed := true
+	clientset.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		assert.Equal(t, "monitoring", attributes.Namespace)
+		review.Status.Allowed = allowed || attributes.Verb != "delete"
+		return true, review, nil
+	})
+	condition := func() cephv1.ClusterCondition {
+		updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+		assert.Nil(t, err)
+		return updated.Status.Conditions[0]
+	}
+
+	// the rules are deployed when monitoring is disabled
+	spec := cephv1.MonitoringSpec{RulesNamespace: "monitoring"}
+	assert.True(t, c.checkMonitoringRulesNamespace(spec))
+	assert.Equal(t, v1.ConditionFalse, condition().Status)
+
+	// the rules namespace does not exist
+	spec.Enabled = true
+	assert.False(t, c.checkMonitoringRulesNamespace(spec))
+	assert.Equal(t, cephv1.ClusterConditionMonitoringRulesNamespaceUnavailable, condition().Type)
+	assert.Equal(t, v1.ConditionTrue, condition().Status)
+	assert.Contains(t, condition().Message, "monitoring does not exist")
+
+	// the operator cannot write to the rules namespace
+	_, err := clientset.CoreV1().Namespaces().Create(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "monitoring"}})
+	assert.Nil(t, err)
+	allowed = false
+	assert.False(t, c.checkMonitoringRulesNamespace(spec))
+	assert.Equal(t, v1.ConditionTrue, condition().Status)
+	assert.Contains(t, condition().Message, "delete prometheusrules.monitoring.coreos.com")
+
+	// the condition is cleared once the permissions are granted
+	allowed = true
+	assert.True(t, c.checkMonitoringRulesNamespace(spec))
+	assert.Equal(t, v1.ConditionFalse, condition().Status)
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"reflect"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	v1 "k8s.io/api/core/v1"
+)
+
+const (
+	// confirmNetworkChangeAnnotation on the CephCluster CR confirms that the network of a running cluster can
+	// be changed to the network of the spec. The annotation is removed once the change was applied.
+	confirmNetworkChangeAnnotation = "ceph.rook.io/confirm-network-change"
+)
+
+// networkChangeConfirmed returns whether the CephCluster CR confirms the change of the network
+func networkChangeConfirmed(cephCluster *cephv1.CephCluster) bool {
+	return cephCluster.GetAnnotations()[confirmNetworkChangeAnnotation] == "true"
+}
+
+// networkChanged returns whether the network of the spec differs from the network the daemons run with. Only the
+// settings that move the daemons to another network are compared, the other settings such as the DNS of the pods
+// are applied without a confirmation.
+func (c *cluster) networkChanged(spec cephv1.ClusterSpec) bool {
+	return daemonNetworkChanged(c.Spec.Network, spec.Network)
+}
+
+// daemonNetworkChanged returns whether the daemons are moved to another network by the change of the network settings
+func daemonNetworkChanged(old, new cephv1.NetworkSpec) bool {
+	if old.HostNetwork != new.HostNetwork || old.Provider != new.Provider {
+		return true
+	}
+	if len(old.Selectors) == 0 && len(new.Selectors) == 0 {
+		return false
+	}
+	return !reflect.DeepEqual(old.Selectors, new.Selectors)
+}
+
+// checkNetworkChange returns whether the change of the network of the running cluster to the network of the
+// updated CR can be applied. Rolling the daemons onto a new network can partition the cluster, so the change
+// must be confirmed with the annotation. Until then the change is reported in the status and not applied. The
+// change is only reported once, the updates of the CR that keep the same change are ignored.
+func (c *cluster) checkNetworkChange(cephCluster *cephv1.CephCluster) bool {
+	c.networkChangeConfirmed = networkChangeConfirmed(cephCluster)
+	if c.networkChangeConfirmed {
+		logger.Infof("the change of the network of cluster %s was confirmed", c.Namespace)
+		c.reportedNetworkChange = ""
+		c.reportCondition(cephv1.ClusterConditionNetworkChangePending, "", "NetworkChangeConfirmed", "the change of the network was confirmed")
+		return true
+	}
+
+	message := fmt.Sprintf("the network of cluster %s changed from %+v to %+v. the change is not applied since moving the daemons to a new network can break the connectivity to the mons. "+
+		"make sure all the nodes can reach each other on the new network, then set the annotation %s=true on the cluster CR to apply the change",
+		c.Namespace, c.Spec.Network, cephCluster.Spec.Network, confirmNetworkChangeAnnotation)
+	if c.reportedNetworkChange == message {
+		logger.Debugf("the network change of cluster %s is still not confirmed", c.Namespace)
+		return false
+	}
+	c.reportedNetworkChange = message
+	c.reportCondition(cephv1.ClusterConditionNetworkChangePending, message, "", "")
+	return false
+}
+
+// checkHeldNetworkChange returns whether a cluster can be started after the operator restarted. The network change
+// that was held before the restart is still pending in the status of the CR. The network the daemons run with is not
+// known anymore, so the cluster is not orchestrated until the change is confirmed.
+func (c *cluster) checkHeldNetworkChange(cephCluster *cephv1.CephCluster) bool {
+	c.networkChangeConfirmed = networkChangeConfirmed(cephCluster)
+	pending := false
+	for _, condition := range cephCluster.Status.Conditions {
+		if condition.Type == cephv1.ClusterConditionNetworkChangePending {
+			pending = condition.Status == v1.ConditionTrue
+		}
+	}
+	if !pending {
+		return true
+	}
+	if !c.networkChangeConfirmed {
+		logger.Warningf("not starting cluster %s until the pending change of the network is confirmed with the annotation %s=true",
+			c.Namespace, confirmNetworkChangeAnnotation)
+		return false
+	}
+	logger.Infof("the change of the network of cluster %s was confirmed", c.Namespace)
+	c.reportCondition(cephv1.ClusterConditionNetworkChangePending, "", "NetworkChangeConfirmed", "the change of the network was confirmed")
+	return true
+}
+
+// clearNetworkChangeConfirmation removes the confirmation annotation once the network change was applied, so
+// the next network change must be confirmed again
+func (c *cluster) clearNetworkChangeConfirmation() {
+	if !c.networkChangeConfirmed {
+		return
+	}
+	c.networkChangeConfirmed = false
+	c.removeAnnotation(confirmNetworkChangeAnnotation)
+}
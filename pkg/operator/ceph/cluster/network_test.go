@@ -0,0 +1,110 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckNetworkChange(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster.DeepCopy(), context, nil)
+
+	updated := cephCluster.DeepCopy()
+	assert.False(t, c.networkChanged(updated.Spec))
+	// the settings that keep the daemons on their network are not a network change
+	updated.Spec.Network.DNSPolicy = v1.DNSClusterFirst
+	updated.Spec.Network.Selectors = map[string]string{}
+	assert.False(t, c.networkChanged(updated.Spec))
+	updated.Spec.Network.Selectors = map[string]string{"public": "public-net"}
+	assert.True(t, c.networkChanged(updated.Spec))
+	updated.Spec.Network.Selectors = nil
+	updated.Spec.Network.HostNetwork = true
+	assert.True(t, c.networkChanged(updated.Spec))
+
+	// the change is not confirmed
+	assert.False(t, c.checkNetworkChange(updated))
+	cr, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cephv1.ClusterConditionNetworkChangePending, cr.Status.Conditions[0].Type)
+	assert.Equal(t, v1.ConditionTrue, cr.Status.Conditions[0].Status)
+	assert.Contains(t, cr.Status.Conditions[0].Message, confirmNetworkChangeAnnotation)
+	events, err := context.Clientset.CoreV1().Events("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events.Items))
+
+	// the event is only recorded once for the same change
+	assert.False(t, c.checkNetworkChange(updated))
+	events, err = context.Clientset.CoreV1().Events("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events.Items))
+
+	// the change is confirmed
+	cr.Annotations = map[string]string{confirmNetworkChangeAnnotation: "true"}
+	_, err = context.RookClientset.CephV1().CephClusters("ns").Update(cr)
+	assert.Nil(t, err)
+	updated.Annotations = cr.Annotations
+	assert.True(t, c.checkNetworkChange(updated))
+	cr, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1.ConditionFalse, cr.Status.Conditions[0].Status)
+
+	// the confirmation is removed once the change was applied
+	c.clearNetworkChangeConfirmation()
+	assert.False(t, c.networkChangeConfirmed)
+	cr, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	_, ok := cr.Annotations[confirmNetworkChangeAnnotation]
+	assert.False(t, ok)
+}
+
+func TestCheckHeldNetworkChange(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster.DeepCopy(), context, nil)
+
+	// a cluster without a pending change is started
+	assert.True(t, c.checkHeldNetworkChange(cephCluster))
+
+	// the change held before the restart of the operator must still be confirmed
+	cephCluster.Status.Conditions = []cephv1.ClusterCondition{{Type: cephv1.ClusterConditionNetworkChangePending, Status: v1.ConditionTrue}}
+	_, err := context.RookClientset.CephV1().CephClusters("ns").Update(cephCluster)
+	assert.Nil(t, err)
+	assert.False(t, c.checkHeldNetworkChange(cephCluster))
+
+	cephCluster.Annotations = map[string]string{confirmNetworkChangeAnnotation: "true"}
+	assert.True(t, c.checkHeldNetworkChange(cephCluster))
+	assert.True(t, c.networkChangeConfirmed)
+	cr, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1.ConditionFalse, cr.Status.Conditions[0].Status)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+const (
+	// the env var to set the number of clusters that are orchestrated at the same time. 0 means no limit.
+	maxConcurrentOrchestrationsEnvVar  = "ROOK_MAX_CONCURRENT_ORCHESTRATIONS"
+	defaultMaxConcurrentOrchestrations = 0
+)
+
+// orchestrationLimiter limits the number of clusters that are orchestrated at the same time, e.g. after a restart
+// of an operator that manages many clusters. The clusters beyond the limit wait in a queue and are orchestrated in
+// the order they arrived, so no cluster waits forever.
+type orchestrationLimiter struct {
+	mux     sync.Mutex
+	limit   int
+	running int
+	// the clusters waiting for a slot, in the order they arrived
+	queue []chan struct{}
+}
+
+// newOrchestrationLimiter creates a limiter of the orchestrations. 0 means no limit.
+func newOrchestrationLimiter(limit int) *orchestrationLimiter {
+	return &orchestrationLimiter{limit: limit}
+}
+
+// maxConcurrentOrchestrations parses the number of clusters that can be orchestrated at the same time
+func maxConcurrentOrchestrations(value string) int {
+	if value == "" {
+		return defaultMaxConcurrentOrchestrations
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		logger.Warningf("invalid value %q for %s, it must be an integer of at least 0. using %d", value, maxConcurrentOrchestrationsEnvVar, defaultMaxConcurrentOrchestrations)
+		return defaultMaxConcurrentOrchestrations
+	}
+	return count
+}
+
+// acquire waits until the orchestration of the cluster in the namespace can run, or until the context is canceled.
+// When it returns no error, the slot must be freed with release.
+func (l *orchestrationLimiter) acquire(ctx context.Context, namespace string) error {
+	if l == nil || l.limit == 0 {
+		return nil
+	}
+	l.mux.Lock()
+	if l.running < l.limit && len(l.queue) == 0 {
+		l.running++
+		l.mux.Unlock()
+		return nil
+	}
+	slot := make(chan struct{})
+	l.queue = append(l.queue, slot)
+	queuedOrchestrationsGauge.Set(float64(len(l.queue)))
+	logger.Infof("waiting for one of the %d running orchestrations to complete before orchestrating cluster %s. %d clusters are waiting", l.limit, namespace, len(l.queue))
+	l.mux.Unlock()
+
+	select {
+	case <-slot:
+		return nil
+	case <-ctx.Done():
+	}
+
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	for i, queued := range l.queue {
+		if queued == slot {
+			l.queue = append(l.queue[:i], l.queue[i+1:]...)
+			queuedOrchestrationsGauge.Set(float64(len(l.queue)))
+			return fmt.Errorf("canceled waiting to orchestrate cluster %s. %+v", namespace, ctx.Err())
+		}
+	}
+	// the slot was handed over while the wait was canceled, so it is passed on to the next cluster
+	l.releaseLocked()
+	return fmt.Errorf("canceled waiting to orchestrate cluster %s. %+v", namespace, ctx.Err())
+}
+
+// release frees the slot of an orchestration that completed. The slot is handed over to the cluster that waited
+// the longest.
+func (l *orchestrationLimiter) release() {
+	if l == nil || l.limit == 0 {
+		return
+	}
+	l.mux.Lock()
+	defer l.mux.Unlock()
+	l.releaseLocked()
+}
+
+func (l *orchestrationLimiter) releaseLocked() {
+	if len(l.queue) == 0 {
+		l.running--
+		return
+	}
+	next := l.queue[0]
+	l.queue = l.queue[1:]
+	queuedOrchestrationsGauge.Set(float64(len(l.queue)))
+	close(next)
+}
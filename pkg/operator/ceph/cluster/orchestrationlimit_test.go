@@ -0,0 +1,95 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func queuedOrchestrationsValue(t *testing.T) float64 {
+	metric := &dto.Metric{}
+	assert.Nil(t, queuedOrchestrationsGauge.Write(metric))
+	return metric.GetGauge().GetValue()
+}
+
+// waitForQueuedOrchestrations waits for the number of clusters in the queue of the limiter
+func waitForQueuedOrchestrations(t *testing.T, l *orchestrationLimiter, count int) {
+	for i := 0; i < 1000; i++ {
+		l.mux.Lock()
+		queued := len(l.queue)
+		l.mux.Unlock()
+		if queued == count {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	assert.Fail(t, "the clusters were not queued", "expected %d clusters in the queue", count)
+}
+
+func TestMaxConcurrentOrchestrations(t *testing.T) {
+	assert.Equal(t, 0, maxConcurrentOrchestrations(""))
+	assert.Equal(t, 3, maxConcurrentOrchestrations("3"))
+	assert.Equal(t, 0, maxConcurrentOrchestrations("-1"))
+	assert.Equal(t, 0, maxConcurrentOrchestrations("many"))
+}
+
+func TestOrchestrationLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	// the orchestrations are not limited by default
+	var nilLimiter *orchestrationLimiter
+	assert.Nil(t, nilLimiter.acquire(ctx, "ns"))
+	nilLimiter.release()
+	unlimited := newOrchestrationLimiter(0)
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, unlimited.acquire(ctx, "ns"))
+	}
+
+	l := newOrchestrationLimiter(1)
+	assert.Nil(t, l.acquire(ctx, "ns-a"))
+
+	// the clusters beyond the limit are orchestrated in the order they arrived
+	order := make(chan string, 2)
+	wait := func(namespace string) {
+		assert.Nil(t, l.acquire(ctx, namespace))
+		order <- namespace
+	}
+	go wait("ns-b")
+	waitForQueuedOrchestrations(t, l, 1)
+	go wait("ns-c")
+	waitForQueuedOrchestrations(t, l, 2)
+	assert.Equal(t, float64(2), queuedOrchestrationsValue(t))
+
+	l.release()
+	assert.Equal(t, "ns-b", <-order)
+	l.release()
+	assert.Equal(t, "ns-c", <-order)
+	assert.Equal(t, float64(0), queuedOrchestrationsValue(t))
+
+	// a canceled wait gives up its place in the queue
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.NotNil(t, l.acquire(canceled, "ns-d"))
+	assert.Equal(t, 0, len(l.queue))
+	l.release()
+	assert.Equal(t, 0, l.running)
+}
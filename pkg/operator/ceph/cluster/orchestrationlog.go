@@ -0,0 +1,88 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// the number of messages of the last orchestration that are kept for the status of the cluster
+	maxOrchestrationMessages = 20
+	// the maximum length of a message or of the error in the status, so the CR stays small
+	maxOrchestrationMessageLength = 1024
+)
+
+// orchestrationLog keeps the key messages of the running orchestration, so the reason of a failed orchestration
+// can be reported in the status of the cluster for the users without access to the logs of the operator. Only
+// the last messages are kept. The orchestrations of a cluster do not run concurrently, so no lock is needed.
+type orchestrationLog struct {
+	messages []string
+	now      func() time.Time
+}
+
+// reset clears the messages at the start of an orchestration
+func (l *orchestrationLog) reset() {
+	l.messages = nil
+}
+
+// add records a message with its time, dropping the oldest message when the log is full
+func (l *orchestrationLog) add(format string, args ...interface{}) {
+	now := time.Now
+	if l.now != nil {
+		now = l.now
+	}
+	message := truncateStatusMessage(fmt.Sprintf("%s %s", formatTime(now().UTC()), fmt.Sprintf(format, args...)))
+	l.messages = append(l.messages, message)
+	if len(l.messages) > maxOrchestrationMessages {
+		l.messages = l.messages[len(l.messages)-maxOrchestrationMessages:]
+	}
+}
+
+// truncateStatusMessage shortens a message to the maximum length of the messages in the status
+func truncateStatusMessage(message string) string {
+	if len(message) <= maxOrchestrationMessageLength {
+		return message
+	}
+	const suffix = "... (truncated)"
+	return message[:maxOrchestrationMessageLength-len(suffix)] + suffix
+}
+
+// logOrchestration logs a key message of the orchestration and keeps it for the status of the cluster
+func (c *cluster) logOrchestration(format string, args ...interface{}) {
+	logger.Infof(format, args...)
+	c.orchestrationLog.add(format, args...)
+}
+
+// updateOrchestrationFailure records the error and the messages of a failed orchestration in the status of the
+// CephCluster CR. They are cleared by the next successful orchestration.
+func (c *cluster) updateOrchestrationFailure(orchestrationErr error) {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to record the orchestration failure. %+v", c.Namespace, err)
+		return
+	}
+
+	cephCluster.Status.LastOrchestrationError = truncateStatusMessage(orchestrationErr.Error())
+	cephCluster.Status.LastOrchestrationMessages = c.orchestrationLog.messages
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to record the orchestration failure of cluster %s. %+v", c.Namespace, err)
+	}
+}
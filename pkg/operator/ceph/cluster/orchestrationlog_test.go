@@ -0,0 +1,75 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOrchestrationLog(t *testing.T) {
+	l := &orchestrationLog{now: func() time.Time { return time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC) }}
+	l.add("starting the %s", "mons")
+	assert.Equal(t, []string{"2019-10-01T12:00:00Z starting the mons"}, l.messages)
+
+	// only the last messages are kept
+	for i := 0; i < maxOrchestrationMessages+5; i++ {
+		l.add("message %d", i)
+	}
+	assert.Equal(t, maxOrchestrationMessages, len(l.messages))
+	assert.Equal(t, "2019-10-01T12:00:00Z message 5", l.messages[0])
+
+	// the long messages are truncated
+	l.reset()
+	assert.Equal(t, 0, len(l.messages))
+	l.add("%s", strings.Repeat("a", 2*maxOrchestrationMessageLength))
+	assert.Equal(t, maxOrchestrationMessageLength, len(l.messages[0]))
+	assert.True(t, strings.HasSuffix(l.messages[0], "(truncated)"))
+}
+
+func TestUpdateOrchestrationFailure(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+
+	c.logOrchestration("starting the mons")
+	c.updateOrchestrationFailure(fmt.Errorf("failed to start the mons"))
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "failed to start the mons", updated.Status.LastOrchestrationError)
+	assert.Equal(t, 1, len(updated.Status.LastOrchestrationMessages))
+	assert.True(t, strings.HasSuffix(updated.Status.LastOrchestrationMessages[0], " starting the mons"))
+
+	// the failure is cleared by a successful orchestration
+	c.updateOrchestrationStatus(time.Now(), 1, "")
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", updated.Status.LastOrchestrationError)
+	assert.Equal(t, 0, len(updated.Status.LastOrchestrationMessages))
+}
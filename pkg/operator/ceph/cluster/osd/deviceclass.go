@@ -0,0 +1,92 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"strings"
+
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	osdconfig "github.com/rook/rook/pkg/operator/ceph/cluster/osd/config"
+)
+
+// applyDeviceClasses sets the device class requested in the storage spec on the running osds of a node. Ceph
+// detects the class when the osd is created, which is often wrong for virtual disks. The class in the config of a
+// device takes precedence over the class of the node. Osds that already have the requested class are skipped, so
+// the reconciles do not change the assignments over and over.
+func (c *Cluster) applyDeviceClasses(n *rookalpha.Node, osds []OSDInfo) {
+	nodeClass := n.Config[osdconfig.DeviceClassKey]
+	deviceClasses := map[string]string{}
+	for _, device := range n.Devices {
+		if class := device.Config[osdconfig.DeviceClassKey]; class != "" {
+			deviceClasses[device.Name] = class
+		}
+	}
+	if nodeClass == "" && len(deviceClasses) == 0 {
+		return
+	}
+
+	crushMap, err := client.GetCrushMap(c.context, c.Namespace)
+	if err != nil {
+		logger.Warningf("failed to get the crush map to set the device classes on node %s. %+v", n.Name, err)
+		return
+	}
+	currentClasses := map[int]string{}
+	for _, device := range crushMap.Devices {
+		currentClasses[device.ID] = device.Class
+	}
+
+	osdDevices := map[int][]string{}
+	if len(deviceClasses) > 0 {
+		metadata, err := client.GetOSDMetadata(c.context, c.Namespace)
+		if err != nil {
+			logger.Warningf("failed to get the osd metadata to set the device classes on node %s. %+v", n.Name, err)
+			return
+		}
+		for _, m := range metadata {
+			osdDevices[m.ID] = strings.Split(m.Devices, ",")
+		}
+	}
+
+	for _, osd := range osds {
+		desired := desiredDeviceClass(nodeClass, deviceClasses, osdDevices[osd.ID])
+		current, ok := currentClasses[osd.ID]
+		if !ok {
+			// the class can only be set once the osd was added to the crush map, the next reconcile will set it
+			logger.Debugf("osd %d is not in the crush map yet, not setting its device class", osd.ID)
+			continue
+		}
+		if desired == "" || desired == current {
+			continue
+		}
+		logger.Infof("setting the device class of osd %d from %q to %q", osd.ID, current, desired)
+		if err := client.SetDeviceClass(c.context, c.Namespace, osd.ID, desired); err != nil {
+			logger.Warningf("failed to set the device class of osd %d. %+v", osd.ID, err)
+		}
+	}
+}
+
+// desiredDeviceClass returns the device class of an osd. The class of a device of the osd takes precedence over
+// the class of the node.
+func desiredDeviceClass(nodeClass string, deviceClasses map[string]string, osdDevices []string) string {
+	for _, device := range osdDevices {
+		if class, ok := deviceClasses[strings.TrimPrefix(device, "/dev/")]; ok {
+			return class
+		}
+	}
+	return nodeClass
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package osd
+
+import (
+	"testing"
+
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDesiredDeviceClass(t *testing.T) {
+	deviceClasses := map[string]string{"sdb": "ssd"}
+	assert.Equal(t, "ssd", desiredDeviceClass("hdd", deviceClasses, []string{"sdb"}))
+	assert.Equal(t, "ssd", desiredDeviceClass("", deviceClasses, []string{"sda", "/dev/sdb"}))
+	assert.Equal(t, "hdd", desiredDeviceClass("hdd", deviceClasses, []string{"sdc"}))
+	assert.Equal(t, "", desiredDeviceClass("", deviceClasses, nil))
+}
+
+func TestApplyDeviceClasses(t *testing.T) {
+	setClasses := map[string]string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			if args[0] == "osd" && args[1] == "crush" && args[2] == "dump" {
+				return `{"devices":[{"id":0,"name":"osd.0","class":"hdd"},{"id":1,"name":"osd.1","class":"ssd"},{"id":2,"name":"osd.2","class":"hdd"}]}`, nil
+			}
+			if args[0] == "osd" && args[1] == "metadata" {
+				return `[{"id":0,"devices":"sdb"},{"id":1,"devices":"sdc"},{"id":2,"devices":"sdd"}]`, nil
+			}
+			if args[0] == "osd" && args[1] == "crush" && args[2] == "set-device-class" {
+				setClasses[args[4]] = args[3]
+			}
+			return "", nil
+		},
+	}
+	c := &Cluster{context: &clusterd.Context{Executor: executor}, Namespace: "ns"}
+	n := &rookalpha.Node{
+		Name:   "node1",
+		Config: map[string]string{"deviceClass": "ssd"},
+		Selection: rookalpha.Selection{
+			Devices: []rookalpha.Device{{Name: "sdd", Config: map[string]string{"deviceClass": "nvme"}}},
+		},
+	}
+
+	// osd.1 already has the class and osd.3 is not in the crush map yet
+	c.applyDeviceClasses(n, []OSDInfo{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}})
+	assert.Equal(t, map[string]string{"osd.0": "ssd", "osd.2": "nvme"}, setClasses)
+}
@@ -18,7 +18,9 @@ limitations under the License.
 package osd
 
 import (
+	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -82,6 +84,29 @@ type Cluster struct {
 	ownerRef        metav1.OwnerReference
 	kv              *k8sutil.ConfigMapKVStore
 	isUpgrade       bool
+	// NodeRemoval controls the removal of the nodes that were removed from the storage spec
+	NodeRemoval NodeRemovalSettings
+	// PendingNodeRemovals are the removed nodes that still have OSDs since their removal was not confirmed
+	PendingNodeRemovals []string
+	// SkippedNodeRemovals are the confirmed removed nodes of which the OSDs could not be removed, e.g. since it was
+	// not safe
+	SkippedNodeRemovals []string
+	// ProvisioningStatus is notified of the progress of the OSD provisioning on each node, if set
+	ProvisioningStatus ProvisioningStatusFunc
+	// StoppedOSDs are the IDs of the OSDs that were stopped for maintenance. Their deployments are kept scaled down.
+	StoppedOSDs map[int]bool
+	// the nodes of which the OSDs are removed, which are reported as removed when their orchestration completes
+	removedNodes map[string]bool
+}
+
+// NodeRemovalSettings controls the removal of the OSDs on the nodes that were removed from the storage spec.
+// By default the OSDs are only removed after the removal of the node was confirmed, so an errant edit of the
+// storage spec does not destroy data.
+type NodeRemovalSettings struct {
+	// SkipConfirmation removes the OSDs of the removed nodes without a confirmation
+	SkipConfirmation bool
+	// Confirmed are the names of the removed nodes of which the removal was confirmed
+	Confirmed []string
 }
 
 // New creates an instance of the OSD manager
@@ -154,8 +179,8 @@ type osdProperties struct {
 }
 
 // Start the osd management
-func (c *Cluster) Start() error {
-	config := newProvisionConfig()
+func (c *Cluster) Start(ctx context.Context) error {
+	config := newProvisionConfig(ctx)
 
 	// Validate pod's memory if specified
 	// This is valid for both Filestore and Bluestore
@@ -175,6 +200,9 @@ func (c *Cluster) Start() error {
 	logger.Infof("start provisioning the osds on pvcs, if needed")
 	c.startProvisioningOverPVCs(config)
 
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("canceled the start of the osds in namespace %s. %+v", c.Namespace, err)
+	}
 	logger.Infof("start provisioning the osds on nodes, if needed")
 	c.startProvisioningOverNodes(config)
 
@@ -418,6 +446,10 @@ func (c *Cluster) startOSDDaemonsOnPVC(pvcName string, config *provisionConfig,
 				logger.Warningf("failed to create osd deployment for pvc %s, osd %v: %+v", osdProps.pvc.ClaimName, osd, err)
 				continue
 			}
+			if c.StoppedOSDs[osd.ID] {
+				logger.Infof("osd %d is stopped for maintenance. not updating its deployment", osd.ID)
+				continue
+			}
 			logger.Infof("deployment for osd %d already exists. updating if needed", osd.ID)
 			// Always invoke ceph version before an upgrade so we are sure to be up-to-date
 			daemon := string(opconfig.OsdType)
@@ -485,6 +517,10 @@ func (c *Cluster) startOSDDaemonsOnNode(nodeName string, config *provisionConfig
 				logger.Warningf("failed to create osd deployment for node %s, osd %v: %+v", n.Name, osd, err)
 				continue
 			}
+			if c.StoppedOSDs[osd.ID] {
+				logger.Infof("osd %d is stopped for maintenance. not updating its deployment", osd.ID)
+				continue
+			}
 			logger.Infof("deployment for osd %d already exists. updating if needed", osd.ID)
 			// Always invoke ceph version before an upgrade so we are sure to be up-to-date
 			daemon := string(opconfig.OsdType)
@@ -509,6 +545,9 @@ func (c *Cluster) startOSDDaemonsOnNode(nodeName string, config *provisionConfig
 		}
 		logger.Infof("started deployment for osd %d (dir=%t, type=%s)", osd.ID, osd.IsDirectory, storeConfig.StoreType)
 	}
+
+	// correct the device classes that ceph detected if the spec requests another class
+	c.applyDeviceClasses(n, osds)
 }
 
 func (c *Cluster) handleRemovedNodes(config *provisionConfig) {
@@ -520,14 +559,24 @@ func (c *Cluster) handleRemovedNodes(config *provisionConfig) {
 	}
 	logger.Infof("processing %d removed nodes", len(removedNodes))
 
+	c.PendingNodeRemovals = []string{}
+	c.SkippedNodeRemovals = []string{}
+	c.removedNodes = map[string]bool{}
 	for removedNode, osdDeployments := range removedNodes {
 		logger.Infof("processing removed node %s", removedNode)
+		if !c.NodeRemoval.isConfirmed(removedNode) {
+			logger.Warningf("deferring the removal of node %s with %d OSDs until the removal is confirmed", removedNode, len(osdDeployments))
+			c.PendingNodeRemovals = append(c.PendingNodeRemovals, removedNode)
+			continue
+		}
 		if err := c.isSafeToRemoveNode(removedNode, osdDeployments); err != nil {
 			logger.Warningf("skipping the removal of node %s because it is not safe to do so: %+v", removedNode, err)
+			c.SkippedNodeRemovals = append(c.SkippedNodeRemovals, removedNode)
 			continue
 		}
 
 		logger.Infof("removing node %s from the cluster with %d OSDs", removedNode, len(osdDeployments))
+		c.removedNodes[removedNode] = true
 
 		var nodeCrushName string
 		errorOnCurrentNode := false
@@ -561,14 +610,30 @@ func (c *Cluster) handleRemovedNodes(config *provisionConfig) {
 
 		if errorOnCurrentNode {
 			logger.Warningf("done processing %d osd removals on node %s with an error removing the osds. skipping node cleanup", len(osdDeployments), removedNode)
+			c.SkippedNodeRemovals = append(c.SkippedNodeRemovals, removedNode)
 		} else {
 			logger.Infof("succeeded processing %d osd removals on node %s. starting cleanup job on the node.", len(osdDeployments), removedNode)
 			c.cleanupRemovedNode(config, removedNode, nodeCrushName)
 		}
 	}
+	sort.Strings(c.PendingNodeRemovals)
+	sort.Strings(c.SkippedNodeRemovals)
 	logger.Infof("done processing removed nodes")
 }
 
+// isConfirmed returns whether the OSDs of the removed node can be removed
+func (s NodeRemovalSettings) isConfirmed(nodeName string) bool {
+	if s.SkipConfirmation {
+		return true
+	}
+	for _, confirmed := range s.Confirmed {
+		if confirmed == nodeName {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Cluster) cleanupRemovedNode(config *provisionConfig, nodeName, crushName string) {
 	// update the orchestration status of this removed node to the starting state
 	if err := c.updateOSDStatus(nodeName, OrchestrationStatus{Status: OrchestrationStatusStarting}); err != nil {
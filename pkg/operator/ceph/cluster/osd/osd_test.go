@@ -16,6 +16,7 @@ limitations under the License.
 package osd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"testing"
@@ -50,11 +51,11 @@ func TestStart(t *testing.T) {
 		rookalpha.StorageScopeSpec{}, "", rookalpha.Placement{}, rookalpha.Annotations{}, cephv1.NetworkSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{}, false)
 
 	// Start the first time
-	err := c.Start()
+	err := c.Start(context.Background())
 	assert.Nil(t, err)
 
 	// Should not fail if it already exists
-	err = c.Start()
+	err = c.Start(context.Background())
 	assert.Nil(t, err)
 }
 
@@ -131,7 +132,7 @@ func TestAddRemoveNode(t *testing.T) {
 	var startErr error
 	startCompleted := false
 	go func() {
-		startErr = c.Start()
+		startErr = c.Start(context.Background())
 		startCompleted = true
 	}()
 
@@ -211,6 +212,7 @@ func TestAddRemoveNode(t *testing.T) {
 	storageSpec.Nodes = []rookalpha.Node{}
 	c = New(clusterInfo, &clusterd.Context{Clientset: clientset, ConfigDir: "/var/lib/rook", Executor: mockExec}, "ns-add-remove", "myversion", cephv1.CephVersionSpec{},
 		storageSpec, "", rookalpha.Placement{}, rookalpha.Annotations{}, cephv1.NetworkSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{}, false)
+	c.NodeRemoval.Confirmed = []string{nodeName}
 
 	// reset the orchestration status watcher
 	statusMapWatcher = watch.NewFake()
@@ -220,7 +222,7 @@ func TestAddRemoveNode(t *testing.T) {
 	startErr = nil
 	startCompleted = false
 	go func() {
-		startErr = c.Start()
+		startErr = c.Start(context.Background())
 		startCompleted = true
 	}()
 
@@ -233,6 +235,7 @@ func TestAddRemoveNode(t *testing.T) {
 	// verify orchestration for removing the node succeeded
 	assert.True(t, startCompleted)
 	assert.Nil(t, startErr)
+	assert.Equal(t, []string{}, c.SkippedNodeRemovals)
 }
 
 func TestGetIDFromDeployment(t *testing.T) {
@@ -341,7 +344,7 @@ func TestAddNodeFailure(t *testing.T) {
 	var startErr error
 	startCompleted := false
 	go func() {
-		startErr = c.Start()
+		startErr = c.Start(context.Background())
 		startCompleted = true
 	}()
 
@@ -352,3 +355,16 @@ func TestAddNodeFailure(t *testing.T) {
 	assert.True(t, startCompleted)
 	assert.NotNil(t, startErr)
 }
+
+func TestNodeRemovalConfirmed(t *testing.T) {
+	// the removal is deferred by default
+	settings := NodeRemovalSettings{}
+	assert.False(t, settings.isConfirmed("node1"))
+
+	settings.Confirmed = []string{"node0", "node1"}
+	assert.True(t, settings.isConfirmed("node1"))
+	assert.False(t, settings.isConfirmed("node2"))
+
+	settings = NodeRemovalSettings{SkipConfirmation: true}
+	assert.True(t, settings.isConfirmed("node2"))
+}
@@ -49,6 +49,7 @@ const (
 	encryptedDeviceEnvVarName           = "ROOK_ENCRYPTED_DEVICE"
 	osdMetadataDeviceEnvVarName         = "ROOK_METADATA_DEVICE"
 	pvcBackedOSDVarName                 = "ROOK_PVC_BACKED_OSD"
+	discoveryCommandEnvVarName          = "ROOK_DEVICE_DISCOVERY_COMMAND"
 	rookBinariesMountPath               = "/rook"
 	rookBinariesVolumeName              = "rook-binaries"
 	blockPVCMapperInitContainer         = "blkdevmapper"
@@ -99,6 +100,9 @@ func (c *Cluster) makeJob(osdProps osdProperties) (*batch.Job, error) {
 func (c *Cluster) makeDeployment(osdProps osdProperties, osd OSDInfo) (*apps.Deployment, error) {
 
 	replicaCount := int32(1)
+	if c.StoppedOSDs[osd.ID] {
+		replicaCount = 0
+	}
 	volumeMounts := opspec.CephVolumeMounts(false)
 	configVolumeMounts := opspec.RookVolumeMounts(false)
 	volumes := opspec.PodVolumes(c.dataDirHostPath, c.Namespace, false)
@@ -361,6 +365,7 @@ func (c *Cluster) makeDeployment(osdProps osdProperties, osd OSDInfo) (*apps.Dep
 	} else {
 		osdProps.placement.ApplyToPodSpec(&deployment.Spec.Template.Spec)
 	}
+	c.cephVersion.ApplyImagePullPolicyToPodSpec(&deployment.Spec.Template.Spec)
 	return deployment, nil
 }
 
@@ -440,6 +445,7 @@ func (c *Cluster) provisionPodTemplateSpec(osdProps osdProperties, restart v1.Re
 	} else {
 		osdProps.placement.ApplyToPodSpec(&podSpec)
 	}
+	c.cephVersion.ApplyImagePullPolicyToPodSpec(&podSpec)
 
 	podMeta := metav1.ObjectMeta{
 		Name: AppName,
@@ -584,6 +590,10 @@ func (c *Cluster) provisionOSDContainer(osdProps osdProperties, copyBinariesMoun
 		envVars = append(envVars, deviceFilterEnvVar("all"))
 		devMountNeeded = true
 	}
+	// the custom discovery of the devices configured for the operator also applies to the prepare jobs
+	if command := os.Getenv(discoveryCommandEnvVarName); command != "" && devMountNeeded {
+		envVars = append(envVars, v1.EnvVar{Name: discoveryCommandEnvVarName, Value: command})
+	}
 	envVars = append(envVars, v1.EnvVar{Name: "ROOK_CEPH_VERSION", Value: c.clusterInfo.CephVersion.CephVersionFormatted()})
 
 	if osdProps.metadataDevice != "" {
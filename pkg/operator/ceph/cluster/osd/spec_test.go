@@ -366,6 +366,34 @@ func TestHostNetwork(t *testing.T) {
 	assert.Equal(t, v1.DNSClusterFirstWithHostNet, r.Spec.Template.Spec.DNSPolicy)
 }
 
+func TestStoppedOSD(t *testing.T) {
+	storageSpec := rookalpha.StorageScopeSpec{
+		Nodes: []rookalpha.Node{{Name: "node1"}},
+	}
+	clusterInfo := &cephconfig.ClusterInfo{
+		CephVersion: cephver.Nautilus,
+	}
+	c := New(clusterInfo, &clusterd.Context{Clientset: fake.NewSimpleClientset(), ConfigDir: "/var/lib/rook", Executor: &exectest.MockExecutor{}}, "ns", "myversion", cephv1.CephVersionSpec{},
+		storageSpec, "", rookalpha.Placement{}, rookalpha.Annotations{}, cephv1.NetworkSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{}, false)
+	n := c.DesiredStorage.ResolveNode(storageSpec.Nodes[0].Name)
+	osdProp := osdProperties{
+		crushHostname: n.Name,
+		selection:     n.Selection,
+		storeConfig:   config.StoreConfig{},
+	}
+
+	// the deployment of a stopped osd has no replicas
+	c.StoppedOSDs = map[int]bool{5: true}
+	r, err := c.makeDeployment(osdProp, OSDInfo{ID: 5})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(0), *r.Spec.Replicas)
+
+	// the other osds are running
+	r, err = c.makeDeployment(osdProp, OSDInfo{ID: 0})
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), *r.Spec.Replicas)
+}
+
 func TestOsdOnSDNFlag(t *testing.T) {
 	network := cephv1.NetworkSpec{}
 	v := cephver.Mimic
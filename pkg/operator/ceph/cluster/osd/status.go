@@ -18,10 +18,12 @@ limitations under the License.
 package osd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
 
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
 	"github.com/rook/rook/pkg/operator/k8sutil"
 	"github.com/rook/rook/pkg/util"
 	apps "k8s.io/api/apps/v1"
@@ -46,11 +48,13 @@ const (
 )
 
 type provisionConfig struct {
+	// the waits for the provisioning of the nodes stop when the context is canceled
+	ctx           context.Context
 	errorMessages []string
 }
 
-func newProvisionConfig() *provisionConfig {
-	return &provisionConfig{}
+func newProvisionConfig(ctx context.Context) *provisionConfig {
+	return &provisionConfig{ctx: ctx}
 }
 
 func (c *provisionConfig) addError(message string, args ...interface{}) {
@@ -58,8 +62,44 @@ func (c *provisionConfig) addError(message string, args ...interface{}) {
 	c.errorMessages = append(c.errorMessages, fmt.Sprintf(message, args...))
 }
 
+// ProvisioningStatusFunc is called when the state of the OSD provisioning on a node or PVC changes
+type ProvisioningStatusFunc func(node string, state cephv1.OSDProvisioningState, message string)
+
 func (c *Cluster) updateOSDStatus(node string, status OrchestrationStatus) error {
-	return UpdateNodeStatus(c.kv, node, status)
+	if err := UpdateNodeStatus(c.kv, node, status); err != nil {
+		return err
+	}
+	c.reportProvisioningStatus(node, &status)
+	return nil
+}
+
+// reportProvisioningStatus reports the orchestration status of the node to the ProvisioningStatus callback
+func (c *Cluster) reportProvisioningStatus(node string, status *OrchestrationStatus) {
+	if c.ProvisioningStatus == nil {
+		return
+	}
+	state := provisioningState(status)
+	if state == cephv1.OSDProvisioningComplete && c.removedNodes[node] {
+		state = cephv1.OSDProvisioningRemoved
+	}
+	c.ProvisioningStatus(node, state, status.Message)
+}
+
+// provisioningState converts the orchestration status of a node to the state reported in the cluster status
+func provisioningState(status *OrchestrationStatus) cephv1.OSDProvisioningState {
+	switch status.Status {
+	case OrchestrationStatusStarting:
+		return cephv1.OSDProvisioningPending
+	case OrchestrationStatusCompleted:
+		// the operator completes the status with a message when the provisioning could not be started
+		if status.Message != "" {
+			return cephv1.OSDProvisioningFailed
+		}
+		return cephv1.OSDProvisioningComplete
+	case OrchestrationStatusFailed:
+		return cephv1.OSDProvisioningFailed
+	}
+	return cephv1.OSDProvisioningInProgress
 }
 
 func UpdateNodeStatus(kv *k8sutil.ConfigMapKVStore, node string, status OrchestrationStatus) error {
@@ -230,6 +270,10 @@ func (c *Cluster) completeOSDsForAllNodes(config *provisionConfig, configOSDs bo
 					}
 				}
 
+			case <-config.ctx.Done():
+				config.addError("canceled waiting for %d nodes: %+v. %+v", remainingNodes.Count(), remainingNodes, config.ctx.Err())
+				return false
+
 			case <-time.After(time.Minute):
 				// log every so often while we are waiting
 				currentTimeoutMinutes++
@@ -261,10 +305,12 @@ func (c *Cluster) handleStatusConfigMapStatus(nodeName string, config *provision
 			// remove the status configmap that indicated the progress
 			c.kv.ClearStore(fmt.Sprintf(orchestrationStatusMapName, nodeName))
 		}
+		c.reportProvisioningStatus(nodeName, status)
 
 		return true
 	}
 
+	c.reportProvisioningStatus(nodeName, status)
 	if status.Status == OrchestrationStatusFailed {
 		config.addError("orchestration for node %s failed: %+v", nodeName, status)
 		return true
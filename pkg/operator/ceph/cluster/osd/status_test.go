@@ -16,6 +16,7 @@ limitations under the License.
 package osd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -65,6 +66,54 @@ func TestOrchestrationStatus(t *testing.T) {
 	assert.Equal(t, status, *retrievedStatus)
 }
 
+func TestProvisioningStatus(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	clusterInfo := &cephconfig.ClusterInfo{
+		CephVersion: cephver.Nautilus,
+	}
+	c := New(clusterInfo, &clusterd.Context{Clientset: clientset, ConfigDir: "/var/lib/rook", Executor: &exectest.MockExecutor{}}, "ns", "myversion", cephv1.CephVersionSpec{},
+		rookalpha.StorageScopeSpec{}, "", rookalpha.Placement{}, rookalpha.Annotations{}, cephv1.NetworkSpec{}, v1.ResourceRequirements{}, metav1.OwnerReference{}, false)
+
+	// no callback is set
+	assert.Nil(t, c.updateOSDStatus("node1", OrchestrationStatus{Status: OrchestrationStatusStarting}))
+
+	reported := map[string]cephv1.OSDProvisioningState{}
+	c.ProvisioningStatus = func(node string, state cephv1.OSDProvisioningState, message string) {
+		reported[node] = state
+	}
+	assert.Nil(t, c.updateOSDStatus("node1", OrchestrationStatus{Status: OrchestrationStatusStarting}))
+	assert.Nil(t, c.updateOSDStatus("node2", OrchestrationStatus{Status: OrchestrationStatusCompleted, Message: "failed to start osd provisioning"}))
+	assert.Equal(t, cephv1.OSDProvisioningPending, reported["node1"])
+	assert.Equal(t, cephv1.OSDProvisioningFailed, reported["node2"])
+
+	// the status written by the provisioning job
+	config := newProvisionConfig(context.Background())
+	status := OrchestrationStatus{Status: OrchestrationStatusOrchestrating}
+	s, _ := json.Marshal(status)
+	configMap := &v1.ConfigMap{Data: map[string]string{orchestrationStatusKey: string(s)}}
+	assert.False(t, c.handleStatusConfigMapStatus("node1", config, configMap, false))
+	assert.Equal(t, cephv1.OSDProvisioningInProgress, reported["node1"])
+
+	status.Status = OrchestrationStatusCompleted
+	s, _ = json.Marshal(status)
+	configMap.Data[orchestrationStatusKey] = string(s)
+	assert.True(t, c.handleStatusConfigMapStatus("node1", config, configMap, false))
+	assert.Equal(t, cephv1.OSDProvisioningComplete, reported["node1"])
+
+	status.Status = OrchestrationStatusFailed
+	s, _ = json.Marshal(status)
+	configMap.Data[orchestrationStatusKey] = string(s)
+	assert.True(t, c.handleStatusConfigMapStatus("node1", config, configMap, false))
+	assert.Equal(t, cephv1.OSDProvisioningFailed, reported["node1"])
+
+	// a removed node is not reported as complete
+	c.removedNodes = map[string]bool{"node3": true}
+	assert.Nil(t, c.updateOSDStatus("node3", OrchestrationStatus{Status: OrchestrationStatusStarting}))
+	assert.Equal(t, cephv1.OSDProvisioningPending, reported["node3"])
+	assert.Nil(t, c.updateOSDStatus("node3", OrchestrationStatus{Status: OrchestrationStatusCompleted}))
+	assert.Equal(t, cephv1.OSDProvisioningRemoved, reported["node3"])
+}
+
 func mockNodeOrchestrationCompletion(c *Cluster, nodeName string, statusMapWatcher *watch.FakeWatcher) {
 	// if no valid osd node, don't need to check its status, return immediately
 	if len(c.DesiredStorage.Nodes) == 0 {
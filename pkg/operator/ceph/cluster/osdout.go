@@ -0,0 +1,211 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/osd"
+	opspec "github.com/rook/rook/pkg/operator/ceph/spec"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// osdOutRequested returns the ID of the OSD requested to be out by the annotation of the CephCluster CR
+func osdOutRequested(cephCluster *cephv1.CephCluster) string {
+	return strings.TrimSpace(cephCluster.GetAnnotations()[osdOutAnnotation])
+}
+
+// parseOSDID parses the ID of an OSD from the osd-out annotation, e.g. "5"
+func parseOSDID(value string) (int, error) {
+	id, err := strconv.Atoi(value)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid osd %q in annotation %s, it must be the ID of an osd", value, osdOutAnnotation)
+	}
+	return id, nil
+}
+
+// stoppedOSDs returns the OSD that was stopped by the osd-out annotation, so the orchestration keeps its deployment
+// scaled down. The caller must hold osdOutMux until the OSDs are started, so the maintenance cannot start in between.
+func (c *cluster) stoppedOSDs() map[int]bool {
+	if c.outOSD == "" {
+		return nil
+	}
+	id, err := parseOSDID(c.outOSD)
+	if err != nil {
+		logger.Warningf("ignoring the stopped osd. %+v", err)
+		return nil
+	}
+	return map[int]bool{id: true}
+}
+
+// requestOSDOut sets the OSD requested to be out by the annotation, applied by the next applyOSDOut
+func (c *cluster) requestOSDOut(requested string) {
+	c.osdOutMux.Lock()
+	defer c.osdOutMux.Unlock()
+	c.requestedOSDOut = requested
+}
+
+// applyOSDOut marks the requested OSD out and stops its daemon if the request changed. The OSD that was out before is
+// started and marked in again, so removing the annotation reverses the maintenance. The data of the OSD is kept. The
+// OSD that is out is reported in the status, which is written before the OSD is stopped and after it was started
+// again, so the OSD remains tracked after a restart of the operator if the status cannot be written.
+func (c *cluster) applyOSDOut() error {
+	c.osdOutMux.Lock()
+	defer c.osdOutMux.Unlock()
+	requested := c.requestedOSDOut
+	if requested == c.outOSD {
+		return nil
+	}
+	requestedID := -1
+	if requested != "" {
+		id, err := parseOSDID(requested)
+		if err != nil {
+			return err
+		}
+		requestedID = id
+	}
+
+	if c.outOSD != "" {
+		id, err := parseOSDID(c.outOSD)
+		if err != nil {
+			return err
+		}
+		logger.Infof("starting osd %d of cluster %s after the maintenance", id, c.Namespace)
+		if err := c.startOSD(id); err != nil {
+			return err
+		}
+		if _, err := client.OSDIn(c.context, c.Namespace, id); err != nil {
+			return fmt.Errorf("failed to mark osd %d in. %+v", id, err)
+		}
+		c.recordEvent(v1.EventTypeNormal, "OSDIn", fmt.Sprintf("osd %d was marked in and started", id))
+		if err := c.updateOutOSDStatus(""); err != nil {
+			return err
+		}
+		c.outOSD = ""
+	}
+
+	if requestedID >= 0 {
+		// the osd is only marked out if it can be stopped
+		if _, err := c.osdDeployments(requestedID); err != nil {
+			return err
+		}
+		if err := c.updateOutOSDStatus(requested); err != nil {
+			return err
+		}
+		logger.Infof("marking osd %d of cluster %s out and stopping it for maintenance", requestedID, c.Namespace)
+		if err := c.stopOSD(requestedID); err != nil {
+			if statusErr := c.updateOutOSDStatus(""); statusErr != nil {
+				logger.Errorf("failed to reset the out osd of cluster %s. %+v", c.Namespace, statusErr)
+			}
+			return err
+		}
+		c.recordEvent(v1.EventTypeNormal, "OSDOut", fmt.Sprintf("osd %d was marked out and stopped for maintenance", requestedID))
+		c.outOSD = requested
+	}
+	return nil
+}
+
+// stopOSD marks the OSD out and scales its deployment down
+func (c *cluster) stopOSD(id int) error {
+	if _, err := client.OSDOut(c.context, c.Namespace, id); err != nil {
+		return fmt.Errorf("failed to mark osd %d out. %+v", id, err)
+	}
+	return c.scaleOSD(id, 0)
+}
+
+// startOSD scales the deployment of the OSD up again. The orchestrations do not update the deployment while the
+// OSD is stopped, so the ceph image of the deployment is updated to the image of the cluster, e.g. after an upgrade.
+func (c *cluster) startOSD(id int) error {
+	deployments, err := c.osdDeployments(id)
+	if err != nil {
+		return err
+	}
+	replicas := int32(1)
+	for i := range deployments {
+		d := &deployments[i]
+		d.Spec.Replicas = &replicas
+		c.updateOSDImage(d)
+		if _, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Update(d); err != nil {
+			return fmt.Errorf("failed to start deployment %s. %+v", d.Name, err)
+		}
+	}
+	return nil
+}
+
+// updateOSDImage sets the ceph image of the cluster on the containers of the OSD deployment that run the ceph image,
+// which is the image of the osd container
+func (c *cluster) updateOSDImage(d *apps.Deployment) {
+	if c.Spec == nil || c.Spec.CephVersion.Image == "" || len(d.Spec.Template.Spec.Containers) == 0 {
+		return
+	}
+	image := c.Spec.CephVersion.Image
+	previousImage := d.Spec.Template.Spec.Containers[0].Image
+	if previousImage == image {
+		return
+	}
+	logger.Infof("updating the image of the stopped deployment %s from %s to %s", d.Name, previousImage, image)
+	podSpec := &d.Spec.Template.Spec
+	for i := range podSpec.InitContainers {
+		if podSpec.InitContainers[i].Image == previousImage {
+			podSpec.InitContainers[i].Image = image
+		}
+	}
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Image == previousImage {
+			podSpec.Containers[i].Image = image
+		}
+	}
+	if c.Info != nil {
+		opspec.AddCephVersionLabelToDeployment(c.Info.CephVersion, d)
+	}
+}
+
+// osdDeployments returns the deployment of the OSD
+func (c *cluster) osdDeployments(id int) ([]apps.Deployment, error) {
+	selector := fmt.Sprintf("%s=%s,%s=%d", k8sutil.AppAttr, osd.AppName, osd.OsdIdLabelKey, id)
+	deployments, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).List(metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the deployment of osd %d. %+v", id, err)
+	}
+	if len(deployments.Items) == 0 {
+		return nil, fmt.Errorf("no deployment found for osd %d", id)
+	}
+	return deployments.Items, nil
+}
+
+// scaleOSD sets the replicas of the deployment of the OSD
+func (c *cluster) scaleOSD(id int, replicas int32) error {
+	deployments, err := c.osdDeployments(id)
+	if err != nil {
+		return err
+	}
+	for i := range deployments {
+		d := &deployments[i]
+		d.Spec.Replicas = &replicas
+		if _, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Update(d); err != nil {
+			return fmt.Errorf("failed to scale deployment %s to %d. %+v", d.Name, replicas, err)
+		}
+	}
+	return nil
+}
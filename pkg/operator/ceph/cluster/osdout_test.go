@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestApplyOSDOut(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "my-cluster",
+			Namespace:   "ns",
+			Annotations: map[string]string{osdOutAnnotation: " 5 "},
+		},
+	}
+	replicas := int32(1)
+	deployment := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "rook-ceph-osd-5",
+			Namespace: "ns",
+			Labels:    map[string]string{"app": "rook-ceph-osd", "ceph-osd-id": "5"},
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+				InitContainers: []v1.Container{{Name: "config-init", Image: "rook/ceph:v1.1.0"}},
+				Containers:     []v1.Container{{Name: "osd", Image: "ceph/ceph:v14.2.4"}},
+			}},
+		},
+	}
+	commands := []string{}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			if args[0] == "osd" {
+				commands = append(commands, args[1]+" "+args[2])
+			}
+			return "", nil
+		},
+	}
+	context := &clusterd.Context{
+		Executor:      executor,
+		Clientset:     fake.NewSimpleClientset(deployment),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := &cluster{Namespace: "ns", crdName: "my-cluster", context: context, Spec: &cephv1.ClusterSpec{}}
+	osdReplicas := func() int32 {
+		d, err := context.Clientset.AppsV1().Deployments("ns").Get("rook-ceph-osd-5", metav1.GetOptions{})
+		assert.Nil(t, err)
+		return *d.Spec.Replicas
+	}
+
+	// the osd from the annotation is marked out and stopped
+	c.requestOSDOut(osdOutRequested(cephCluster))
+	assert.Nil(t, c.applyOSDOut())
+	assert.Equal(t, []string{"out 5"}, commands)
+	assert.Equal(t, int32(0), osdReplicas())
+	assert.Equal(t, map[int]bool{5: true}, c.stoppedOSDs())
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "5", updated.Status.OutOSD)
+
+	// the same osd is not stopped again
+	c.requestOSDOut("5")
+	assert.Nil(t, c.applyOSDOut())
+	assert.Equal(t, 1, len(commands))
+
+	// an invalid osd is rejected and the osd stays out
+	c.requestOSDOut("osd.5")
+	assert.NotNil(t, c.applyOSDOut())
+	assert.Equal(t, 1, len(commands))
+	assert.Equal(t, "5", c.outOSD)
+
+	// the osd is started and marked in when the annotation is removed, on the image of an upgrade during the maintenance
+	c.Spec.CephVersion.Image = "ceph/ceph:v14.2.5"
+	c.requestOSDOut("")
+	assert.Nil(t, c.applyOSDOut())
+	assert.Equal(t, []string{"out 5", "in 5"}, commands)
+	assert.Equal(t, int32(1), osdReplicas())
+	d, err := context.Clientset.AppsV1().Deployments("ns").Get("rook-ceph-osd-5", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "ceph/ceph:v14.2.5", d.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, "rook/ceph:v1.1.0", d.Spec.Template.Spec.InitContainers[0].Image)
+	assert.Nil(t, c.stoppedOSDs())
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", updated.Status.OutOSD)
+
+	// an osd without a deployment cannot be stopped
+	c.requestOSDOut("7")
+	assert.NotNil(t, c.applyOSDOut())
+	assert.Equal(t, 2, len(commands))
+	assert.Equal(t, "", c.outOSD)
+
+	// the osd is not stopped if the status cannot be written
+	context.RookClientset.(*rookfake.Clientset).PrependReactor("update", "cephclusters", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("conflict")
+	})
+	c.requestOSDOut("5")
+	assert.NotNil(t, c.applyOSDOut())
+	assert.Equal(t, 2, len(commands))
+	assert.Equal(t, int32(1), osdReplicas())
+	assert.Equal(t, "", c.outOSD)
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// hasOwnerRef checks whether the object is owned by the owner
+func hasOwnerRef(object metav1.ObjectMeta, ownerRef metav1.OwnerReference) bool {
+	for _, ref := range object.OwnerReferences {
+		if ref.UID == ownerRef.UID {
+			return true
+		}
+	}
+	return false
+}
+
+// repairOwnerRefs adds the owner reference of the cluster again to the deployments and services created by the
+// operator for the cluster, in case it was removed. Without the owner reference the resources would not be garbage
+// collected when the cluster is deleted. The other owner references of the resources are kept.
+func (c *cluster) repairOwnerRefs() error {
+	listOptions := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.ClusterAttr, c.Namespace)}
+
+	deployments, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).List(listOptions)
+	if err != nil {
+		return fmt.Errorf("failed to list the deployments. %+v", err)
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		if hasOwnerRef(d.ObjectMeta, c.ownerRef) {
+			continue
+		}
+		logger.Warningf("deployment %s is missing the owner reference of cluster %s, setting it again", d.Name, c.Namespace)
+		d.OwnerReferences = append(d.OwnerReferences, c.ownerRef)
+		if _, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Update(d); err != nil {
+			return fmt.Errorf("failed to set the owner reference of deployment %s. %+v", d.Name, err)
+		}
+	}
+
+	services, err := c.context.Clientset.CoreV1().Services(c.Namespace).List(listOptions)
+	if err != nil {
+		return fmt.Errorf("failed to list the services. %+v", err)
+	}
+	for i := range services.Items {
+		s := &services.Items[i]
+		if hasOwnerRef(s.ObjectMeta, c.ownerRef) {
+			continue
+		}
+		logger.Warningf("service %s is missing the owner reference of cluster %s, setting it again", s.Name, c.Namespace)
+		s.OwnerReferences = append(s.OwnerReferences, c.ownerRef)
+		if _, err := c.context.Clientset.CoreV1().Services(c.Namespace).Update(s); err != nil {
+			return fmt.Errorf("failed to set the owner reference of service %s. %+v", s.Name, err)
+		}
+	}
+	return nil
+}
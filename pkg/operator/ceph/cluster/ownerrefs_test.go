@@ -0,0 +1,57 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRepairOwnerRefs(t *testing.T) {
+	clientset := testop.New(1)
+	ownerRef := ClusterOwnerRef("my-cluster", "cluster-uid")
+	c := &cluster{Namespace: "ns", context: &clusterd.Context{Clientset: clientset}, ownerRef: ownerRef}
+	labels := map[string]string{"app": "rook-ceph-mgr", "rook_cluster": "ns"}
+
+	// the owner reference was removed from the mgr deployment and service, another owner of the deployment is kept
+	otherRef := metav1.OwnerReference{APIVersion: "v1", Kind: "ConfigMap", Name: "backup-config", UID: "backup-uid"}
+	_, err := clientset.AppsV1().Deployments("ns").Create(&apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mgr-a", Namespace: "ns", Labels: labels,
+		OwnerReferences: []metav1.OwnerReference{otherRef}}})
+	assert.Nil(t, err)
+	_, err = clientset.CoreV1().Services("ns").Create(&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mgr", Namespace: "ns", Labels: labels}})
+	assert.Nil(t, err)
+	// a resource that was not created for the cluster is not changed
+	_, err = clientset.CoreV1().Services("ns").Create(&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "my-app", Namespace: "ns"}})
+	assert.Nil(t, err)
+
+	assert.Nil(t, c.repairOwnerRefs())
+	d, err := clientset.AppsV1().Deployments("ns").Get("rook-ceph-mgr-a", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []metav1.OwnerReference{otherRef, ownerRef}, d.OwnerReferences)
+	s, err := clientset.CoreV1().Services("ns").Get("rook-ceph-mgr", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []metav1.OwnerReference{ownerRef}, s.OwnerReferences)
+	s, err = clientset.CoreV1().Services("ns").Get("my-app", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(s.OwnerReferences))
+}
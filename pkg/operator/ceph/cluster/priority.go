@@ -0,0 +1,155 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"sort"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterPriorityInfo is the state of a cluster that the priority of its orchestration is computed from
+type ClusterPriorityInfo struct {
+	Namespace string
+	// Upgrading is whether the daemons of the cluster are being upgraded to a new ceph version
+	Upgrading bool
+	// Health is the last health reported in the status of the cluster, e.g. HEALTH_WARN. Empty if unknown.
+	Health string
+	// OrchestrationNeeded is whether the cluster has an orchestration pending
+	OrchestrationNeeded bool
+}
+
+// OrchestrationPriorityFunc returns the priority of the orchestration of a cluster. When several clusters
+// need to be orchestrated for the same event, the clusters with the highest priority are orchestrated first.
+type OrchestrationPriorityFunc func(info ClusterPriorityInfo) int
+
+// DefaultOrchestrationPriority orchestrates the upgrading clusters first, then the unhealthy clusters
+func DefaultOrchestrationPriority(info ClusterPriorityInfo) int {
+	priority := 0
+	if info.Upgrading {
+		priority += 100
+	}
+	switch info.Health {
+	case "HEALTH_ERR":
+		priority += 20
+	case "HEALTH_WARN":
+		priority += 10
+	}
+	if info.OrchestrationNeeded {
+		priority++
+	}
+	return priority
+}
+
+// priorityInfo returns the state of the cluster to compute the priority of its orchestration
+func (c *cluster) priorityInfo() ClusterPriorityInfo {
+	info := ClusterPriorityInfo{
+		Namespace: c.Namespace,
+	}
+
+	c.orchMux.Lock()
+	info.Upgrading = c.isUpgrade || c.upgradeInProgress
+	info.OrchestrationNeeded = c.orchestrationNeeded
+	c.orchMux.Unlock()
+
+	// the health is updated in the CR status by the ceph status checker
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get the health of cluster %s to prioritize its orchestration. %+v", c.Namespace, err)
+	} else if cephCluster.Status.CephStatus != nil {
+		info.Health = cephCluster.Status.CephStatus.Health
+	}
+	return info
+}
+
+// clustersByPriority returns the clusters in the order they are orchestrated, the cluster with the highest
+// priority first. The clusters with the same priority are sorted by namespace.
+func (c *ClusterController) clustersByPriority() []*cluster {
+	c.clusterMapMux.Lock()
+	clusters := make([]*cluster, 0, len(c.clusterMap))
+	for _, cluster := range c.clusterMap {
+		clusters = append(clusters, cluster)
+	}
+	c.clusterMapMux.Unlock()
+
+	priorities := map[string]int{}
+	for _, cluster := range clusters {
+		priorities[cluster.Namespace] = c.orchestrationPriority(cluster.priorityInfo())
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		pi, pj := priorities[clusters[i].Namespace], priorities[clusters[j].Namespace]
+		if pi != pj {
+			return pi > pj
+		}
+		return clusters[i].Namespace < clusters[j].Namespace
+	})
+	return clusters
+}
+
+// existingClusterPriorityInfo returns the state of a cluster that is not orchestrated yet by the operator, e.g. after a
+// restart of the operator, from its CR. The cluster is upgrading if the ceph image of the spec differs from the image
+// of the last successful orchestration.
+func existingClusterPriorityInfo(clusterObj *cephv1.CephCluster) ClusterPriorityInfo {
+	info := ClusterPriorityInfo{
+		Namespace:           clusterObj.Namespace,
+		OrchestrationNeeded: true,
+	}
+	lastImage := clusterObj.Status.LastAppliedCephImage
+	info.Upgrading = lastImage != "" && lastImage != clusterObj.Spec.CephVersion.Image
+	if clusterObj.Status.CephStatus != nil {
+		info.Health = clusterObj.Status.CephStatus.Health
+	}
+	return info
+}
+
+// existingClustersByPriority returns the CRs of the clusters that exist when the controller starts in the order they
+// are added, the cluster with the highest priority first. The clusters are added one at a time since each addition
+// waits for the orchestration of the cluster, so the order of the informer would delay the critical clusters.
+func (c *ClusterController) existingClustersByPriority(namespace string) ([]cephv1.CephCluster, error) {
+	clusterList, err := c.context.RookClientset.CephV1().CephClusters(namespace).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the clusters. %+v", err)
+	}
+	clusters := clusterList.Items
+	priorities := map[string]int{}
+	for i := range clusters {
+		priorities[clusters[i].Namespace] = c.orchestrationPriority(existingClusterPriorityInfo(&clusters[i]))
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		pi, pj := priorities[clusters[i].Namespace], priorities[clusters[j].Namespace]
+		if pi != pj {
+			return pi > pj
+		}
+		return clusters[i].Namespace < clusters[j].Namespace
+	})
+	return clusters, nil
+}
+
+// addExistingClusters adds the clusters that exist when the controller starts by priority. The informer then skips
+// them when it adds the same CRs.
+func (c *ClusterController) addExistingClusters(namespace string) {
+	clusters, err := c.existingClustersByPriority(namespace)
+	if err != nil {
+		logger.Warningf("failed to add the existing clusters by priority, the clusters are added in any order. %+v", err)
+		return
+	}
+	for i := range clusters {
+		c.onAdd(&clusters[i])
+	}
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDefaultOrchestrationPriority(t *testing.T) {
+	idle := DefaultOrchestrationPriority(ClusterPriorityInfo{Health: "HEALTH_OK"})
+	warn := DefaultOrchestrationPriority(ClusterPriorityInfo{Health: "HEALTH_WARN"})
+	err := DefaultOrchestrationPriority(ClusterPriorityInfo{Health: "HEALTH_ERR"})
+	upgrading := DefaultOrchestrationPriority(ClusterPriorityInfo{Health: "HEALTH_OK", Upgrading: true})
+	pending := DefaultOrchestrationPriority(ClusterPriorityInfo{Health: "HEALTH_OK", OrchestrationNeeded: true})
+
+	assert.True(t, upgrading > err)
+	assert.True(t, err > warn)
+	assert.True(t, warn > pending)
+	assert.True(t, pending > idle)
+}
+
+func TestClustersByPriority(t *testing.T) {
+	newCephCluster := func(namespace, health string) *cephv1.CephCluster {
+		return &cephv1.CephCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: namespace},
+			Status:     cephv1.ClusterStatus{CephStatus: &cephv1.CephStatus{Health: health}},
+		}
+	}
+	context := &clusterd.Context{RookClientset: rookfake.NewSimpleClientset(
+		newCephCluster("healthy", "HEALTH_OK"),
+		newCephCluster("unhealthy", "HEALTH_ERR"),
+		newCephCluster("upgrading", "HEALTH_OK"),
+		newCephCluster("another-healthy", "HEALTH_OK"),
+	)}
+	c := &ClusterController{clusterMap: map[string]*cluster{}, orchestrationPriority: DefaultOrchestrationPriority}
+	for _, namespace := range []string{"healthy", "unhealthy", "upgrading", "another-healthy", "unknown"} {
+		c.clusterMap[namespace] = &cluster{Namespace: namespace, crdName: "my-cluster", context: context}
+	}
+	c.clusterMap["upgrading"].isUpgrade = true
+
+	namespaces := func() []string {
+		result := []string{}
+		for _, cluster := range c.clustersByPriority() {
+			result = append(result, cluster.Namespace)
+		}
+		return result
+	}
+	assert.Equal(t, []string{"upgrading", "unhealthy", "another-healthy", "healthy", "unknown"}, namespaces())
+
+	// a custom priority
+	c.SetOrchestrationPriority(func(info ClusterPriorityInfo) int {
+		if info.Namespace == "healthy" {
+			return 1
+		}
+		return 0
+	})
+	assert.Equal(t, []string{"healthy", "another-healthy", "unhealthy", "unknown", "upgrading"}, namespaces())
+}
+
+func TestExistingClustersByPriority(t *testing.T) {
+	newCephCluster := func(namespace, image, lastImage, health string) *cephv1.CephCluster {
+		return &cephv1.CephCluster{
+			ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: namespace},
+			Spec:       cephv1.ClusterSpec{CephVersion: cephv1.CephVersionSpec{Image: image}},
+			Status: cephv1.ClusterStatus{
+				CephStatus:           &cephv1.CephStatus{Health: health},
+				LastAppliedCephImage: lastImage,
+			},
+		}
+	}
+	context := &clusterd.Context{RookClientset: rookfake.NewSimpleClientset(
+		newCephCluster("healthy", "ceph/ceph:v14.2.4", "ceph/ceph:v14.2.4", "HEALTH_OK"),
+		newCephCluster("unhealthy", "ceph/ceph:v14.2.4", "ceph/ceph:v14.2.4", "HEALTH_WARN"),
+		newCephCluster("upgrading", "ceph/ceph:v14.2.5", "ceph/ceph:v14.2.4", "HEALTH_OK"),
+		newCephCluster("new", "ceph/ceph:v14.2.5", "", ""),
+	)}
+	c := &ClusterController{context: context, orchestrationPriority: DefaultOrchestrationPriority}
+
+	// the clusters are added in the order of the priority after a restart of the operator
+	clusters, err := c.existingClustersByPriority("")
+	assert.Nil(t, err)
+	namespaces := []string{}
+	for _, cluster := range clusters {
+		namespaces = append(namespaces, cluster.Namespace)
+	}
+	assert.Equal(t, []string{"upgrading", "unhealthy", "healthy", "new"}, namespaces)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	authv1 "k8s.io/api/authorization/v1"
+)
+
+// rbacPermission is a verb on a resource in the namespace of the cluster
+type rbacPermission struct {
+	verb     string
+	group    string
+	resource string
+}
+
+func (p rbacPermission) String() string {
+	if p.group == "" {
+		return fmt.Sprintf("%s %s", p.verb, p.resource)
+	}
+	return fmt.Sprintf("%s %s.%s", p.verb, p.resource, p.group)
+}
+
+// the permissions in the namespace of the cluster that the orchestration needs to start the daemons
+var orchestrationPermissions = []rbacPermission{
+	{verb: "create", group: "apps", resource: "deployments"},
+	{verb: "update", group: "apps", resource: "deployments"},
+	{verb: "delete", group: "apps", resource: "deployments"},
+	{verb: "create", group: "batch", resource: "jobs"},
+	{verb: "delete", group: "batch", resource: "jobs"},
+	{verb: "create", resource: "services"},
+	{verb: "create", resource: "configmaps"},
+	{verb: "update", resource: "configmaps"},
+	{verb: "create", resource: "secrets"},
+	{verb: "get", resource: "secrets"},
+	{verb: "list", resource: "pods"},
+}
+
+// missingPermissions returns the permissions that the service account of the operator does not have in the namespace
+func (c *cluster) missingPermissions(namespace string, permissions []rbacPermission) ([]string, error) {
+	missing := []string{}
+	for _, p := range permissions {
+		review := &authv1.SelfSubjectAccessReview{
+			Spec: authv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authv1.ResourceAttributes{
+					Namespace: namespace,
+					Verb:      p.verb,
+					Group:     p.group,
+					Resource:  p.resource,
+				},
+			},
+		}
+		result, err := c.context.Clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(review)
+		if err != nil {
+			return nil, fmt.Errorf("failed to review the permission to %s. %+v", p.String(), err)
+		}
+		if !result.Status.Allowed {
+			missing = append(missing, p.String())
+		}
+	}
+	return missing, nil
+}
+
+// checkRBAC stops the orchestration before it starts when the operator is missing permissions it needs, so the
+// missing permissions are reported at once instead of failing the orchestration midway. The orchestration continues
+// when the permissions cannot be reviewed.
+func (c *cluster) checkRBAC() error {
+	missing, err := c.missingPermissions(c.Namespace, orchestrationPermissions)
+	if err != nil {
+		logger.Warningf("failed to check the rbac of the operator in namespace %s. %+v", c.Namespace, err)
+		return nil
+	}
+
+	problem := ""
+	if len(missing) > 0 {
+		problem = fmt.Sprintf("the service account of the operator is not allowed to %s in namespace %s", strings.Join(missing, ", "), c.Namespace)
+	}
+	c.reportCondition(cephv1.ClusterConditionInsufficientRBAC, problem, "RBACSufficient", "the operator has the permissions needed by the orchestration")
+	if problem != "" {
+		return fmt.Errorf("%s", problem)
+	}
+	return nil
+}
@@ -18,6 +18,7 @@ limitations under the License.
 package rbd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/coreos/pkg/capnslog"
@@ -95,8 +96,17 @@ func New(
 
 var updateDeploymentAndWait = mon.UpdateCephDeploymentAndWait
 
+// ValidateSpec checks the rbd mirroring spec before any daemon of the cluster is started. No rbd mirror is started
+// with 0 workers, and the existing rbd mirrors are removed.
+func ValidateSpec(spec cephv1.RBDMirroringSpec) error {
+	if spec.Workers < 0 {
+		return fmt.Errorf("invalid number of rbd mirroring workers %d, it must be 0 to disable the rbd mirroring or more", spec.Workers)
+	}
+	return nil
+}
+
 // Start begins the process of running rbd mirroring daemons.
-func (m *Mirroring) Start() error {
+func (m *Mirroring) Start(ctx context.Context) error {
 	// Validate pod's memory if specified
 	err := opspec.CheckPodMemory(m.resources, cephRbdMirrorPodMinimumMemory)
 	if err != nil {
@@ -106,6 +116,10 @@ func (m *Mirroring) Start() error {
 	logger.Infof("configure rbd-mirroring with %d workers", m.spec.Workers)
 
 	for i := 0; i < m.spec.Workers; i++ {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("canceled the start of the rbd mirrors. %+v", err)
+		}
+
 		daemonID := k8sutil.IndexToName(i)
 		resourceName := fmt.Sprintf("%s-%s", appName, daemonID)
 		daemonConf := &daemonConfig{
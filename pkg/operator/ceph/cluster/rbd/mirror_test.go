@@ -17,6 +17,7 @@ limitations under the License.
 package rbd
 
 import (
+	"context"
 	"testing"
 
 	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
@@ -26,6 +27,7 @@ import (
 	testop "github.com/rook/rook/pkg/operator/test"
 	exectest "github.com/rook/rook/pkg/util/exec/test"
 	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -60,7 +62,7 @@ func TestRBDMirror(t *testing.T) {
 		false,
 	)
 
-	err := c.Start()
+	err := c.Start(context.Background())
 	assert.Nil(t, err)
 	assert.True(t, keysCreated[fullDaemonName("a")])
 	assert.True(t, keysCreated[fullDaemonName("b")])
@@ -74,3 +76,22 @@ func TestRBDMirror(t *testing.T) {
 		assert.True(t, keysCreated[fullDaemonName(daemonName)])
 	}
 }
+
+func TestValidateSpec(t *testing.T) {
+	assert.Nil(t, ValidateSpec(cephv1.RBDMirroringSpec{Workers: 2}))
+	assert.NotNil(t, ValidateSpec(cephv1.RBDMirroringSpec{Workers: -1}))
+
+	// the rbd mirroring is disabled without workers, so the existing rbd mirrors are removed
+	assert.Nil(t, ValidateSpec(cephv1.RBDMirroringSpec{Workers: 0}))
+	clientset := testop.New(1)
+	d := &apps.Deployment{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-rbd-mirror-a", Namespace: "ns", Labels: map[string]string{"app": appName, "rbdmirror": "a"}}}
+	_, err := clientset.AppsV1().Deployments("ns").Create(d)
+	assert.Nil(t, err)
+	c := New(&cephconfig.ClusterInfo{FSID: "myfsid"}, &clusterd.Context{Clientset: clientset, Executor: &exectest.MockExecutor{}}, "ns", "rook/rook:myversion",
+		cephv1.CephVersionSpec{}, rookalpha.Placement{}, rookalpha.Annotations{}, cephv1.NetworkSpec{}, cephv1.RBDMirroringSpec{},
+		v1.ResourceRequirements{}, metav1.OwnerReference{}, "/var/lib/rook/", false)
+	assert.Nil(t, c.Start(context.Background()))
+	deployments, err := clientset.AppsV1().Deployments("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(deployments.Items))
+}
@@ -46,6 +46,7 @@ func (m *Mirroring) makeDeployment(daemonConfig *daemonConfig) *apps.Deployment
 		podSpec.Spec.DNSPolicy = v1.DNSClusterFirstWithHostNet
 	}
 	m.placement.ApplyToPodSpec(&podSpec.Spec)
+	m.cephVersion.ApplyImagePullPolicyToPodSpec(&podSpec.Spec)
 
 	replicas := int32(1)
 	d := &apps.Deployment{
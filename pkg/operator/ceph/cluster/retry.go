@@ -0,0 +1,73 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"strconv"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+const (
+	// the env var to set the max fraction of the retry interval that is randomly added to each orchestration retry
+	orchestrationRetryJitterEnvVar  = "ROOK_ORCHESTRATION_RETRY_JITTER"
+	defaultOrchestrationRetryJitter = 0.5
+)
+
+// orchestrationRetryJitter parses the jitter factor of the orchestration retries
+func orchestrationRetryJitter(value string) float64 {
+	if value == "" {
+		return defaultOrchestrationRetryJitter
+	}
+	jitter, err := strconv.ParseFloat(value, 64)
+	if err != nil || jitter < 0 {
+		logger.Warningf("invalid value %q for %s, it must be a number of at least 0. using %.2f", value, orchestrationRetryJitterEnvVar, defaultOrchestrationRetryJitter)
+		return defaultOrchestrationRetryJitter
+	}
+	return jitter
+}
+
+// orchestrationRetryDelay returns the delay before the next orchestration retry. A random delay of up to
+// jitter*interval is added so the retries of many clusters are spread out after a failure of the API server.
+func orchestrationRetryDelay(interval time.Duration, jitter float64) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	return wait.Jitter(interval, jitter)
+}
+
+// pollWithJitter runs the condition until it is done, fails, or the timeout expires. Like wait.Poll the
+// condition is run for the first time after the first delay, but each delay is jittered.
+func pollWithJitter(interval, timeout time.Duration, jitter float64, condition wait.ConditionFunc) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		delay := orchestrationRetryDelay(interval, jitter)
+		if time.Now().Add(delay).After(deadline) {
+			return wait.ErrWaitTimeout
+		}
+		<-time.After(delay)
+
+		done, err := condition()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+func TestOrchestrationRetryDelay(t *testing.T) {
+	interval := 6 * time.Second
+
+	// no jitter keeps the interval
+	assert.Equal(t, interval, orchestrationRetryDelay(interval, 0))
+
+	// the jittered delay is between the interval and interval*(1+jitter)
+	for i := 0; i < 100; i++ {
+		delay := orchestrationRetryDelay(interval, 0.5)
+		assert.True(t, delay >= interval, delay.String())
+		assert.True(t, delay <= 9*time.Second, delay.String())
+	}
+}
+
+func TestOrchestrationRetryJitter(t *testing.T) {
+	assert.Equal(t, defaultOrchestrationRetryJitter, orchestrationRetryJitter(""))
+	assert.Equal(t, 0.2, orchestrationRetryJitter("0.2"))
+	assert.Equal(t, 0.0, orchestrationRetryJitter("0"))
+	assert.Equal(t, defaultOrchestrationRetryJitter, orchestrationRetryJitter("-1"))
+	assert.Equal(t, defaultOrchestrationRetryJitter, orchestrationRetryJitter("abc"))
+}
+
+func TestPollWithJitter(t *testing.T) {
+	count := 0
+	err := pollWithJitter(time.Millisecond, time.Second, 0.5, func() (bool, error) {
+		count++
+		return count == 3, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	err = pollWithJitter(time.Millisecond, 10*time.Millisecond, 0.5, func() (bool, error) {
+		return false, nil
+	})
+	assert.Equal(t, wait.ErrWaitTimeout, err)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"reflect"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// the env var to set how the changes of the cluster spec are logged. "full" logs the diff of the values, "paths"
+	// only logs the paths of the changed fields and logs the diff at the debug level.
+	specDiffLoggingEnvVar  = "ROOK_SPEC_DIFF_LOGGING"
+	specDiffLoggingFull    = "full"
+	specDiffLoggingPaths   = "paths"
+	defaultSpecDiffLogging = specDiffLoggingFull
+)
+
+var quantityType = reflect.TypeOf(resource.Quantity{})
+
+// specDiffLogging parses how the changes of the cluster spec are logged
+func specDiffLogging(value string) string {
+	switch value {
+	case "":
+		return defaultSpecDiffLogging
+	case specDiffLoggingFull, specDiffLoggingPaths:
+		return value
+	}
+	logger.Warningf("invalid value %q for %s, it must be %q or %q. using %q", value, specDiffLoggingEnvVar, specDiffLoggingFull, specDiffLoggingPaths, defaultSpecDiffLogging)
+	return defaultSpecDiffLogging
+}
+
+// changedSpecFields returns the json paths of the fields that differ between the two specs, without their values.
+// The fields of nested structs are compared one by one, any other field is reported as a whole.
+func changedSpecFields(oldSpec, newSpec interface{}) []string {
+	paths := []string{}
+	appendChangedFields(reflect.ValueOf(oldSpec), reflect.ValueOf(newSpec), "", &paths)
+	return paths
+}
+
+func appendChangedFields(oldValue, newValue reflect.Value, path string, paths *[]string) {
+	if oldValue.Type() == quantityType {
+		oldQty := oldValue.Interface().(resource.Quantity)
+		newQty := newValue.Interface().(resource.Quantity)
+		if oldQty.Cmp(newQty) != 0 {
+			*paths = append(*paths, path)
+		}
+		return
+	}
+	if oldValue.Kind() != reflect.Struct {
+		if !reflect.DeepEqual(oldValue.Interface(), newValue.Interface()) {
+			*paths = append(*paths, path)
+		}
+		return
+	}
+
+	for i := 0; i < oldValue.NumField(); i++ {
+		field := oldValue.Type().Field(i)
+		if field.PkgPath != "" {
+			// unexported
+			continue
+		}
+		name := specFieldName(field)
+		if name == "-" {
+			continue
+		}
+		fieldPath := path
+		if name != "" {
+			fieldPath = strings.TrimPrefix(path+"."+name, ".")
+		}
+		appendChangedFields(oldValue.Field(i), newValue.Field(i), fieldPath, paths)
+	}
+}
+
+// specFieldName returns the json name of the field. The name of inlined structs is empty.
+func specFieldName(field reflect.StructField) string {
+	tag := field.Tag.Get("json")
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		if strings.Contains(tag, "inline") || field.Anonymous {
+			return ""
+		}
+		return field.Name
+	}
+	return name
+}
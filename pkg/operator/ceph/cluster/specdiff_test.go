@@ -0,0 +1,47 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookalpha "github.com/rook/rook/pkg/apis/rook.io/v1alpha2"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestSpecDiffLogging(t *testing.T) {
+	assert.Equal(t, specDiffLoggingFull, specDiffLogging(""))
+	assert.Equal(t, specDiffLoggingPaths, specDiffLogging("paths"))
+	assert.Equal(t, specDiffLoggingFull, specDiffLogging("values"))
+}
+
+func TestChangedSpecFields(t *testing.T) {
+	old := cephv1.ClusterSpec{
+		Mon:       cephv1.MonSpec{Count: 3},
+		Resources: rookalpha.ResourceSpec{"mgr": v1.ResourceRequirements{Limits: v1.ResourceList{v1.ResourceMemory: resource.MustParse("1Gi")}}},
+	}
+	new := *old.DeepCopy()
+	assert.Equal(t, []string{}, changedSpecFields(old, new))
+
+	new.Mon.Count = 5
+	new.Dashboard.Enabled = true
+	new.Storage.Nodes = []rookalpha.Node{{Name: "node1"}}
+	assert.Equal(t, []string{"storage.nodes", "mon.count", "dashboard.enabled"}, changedSpecFields(old, new))
+}
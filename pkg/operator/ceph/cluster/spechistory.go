@@ -0,0 +1,102 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	specHistoryAppName      = "rook-ceph-spec-history"
+	specHistoryPrefix       = "rook-ceph-spec-"
+	specHistoryKey          = "spec"
+	defaultSpecHistoryLimit = 10
+)
+
+// saveSpecRevision writes the cluster spec to a ConfigMap named after the time of the change and prunes the oldest
+// revisions beyond the history limit. The ConfigMaps are owned by the cluster so they are removed with it.
+func (c *cluster) saveSpecRevision(spec cephv1.ClusterSpec) error {
+	limit := specHistoryLimit(spec)
+	if limit <= 0 {
+		return nil
+	}
+
+	serialized, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the spec of cluster %s. %+v", c.Namespace, err)
+	}
+
+	now := time.Now().UTC()
+	cm := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			// the name sorts by the time of the change
+			Name:      fmt.Sprintf("%s%s-%09d", specHistoryPrefix, now.Format("20060102-150405"), now.Nanosecond()),
+			Namespace: c.Namespace,
+			Labels:    map[string]string{k8sutil.AppAttr: specHistoryAppName},
+		},
+		Data: map[string]string{specHistoryKey: string(serialized)},
+	}
+	k8sutil.SetOwnerRef(&cm.ObjectMeta, &c.ownerRef)
+	if _, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Create(cm); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to save the spec revision %s. %+v", cm.Name, err)
+	}
+	logger.Infof("saved the spec of cluster %s in configmap %s", c.Namespace, cm.Name)
+
+	return c.pruneSpecRevisions(limit)
+}
+
+// pruneSpecRevisions deletes the oldest revisions of the spec so at most limit revisions are kept
+func (c *cluster) pruneSpecRevisions(limit int) error {
+	opts := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, specHistoryAppName)}
+	revisions, err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).List(opts)
+	if err != nil {
+		return fmt.Errorf("failed to list the spec revisions of cluster %s. %+v", c.Namespace, err)
+	}
+	if len(revisions.Items) <= limit {
+		return nil
+	}
+
+	names := []string{}
+	for _, revision := range revisions.Items {
+		names = append(names, revision.Name)
+	}
+	sort.Strings(names)
+	for _, name := range names[:len(names)-limit] {
+		if err := c.context.Clientset.CoreV1().ConfigMaps(c.Namespace).Delete(name, &metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete the spec revision %s. %+v", name, err)
+		}
+		logger.Debugf("deleted the spec revision %s", name)
+	}
+	return nil
+}
+
+// specHistoryLimit returns the number of spec revisions to keep
+func specHistoryLimit(spec cephv1.ClusterSpec) int {
+	if spec.SpecHistoryLimit == 0 {
+		return defaultSpecHistoryLimit
+	}
+	return spec.SpecHistoryLimit
+}
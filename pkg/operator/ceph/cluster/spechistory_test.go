@@ -0,0 +1,59 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"sort"
+	"testing"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSaveSpecRevision(t *testing.T) {
+	c := testSpec()
+	c.Namespace = "ns"
+
+	spec := cephv1.ClusterSpec{SpecHistoryLimit: 2}
+	for _, path := range []string{"/var/lib/rook1", "/var/lib/rook2", "/var/lib/rook3"} {
+		spec.DataDirHostPath = path
+		assert.Nil(t, c.saveSpecRevision(spec))
+	}
+
+	// only the latest revisions are kept
+	revisions, err := c.context.Clientset.CoreV1().ConfigMaps("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions.Items))
+	sort.Slice(revisions.Items, func(i, j int) bool { return revisions.Items[i].Name < revisions.Items[j].Name })
+	var saved cephv1.ClusterSpec
+	assert.Nil(t, json.Unmarshal([]byte(revisions.Items[1].Data[specHistoryKey]), &saved))
+	assert.Equal(t, "/var/lib/rook3", saved.DataDirHostPath)
+
+	// the history can be disabled
+	spec.SpecHistoryLimit = -1
+	assert.Nil(t, c.saveSpecRevision(spec))
+	revisions, err = c.context.Clientset.CoreV1().ConfigMaps("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(revisions.Items))
+}
+
+func TestSpecHistoryLimit(t *testing.T) {
+	assert.Equal(t, defaultSpecHistoryLimit, specHistoryLimit(cephv1.ClusterSpec{}))
+	assert.Equal(t, 3, specHistoryLimit(cephv1.ClusterSpec{SpecHistoryLimit: 3}))
+}
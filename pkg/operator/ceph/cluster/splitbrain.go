@@ -0,0 +1,104 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+)
+
+var (
+	// how long to wait for the quorum status of a single mon
+	monQuorumStatusTimeout = 15 * time.Second
+	// the quorums are compared a second time after this delay before the split brain is reported, since the
+	// views of the mons may differ for a moment during an election
+	monSplitBrainRecheckDelay = 10 * time.Second
+)
+
+// checkMonSplitBrain compares the quorum reported by each mon. When the mons report different quorums, for
+// example because a mon was restored with the data of an old monmap, continuing the orchestration could make it
+// worse, so the MonSplitBrain condition is set with the manual recovery steps and the orchestration is stopped.
+func (c *cluster) checkMonSplitBrain(ctx context.Context) error {
+	quorums := c.monQuorumViews()
+	if len(quorums) > 1 {
+		logger.Warningf("the mons of cluster %s report different quorums %v. checking again in %s", c.Namespace, quorums, monSplitBrainRecheckDelay)
+		select {
+		case <-time.After(monSplitBrainRecheckDelay):
+		case <-ctx.Done():
+			return fmt.Errorf("canceled the split brain check of the mons of cluster %s. %+v", c.Namespace, ctx.Err())
+		}
+		quorums = c.monQuorumViews()
+	}
+
+	problem := ""
+	if len(quorums) > 1 {
+		problem = c.monSplitBrainMessage(quorums)
+	}
+	c.reportCondition(cephv1.ClusterConditionMonSplitBrain, problem, "MonQuorumAgreed", "the mons agree on the quorum")
+	if problem != "" {
+		return fmt.Errorf("%s", problem)
+	}
+	return nil
+}
+
+// monQuorumViews returns the mons that report each quorum, keyed by the sorted names of the mons in the quorum.
+// The mons that do not respond are not in a quorum and are skipped.
+func (c *cluster) monQuorumViews() map[string][]string {
+	quorums := map[string][]string{}
+	for _, mon := range c.Info.Monitors {
+		status, err := client.GetMonQuorumStatusFromMon(c.context, c.Info.Name, mon.Endpoint, monQuorumStatusTimeout)
+		if err != nil {
+			logger.Warningf("failed to get the quorum status from mon %s. %+v", mon.Name, err)
+			continue
+		}
+		if len(status.QuorumNames) == 0 {
+			continue
+		}
+		names := append([]string{}, status.QuorumNames...)
+		sort.Strings(names)
+		key := strings.Join(names, ",")
+		quorums[key] = append(quorums[key], mon.Name)
+	}
+	for key := range quorums {
+		sort.Strings(quorums[key])
+	}
+	return quorums
+}
+
+// monSplitBrainMessage describes the quorums and the steps to recover from the split brain
+func (c *cluster) monSplitBrainMessage(quorums map[string][]string) string {
+	keys := []string{}
+	for key := range quorums {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	views := []string{}
+	for _, key := range keys {
+		views = append(views, fmt.Sprintf("mons %s report the quorum [%s]", strings.Join(quorums[key], ","), key))
+	}
+	return fmt.Sprintf("the mons of cluster %s report different quorums: %s. the orchestration is stopped until the quorums agree. "+
+		"to recover, keep the quorum with the majority of the mons and for each mon of the other quorums: "+
+		"scale down its deployment with 'kubectl -n %s scale deployment rook-ceph-mon-<id> --replicas=0', "+
+		"back up and remove its data in %s/mon-<id>, and let the operator fail over the mon",
+		c.Namespace, strings.Join(views, "; "), c.Namespace, c.Spec.DataDirHostPath)
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCheckMonSplitBrain(t *testing.T) {
+	defer func(delay time.Duration) { monSplitBrainRecheckDelay = delay }(monSplitBrainRecheckDelay)
+	monSplitBrainRecheckDelay = 0
+	ctx := context.Background()
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	// the quorum reported by the mon of each endpoint
+	quorums := map[string]string{
+		"1.1.1.1:6789": `["a","b","c"]`,
+		"2.2.2.2:6789": `["c","a","b"]`,
+		"3.3.3.3:6789": `["a","b","c"]`,
+	}
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFileTimeout: func(debug bool, timeout time.Duration, actionName, command, outfileArg string, args ...string) (string, error) {
+			if args[0] == "quorum_status" && args[1] == "-m" {
+				if quorum, ok := quorums[args[2]]; ok {
+					return `{"quorum_names":` + quorum + `}`, nil
+				}
+				return "", fmt.Errorf("timed out")
+			}
+			return "", fmt.Errorf("unexpected command %v", args)
+		},
+	}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+		Executor:      executor,
+	}
+	c := newCluster(cephCluster, context, nil)
+	c.Spec.DataDirHostPath = "/var/lib/rook"
+	c.Info = &cephconfig.ClusterInfo{Name: "ns", Monitors: map[string]*cephconfig.MonInfo{
+		"a": {Name: "a", Endpoint: "1.1.1.1:6789"},
+		"b": {Name: "b", Endpoint: "2.2.2.2:6789"},
+		"c": {Name: "c", Endpoint: "3.3.3.3:6789"},
+	}}
+
+	assert.Nil(t, c.checkMonSplitBrain(ctx))
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cephv1.ClusterConditionMonSplitBrain, updated.Status.Conditions[0].Type)
+	assert.Equal(t, v1.ConditionFalse, updated.Status.Conditions[0].Status)
+
+	// a mon that is not in quorum does not respond
+	delete(quorums, "3.3.3.3:6789")
+	assert.Nil(t, c.checkMonSplitBrain(ctx))
+
+	// mon c formed its own quorum
+	quorums["1.1.1.1:6789"] = `["a","b"]`
+	quorums["2.2.2.2:6789"] = `["a","b"]`
+	quorums["3.3.3.3:6789"] = `["c"]`
+	assert.NotNil(t, c.checkMonSplitBrain(ctx))
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, v1.ConditionTrue, updated.Status.Conditions[0].Status)
+	message := updated.Status.Conditions[0].Message
+	assert.True(t, strings.Contains(message, "mons a,b report the quorum [a,b]; mons c report the quorum [c]"))
+	assert.True(t, strings.Contains(message, "/var/lib/rook/mon-<id>"))
+
+	// the split brain is only recorded as an event when it changed
+	assert.NotNil(t, c.checkMonSplitBrain(ctx))
+	events, err := context.Clientset.CoreV1().Events("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events.Items))
+
+	// the recheck stops when the orchestration is canceled
+	monSplitBrainRecheckDelay = time.Hour
+	err = c.checkMonSplitBrain(canceled)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "canceled the split brain check")
+}
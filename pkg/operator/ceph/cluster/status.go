@@ -0,0 +1,306 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/clusterd"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	eventSourceComponent = "rook-ceph-operator"
+)
+
+// recordEvent creates a kubernetes event on the CephCluster CR so the user can find it with `kubectl describe`
+func (c *cluster) recordEvent(eventType, reason, message string) {
+	now := metav1.Now()
+	event := &v1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", c.crdName, now.UnixNano()),
+			Namespace: c.Namespace,
+		},
+		InvolvedObject: v1.ObjectReference{
+			APIVersion: c.ownerRef.APIVersion,
+			Kind:       c.ownerRef.Kind,
+			Name:       c.crdName,
+			Namespace:  c.Namespace,
+			UID:        c.ownerRef.UID,
+		},
+		Reason:         reason,
+		Message:        message,
+		Type:           eventType,
+		Count:          1,
+		FirstTimestamp: now,
+		LastTimestamp:  now,
+		Source:         v1.EventSource{Component: eventSourceComponent},
+	}
+	if _, err := c.context.Clientset.CoreV1().Events(c.Namespace).Create(event); err != nil {
+		logger.Warningf("failed to record event %s on cluster %s. %+v", reason, c.Namespace, err)
+	}
+}
+
+// updateClusterStatus updates the status of the CephCluster CR. The CRDs created before the status subresource was
+// enabled do not serve the status endpoint, so the status is updated with the whole CR in that case.
+func updateClusterStatus(context *clusterd.Context, cephCluster *cephv1.CephCluster) error {
+	clusters := context.RookClientset.CephV1().CephClusters(cephCluster.Namespace)
+	_, err := clusters.UpdateStatus(cephCluster)
+	if err != nil && errors.IsNotFound(err) {
+		logger.Debugf("the status subresource of cluster %s is not found, updating the whole CR. %+v", cephCluster.Namespace, err)
+		_, err = clusters.Update(cephCluster)
+	}
+	return err
+}
+
+// updateStatusCondition sets the condition in the status of the CephCluster CR. It returns whether the status or the
+// message of the condition changed, which is assumed when the CR cannot be read.
+func (c *cluster) updateStatusCondition(condition cephv1.ClusterCondition) bool {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to set the %s condition. %+v", c.Namespace, condition.Type, err)
+		return true
+	}
+
+	changed := true
+	for _, existing := range cephCluster.Status.Conditions {
+		if existing.Type == condition.Type {
+			changed = existing.Status != condition.Status || existing.Message != condition.Message
+		}
+	}
+	cephCluster.Status.Conditions = setClusterCondition(cephCluster.Status.Conditions, condition)
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to set the %s condition on cluster %s. %+v", condition.Type, c.Namespace, err)
+	}
+	return changed
+}
+
+// reportCondition sets the condition in the status of the CephCluster CR from the result of a check. A problem sets
+// the condition with the reason of its type and is logged, and recorded as a warning event when the condition
+// changed. Without a problem the condition is cleared with the reason and message of the passed check.
+func (c *cluster) reportCondition(conditionType cephv1.ClusterConditionType, problem, okReason, okMessage string) {
+	condition := cephv1.ClusterCondition{
+		Type:    conditionType,
+		Status:  v1.ConditionFalse,
+		Reason:  okReason,
+		Message: okMessage,
+	}
+	if problem != "" {
+		logger.Warning(problem)
+		condition.Status = v1.ConditionTrue
+		condition.Reason = string(conditionType)
+		condition.Message = problem
+	}
+	// the same problem is reported by each check, but only recorded as an event when it changed
+	if c.updateStatusCondition(condition) && problem != "" {
+		c.recordEvent(v1.EventTypeWarning, string(conditionType), problem)
+	}
+}
+
+// updateOrchestrationStatus records the completion time and duration of a successful orchestration in the
+// status of the CephCluster CR so stalled clusters can be detected. The generation of the orchestrated spec is
+// recorded as the observed generation, so tools can wait until the operator applied the latest spec. The ceph image
+// of the orchestrated spec is recorded as the last applied image, even if the spec changed during the orchestration.
+func (c *cluster) updateOrchestrationStatus(startTime time.Time, generation int64, cephImage string) {
+	c.orchMux.Lock()
+	c.lastOrchestrationTime = time.Now()
+	c.orchMux.Unlock()
+
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to update the orchestration status. %+v", c.Namespace, err)
+		return
+	}
+
+	now := time.Now()
+	cephCluster.Status.LastOrchestrationTime = formatTime(now.UTC())
+	cephCluster.Status.LastOrchestrationDuration = now.Sub(startTime).Round(time.Second).String()
+	cephCluster.Status.ObservedGeneration = generation
+	if cephImage != "" {
+		cephCluster.Status.LastAppliedCephImage = cephImage
+	}
+	// the error of a previous failed orchestration does not apply anymore
+	cephCluster.Status.LastOrchestrationError = ""
+	cephCluster.Status.LastOrchestrationMessages = nil
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to update the orchestration status of cluster %s. %+v", c.Namespace, err)
+	}
+}
+
+// updatePendingNodeRemovals records the removed nodes that wait for the confirmation of their removal in the
+// status of the CephCluster CR. Nil means the removed nodes were not checked, so the status is kept.
+func (c *cluster) updatePendingNodeRemovals(nodes []string) {
+	if nodes == nil {
+		return
+	}
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to update the pending node removals. %+v", c.Namespace, err)
+		return
+	}
+	if len(nodes) == 0 && len(cephCluster.Status.PendingNodeRemovals) == 0 {
+		return
+	}
+	if len(nodes) > 0 {
+		logger.Warningf("the removal of the nodes %v of cluster %s is pending. confirm it with the annotation %s", nodes, c.Namespace, confirmNodeRemovalAnnotation)
+	}
+
+	cephCluster.Status.PendingNodeRemovals = nodes
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to update the pending node removals of cluster %s. %+v", c.Namespace, err)
+	}
+}
+
+// updateMgrDebugLevelStatus records the applied mgr debug level in the status of the CephCluster CR
+func (c *cluster) updateMgrDebugLevelStatus(level string) {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to update the mgr debug level. %+v", c.Namespace, err)
+		return
+	}
+	cephCluster.Status.MgrDebugLevel = level
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to update the mgr debug level of cluster %s. %+v", c.Namespace, err)
+	}
+}
+
+// updateOutOSDStatus records the OSD that was marked out and stopped in the status of the CephCluster CR
+func (c *cluster) updateOutOSDStatus(osdID string) error {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get cluster %s to update the out osd. %+v", c.Namespace, err)
+	}
+	cephCluster.Status.OutOSD = osdID
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		return fmt.Errorf("failed to update the out osd of cluster %s. %+v", c.Namespace, err)
+	}
+	return nil
+}
+
+// updateDaemonVersionsStatus records the number of daemons running each ceph version in the status of the CephCluster
+// CR. The status is only updated when the versions changed.
+func (c *cluster) updateDaemonVersionsStatus(versions client.CephDaemonsVersions) {
+	daemonVersions := formatDaemonVersions(versions)
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to update the daemon versions. %+v", c.Namespace, err)
+		return
+	}
+	if reflect.DeepEqual(cephCluster.Status.DaemonVersions, daemonVersions) {
+		return
+	}
+	logger.Infof("ceph versions of the daemons of cluster %s: %v", c.Namespace, daemonVersions)
+	cephCluster.Status.DaemonVersions = daemonVersions
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to update the daemon versions of cluster %s. %+v", c.Namespace, err)
+	}
+}
+
+// formatDaemonVersions summarizes the versions of each type of daemon, e.g. "osd": "10 on 14.2.4, 2 on 13.2.6".
+// The daemon types without any daemon are omitted.
+func formatDaemonVersions(versions client.CephDaemonsVersions) map[string]string {
+	daemons := map[string]map[string]int{
+		"mon":        versions.Mon,
+		"mgr":        versions.Mgr,
+		"osd":        versions.Osd,
+		"rgw":        versions.Rgw,
+		"mds":        versions.Mds,
+		"rbd-mirror": versions.RbdMirror,
+		"overall":    versions.Overall,
+	}
+	formatted := map[string]string{}
+	for daemon, counts := range daemons {
+		if len(counts) == 0 {
+			continue
+		}
+		entries := []string{}
+		for v, count := range counts {
+			name := v
+			if version, err := cephver.ExtractCephVersion(v); err == nil {
+				name = fmt.Sprintf("%d.%d.%d", version.Major, version.Minor, version.Extra)
+			}
+			entries = append(entries, fmt.Sprintf("%d on %s", count, name))
+		}
+		sort.Strings(entries)
+		formatted[daemon] = strings.Join(entries, ", ")
+	}
+	return formatted
+}
+
+// resetOSDProvisioningStatus clears the OSD provisioning status of the previous orchestration, so the nodes that
+// are no longer provisioned are not reported
+func (c *cluster) resetOSDProvisioningStatus() {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to reset the osd provisioning status. %+v", c.Namespace, err)
+		return
+	}
+	if len(cephCluster.Status.OSDProvisioning) == 0 {
+		return
+	}
+	cephCluster.Status.OSDProvisioning = nil
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to reset the osd provisioning status of cluster %s. %+v", c.Namespace, err)
+	}
+}
+
+// updateOSDProvisioningStatus records the state of the OSD provisioning on the node in the status of the
+// CephCluster CR, so the progress of a rollout can be watched with kubectl
+func (c *cluster) updateOSDProvisioningStatus(node string, state cephv1.OSDProvisioningState, message string) {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to update the osd provisioning status of node %s. %+v", c.Namespace, node, err)
+		return
+	}
+	nodeStatus := cephv1.NodeOSDProvisioningStatus{State: state, Message: message}
+	if current, ok := cephCluster.Status.OSDProvisioning[node]; ok && current == nodeStatus {
+		return
+	}
+	if cephCluster.Status.OSDProvisioning == nil {
+		cephCluster.Status.OSDProvisioning = map[string]cephv1.NodeOSDProvisioningStatus{}
+	}
+	cephCluster.Status.OSDProvisioning[node] = nodeStatus
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to update the osd provisioning status of node %s in cluster %s. %+v", node, c.Namespace, err)
+	}
+}
+
+// setClusterCondition adds the condition or replaces the existing condition of the same type.
+// The transition time is only updated when the status of the condition changes.
+func setClusterCondition(conditions []cephv1.ClusterCondition, condition cephv1.ClusterCondition) []cephv1.ClusterCondition {
+	condition.LastTransitionTime = formatTime(time.Now().UTC())
+	for i := range conditions {
+		if conditions[i].Type != condition.Type {
+			continue
+		}
+		if conditions[i].Status == condition.Status {
+			condition.LastTransitionTime = conditions[i].LastTransitionTime
+		}
+		conditions[i] = condition
+		return conditions
+	}
+	return append(conditions, condition)
+}
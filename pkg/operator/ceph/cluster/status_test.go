@@ -0,0 +1,174 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	testop "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSetClusterCondition(t *testing.T) {
+	condition := cephv1.ClusterCondition{Type: cephv1.ClusterConditionVersionMismatch, Status: v1.ConditionTrue, Reason: "a"}
+	conditions := setClusterCondition(nil, condition)
+	assert.Equal(t, 1, len(conditions))
+	assert.Equal(t, v1.ConditionTrue, conditions[0].Status)
+	assert.NotEqual(t, "", conditions[0].LastTransitionTime)
+
+	// the transition time is kept when the status does not change
+	conditions[0].LastTransitionTime = "2019-01-01T00:00:00Z"
+	condition.Reason = "b"
+	conditions = setClusterCondition(conditions, condition)
+	assert.Equal(t, 1, len(conditions))
+	assert.Equal(t, "b", conditions[0].Reason)
+	assert.Equal(t, "2019-01-01T00:00:00Z", conditions[0].LastTransitionTime)
+
+	// the transition time is updated when the status changes
+	condition.Status = v1.ConditionFalse
+	conditions = setClusterCondition(conditions, condition)
+	assert.Equal(t, 1, len(conditions))
+	assert.Equal(t, v1.ConditionFalse, conditions[0].Status)
+	assert.NotEqual(t, "2019-01-01T00:00:00Z", conditions[0].LastTransitionTime)
+
+	// a condition of another type is added
+	conditions = setClusterCondition(conditions, cephv1.ClusterCondition{Type: "other", Status: v1.ConditionTrue})
+	assert.Equal(t, 2, len(conditions))
+}
+
+func TestRecordEventAndCondition(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+
+	c.recordEvent(v1.EventTypeWarning, "VersionMismatch", "my message")
+	events, err := context.Clientset.CoreV1().Events("ns").List(metav1.ListOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(events.Items))
+	assert.Equal(t, v1.EventTypeWarning, events.Items[0].Type)
+	assert.Equal(t, "my-cluster", events.Items[0].InvolvedObject.Name)
+	assert.Equal(t, "my message", events.Items[0].Message)
+
+	c.updateStatusCondition(cephv1.ClusterCondition{Type: cephv1.ClusterConditionVersionMismatch, Status: v1.ConditionTrue})
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(updated.Status.Conditions))
+	assert.Equal(t, v1.ConditionTrue, updated.Status.Conditions[0].Status)
+}
+
+func TestUpdateOrchestrationStatus(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+
+	c.updateOrchestrationStatus(time.Now().Add(-time.Minute), 3, "ceph/ceph:v14.2.4")
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.NotEqual(t, "", updated.Status.LastOrchestrationTime)
+	assert.False(t, c.lastOrchestrationTime.IsZero())
+	assert.Equal(t, "1m0s", updated.Status.LastOrchestrationDuration)
+	assert.Equal(t, int64(3), updated.Status.ObservedGeneration)
+	assert.Equal(t, "ceph/ceph:v14.2.4", updated.Status.LastAppliedCephImage)
+}
+
+func TestUpdateClusterStatusWithoutSubresource(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	clientset := rookfake.NewSimpleClientset(cephCluster)
+	context := &clusterd.Context{RookClientset: clientset}
+
+	// the CRDs of the older versions do not serve the status subresource
+	clientset.PrependReactor("update", "cephclusters", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() == "status" {
+			return true, nil, errors.NewNotFound(cephv1.Resource("cephclusters"), "my-cluster")
+		}
+		return false, nil, nil
+	})
+
+	cephCluster.Status.Message = "updated"
+	assert.Nil(t, updateClusterStatus(context, cephCluster))
+	updated, err := clientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "updated", updated.Status.Message)
+}
+
+func TestUpdatePendingNodeRemovals(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+
+	c.updatePendingNodeRemovals([]string{"node1"})
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"node1"}, updated.Status.PendingNodeRemovals)
+
+	// the status is kept if the removed nodes were not checked
+	c.updatePendingNodeRemovals(nil)
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"node1"}, updated.Status.PendingNodeRemovals)
+
+	c.updatePendingNodeRemovals([]string{})
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(updated.Status.PendingNodeRemovals))
+}
+
+func TestUpdateOSDProvisioningStatus(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+
+	c.updateOSDProvisioningStatus("node1", cephv1.OSDProvisioningInProgress, "")
+	c.updateOSDProvisioningStatus("node2", cephv1.OSDProvisioningFailed, "failed to start osd provisioning")
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(updated.Status.OSDProvisioning))
+	assert.Equal(t, cephv1.OSDProvisioningInProgress, updated.Status.OSDProvisioning["node1"].State)
+	assert.Equal(t, "failed to start osd provisioning", updated.Status.OSDProvisioning["node2"].Message)
+
+	c.updateOSDProvisioningStatus("node1", cephv1.OSDProvisioningComplete, "")
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, cephv1.OSDProvisioningComplete, updated.Status.OSDProvisioning["node1"].State)
+
+	// the next orchestration starts with a clean status
+	c.resetOSDProvisioningStatus()
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, len(updated.Status.OSDProvisioning))
+}
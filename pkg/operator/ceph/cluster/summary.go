@@ -0,0 +1,91 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+const (
+	// ClusterStatusesPath is the path of the endpoint that serves the status summary of all the clusters
+	ClusterStatusesPath = "/clusters/status"
+)
+
+// ClusterStatusSummary is the status of a cluster managed by the operator, e.g. for an overview of a fleet of
+// clusters
+type ClusterStatusSummary struct {
+	Namespace             string `json:"namespace"`
+	Name                  string `json:"name"`
+	CephVersion           string `json:"cephVersion,omitempty"`
+	Health                string `json:"health,omitempty"`
+	UpgradeInProgress     bool   `json:"upgradeInProgress"`
+	LastOrchestrationTime string `json:"lastOrchestrationTime,omitempty"`
+}
+
+// statusSummary returns the status of the cluster known by the operator, without querying the cluster
+func (c *cluster) statusSummary() ClusterStatusSummary {
+	summary := ClusterStatusSummary{
+		Namespace: c.Namespace,
+		Name:      c.crdName,
+	}
+	// the identity is cached by the mons when they release the orchestration lock, so a running orchestration
+	// does not delay the summary
+	if c.mons != nil {
+		if fsid, cephVersion := c.mons.Identity(); fsid != "" {
+			summary.CephVersion = cephVersion.String()
+		}
+	}
+
+	c.orchMux.Lock()
+	summary.UpgradeInProgress = c.upgradeInProgress
+	if !c.lastOrchestrationTime.IsZero() {
+		summary.LastOrchestrationTime = formatTime(c.lastOrchestrationTime.UTC())
+	}
+	c.orchMux.Unlock()
+
+	if health := c.HealthSummary(); health != nil {
+		summary.Health = health.Status
+	}
+	return summary
+}
+
+// AllClusterStatuses returns the status of all the clusters managed by the operator, sorted by namespace
+func (c *ClusterController) AllClusterStatuses() []ClusterStatusSummary {
+	clusters := c.trackedClusters()
+	summaries := make([]ClusterStatusSummary, 0, len(clusters))
+	for _, cluster := range clusters {
+		summaries = append(summaries, cluster.statusSummary())
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Namespace < summaries[j].Namespace })
+	return summaries
+}
+
+// ServeClusterStatuses is the handler of the endpoint that serves the status of all the clusters as json
+func (c *ClusterController) ServeClusterStatuses(w http.ResponseWriter, req *http.Request) {
+	body, err := json.MarshalIndent(c.AllClusterStatuses(), "", "  ")
+	if err != nil {
+		logger.Errorf("failed to serialize the status of the clusters. %+v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if _, err := w.Write(body); err != nil {
+		logger.Warningf("failed to write the status of the clusters. %+v", err)
+	}
+}
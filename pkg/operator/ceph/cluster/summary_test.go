@@ -0,0 +1,67 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllClusterStatuses(t *testing.T) {
+	c := &ClusterController{clusterMap: map[string]*cluster{}}
+	assert.Equal(t, []ClusterStatusSummary{}, c.AllClusterStatuses())
+
+	// a new cluster is not orchestrated yet
+	c.clusterMap["ns2"] = &cluster{Namespace: "ns2", crdName: "cluster2"}
+	c.clusterMap["ns1"] = &cluster{
+		Namespace:             "ns1",
+		crdName:               "cluster1",
+		upgradeInProgress:     true,
+		lastOrchestrationTime: time.Date(2019, 10, 1, 12, 0, 0, 0, time.UTC),
+		healthSummary:         &CephHealthSummary{Status: "HEALTH_WARN"},
+		mons:                  &mon.Cluster{ClusterInfo: &cephconfig.ClusterInfo{FSID: "myfsid", AdminSecret: "adminsecret", CephVersion: cephver.Nautilus}},
+	}
+	c.clusterMap["ns1"].mons.RefreshInfoSnapshot()
+
+	w := httptest.NewRecorder()
+	c.ServeClusterStatuses(w, httptest.NewRequest("GET", ClusterStatusesPath, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	assert.NotContains(t, w.Body.String(), "adminsecret")
+
+	var summaries []ClusterStatusSummary
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), &summaries))
+	assert.Equal(t, []ClusterStatusSummary{
+		{
+			Namespace:             "ns1",
+			Name:                  "cluster1",
+			CephVersion:           cephver.Nautilus.String(),
+			Health:                "HEALTH_WARN",
+			UpgradeInProgress:     true,
+			LastOrchestrationTime: "2019-10-01T12:00:00Z",
+		},
+		{Namespace: "ns2", Name: "cluster2"},
+	}, summaries)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+)
+
+const (
+	// the env var to trace the orchestrations without a tracer set by the code embedding the operator.
+	// "log" logs each span when it ends.
+	orchestrationTracingEnvVar = "ROOK_ORCHESTRATION_TRACING"
+	tracingLog                 = "log"
+)
+
+// Tracer creates the spans that trace the orchestrations of the clusters. The interface follows the tracer of
+// OpenTelemetry, so an OpenTelemetry exporter can be wired with a small adapter without the operator depending
+// on a tracing library.
+type Tracer interface {
+	// Start starts a span that is a child of the span in the context, if any. The returned context carries the
+	// new span.
+	Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span)
+}
+
+// Span is a traced step of an orchestration
+type Span interface {
+	// End ends the span. The error is nil if the step succeeded.
+	End(err error)
+}
+
+// nullTracer is the default tracer that does not record the spans
+type nullTracer struct{}
+
+type nullSpan struct{}
+
+func (nullTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span) {
+	return ctx, nullSpan{}
+}
+
+func (nullSpan) End(err error) {}
+
+// newTracer returns the tracer configured with the env var of the operator
+func newTracer(value string) Tracer {
+	switch value {
+	case "":
+		return nullTracer{}
+	case tracingLog:
+		return &logTracer{now: time.Now}
+	}
+	logger.Warningf("invalid value %q for %s, the orchestrations are not traced", value, orchestrationTracingEnvVar)
+	return nullTracer{}
+}
+
+type spanContextKey struct{}
+
+// logTracer logs the duration and the result of each span when it ends
+type logTracer struct {
+	now func() time.Time
+}
+
+type logSpan struct {
+	tracer     *logTracer
+	name       string
+	attributes map[string]string
+	start      time.Time
+}
+
+func (t *logTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span) {
+	if parent, ok := ctx.Value(spanContextKey{}).(*logSpan); ok {
+		name = parent.name + "/" + name
+	}
+	span := &logSpan{tracer: t, name: name, attributes: attributes, start: t.now()}
+	return context.WithValue(ctx, spanContextKey{}, span), span
+}
+
+func (s *logSpan) End(err error) {
+	duration := s.tracer.now().Sub(s.start)
+	if err != nil {
+		logger.Infof("span %s ended after %s with error [%s]. %+v", s.name, duration, formatSpanAttributes(s.attributes), err)
+		return
+	}
+	logger.Infof("span %s ended after %s [%s]", s.name, duration, formatSpanAttributes(s.attributes))
+}
+
+// formatSpanAttributes formats the attributes sorted by key
+func formatSpanAttributes(attributes map[string]string) string {
+	pairs := make([]string, 0, len(attributes))
+	for key, value := range attributes {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
+
+// startSpan starts a span of the orchestration of the cluster
+func (c *cluster) startSpan(ctx context.Context, name string, cephVersion cephver.CephVersion) (context.Context, Span) {
+	if c.tracer == nil {
+		return ctx, nullSpan{}
+	}
+	isUpgrade, _ := c.upgradeState()
+	return c.tracer.Start(ctx, name, map[string]string{
+		"namespace": c.Namespace,
+		"version":   cephVersion.String(),
+		"isUpgrade": strconv.FormatBool(isUpgrade),
+	})
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeSpan struct {
+	name       string
+	attributes map[string]string
+	ended      bool
+	err        error
+}
+
+func (s *fakeSpan) End(err error) {
+	s.ended = true
+	s.err = err
+}
+
+type fakeTracer struct {
+	spans []*fakeSpan
+}
+
+func (t *fakeTracer) Start(ctx context.Context, name string, attributes map[string]string) (context.Context, Span) {
+	span := &fakeSpan{name: name, attributes: attributes}
+	t.spans = append(t.spans, span)
+	return ctx, span
+}
+
+func TestNewTracer(t *testing.T) {
+	assert.Equal(t, nullTracer{}, newTracer(""))
+	assert.Equal(t, nullTracer{}, newTracer("jaeger"))
+	_, ok := newTracer("log").(*logTracer)
+	assert.True(t, ok)
+}
+
+func TestStartSpan(t *testing.T) {
+	tracer := &fakeTracer{}
+	c := &cluster{Namespace: "ns", isUpgrade: true, tracer: tracer}
+
+	_, span := c.startSpan(context.Background(), "mons", cephver.Nautilus)
+	span.End(fmt.Errorf("mons failed"))
+	assert.Equal(t, 1, len(tracer.spans))
+	assert.Equal(t, "mons", tracer.spans[0].name)
+	assert.Equal(t, map[string]string{"namespace": "ns", "version": cephver.Nautilus.String(), "isUpgrade": "true"}, tracer.spans[0].attributes)
+	assert.True(t, tracer.spans[0].ended)
+	assert.NotNil(t, tracer.spans[0].err)
+
+	// the spans are not recorded without a tracer
+	c.tracer = nil
+	_, span = c.startSpan(context.Background(), "mons", cephver.Nautilus)
+	span.End(nil)
+	assert.Equal(t, 1, len(tracer.spans))
+}
+
+func TestLogTracer(t *testing.T) {
+	tracer := newTracer(tracingLog)
+	ctx, parent := tracer.Start(context.Background(), "createInstance", nil)
+	_, child := tracer.Start(ctx, "osds", map[string]string{"namespace": "ns"})
+	assert.Equal(t, "createInstance/osds", child.(*logSpan).name)
+	child.End(nil)
+	parent.End(nil)
+
+	assert.Equal(t, "a=1 b=2", formatSpanAttributes(map[string]string{"b": "2", "a": "1"}))
+}
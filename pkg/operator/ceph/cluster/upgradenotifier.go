@@ -0,0 +1,131 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	v1 "k8s.io/api/core/v1"
+)
+
+// UpgradeNotifier is notified when the ceph version of a cluster is upgraded, for example to send a message
+// to a chat or a webhook
+type UpgradeNotifier interface {
+	// UpgradeStarted is called when an orchestration is going to upgrade the daemons of the cluster
+	UpgradeStarted(namespace string, from, to cephver.CephVersion)
+	// UpgradeFinished is called when the orchestration of the upgrade completed. The error is nil if the
+	// orchestration succeeded.
+	UpgradeFinished(namespace string, to cephver.CephVersion, err error)
+}
+
+// nullUpgradeNotifier is the default notifier that ignores the upgrades
+type nullUpgradeNotifier struct{}
+
+func (nullUpgradeNotifier) UpgradeStarted(namespace string, from, to cephver.CephVersion) {}
+
+func (nullUpgradeNotifier) UpgradeFinished(namespace string, to cephver.CephVersion, err error) {}
+
+// startUpgrade flags the next orchestration as an upgrade and notifies that the upgrade started
+func (c *cluster) startUpgrade(runningVersions client.CephDaemonsVersions, to cephver.CephVersion) {
+	c.orchMux.Lock()
+	c.isUpgrade = true
+	c.upgradeInProgress = true
+	c.upgradeGeneration++
+	c.orchMux.Unlock()
+	c.upgradeNotifier.UpgradeStarted(c.Namespace, oldestRunningVersion(runningVersions), to)
+}
+
+// upgradeState returns whether the next orchestration is an upgrade and the generation of the upgrade, which an
+// orchestration snapshots before it starts
+func (c *cluster) upgradeState() (bool, int) {
+	c.orchMux.Lock()
+	defer c.orchMux.Unlock()
+	return c.isUpgrade, c.upgradeGeneration
+}
+
+// finishUpgrade notifies that the orchestration of an upgrade completed, successfully or not. Once the upgrade
+// succeeded the next orchestrations are not upgrades anymore, a failed upgrade is retried by the next orchestration.
+// If another upgrade was started since the orchestration snapshotted the upgrade generation, the upgrade is
+// completed by the next orchestration instead.
+func (c *cluster) finishUpgrade(to cephver.CephVersion, err error, upgradeGeneration int) {
+	c.orchMux.Lock()
+	if upgradeGeneration != c.upgradeGeneration {
+		c.orchMux.Unlock()
+		return
+	}
+	if err == nil {
+		c.isUpgrade = false
+	}
+	inProgress := c.upgradeInProgress
+	c.upgradeInProgress = false
+	c.orchMux.Unlock()
+	if !inProgress {
+		return
+	}
+	c.upgradeNotifier.UpgradeFinished(c.Namespace, to, err)
+}
+
+// IsUpgrading returns whether the daemons of the cluster are being upgraded to a new ceph version, i.e. an upgrade
+// was started and its orchestration did not complete yet. It is safe to call from any goroutine.
+func (c *cluster) IsUpgrading() bool {
+	c.orchMux.Lock()
+	defer c.orchMux.Unlock()
+	return c.upgradeInProgress || (c.isUpgrade && c.orchestrationRunning)
+}
+
+// updateUpgradingCondition sets the Upgrading condition of the CR while the orchestration of an upgrade runs
+func (c *cluster) updateUpgradingCondition(upgrading bool, to cephver.CephVersion, err error) {
+	condition := cephv1.ClusterCondition{
+		Type:    cephv1.ClusterConditionUpgrading,
+		Status:  v1.ConditionTrue,
+		Reason:  "UpgradeStarted",
+		Message: fmt.Sprintf("upgrading the daemons to ceph version %s", to.String()),
+	}
+	if !upgrading {
+		condition.Status = v1.ConditionFalse
+		condition.Reason = "UpgradeCompleted"
+		condition.Message = fmt.Sprintf("the daemons were upgraded to ceph version %s", to.String())
+		if err != nil {
+			condition.Reason = "UpgradeFailed"
+			condition.Message = fmt.Sprintf("the upgrade to ceph version %s failed and is retried by the next orchestration. %+v", to.String(), err)
+		}
+	}
+	c.updateStatusCondition(condition)
+}
+
+// oldestRunningVersion returns the oldest ceph version that the daemons are running, which is the version the
+// cluster is upgraded from
+func oldestRunningVersion(runningVersions client.CephDaemonsVersions) cephver.CephVersion {
+	var oldest *cephver.CephVersion
+	for v := range runningVersions.Overall {
+		version, err := cephver.ExtractCephVersion(v)
+		if err != nil {
+			logger.Warningf("failed to extract the running ceph version. %+v", err)
+			continue
+		}
+		if oldest == nil || cephver.IsInferior(*version, *oldest) {
+			oldest = version
+		}
+	}
+	if oldest == nil {
+		return cephver.CephVersion{}
+	}
+	return *oldest
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	cephver "github.com/rook/rook/pkg/operator/ceph/version"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeUpgradeNotifier struct {
+	started  int
+	finished int
+	from     cephver.CephVersion
+	to       cephver.CephVersion
+	err      error
+}
+
+func (n *fakeUpgradeNotifier) UpgradeStarted(namespace string, from, to cephver.CephVersion) {
+	n.started++
+	n.from = from
+	n.to = to
+}
+
+func (n *fakeUpgradeNotifier) UpgradeFinished(namespace string, to cephver.CephVersion, err error) {
+	n.finished++
+	n.to = to
+	n.err = err
+}
+
+func TestUpgradeNotifier(t *testing.T) {
+	notifier := &fakeUpgradeNotifier{}
+	c := &cluster{Namespace: "ns", upgradeNotifier: notifier}
+	running := client.CephDaemonsVersions{
+		Overall: map[string]int{
+			"ceph version 14.2.2 (4f8fa0a0024755aae7d95567c63f11d6862d55be) nautilus (stable)": 2,
+			"ceph version 13.2.6 (02899bfda814146b021136e9d8e80eba494e1126) mimic (stable)":    1,
+		},
+	}
+	to := cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}
+
+	// an orchestration that is not an upgrade is not notified
+	c.finishUpgrade(to, nil, c.upgradeGeneration)
+	assert.Equal(t, 0, notifier.finished)
+
+	c.startUpgrade(running, to)
+	assert.True(t, c.isUpgrade)
+	assert.True(t, c.IsUpgrading())
+	assert.Equal(t, 1, notifier.started)
+	assert.Equal(t, cephver.CephVersion{Major: 13, Minor: 2, Extra: 6}, notifier.from)
+	assert.Equal(t, to, notifier.to)
+
+	// the completion is only notified once
+	err := fmt.Errorf("failed")
+	c.finishUpgrade(to, err, c.upgradeGeneration)
+	c.finishUpgrade(to, nil, c.upgradeGeneration)
+	assert.Equal(t, 1, notifier.finished)
+	assert.Equal(t, err, notifier.err)
+	assert.False(t, c.IsUpgrading())
+}
+
+func TestIsUpgrading(t *testing.T) {
+	c := &cluster{Namespace: "ns", upgradeNotifier: nullUpgradeNotifier{}}
+	to := cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}
+	assert.False(t, c.IsUpgrading())
+
+	// a failed upgrade is retried by the next orchestration, but is not running in between
+	c.startUpgrade(client.CephDaemonsVersions{}, to)
+	c.orchestrationRunning = true
+	c.finishUpgrade(to, fmt.Errorf("failed"), c.upgradeGeneration)
+	assert.True(t, c.isUpgrade)
+	assert.True(t, c.IsUpgrading())
+	c.orchestrationRunning = false
+	assert.False(t, c.IsUpgrading())
+
+	// the flag is reset once the upgrade succeeded
+	c.orchestrationRunning = true
+	c.finishUpgrade(to, nil, c.upgradeGeneration)
+	assert.False(t, c.isUpgrade)
+	assert.False(t, c.IsUpgrading())
+}
+
+func TestUpgradeStartedDuringOrchestration(t *testing.T) {
+	notifier := &fakeUpgradeNotifier{}
+	c := &cluster{Namespace: "ns", upgradeNotifier: notifier}
+	to := cephver.CephVersion{Major: 14, Minor: 2, Extra: 4}
+
+	// the orchestration snapshots the upgrade before another upgrade is started
+	c.startUpgrade(client.CephDaemonsVersions{}, to)
+	isUpgrade, generation := c.upgradeState()
+	assert.True(t, isUpgrade)
+	c.startUpgrade(client.CephDaemonsVersions{}, cephver.CephVersion{Major: 14, Minor: 2, Extra: 5})
+
+	// the newer upgrade is still applied by the next orchestration
+	c.finishUpgrade(to, nil, generation)
+	assert.Equal(t, 0, notifier.finished)
+	isUpgrade, generation = c.upgradeState()
+	assert.True(t, isUpgrade)
+	assert.True(t, c.IsUpgrading())
+
+	c.finishUpgrade(to, nil, generation)
+	assert.Equal(t, 1, notifier.finished)
+	isUpgrade, _ = c.upgradeState()
+	assert.False(t, isUpgrade)
+}
+
+func TestOldestRunningVersion(t *testing.T) {
+	assert.Equal(t, cephver.CephVersion{}, oldestRunningVersion(client.CephDaemonsVersions{}))
+
+	running := client.CephDaemonsVersions{
+		Overall: map[string]int{
+			"ceph version 14.2.2 (4f8fa0a0024755aae7d95567c63f11d6862d55be) nautilus (stable)": 3,
+		},
+	}
+	assert.Equal(t, cephver.CephVersion{Major: 14, Minor: 2, Extra: 2}, oldestRunningVersion(running))
+}
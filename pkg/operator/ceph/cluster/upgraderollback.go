@@ -0,0 +1,186 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	"github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/k8sutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the default minutes the cluster may be unhealthy after the daemons of a type were upgraded before they are rolled back
+const defaultUpgradeUnhealthyTimeoutMinutes = 10
+
+// how often the health is checked after the daemons of a type were upgraded
+var upgradeHealthCheckInterval = 15 * time.Second
+
+// rollbackImage returns the ceph image of the last successful orchestration that the daemons are rolled back to if
+// the upgrade to the image leaves the cluster unhealthy. Empty if the upgrade is not rolled back automatically.
+// The daemons are only rolled back within a ceph release, since the daemons of a new release may update their data
+// to a format that the previous release cannot read. A cluster that was already in HEALTH_ERR before the upgrade is
+// not rolled back either, since its health does not tell whether the upgrade failed.
+func (c *cluster) rollbackImage(spec cephv1.UpgradeRollbackSpec, image string, isUpgrade bool) string {
+	enabled := spec.Enabled || c.featureEnabled(featureUpgradeRollback)
+	if !enabled || !isUpgrade {
+		return ""
+	}
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get cluster %s to read the last applied ceph image. %+v", c.Namespace, err)
+		return ""
+	}
+	previousImage := cephCluster.Status.LastAppliedCephImage
+	if previousImage == "" || previousImage == image {
+		logger.Debugf("no previous ceph image to roll back the daemons of cluster %s", c.Namespace)
+		return ""
+	}
+	previousVersion, previousFound := versionFromImageTag(previousImage)
+	version, found := versionFromImageTag(image)
+	if !previousFound || !found || previousVersion.Major != version.Major {
+		logger.Warningf("the upgrade of cluster %s from image %s to %s is not rolled back if it fails since the images are not of the same ceph release",
+			c.Namespace, previousImage, image)
+		return ""
+	}
+	if cephCluster.Status.CephStatus != nil && cephCluster.Status.CephStatus.Health == "HEALTH_ERR" {
+		logger.Warningf("the upgrade of cluster %s to image %s is not rolled back if it fails since the cluster was in HEALTH_ERR before the upgrade",
+			c.Namespace, image)
+		return ""
+	}
+	return previousImage
+}
+
+// checkRolledBackUpgrade refuses the upgrade to an image that was already rolled back. The upgrade is retried once
+// the image in the spec was changed.
+func (c *cluster) checkRolledBackUpgrade(image string) error {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Warningf("failed to get cluster %s to check for a rolled back upgrade. %+v", c.Namespace, err)
+		return nil
+	}
+	rolledBack := cephCluster.Status.RolledBackCephImage
+	if rolledBack == "" {
+		return nil
+	}
+	if rolledBack == image {
+		return fmt.Errorf("the upgrade of cluster %s to image %s was rolled back. change the image to retry the upgrade", c.Namespace, image)
+	}
+
+	cephCluster.Status.RolledBackCephImage = ""
+	if err := updateClusterStatus(c.context, cephCluster); err != nil {
+		logger.Errorf("failed to clear the rolled back image of cluster %s. %+v", c.Namespace, err)
+	}
+	c.reportCondition(cephv1.ClusterConditionUpgradeRolledBack, "", "CephImageChanged",
+		fmt.Sprintf("the ceph image changed to %s after the upgrade to %s was rolled back", image, rolledBack))
+	return nil
+}
+
+// checkUpgradeHealth waits for the cluster to be healthy after the daemons with the app label were upgraded. If the
+// cluster is still unhealthy after the timeout, the deployments of the daemons are rolled back to the previous image
+// returned by rollbackImage and the orchestration fails.
+func (c *cluster) checkUpgradeHealth(ctx context.Context, spec cephv1.UpgradeRollbackSpec, app, image, previousImage string) error {
+	if previousImage == "" || previousImage == image {
+		return nil
+	}
+	timeoutMinutes := spec.UnhealthyTimeoutMinutes
+	if timeoutMinutes <= 0 {
+		timeoutMinutes = defaultUpgradeUnhealthyTimeoutMinutes
+	}
+	timeout := time.Duration(timeoutMinutes) * time.Minute
+	deadline := time.Now().Add(timeout)
+
+	for {
+		if client.IsCephHealthy(c.context, c.Info.Name) {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			break
+		}
+		logger.Infof("waiting for cluster %s to be healthy after the upgrade of the %s daemons", c.Namespace, app)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("stopped waiting for cluster %s to be healthy since the orchestration was canceled. %+v", c.Namespace, ctx.Err())
+		case <-time.After(upgradeHealthCheckInterval):
+		}
+	}
+
+	rolledBack, err := c.rollbackDeployments(app, image, previousImage)
+	if err != nil {
+		return fmt.Errorf("cluster %s is unhealthy after the upgrade of the %s daemons and the rollback failed. %+v", c.Namespace, app, err)
+	}
+	message := fmt.Sprintf("cluster %s was unhealthy for %s after the upgrade of the %s daemons to %s. rolled back %d deployments to %s",
+		c.Namespace, timeout, app, image, rolledBack, previousImage)
+	c.recordRolledBackUpgrade(image, message)
+	return fmt.Errorf("%s", message)
+}
+
+// rollbackDeployments sets the containers of the deployments with the app label that run the image back to the
+// previous image. It returns the number of deployments that were rolled back.
+func (c *cluster) rollbackDeployments(app, image, previousImage string) (int, error) {
+	deployments, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).List(metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", k8sutil.AppAttr, app)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to list the %s deployments. %+v", app, err)
+	}
+	count := 0
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		// both the init containers and the containers must be rolled back
+		initChanged := replaceContainerImages(d.Spec.Template.Spec.InitContainers, image, previousImage)
+		changed := replaceContainerImages(d.Spec.Template.Spec.Containers, image, previousImage)
+		if !initChanged && !changed {
+			continue
+		}
+		if _, err := c.context.Clientset.AppsV1().Deployments(c.Namespace).Update(d); err != nil {
+			return count, fmt.Errorf("failed to roll back deployment %s. %+v", d.Name, err)
+		}
+		logger.Infof("rolled back deployment %s to image %s", d.Name, previousImage)
+		count++
+	}
+	return count, nil
+}
+
+// replaceContainerImages replaces the image of the containers and returns whether any container was changed
+func replaceContainerImages(containers []v1.Container, image, newImage string) bool {
+	replaced := false
+	for i := range containers {
+		if containers[i].Image == image {
+			containers[i].Image = newImage
+			replaced = true
+		}
+	}
+	return replaced
+}
+
+// recordRolledBackUpgrade records the image of the rolled back upgrade in the status, so the upgrade is not retried
+// until the image in the spec changes, and reports the rollback with the UpgradeRolledBack condition
+func (c *cluster) recordRolledBackUpgrade(image, message string) {
+	cephCluster, err := c.context.RookClientset.CephV1().CephClusters(c.Namespace).Get(c.crdName, metav1.GetOptions{})
+	if err != nil {
+		logger.Errorf("failed to get cluster %s to record the rolled back upgrade. %+v", c.Namespace, err)
+	} else {
+		cephCluster.Status.RolledBackCephImage = image
+		if err := updateClusterStatus(c.context, cephCluster); err != nil {
+			logger.Errorf("failed to record the rolled back upgrade of cluster %s. %+v", c.Namespace, err)
+		}
+	}
+	c.reportCondition(cephv1.ClusterConditionUpgradeRolledBack, message, "", "")
+}
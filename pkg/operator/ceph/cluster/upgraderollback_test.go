@@ -0,0 +1,161 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	cephv1 "github.com/rook/rook/pkg/apis/ceph.rook.io/v1"
+	rookfake "github.com/rook/rook/pkg/client/clientset/versioned/fake"
+	"github.com/rook/rook/pkg/clusterd"
+	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mgr"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/mon"
+	testop "github.com/rook/rook/pkg/operator/test"
+	exectest "github.com/rook/rook/pkg/util/exec/test"
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRollbackDeployments(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"}}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+
+	newDeployment := func(name, app, image string) {
+		d := &apps.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", Labels: map[string]string{"app": app}},
+			Spec: apps.DeploymentSpec{Template: v1.PodTemplateSpec{Spec: v1.PodSpec{
+				InitContainers: []v1.Container{{Name: "init", Image: image}},
+				Containers:     []v1.Container{{Name: "daemon", Image: image}, {Name: "sidecar", Image: "sidecar:v1"}},
+			}}},
+		}
+		_, err := context.Clientset.AppsV1().Deployments("ns").Create(d)
+		assert.Nil(t, err)
+	}
+	newDeployment("rook-ceph-mon-a", mon.AppName, "ceph/ceph:v14.2.4")
+	newDeployment("rook-ceph-mon-b", mon.AppName, "ceph/ceph:v13.2.6")
+	newDeployment("rook-ceph-mgr-a", mgr.AppName, "ceph/ceph:v14.2.4")
+
+	// only the upgraded daemons of the app are rolled back
+	count, err := c.rollbackDeployments(mon.AppName, "ceph/ceph:v14.2.4", "ceph/ceph:v13.2.6")
+	assert.Nil(t, err)
+	assert.Equal(t, 1, count)
+	d, err := context.Clientset.AppsV1().Deployments("ns").Get("rook-ceph-mon-a", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "ceph/ceph:v13.2.6", d.Spec.Template.Spec.InitContainers[0].Image)
+	assert.Equal(t, "ceph/ceph:v13.2.6", d.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, "sidecar:v1", d.Spec.Template.Spec.Containers[1].Image)
+	d, err = context.Clientset.AppsV1().Deployments("ns").Get("rook-ceph-mgr-a", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "ceph/ceph:v14.2.4", d.Spec.Template.Spec.Containers[0].Image)
+
+	// the rolled back upgrade is refused until the image changes
+	c.recordRolledBackUpgrade("ceph/ceph:v14.2.4", "rolled back")
+	assert.NotNil(t, c.checkRolledBackUpgrade("ceph/ceph:v14.2.4"))
+	updated, err := context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "ceph/ceph:v14.2.4", updated.Status.RolledBackCephImage)
+	assert.Equal(t, cephv1.ClusterConditionUpgradeRolledBack, updated.Status.Conditions[0].Type)
+	assert.Equal(t, v1.ConditionTrue, updated.Status.Conditions[0].Status)
+
+	assert.Nil(t, c.checkRolledBackUpgrade("ceph/ceph:v14.2.5"))
+	updated, err = context.RookClientset.CephV1().CephClusters("ns").Get("my-cluster", metav1.GetOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, "", updated.Status.RolledBackCephImage)
+	assert.Equal(t, v1.ConditionFalse, updated.Status.Conditions[0].Status)
+}
+
+func TestCheckUpgradeHealth(t *testing.T) {
+	upgradeHealthCheckInterval = time.Millisecond
+	health := "HEALTH_ERR"
+	statusCalls := 0
+	executor := &exectest.MockExecutor{
+		MockExecuteCommandWithOutputFile: func(debug bool, actionName, command, outputFile string, args ...string) (string, error) {
+			if args[0] == "status" {
+				statusCalls++
+				if statusCalls > 2 {
+					health = "HEALTH_WARN"
+				}
+				return `{"health":{"status":"` + health + `"}}`, nil
+			}
+			return "", nil
+		},
+	}
+	c := &cluster{
+		Namespace: "ns",
+		Info:      &cephconfig.ClusterInfo{Name: "ns"},
+		context:   &clusterd.Context{Executor: executor},
+	}
+	spec := cephv1.UpgradeRollbackSpec{}
+
+	// the health is not checked when there is no previous image
+	assert.Nil(t, c.checkUpgradeHealth(context.Background(), spec, mon.AppName, "ceph/ceph:v14.2.5", ""))
+	assert.Equal(t, 0, statusCalls)
+
+	// the cluster becomes healthy before the timeout
+	assert.Nil(t, c.checkUpgradeHealth(context.Background(), spec, mon.AppName, "ceph/ceph:v14.2.5", "ceph/ceph:v14.2.4"))
+	assert.Equal(t, 3, statusCalls)
+
+	// the wait is stopped when the orchestration is canceled
+	health = "HEALTH_ERR"
+	statusCalls = -100
+	upgradeHealthCheckInterval = time.Hour
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := c.checkUpgradeHealth(ctx, spec, mon.AppName, "ceph/ceph:v14.2.5", "ceph/ceph:v14.2.4")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "the orchestration was canceled")
+}
+
+func TestRollbackImage(t *testing.T) {
+	cephCluster := &cephv1.CephCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "my-cluster", Namespace: "ns"},
+		Status:     cephv1.ClusterStatus{LastAppliedCephImage: "ceph/ceph:v14.2.4"},
+	}
+	context := &clusterd.Context{
+		Clientset:     testop.New(1),
+		RookClientset: rookfake.NewSimpleClientset(cephCluster),
+	}
+	c := newCluster(cephCluster, context, nil)
+	spec := cephv1.UpgradeRollbackSpec{Enabled: true}
+
+	// the upgrade within the release is rolled back to the last applied image
+	assert.Equal(t, "ceph/ceph:v14.2.4", c.rollbackImage(spec, "ceph/ceph:v14.2.5", true))
+
+	// no rollback when it is disabled, not an upgrade, or the same image
+	assert.Equal(t, "", c.rollbackImage(cephv1.UpgradeRollbackSpec{}, "ceph/ceph:v14.2.5", true))
+	assert.Equal(t, "", c.rollbackImage(spec, "ceph/ceph:v14.2.5", false))
+	assert.Equal(t, "", c.rollbackImage(spec, "ceph/ceph:v14.2.4", true))
+
+	// the upgrade to another release or an image without a version is not rolled back
+	assert.Equal(t, "", c.rollbackImage(spec, "ceph/ceph:v15.2.0", true))
+	assert.Equal(t, "", c.rollbackImage(spec, "ceph/ceph:latest", true))
+
+	// the cluster was already unhealthy before the upgrade
+	cephCluster.Status.CephStatus = &cephv1.CephStatus{Health: "HEALTH_ERR"}
+	_, err := context.RookClientset.CephV1().CephClusters("ns").Update(cephCluster)
+	assert.Nil(t, err)
+	assert.Equal(t, "", c.rollbackImage(spec, "ceph/ceph:v14.2.5", true))
+}
@@ -52,6 +52,17 @@ func (m *MonStore) Set(who, option, value string) error {
 	return nil
 }
 
+// Delete removes a config from the centralized mon configuration database, so the default value applies again.
+func (m *MonStore) Delete(who, option string) error {
+	args := []string{"config", "rm", who, normalizeKey(option)}
+	cephCmd := client.NewCephCommand(m.context, m.namespace, args)
+	out, err := cephCmd.Run()
+	if err != nil {
+		return fmt.Errorf("failed to delete Ceph config in the centralized mon configuration database. output: %s. %+v", string(out), err)
+	}
+	return nil
+}
+
 // SetAll sets all configs from the overrides in the centralized mon configuration database.
 // See MonStore.Set for more.
 func (m *MonStore) SetAll(configOverrides rookceph.ConfigOverridesSpec) error {
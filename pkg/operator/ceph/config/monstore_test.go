@@ -72,6 +72,37 @@ func TestMonStore_Set(t *testing.T) {
 	assert.Contains(t, execedCmd, " config set mon.* unknown_setting 10 ")
 }
 
+func TestMonStore_Delete(t *testing.T) {
+	executor := &exectest.MockExecutor{}
+	ctx := &clusterd.Context{
+		Clientset: testop.New(1),
+		Executor:  executor,
+	}
+
+	execedCmd := ""
+	execInjectErr := false
+	executor.MockExecuteCommandWithOutputFile =
+		func(debug bool, actionName string, command string, outfile string, args ...string) (string, error) {
+			execedCmd = command + " " + strings.Join(args, " ")
+			if execInjectErr {
+				return "output from cmd with error", fmt.Errorf("mocked error")
+			}
+			return "", nil
+		}
+
+	monStore := GetMonStore(ctx, "ns")
+
+	// the key is normalized like when it is set
+	e := monStore.Delete("mgr", "debug mgr")
+	assert.NoError(t, e)
+	assert.Contains(t, execedCmd, " config rm mgr debug_mgr ")
+
+	// errors returned as expected
+	execInjectErr = true
+	e = monStore.Delete("mgr", "debug_mgr")
+	assert.Error(t, e)
+}
+
 func TestMonStore_SetAll(t *testing.T) {
 	executor := &exectest.MockExecutor{}
 	ctx := &clusterd.Context{
@@ -36,10 +36,14 @@ import (
 const (
 	// StoreName is the name of the configmap containing ceph configuration options
 	StoreName = "rook-ceph-config"
+	// ClientConfigMapName is the name of the configmap with the fsid and the mons of the cluster for the ceph clients
+	// in the namespace of the cluster
+	ClientConfigMapName = "rook-ceph-client-config"
 
 	configVolumeName = "rook-ceph-config"
 
 	confFileName         = "ceph.conf"
+	fsidKey              = "fsid"
 	monHostKey           = "mon_host"
 	monInitialMembersKey = "mon_initial_members"
 	// Msgr2port is the listening port of the messenger v2 protocol
@@ -72,12 +76,15 @@ func (s *Store) CreateOrUpdate(clusterInfo *cephconfig.ClusterInfo) error {
 	if err := s.createOrUpdateMonHostSecrets(clusterInfo); err != nil {
 		return fmt.Errorf("failed to store mon host configs. %+v", err)
 	}
+	if err := s.createOrUpdateClientConfigMap(clusterInfo); err != nil {
+		return fmt.Errorf("failed to store the client config. %+v", err)
+	}
 
 	return nil
 }
 
-// update "mon_host" and "mon_initial_members" in the stored config
-func (s *Store) createOrUpdateMonHostSecrets(clusterInfo *cephconfig.ClusterInfo) error {
+// monHostsAndMembers returns the "mon_host" and "mon_initial_members" of the mons in the cluster info
+func monHostsAndMembers(clusterInfo *cephconfig.ClusterInfo) ([]string, []string) {
 	hosts := make([]string, len(clusterInfo.Monitors))
 	members := make([]string, len(clusterInfo.Monitors))
 	i := 0
@@ -107,6 +114,12 @@ func (s *Store) createOrUpdateMonHostSecrets(clusterInfo *cephconfig.ClusterInfo
 		members[i] = m.Name
 		i++
 	}
+	return hosts, members
+}
+
+// update "mon_host" and "mon_initial_members" in the stored config
+func (s *Store) createOrUpdateMonHostSecrets(clusterInfo *cephconfig.ClusterInfo) error {
+	hosts, members := monHostsAndMembers(clusterInfo)
 
 	// store these in a secret instead of the configmap; secrets are required by CSI drivers
 	secret := &v1.Secret{
@@ -144,6 +157,36 @@ func (s *Store) createOrUpdateMonHostSecrets(clusterInfo *cephconfig.ClusterInfo
 	return nil
 }
 
+// createOrUpdateClientConfigMap stores the "fsid", "mon_host" and "mon_initial_members" in a configmap, so the
+// applications in the namespace of the cluster that use librados can mount them
+func (s *Store) createOrUpdateClientConfigMap(clusterInfo *cephconfig.ClusterInfo) error {
+	hosts, members := monHostsAndMembers(clusterInfo)
+	configMap := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ClientConfigMapName,
+			Namespace: s.namespace,
+		},
+		Data: map[string]string{
+			fsidKey:              clusterInfo.FSID,
+			monHostKey:           strings.Join(hosts, ","),
+			monInitialMembersKey: strings.Join(members, ","),
+		},
+	}
+	k8sutil.SetOwnerRef(&configMap.ObjectMeta, s.ownerRef)
+
+	clientset := s.context.Clientset
+	if _, err := clientset.CoreV1().ConfigMaps(s.namespace).Create(configMap); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create configmap %s. %+v", ClientConfigMapName, err)
+		}
+		logger.Debugf("updating configmap %s", ClientConfigMapName)
+		if _, err := clientset.CoreV1().ConfigMaps(s.namespace).Update(configMap); err != nil {
+			return fmt.Errorf("failed to update configmap %s. %+v", ClientConfigMapName, err)
+		}
+	}
+	return nil
+}
+
 // StoredMonHostEnvVars returns a container environment variable defined by the most updated stored
 // "mon_host" and "mon_initial_members" information.
 func StoredMonHostEnvVars() []v1.EnvVar {
@@ -62,6 +62,36 @@ func TestStore(t *testing.T) {
 	assertConfigStore(i3)
 }
 
+func TestClientConfigMap(t *testing.T) {
+	clientset := testop.New(1)
+	ctx := &clusterd.Context{
+		Clientset: clientset,
+	}
+	ns := "rook-ceph"
+	owner := metav1.OwnerReference{}
+
+	s := GetStore(ctx, ns, &owner)
+
+	assertClientConfigMap := func(ci *cephconfig.ClusterInfo) {
+		cm, e := clientset.CoreV1().ConfigMaps(ns).Get(ClientConfigMapName, metav1.GetOptions{})
+		assert.NoError(t, e)
+		assert.Equal(t, ci.FSID, cm.Data["fsid"])
+		mh := strings.Split(cm.Data["mon_host"], ",")
+		mim := strings.Split(cm.Data["mon_initial_members"], ",")
+		assert.Equal(t, len(ci.Monitors), len(mh))
+		assert.Equal(t, len(ci.Monitors), len(mim))
+		for _, id := range mim {
+			assert.Contains(t, mh, ci.Monitors[id].Endpoint)
+		}
+	}
+
+	// the configmap is refreshed when the mons change
+	assert.NoError(t, s.CreateOrUpdate(testop.CreateConfigDir(1)))
+	assertClientConfigMap(testop.CreateConfigDir(1))
+	assert.NoError(t, s.CreateOrUpdate(testop.CreateConfigDir(3)))
+	assertClientConfigMap(testop.CreateConfigDir(3))
+}
+
 func TestEnvVarsAndFlags(t *testing.T) {
 	clientset := testop.New(1)
 	ctx := &clusterd.Context{
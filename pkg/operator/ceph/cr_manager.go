@@ -17,12 +17,25 @@ limitations under the License.
 package operator
 
 import (
+	"os"
+	"strconv"
+
 	controllers "github.com/rook/rook/pkg/operator/ceph/disruption"
 	"github.com/rook/rook/pkg/operator/ceph/disruption/controllerconfig"
 
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
+const (
+	// the env var to set the number of reconciles each disruption controller can run in parallel
+	maxConcurrentReconcilesEnvVar  = "ROOK_DISRUPTION_MAX_CONCURRENT_RECONCILES"
+	defaultMaxConcurrentReconciles = 1
+	// the env var to only run the controllers of the drain canaries
+	canariesOnlyEnvVar = "ROOK_DISRUPTION_CANARIES_ONLY"
+	// the env var to set the number of OSDs that must remain up and in before a node drain is allowed
+	minOSDsUpInEnvVar = "ROOK_DISRUPTION_MIN_OSDS_UP_IN"
+)
+
 func (o *Operator) startManager(stopCh <-chan struct{}) {
 
 	// Set up a manager
@@ -38,9 +51,15 @@ func (o *Operator) startManager(stopCh <-chan struct{}) {
 	}
 	// options to pass to the controllers
 	controllerOpts := &controllerconfig.Context{
-		ClusterdContext:   o.context,
-		OperatorNamespace: o.operatorNamespace,
-		ReconcileCanaries: &controllerconfig.LockingBool{},
+		ClusterdContext:         o.context,
+		OperatorNamespace:       o.operatorNamespace,
+		ReconcileCanaries:       &controllerconfig.LockingBool{},
+		MaxConcurrentReconciles: maxConcurrentReconciles(os.Getenv(maxConcurrentReconcilesEnvVar)),
+		CanariesOnly:            os.Getenv(canariesOnlyEnvVar) == "true",
+		MinOSDsUpIn:             minOSDsUpIn(os.Getenv(minOSDsUpInEnvVar)),
+	}
+	if controllerOpts.CanariesOnly {
+		logger.Infof("only running the drain canary controllers since %s is set. the PodDisruptionBudgets are not managed", canariesOnlyEnvVar)
 	}
 
 	// Add the registered controllers to the manager (entrypoint for controllers)
@@ -55,3 +74,31 @@ func (o *Operator) startManager(stopCh <-chan struct{}) {
 		return
 	}
 }
+
+// maxConcurrentReconciles parses the number of parallel reconciles. It must be at least 1, otherwise the
+// default of a single reconcile at a time is used.
+func maxConcurrentReconciles(value string) int {
+	if value == "" {
+		return defaultMaxConcurrentReconciles
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 1 {
+		logger.Warningf("invalid value %q for %s, it must be an integer of at least 1. using %d", value, maxConcurrentReconcilesEnvVar, defaultMaxConcurrentReconciles)
+		return defaultMaxConcurrentReconciles
+	}
+	return count
+}
+
+// minOSDsUpIn parses the number of OSDs that must remain up and in during the drains. Invalid values disable the
+// floor.
+func minOSDsUpIn(value string) int {
+	if value == "" {
+		return 0
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		logger.Warningf("invalid value %q for %s, it must be an integer of at least 0. not enforcing a minimum of osds", value, minOSDsUpInEnvVar)
+		return 0
+	}
+	return count
+}
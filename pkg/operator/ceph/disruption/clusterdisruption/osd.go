@@ -164,8 +164,8 @@ func (r *ReconcileClusterDisruption) reconcilePDBsForOSDs(
 	}
 	if len(drainingFailureDomains) != 0 {
 		logger.Infof("pg health: %s. detected drains on %ss: %v", pgHealthMsg, poolFailureDomain, drainingFailureDomains)
-		// change only when clean
-		if clean {
+		// change only when clean and when enough osds remain up and in
+		if clean && r.drainKeepsMinOSDs(request.Namespace, drainingFailureDomains[0], drainingFailureDomainsMap[drainingFailureDomains[0]]) {
 			pdbStateMap.Data[disabledPDBKey] = drainingFailureDomains[0]
 		}
 	} else {
@@ -198,6 +198,41 @@ func (r *ReconcileClusterDisruption) reconcilePDBsForOSDs(
 	return nil
 }
 
+// drainKeepsMinOSDs returns whether the OSDs that are up and in outside of the draining failure domain meet the
+// configured minimum, so the PDBs of the failure domain can be removed. The drain is blocked if the OSDs cannot be
+// counted.
+func (r *ReconcileClusterDisruption) drainKeepsMinOSDs(namespace, failureDomain string, drainingOSDs []OsdData) bool {
+	if r.context.MinOSDsUpIn <= 0 {
+		return true
+	}
+	osdDump, err := cephClient.GetOSDDump(r.context.ClusterdContext, namespace)
+	if err != nil {
+		logger.Errorf("could not get osddump to count the osds that are up and in namespace %s, not allowing the drain of %s. %+v", namespace, failureDomain, err)
+		return false
+	}
+	remaining := osdsUpInAfterDrain(osdDump, drainingOSDs)
+	if remaining < r.context.MinOSDsUpIn {
+		logger.Warningf("not allowing the drain of %s in namespace %s since only %d osds would remain up and in, the minimum is %d", failureDomain, namespace, remaining, r.context.MinOSDsUpIn)
+		return false
+	}
+	return true
+}
+
+// osdsUpInAfterDrain returns the number of OSDs that are up and in, not counting the OSDs of the draining deployments
+func osdsUpInAfterDrain(osdDump *cephClient.OSDDump, drainingOSDs []OsdData) int {
+	draining := map[string]bool{}
+	for _, osdData := range drainingOSDs {
+		draining[osdData.Deployment.ObjectMeta.GetLabels()[osd.OsdIdLabelKey]] = true
+	}
+	count := 0
+	for _, o := range osdDump.OSDs {
+		if o.Up.String() == "1" && o.In.String() == "1" && !draining[o.OSD.String()] {
+			count++
+		}
+	}
+	return count
+}
+
 func (r *ReconcileClusterDisruption) updateNoout(pdbStateMap *corev1.ConfigMap, allFailureDomainsMap map[string][]OsdData) error {
 	disabledFailureDomain := pdbStateMap.Data[disabledPDBKey]
 	namespace := pdbStateMap.ObjectMeta.Namespace
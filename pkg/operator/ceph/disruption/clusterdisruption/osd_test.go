@@ -0,0 +1,47 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package clusterdisruption
+
+import (
+	"encoding/json"
+	"testing"
+
+	cephClient "github.com/rook/rook/pkg/daemon/ceph/client"
+	"github.com/rook/rook/pkg/operator/ceph/cluster/osd"
+	"github.com/rook/rook/pkg/operator/ceph/disruption/controllerconfig"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOSDsUpInAfterDrain(t *testing.T) {
+	var osdDump cephClient.OSDDump
+	dump := `{"osds":[{"osd":0,"up":1,"in":1},{"osd":1,"up":1,"in":1},{"osd":2,"up":0,"in":1},{"osd":3,"up":1,"in":0},{"osd":4,"up":1,"in":1}]}`
+	assert.Nil(t, json.Unmarshal([]byte(dump), &osdDump))
+
+	drainingOSD := func(id string) OsdData {
+		return OsdData{Deployment: appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{osd.OsdIdLabelKey: id}}}}
+	}
+	assert.Equal(t, 3, osdsUpInAfterDrain(&osdDump, nil))
+	assert.Equal(t, 1, osdsUpInAfterDrain(&osdDump, []OsdData{drainingOSD("0"), drainingOSD("4")}))
+	// the osds that are already down do not change the count
+	assert.Equal(t, 3, osdsUpInAfterDrain(&osdDump, []OsdData{drainingOSD("2")}))
+
+	// the osds are not counted without a minimum
+	r := &ReconcileClusterDisruption{context: &controllerconfig.Context{}}
+	assert.True(t, r.drainKeepsMinOSDs("ns", "zone-a", nil))
+}
@@ -27,6 +27,14 @@ type Context struct {
 	ClusterdContext   *clusterd.Context
 	OperatorNamespace string
 	ReconcileCanaries *LockingBool
+	// MaxConcurrentReconciles is the number of reconciles each controller can run in parallel
+	MaxConcurrentReconciles int
+	// CanariesOnly only runs the controllers of the drain canaries, e.g. to investigate the canaries without
+	// managing the PodDisruptionBudgets
+	CanariesOnly bool
+	// MinOSDsUpIn is the number of OSDs that must remain up and in for the PodDisruptionBudgets of a draining
+	// failure domain to be removed, so too many drains at once cannot take away the redundancy. 0 disables the floor.
+	MinOSDsUpIn int
 }
 
 // LockingBool is a bool coupled with a sync.Mutex
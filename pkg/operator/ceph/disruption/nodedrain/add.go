@@ -50,7 +50,7 @@ func Add(mgr manager.Manager, context *controllerconfig.Context) error {
 	}
 	reconciler := reconcile.Reconciler(reconcileNode)
 	// Create a new controller
-	c, err := controller.New(controllerName, mgr, controller.Options{Reconciler: reconciler})
+	c, err := controller.New(controllerName, mgr, controller.Options{Reconciler: reconciler, MaxConcurrentReconciles: context.MaxConcurrentReconciles})
 	if err != nil {
 		return err
 	}
@@ -35,6 +35,11 @@ var AddToManagerFuncs = []func(manager.Manager, *controllerconfig.Context) error
 	clusterdisruption.Add,
 }
 
+// CanaryAddToManagerFuncs is the list of functions to add the controllers of the drain canaries
+var CanaryAddToManagerFuncs = []func(manager.Manager, *controllerconfig.Context) error{
+	nodedrain.Add,
+}
+
 // AddToManager adds all the registered controllers to the passed manager.
 // each controller package will have an Add method listed in AddToManagerFuncs
 // which will setup all the necessary watch
@@ -42,7 +47,13 @@ func AddToManager(m manager.Manager, c *controllerconfig.Context) error {
 	if c == nil {
 		return fmt.Errorf("nil controllercontext passed")
 	}
-	for _, f := range AddToManagerFuncs {
+	addFuncs := AddToManagerFuncs
+	if c.CanariesOnly {
+		// the canaries are normally enabled by the cluster disruption controller, which is not running
+		c.ReconcileCanaries.Update(true)
+		addFuncs = CanaryAddToManagerFuncs
+	}
+	for _, f := range addFuncs {
 		if err := f(m, c); err != nil {
 			return err
 		}
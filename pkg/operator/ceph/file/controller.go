@@ -140,12 +140,15 @@ func (c *FilesystemController) onUpdate(oldObj, newObj interface{}) {
 }
 
 // ParentClusterChanged determines wether or not a CR update has been sent
-func (c *FilesystemController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade bool) {
+func (c *FilesystemController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade, healthy bool) {
 	c.clusterInfo = clusterInfo
 	if !isUpgrade {
 		logger.Debugf("No need to update the file system after the parent cluster changed")
 		return
 	}
+	if !healthy {
+		logger.Warningf("updating the file systems while the parent cluster is not healthy, the update may fail")
+	}
 
 	// This is an upgrade so let's activate the flag
 	c.isUpgrade = isUpgrade
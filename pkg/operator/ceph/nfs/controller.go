@@ -149,12 +149,15 @@ func (c *CephNFSController) onDelete(obj interface{}) {
 
 // ParentClusterChanged performs the steps needed to update the NFS cluster when the parent Ceph
 // cluster has changed.
-func (c *CephNFSController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade bool) {
+func (c *CephNFSController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade, healthy bool) {
 	c.clusterInfo = clusterInfo
 	if cluster.CephVersion.Image == c.clusterSpec.CephVersion.Image || !c.clusterInfo.CephVersion.IsAtLeastNautilus() {
 		logger.Debugf("No need to update the nfs daemons after the parent cluster changed")
 		return
 	}
+	if !healthy {
+		logger.Warningf("updating the nfs daemons while the parent cluster is not healthy, the update may fail")
+	}
 
 	// This is mostly a placeholder since we don't perform any upgrade checks for nfs since it's not in Ceph's servicemap yet
 	// This is an upgrade so let's activate the flag
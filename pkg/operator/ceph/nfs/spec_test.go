@@ -24,6 +24,7 @@ import (
 	cephconfig "github.com/rook/rook/pkg/daemon/ceph/config"
 	"github.com/rook/rook/pkg/operator/ceph/config"
 	optest "github.com/rook/rook/pkg/operator/test"
+	"github.com/stretchr/testify/assert"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -94,4 +95,11 @@ func TestDeploymentSpec(t *testing.T) {
 			MemoryResourceRequest: "512",
 		},
 	)
+
+	// the pull policy of the ceph image applies to all the containers
+	c.clusterSpec.CephVersion.ImagePullPolicy = v1.PullAlways
+	d = c.makeDeployment(nfs, cfg)
+	for _, container := range append(d.Spec.Template.Spec.InitContainers, d.Spec.Template.Spec.Containers...) {
+		assert.Equal(t, v1.PullAlways, container.ImagePullPolicy)
+	}
 }
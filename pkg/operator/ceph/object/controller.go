@@ -165,12 +165,15 @@ func (c *ObjectStoreController) onDelete(obj interface{}) {
 }
 
 // ParentClusterChanged determines wether or not a CR update has been sent
-func (c *ObjectStoreController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *daemonconfig.ClusterInfo, isUpgrade bool) {
+func (c *ObjectStoreController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *daemonconfig.ClusterInfo, isUpgrade, healthy bool) {
 	c.clusterInfo = clusterInfo
 	if !isUpgrade {
 		logger.Debugf("No need to update the object store after the parent cluster changed")
 		return
 	}
+	if !healthy {
+		logger.Warningf("updating the object stores while the parent cluster is not healthy, the update may fail")
+	}
 
 	// This is an upgrade so let's activate the flag
 	c.isUpgrade = isUpgrade
@@ -89,6 +89,7 @@ func (c *clusterConfig) makeRGWPodSpec(rgwConfig *rgwConfig) v1.PodTemplateSpec
 		podSpec.Volumes = append(podSpec.Volumes, certVol)
 	}
 	c.store.Spec.Gateway.Placement.ApplyToPodSpec(&podSpec)
+	c.clusterSpec.CephVersion.ApplyImagePullPolicyToPodSpec(&podSpec)
 
 	podTemplateSpec := v1.PodTemplateSpec{
 		ObjectMeta: metav1.ObjectMeta{
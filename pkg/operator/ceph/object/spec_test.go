@@ -71,6 +71,11 @@ func TestPodSpecs(t *testing.T) {
 	podTemplate := cephtest.NewPodTemplateSpecTester(t, &s)
 	podTemplate.RunFullSuite(cephconfig.RgwType, "default", "rook-ceph-rgw", "mycluster", "ceph/ceph:myversion",
 		"200", "100", "1337", "500" /* resources */)
+
+	// the pull policy of the ceph image applies to the rgw
+	c.clusterSpec.CephVersion.ImagePullPolicy = v1.PullAlways
+	s = c.makeRGWPodSpec(rgwConfig)
+	assert.Equal(t, v1.PullAlways, s.Spec.Containers[0].ImagePullPolicy)
 }
 
 func TestSSLPodSpec(t *testing.T) {
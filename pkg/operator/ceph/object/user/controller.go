@@ -117,7 +117,7 @@ func (c *ObjectStoreUserController) onDelete(obj interface{}) {
 }
 
 // ParentClusterChanged determines wether or not a CR update has been sent
-func (c *ObjectStoreUserController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade bool) {
+func (c *ObjectStoreUserController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade, healthy bool) {
 	logger.Debugf("No need to update object store users after the parent cluster changed")
 }
 
@@ -19,8 +19,11 @@ package operator
 
 import (
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/coreos/pkg/capnslog"
@@ -39,6 +42,7 @@ import (
 	"github.com/rook/rook/pkg/operator/ceph/provisioner"
 	"github.com/rook/rook/pkg/operator/discover"
 	"github.com/rook/rook/pkg/operator/k8sutil"
+	"github.com/rook/rook/pkg/operator/k8sutil/cmdreporter"
 	v1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/sig-storage-lib-external-provisioner/controller"
 )
@@ -49,6 +53,13 @@ const (
 	provisionerNameLegacy = "rook.io/block"
 )
 
+const (
+	// the env var to set the number of CmdReporter jobs, e.g. the ceph version detection jobs, that can run at
+	// the same time. 0 means no limit.
+	maxConcurrentCmdReporterJobsEnvVar  = "ROOK_CMD_REPORTER_MAX_CONCURRENT_JOBS"
+	defaultMaxConcurrentCmdReporterJobs = 5
+)
+
 var logger = capnslog.NewPackageLogger("github.com/rook/rook", "operator")
 
 // The supported configurations for the volume provisioner
@@ -62,6 +73,9 @@ var (
 	EnableFlexDriver = true
 	// Whether to enable the daemon for device discovery. If true, the rook-ceph-discover daemonset will be started.
 	EnableDiscoveryDaemon = true
+	// The address of the debug endpoint that dumps the state of the clusters, e.g. "localhost:9090". The endpoint
+	// is disabled if the address is empty.
+	DebugEndpointAddress = ""
 )
 
 // Operator type for managing storage
@@ -94,9 +108,42 @@ func New(context *clusterd.Context, volumeAttachmentWrapper attachment.Attachmen
 		o.startSystemDaemons,
 	}
 	o.clusterController = cluster.NewClusterController(context, rookImage, volumeAttachmentWrapper, callbacks)
+	cmdreporter.SetMaxConcurrentJobs(maxConcurrentCmdReporterJobs(os.Getenv(maxConcurrentCmdReporterJobsEnvVar)))
 	return o
 }
 
+// maxConcurrentCmdReporterJobs parses the number of CmdReporter jobs that can run at the same time. It must be
+// at least 0, otherwise the default is used.
+func maxConcurrentCmdReporterJobs(value string) int {
+	if value == "" {
+		return defaultMaxConcurrentCmdReporterJobs
+	}
+	count, err := strconv.Atoi(value)
+	if err != nil || count < 0 {
+		logger.Warningf("invalid value %q for %s, it must be an integer of at least 0. using %d", value, maxConcurrentCmdReporterJobsEnvVar, defaultMaxConcurrentCmdReporterJobs)
+		return defaultMaxConcurrentCmdReporterJobs
+	}
+	return count
+}
+
+// SetUpgradeNotifier sets the notifier that is called when the ceph version of a cluster is upgraded. It must be
+// called before the operator is started.
+func (o *Operator) SetUpgradeNotifier(notifier cluster.UpgradeNotifier) {
+	o.clusterController.SetUpgradeNotifier(notifier)
+}
+
+// SetOrchestrationPriority sets the function that computes which clusters are orchestrated first when several
+// clusters need to be orchestrated at the same time. It must be called before the operator is started.
+func (o *Operator) SetOrchestrationPriority(priority cluster.OrchestrationPriorityFunc) {
+	o.clusterController.SetOrchestrationPriority(priority)
+}
+
+// SetTracer sets the tracer of the orchestrations of the clusters, for example an adapter to an OpenTelemetry
+// exporter. It must be called before the operator is started.
+func (o *Operator) SetTracer(tracer cluster.Tracer) {
+	o.clusterController.SetTracer(tracer)
+}
+
 // Run the operator instance
 func (o *Operator) Run() error {
 
@@ -145,6 +192,10 @@ func (o *Operator) Run() error {
 	// Start the controller-runtime Manager.
 	go o.startManager(stopChan)
 
+	if DebugEndpointAddress != "" {
+		go o.serveDebugEndpoint(DebugEndpointAddress)
+	}
+
 	// watch for changes to the rook clusters
 	o.clusterController.StartWatch(namespaceToWatch, stopChan)
 
@@ -159,6 +210,40 @@ func (o *Operator) Run() error {
 	}
 }
 
+// serveDebugEndpoint serves the state of the clusters tracked by the operator for debugging
+func (o *Operator) serveDebugEndpoint(address string) {
+	address, err := localDebugAddress(address)
+	if err != nil {
+		logger.Errorf("not serving the debug endpoint. %+v", err)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(cluster.DebugClustersPath, o.clusterController.ServeDebugClusters)
+	mux.HandleFunc(cluster.ClusterStatusesPath, o.clusterController.ServeClusterStatuses)
+	mux.HandleFunc(cluster.DebugDiagnosePath, o.clusterController.ServeDiagnosis)
+	logger.Infof("serving the debug endpoint on %s%s and the cluster statuses on %s%s", address, cluster.DebugClustersPath, address, cluster.ClusterStatusesPath)
+	if err := http.ListenAndServe(address, mux); err != nil {
+		logger.Errorf("failed to serve the debug endpoint. %+v", err)
+	}
+}
+
+// localDebugAddress returns the address of the debug endpoint on the loopback interface. The endpoint is only meant
+// to be reached with "kubectl port-forward", so an address without a host binds to localhost and other hosts are
+// refused.
+func localDebugAddress(address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", fmt.Errorf("invalid debug endpoint address %q. %+v", address, err)
+	}
+	if host == "" {
+		return net.JoinHostPort("localhost", port), nil
+	}
+	if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+		return "", fmt.Errorf("the debug endpoint must be bound to localhost, not to %s", host)
+	}
+	return address, nil
+}
+
 func (o *Operator) startSystemDaemons(clusterSpec *cephv1.ClusterSpec) error {
 	if o.delayedDaemonsStarted {
 		return nil
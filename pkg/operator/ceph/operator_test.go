@@ -48,3 +48,55 @@ func TestOperator(t *testing.T) {
 		}
 	}
 }
+
+func TestMaxConcurrentReconciles(t *testing.T) {
+	assert.Equal(t, 1, maxConcurrentReconciles(""))
+	assert.Equal(t, 4, maxConcurrentReconciles("4"))
+
+	// invalid values fall back to the default
+	assert.Equal(t, 1, maxConcurrentReconciles("0"))
+	assert.Equal(t, 1, maxConcurrentReconciles("-2"))
+	assert.Equal(t, 1, maxConcurrentReconciles("many"))
+}
+
+func TestMinOSDsUpIn(t *testing.T) {
+	assert.Equal(t, 0, minOSDsUpIn(""))
+	assert.Equal(t, 12, minOSDsUpIn("12"))
+
+	// invalid values disable the floor
+	assert.Equal(t, 0, minOSDsUpIn("-1"))
+	assert.Equal(t, 0, minOSDsUpIn("half"))
+}
+
+func TestMaxConcurrentCmdReporterJobs(t *testing.T) {
+	assert.Equal(t, 5, maxConcurrentCmdReporterJobs(""))
+	assert.Equal(t, 2, maxConcurrentCmdReporterJobs("2"))
+	// 0 removes the limit
+	assert.Equal(t, 0, maxConcurrentCmdReporterJobs("0"))
+
+	// invalid values fall back to the default
+	assert.Equal(t, 5, maxConcurrentCmdReporterJobs("-1"))
+	assert.Equal(t, 5, maxConcurrentCmdReporterJobs("many"))
+}
+
+func TestLocalDebugAddress(t *testing.T) {
+	address, err := localDebugAddress("localhost:9090")
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost:9090", address)
+	address, err = localDebugAddress("127.0.0.1:9090")
+	assert.Nil(t, err)
+	assert.Equal(t, "127.0.0.1:9090", address)
+
+	// an address without a host binds to localhost
+	address, err = localDebugAddress(":9090")
+	assert.Nil(t, err)
+	assert.Equal(t, "localhost:9090", address)
+
+	// the endpoint is never served on the other interfaces
+	_, err = localDebugAddress("0.0.0.0:9090")
+	assert.NotNil(t, err)
+	_, err = localDebugAddress("10.0.0.5:9090")
+	assert.NotNil(t, err)
+	_, err = localDebugAddress("9090")
+	assert.NotNil(t, err)
+}
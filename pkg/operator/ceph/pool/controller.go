@@ -125,7 +125,7 @@ func (c *PoolController) onUpdate(oldObj, newObj interface{}) {
 }
 
 // ParentClusterChanged determines wether or not a CR update has been sent
-func (c *PoolController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade bool) {
+func (c *PoolController) ParentClusterChanged(cluster cephv1.ClusterSpec, clusterInfo *cephconfig.ClusterInfo, isUpgrade, healthy bool) {
 	logger.Debugf("No need to update the pool after the parent cluster changed")
 }
 
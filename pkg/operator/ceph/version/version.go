@@ -51,6 +51,9 @@ var (
 
 	// for parsing the output of `ceph --version`
 	versionPattern = regexp.MustCompile(`ceph version (\d+)\.(\d+)\.(\d+)`)
+	// for detecting the development builds and release candidates in the output of `ceph --version`, which end with
+	// the "(dev)" or "(rc)" release type instead of "(stable)", or have a "-rc" version suffix
+	preReleasePattern = regexp.MustCompile(`\((dev|rc)\)|ceph version \d+\.\d+\.\d+-rc`)
 )
 
 func (v *CephVersion) String() string {
@@ -103,6 +106,11 @@ func ExtractCephVersion(src string) (*CephVersion, error) {
 	return &CephVersion{major, minor, extra}, nil
 }
 
+// IsPreRelease checks if the output of `ceph --version` is a development build or a release candidate
+func IsPreRelease(src string) bool {
+	return preReleasePattern.MatchString(src)
+}
+
 // Supported checks if a given release is supported
 func (v *CephVersion) Supported() bool {
 	for _, sv := range supportedVersions {
@@ -92,6 +92,18 @@ ceph version Development (no_version) nautilus (rc)
 	assert.Nil(t, v)
 }
 
+func TestIsPreRelease(t *testing.T) {
+	assert.False(t, IsPreRelease("ceph version 13.2.6 (ae699615bac534ea496ee965ac6192cb7e0e07c1) mimic (stable)"))
+	assert.True(t, IsPreRelease("ceph version 14.1.0 (adfd3a9e7f34a19c5b26bbd0b9e8a48d0bcbc3d2) nautilus (rc)"))
+	assert.True(t, IsPreRelease("ceph version 15.0.0-5786-g5c8dc56 (5c8dc56d1e2fd3997e4a1c6e7656eee3e8b8a38b) octopus (dev)"))
+	assert.True(t, IsPreRelease("ceph version 14.2.5-rc1"))
+	assert.True(t, IsPreRelease(`
+bin/ceph --version
+ceph version 14.1.33-403-g7ba6bece41
+(7ba6bece4187eda5d05a9b84211fe6ba8dd287bd) nautilus (rc)
+`))
+}
+
 func TestSupported(t *testing.T) {
 	for _, v := range supportedVersions {
 		assert.True(t, v.Supported())
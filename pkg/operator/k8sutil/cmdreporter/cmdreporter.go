@@ -17,6 +17,7 @@ limitations under the License.
 package cmdreporter
 
 import (
+	"context"
 	"fmt"
 	"path"
 	"strconv"
@@ -49,8 +50,49 @@ const (
 
 var (
 	logger = capnslog.NewPackageLogger("github.com/rook/rook", "CmdReporter")
+
+	// the slots of the jobs that can run at the same time, nil if the number of jobs is not limited
+	jobSlots chan struct{}
 )
 
+// SetMaxConcurrentJobs limits the number of CmdReporter jobs that run at the same time in the operator, so a burst
+// of jobs, e.g. the version detection of all the clusters after a restart of the operator, does not swamp the
+// scheduler. The other jobs wait for a free slot. 0 removes the limit. It must be called before any job is run.
+func SetMaxConcurrentJobs(max int) {
+	if max <= 0 {
+		jobSlots = nil
+		return
+	}
+	jobSlots = make(chan struct{}, max)
+}
+
+// acquireJobSlot waits until a job can run, or until the context is canceled
+func acquireJobSlot(ctx context.Context, jobName string) error {
+	if jobSlots == nil {
+		return nil
+	}
+	select {
+	case jobSlots <- struct{}{}:
+		return nil
+	default:
+	}
+	logger.Infof("waiting for one of the %d running CmdReporter jobs to complete before running job %s", cap(jobSlots), jobName)
+	select {
+	case jobSlots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("canceled waiting to run the job. %+v", ctx.Err())
+	}
+}
+
+// releaseJobSlot frees the slot of a job that completed
+func releaseJobSlot() {
+	if jobSlots == nil {
+		return
+	}
+	<-jobSlots
+}
+
 // CmdReporter is a wrapper for Rook's cmd-reporter commandline utility allowing operators to use
 // the utility without fully specifying the job, pod, and container templates manually.
 type CmdReporter struct {
@@ -59,6 +101,10 @@ type CmdReporter struct {
 
 	// filled in during creation
 	job *batch.Job
+
+	// KeepJob keeps the job and its pod after the command completed, e.g. to debug the job. By default the job
+	// is deleted once the results were retrieved.
+	KeepJob bool
 }
 
 type cmdReporterCfg struct {
@@ -136,12 +182,19 @@ func (cr *CmdReporter) Job() *batch.Job {
 // Run runs the Kubernetes job and waits for the output ConfigMap. It returns the stdout, stderr,
 // and retcode of the command as long as the image ran it, even if the retcode is nonzero (failure).
 // An error is reported only if the command was not run to completion successfully. When this
-// returns, the ConfigMap is cleaned up (destroyed).
-func (cr *CmdReporter) Run(timeout time.Duration) (stdout, stderr string, retcode int, retErr error) {
+// returns, the ConfigMap is cleaned up (destroyed), and so is the job with its pod unless KeepJob is set.
+// The wait for the output stops with an error when the context is canceled.
+func (cr *CmdReporter) Run(ctx context.Context, timeout time.Duration) (stdout, stderr string, retcode int, retErr error) {
 	jobName := cr.job.Name
 	namespace := cr.job.Namespace
 	errMsg := fmt.Sprintf("failed to run CmdReporter %s successfully", jobName)
 
+	// limit the number of jobs that run at the same time
+	if err := acquireJobSlot(ctx, jobName); err != nil {
+		return "", "", -1, fmt.Errorf("%s. %+v", errMsg, err)
+	}
+	defer releaseJobSlot()
+
 	// the configmap MUST be deleted, because we will wait on its presence to determine when the
 	// job is done running
 	delOpts := &k8sutil.DeleteOptions{}
@@ -157,7 +210,8 @@ func (cr *CmdReporter) Run(timeout time.Duration) (stdout, stderr string, retcod
 		return "", "", -1, fmt.Errorf("%s. failed to run job. %+v", errMsg, err)
 	}
 
-	if err := cr.waitForConfigMap(timeout); err != nil {
+	if err := cr.waitForConfigMap(ctx, timeout); err != nil {
+		cr.deleteJob()
 		return "", "", -1, fmt.Errorf("%s. failed waiting for results ConfigMap %s. %+v", errMsg, jobName, err)
 	}
 	logger.Debugf("job %s has returned results", jobName)
@@ -167,9 +221,8 @@ func (cr *CmdReporter) Run(timeout time.Duration) (stdout, stderr string, retcod
 		return "", "", -1, fmt.Errorf("%s. results ConfigMap %s should be available, but got an error instead. %+v", errMsg, jobName, err)
 	}
 
-	if err := k8sutil.DeleteBatchJob(cr.clientset, namespace, jobName, false); err != nil {
-		logger.Errorf("continuing after failing delete job %s; user may need to delete it manually. %+v", jobName, err)
-	}
+	// the results were retrieved, so the job is not needed anymore
+	cr.deleteJob()
 
 	// just to be explicit: delete idempotently, and don't wait for delete to complete
 	delOpts = &k8sutil.DeleteOptions{MustDelete: false, WaitOptions: k8sutil.WaitOptions{Wait: false}}
@@ -197,6 +250,18 @@ func (cr *CmdReporter) Run(timeout time.Duration) (stdout, stderr string, retcod
 	return stdout, stderr, retcode, nil
 }
 
+// deleteJob deletes the job and its pod unless they must be kept
+func (cr *CmdReporter) deleteJob() {
+	if cr.KeepJob {
+		logger.Infof("keeping job %s", cr.job.Name)
+		return
+	}
+	// the deletion is propagated to the pod of the job
+	if err := k8sutil.DeleteBatchJob(cr.clientset, cr.job.Namespace, cr.job.Name, false); err != nil {
+		logger.Errorf("continuing after failing delete job %s; user may need to delete it manually. %+v", cr.job.Name, err)
+	}
+}
+
 // return watcher or nil if configmap exists
 func (cr *CmdReporter) newWatcher() (watch.Interface, error) {
 	jobName := cr.job.Name
@@ -230,7 +295,7 @@ func (cr *CmdReporter) newWatcher() (watch.Interface, error) {
 }
 
 // return nil when configmap exists
-func (cr *CmdReporter) waitForConfigMap(timeout time.Duration) error {
+func (cr *CmdReporter) waitForConfigMap(ctx context.Context, timeout time.Duration) error {
 	jobName := cr.job.Name
 
 	watcher, err := cr.newWatcher()
@@ -269,6 +334,8 @@ func (cr *CmdReporter) waitForConfigMap(timeout time.Duration) error {
 			}
 		case <-timeoutCh:
 			return fmt.Errorf("timed out waiting for results ConfigMap")
+		case <-ctx.Done():
+			return fmt.Errorf("canceled waiting for results ConfigMap. %+v", ctx.Err())
 		}
 	}
 	// unreachable
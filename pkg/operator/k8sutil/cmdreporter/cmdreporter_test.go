@@ -0,0 +1,47 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmdreporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobSlots(t *testing.T) {
+	defer SetMaxConcurrentJobs(0)
+
+	// no limit
+	SetMaxConcurrentJobs(0)
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, acquireJobSlot(context.Background(), "job"))
+	}
+
+	SetMaxConcurrentJobs(2)
+	assert.Nil(t, acquireJobSlot(context.Background(), "job1"))
+	assert.Nil(t, acquireJobSlot(context.Background(), "job2"))
+
+	// the third job waits until the context is canceled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NotNil(t, acquireJobSlot(ctx, "job3"))
+
+	// a slot is free again after a job completed
+	releaseJobSlot()
+	assert.Nil(t, acquireJobSlot(context.Background(), "job3"))
+}
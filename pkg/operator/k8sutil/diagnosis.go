@@ -0,0 +1,116 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+)
+
+// the number of warning events of a pod that are included in the diagnosis
+const diagnosisEventCount = 3
+
+// DiagnoseDeployment describes why the pods of a deployment are not ready, e.g. an image that cannot be pulled, a
+// container in CrashLoopBackOff or a pod that cannot be scheduled. It returns an empty string if no reason is found.
+func DiagnoseDeployment(clientset kubernetes.Interface, namespace string, deployment *apps.Deployment) string {
+	if deployment.Spec.Selector == nil {
+		return ""
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deployment.Spec.Selector)
+	if err != nil {
+		logger.Warningf("failed to parse the selector of deployment %s. %+v", deployment.Name, err)
+		return ""
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		logger.Warningf("failed to list the pods of deployment %s. %+v", deployment.Name, err)
+		return ""
+	}
+	if len(pods.Items) == 0 {
+		return fmt.Sprintf("deployment %s has no pods", deployment.Name)
+	}
+
+	diagnosis := []string{}
+	for _, pod := range pods.Items {
+		reasons := podNotReadyReasons(pod)
+		reasons = append(reasons, podWarningEvents(clientset, pod)...)
+		if len(reasons) > 0 {
+			diagnosis = append(diagnosis, fmt.Sprintf("pod %s: %s", pod.Name, strings.Join(reasons, "; ")))
+		}
+	}
+	return strings.Join(diagnosis, ". ")
+}
+
+// podNotReadyReasons returns the reasons found in the status of the pod why it is not ready
+func podNotReadyReasons(pod v1.Pod) []string {
+	reasons := []string{}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionFalse {
+			reasons = append(reasons, fmt.Sprintf("%s: %s", condition.Reason, condition.Message))
+		}
+	}
+	statuses := append([]v1.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, status := range statuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason != "" && status.State.Waiting.Reason != "ContainerCreating" && status.State.Waiting.Reason != "PodInitializing" {
+			reason := fmt.Sprintf("container %s is waiting with %s", status.Name, status.State.Waiting.Reason)
+			if status.State.Waiting.Message != "" {
+				reason = fmt.Sprintf("%s: %s", reason, status.State.Waiting.Message)
+			}
+			reasons = append(reasons, reason)
+		}
+		if status.LastTerminationState.Terminated != nil && status.RestartCount > 0 {
+			terminated := status.LastTerminationState.Terminated
+			reasons = append(reasons, fmt.Sprintf("container %s restarted %d times, last exit code %d (%s)", status.Name, status.RestartCount, terminated.ExitCode, terminated.Reason))
+		}
+	}
+	return reasons
+}
+
+// podWarningEvents returns the most recent warning events of the pod
+func podWarningEvents(clientset kubernetes.Interface, pod v1.Pod) []string {
+	selector := fields.Set{"involvedObject.name": pod.Name, "type": v1.EventTypeWarning}.AsSelector().String()
+	events, err := clientset.CoreV1().Events(pod.Namespace).List(metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		logger.Warningf("failed to list the events of pod %s. %+v", pod.Name, err)
+		return []string{}
+	}
+
+	warnings := []v1.Event{}
+	for _, event := range events.Items {
+		if event.InvolvedObject.Name == pod.Name && event.Type == v1.EventTypeWarning {
+			warnings = append(warnings, event)
+		}
+	}
+	// the list is not ordered, the most recent events come first
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[j].LastTimestamp.Before(&warnings[i].LastTimestamp)
+	})
+
+	messages := []string{}
+	for i := 0; i < len(warnings) && i < diagnosisEventCount; i++ {
+		messages = append(messages, fmt.Sprintf("event %s: %s", warnings[i].Reason, warnings[i].Message))
+	}
+	return messages
+}
@@ -0,0 +1,115 @@
+/*
+Copyright 2019 The Rook Authors. All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package k8sutil
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	apps "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDiagnoseDeployment(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	labels := map[string]string{"app": "rook-ceph-mgr", "mgr": "a"}
+	d := &apps.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mgr-a", Namespace: "ns"},
+		Spec:       apps.DeploymentSpec{Selector: &metav1.LabelSelector{MatchLabels: labels}},
+	}
+
+	// no pod was created
+	assert.Equal(t, "deployment rook-ceph-mgr-a has no pods", DiagnoseDeployment(clientset, "ns", d))
+
+	// the pod is running and there is nothing to report
+	pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "rook-ceph-mgr-a-123", Namespace: "ns", Labels: labels}}
+	pod, err := clientset.CoreV1().Pods("ns").Create(pod)
+	assert.Nil(t, err)
+	assert.Equal(t, "", DiagnoseDeployment(clientset, "ns", d))
+
+	// the image cannot be pulled
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{Name: "mgr", State: v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "ImagePullBackOff", Message: "Back-off pulling image"}}},
+	}
+	pod, err = clientset.CoreV1().Pods("ns").Update(pod)
+	assert.Nil(t, err)
+	assert.Equal(t, "pod rook-ceph-mgr-a-123: container mgr is waiting with ImagePullBackOff: Back-off pulling image", DiagnoseDeployment(clientset, "ns", d))
+
+	// the container crashes
+	pod.Status.ContainerStatuses = []v1.ContainerStatus{
+		{
+			Name:                 "mgr",
+			RestartCount:         4,
+			State:                v1.ContainerState{Waiting: &v1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+		},
+	}
+	pod, err = clientset.CoreV1().Pods("ns").Update(pod)
+	assert.Nil(t, err)
+	assert.Equal(t, "pod rook-ceph-mgr-a-123: container mgr is waiting with CrashLoopBackOff; container mgr restarted 4 times, last exit code 1 (Error)", DiagnoseDeployment(clientset, "ns", d))
+
+	// the pod cannot be scheduled and the warning events of the pod are reported
+	pod.Status.ContainerStatuses = nil
+	pod.Status.Conditions = []v1.PodCondition{
+		{Type: v1.PodScheduled, Status: v1.ConditionFalse, Reason: "Unschedulable", Message: "0/3 nodes are available"},
+	}
+	_, err = clientset.CoreV1().Pods("ns").Update(pod)
+	assert.Nil(t, err)
+	event := &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "event-1", Namespace: "ns"},
+		InvolvedObject: v1.ObjectReference{Name: "rook-ceph-mgr-a-123"},
+		Type:           v1.EventTypeWarning,
+		Reason:         "FailedScheduling",
+		Message:        "0/3 nodes are available",
+	}
+	_, err = clientset.CoreV1().Events("ns").Create(event)
+	assert.Nil(t, err)
+	// the events of other pods are ignored
+	event = &v1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Name: "event-2", Namespace: "ns"},
+		InvolvedObject: v1.ObjectReference{Name: "my-app"},
+		Type:           v1.EventTypeWarning,
+		Reason:         "BackOff",
+	}
+	_, err = clientset.CoreV1().Events("ns").Create(event)
+	assert.Nil(t, err)
+	assert.Equal(t, "pod rook-ceph-mgr-a-123: Unschedulable: 0/3 nodes are available; event FailedScheduling: 0/3 nodes are available", DiagnoseDeployment(clientset, "ns", d))
+
+	// only the most recent warning events are reported
+	pod.Status.Conditions = nil
+	_, err = clientset.CoreV1().Pods("ns").Update(pod)
+	assert.Nil(t, err)
+	now := time.Now()
+	ages := map[string]time.Duration{"Newest": 0, "New": time.Minute, "Old": 2 * time.Minute, "Oldest": 3 * time.Minute}
+	for i, reason := range []string{"Newest", "Oldest", "Old", "New"} {
+		event = &v1.Event{
+			ObjectMeta:     metav1.ObjectMeta{Name: fmt.Sprintf("event-%d", i+3), Namespace: "ns"},
+			InvolvedObject: v1.ObjectReference{Name: "rook-ceph-mgr-a-123"},
+			Type:           v1.EventTypeWarning,
+			Reason:         reason,
+			Message:        "message",
+			LastTimestamp:  metav1.NewTime(now.Add(-ages[reason])),
+		}
+		_, err = clientset.CoreV1().Events("ns").Create(event)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, "pod rook-ceph-mgr-a-123: event Newest: message; event New: message; event Old: message", DiagnoseDeployment(clientset, "ns", d))
+}
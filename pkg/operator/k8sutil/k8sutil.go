@@ -56,6 +56,9 @@ const (
 	// RookVersionLabelKey is the key used for reporting the Rook version which last created or
 	// modified a resource.
 	RookVersionLabelKey = "rook-version"
+	// RookVersionAnnotationKey is the key of the annotation with the version of the Rook operator which last created
+	// or modified a resource. Unlike the label, the annotation has the exact version string.
+	RookVersionAnnotationKey = "ceph.rook.io/operator-version"
 )
 
 // GetK8SVersion gets the version of the running K8S cluster
@@ -147,6 +150,15 @@ func addRookVersionLabel(labels map[string]string) {
 	labels[RookVersionLabelKey] = value
 }
 
+// AddRookVersionAnnotationToObjectMeta adds or updates an annotation reporting the Rook operator version which last
+// created or modified a resource. Like the label, this should *not* be used on pod specifications.
+func AddRookVersionAnnotationToObjectMeta(meta *metav1.ObjectMeta) {
+	if meta.Annotations == nil {
+		meta.Annotations = map[string]string{}
+	}
+	meta.Annotations[RookVersionAnnotationKey] = rookversion.Version
+}
+
 // validateLabelValue replaces any invalid characters
 // in the input string with a replacement character,
 // and enforces other limitations for k8s label values.
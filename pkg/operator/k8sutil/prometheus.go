@@ -2,16 +2,40 @@ package k8sutil
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 
 	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	monitoringclient "github.com/coreos/prometheus-operator/pkg/client/versioned"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sYAML "k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
+// the annotation with the hash of the last applied content of a monitoring resource
+const lastAppliedHashAnnotation = "rook.io/last-applied-hash"
+
+// PrometheusCRDsAvailable returns whether the ServiceMonitor and PrometheusRule CRDs of the prometheus operator
+// are registered in the cluster
+func PrometheusCRDsAvailable(clientset kubernetes.Interface) (bool, error) {
+	resources, err := clientset.Discovery().ServerResourcesForGroupVersion(monitoringv1.SchemeGroupVersion.String())
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to discover the resources of %s. %+v", monitoringv1.SchemeGroupVersion.String(), err)
+	}
+
+	found := map[string]bool{}
+	for _, resource := range resources.APIResources {
+		found[resource.Kind] = true
+	}
+	return found["ServiceMonitor"] && found["PrometheusRule"], nil
+}
+
 func getMonitoringClient() (*monitoringclient.Clientset, error) {
 	cfg, err := clientcmd.BuildConfigFromFlags("", "")
 	client, err := monitoringclient.NewForConfig(cfg)
@@ -39,21 +63,34 @@ func GetServiceMonitor(filePath string) (*monitoringv1.ServiceMonitor, error) {
 func CreateOrUpdateServiceMonitor(serviceMonitorDefinition *monitoringv1.ServiceMonitor) (*monitoringv1.ServiceMonitor, error) {
 	name := serviceMonitorDefinition.GetName()
 	namespace := serviceMonitorDefinition.GetNamespace()
-	logger.Debugf("creating servicemonitor %s", name)
+	if err := setLastAppliedHash(serviceMonitorDefinition, serviceMonitorDefinition.Spec); err != nil {
+		return nil, fmt.Errorf("failed to hash servicemonitor %s. %+v", name, err)
+	}
 	client, err := getMonitoringClient()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get monitoring client. %+v", err)
 	}
-	sm, err := client.MonitoringV1().ServiceMonitors(namespace).Create(serviceMonitorDefinition)
+	existing, err := client.MonitoringV1().ServiceMonitors(namespace).Get(name, metav1.GetOptions{})
 	if err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return nil, fmt.Errorf("failed to create servicemonitor. %+v", err)
+		if !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get servicemonitor. %+v", err)
 		}
-		sm, err = client.MonitoringV1().ServiceMonitors(namespace).Update(sm)
+		logger.Debugf("creating servicemonitor %s", name)
+		sm, err := client.MonitoringV1().ServiceMonitors(namespace).Create(serviceMonitorDefinition)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update servicemonitor. %+v", err)
-
+			return nil, fmt.Errorf("failed to create servicemonitor. %+v", err)
 		}
+		return sm, nil
+	}
+	if lastAppliedHashMatches(existing, serviceMonitorDefinition) {
+		logger.Debugf("servicemonitor %s is unchanged", name)
+		return existing, nil
+	}
+	logger.Debugf("updating servicemonitor %s", name)
+	serviceMonitorDefinition.SetResourceVersion(existing.GetResourceVersion())
+	sm, err := client.MonitoringV1().ServiceMonitors(namespace).Update(serviceMonitorDefinition)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update servicemonitor. %+v", err)
 	}
 	return sm, nil
 }
@@ -76,20 +113,88 @@ func GetPrometheusRule(ruleFilePath string) (*monitoringv1.PrometheusRule, error
 func CreateOrUpdatePrometheusRule(prometheusRule *monitoringv1.PrometheusRule) (*monitoringv1.PrometheusRule, error) {
 	name := prometheusRule.GetName()
 	namespace := prometheusRule.GetNamespace()
-	logger.Debugf("creating prometheusRule %s", name)
+	if err := setLastAppliedHash(prometheusRule, prometheusRule.Spec); err != nil {
+		return nil, fmt.Errorf("failed to hash prometheusRule %s. %+v", name, err)
+	}
 	client, err := getMonitoringClient()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get monitoring client. %+v", err)
 	}
-	promRule, err := client.MonitoringV1().PrometheusRules(namespace).Create(prometheusRule)
+	existing, err := client.MonitoringV1().PrometheusRules(namespace).Get(name, metav1.GetOptions{})
 	if err != nil {
-		if !errors.IsAlreadyExists(err) {
-			return nil, fmt.Errorf("failed to create prometheusRules. %+v", err)
+		if !errors.IsNotFound(err) {
+			return nil, fmt.Errorf("failed to get prometheusRule. %+v", err)
 		}
-		promRule, err = client.MonitoringV1().PrometheusRules(namespace).Update(prometheusRule)
+		logger.Debugf("creating prometheusRule %s", name)
+		promRule, err := client.MonitoringV1().PrometheusRules(namespace).Create(prometheusRule)
 		if err != nil {
-			return nil, fmt.Errorf("failed to update prometheusRule. %+v", err)
+			return nil, fmt.Errorf("failed to create prometheusRules. %+v", err)
 		}
+		return promRule, nil
+	}
+	if lastAppliedHashMatches(existing, prometheusRule) {
+		logger.Debugf("prometheusRule %s is unchanged", name)
+		return existing, nil
+	}
+	logger.Debugf("updating prometheusRule %s", name)
+	prometheusRule.SetResourceVersion(existing.GetResourceVersion())
+	promRule, err := client.MonitoringV1().PrometheusRules(namespace).Update(prometheusRule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update prometheusRule. %+v", err)
 	}
 	return promRule, nil
 }
+
+// setLastAppliedHash annotates the monitoring resource with the hash of its rendered content, i.e. the spec, the
+// labels and the owners
+func setLastAppliedHash(obj metav1.Object, spec interface{}) error {
+	content, err := json.Marshal(struct {
+		Spec   interface{}             `json:"spec"`
+		Labels map[string]string       `json:"labels"`
+		Owners []metav1.OwnerReference `json:"owners"`
+	}{spec, obj.GetLabels(), obj.GetOwnerReferences()})
+	if err != nil {
+		return err
+	}
+	annotations := obj.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	annotations[lastAppliedHashAnnotation] = Hash(string(content))
+	obj.SetAnnotations(annotations)
+	return nil
+}
+
+// lastAppliedHashMatches returns whether the deployed monitoring resource was applied with the same content, in
+// which case it does not need to be updated
+func lastAppliedHashMatches(deployed, desired metav1.Object) bool {
+	hash, ok := deployed.GetAnnotations()[lastAppliedHashAnnotation]
+	return ok && hash == desired.GetAnnotations()[lastAppliedHashAnnotation]
+}
+
+// ListPrometheusRules returns the prometheusRules in the namespace matching the label selector
+func ListPrometheusRules(namespace, labelSelector string) ([]*monitoringv1.PrometheusRule, error) {
+	client, err := getMonitoringClient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get monitoring client. %+v", err)
+	}
+	rules, err := client.MonitoringV1().PrometheusRules(namespace).List(metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list prometheusRules. %+v", err)
+	}
+	return rules.Items, nil
+}
+
+// DeletePrometheusRule deletes the prometheusRule, ignoring it if it does not exist
+func DeletePrometheusRule(namespace, name string) error {
+	logger.Debugf("deleting prometheusRule %s", name)
+	client, err := getMonitoringClient()
+	if err != nil {
+		return fmt.Errorf("failed to get monitoring client. %+v", err)
+	}
+	err = client.MonitoringV1().PrometheusRules(namespace).Delete(name, &metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete prometheusRule %s. %+v", name, err)
+	}
+	return nil
+}
@@ -5,7 +5,10 @@ import (
 	"path"
 	"testing"
 
+	monitoringv1 "github.com/coreos/prometheus-operator/pkg/apis/monitoring/v1"
 	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 func TestGetServiceMonitor(t *testing.T) {
@@ -30,3 +33,59 @@ func TestGetPrometheusRule(t *testing.T) {
 	assert.NotNil(t, rules.GetLabels())
 	assert.NotNil(t, rules.Spec.Groups)
 }
+
+func TestPrometheusCRDsAvailable(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+
+	// the monitoring group is not registered
+	available, _ := PrometheusCRDsAvailable(clientset)
+	assert.False(t, available)
+
+	// only the service monitor is registered
+	clientset.Fake.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "monitoring.coreos.com/v1",
+			APIResources: []metav1.APIResource{{Name: "servicemonitors", Kind: "ServiceMonitor"}},
+		},
+	}
+	available, err := PrometheusCRDsAvailable(clientset)
+	assert.Nil(t, err)
+	assert.False(t, available)
+
+	clientset.Fake.Resources[0].APIResources = append(clientset.Fake.Resources[0].APIResources,
+		metav1.APIResource{Name: "prometheusrules", Kind: "PrometheusRule"})
+	available, err = PrometheusCRDsAvailable(clientset)
+	assert.Nil(t, err)
+	assert.True(t, available)
+}
+
+func TestLastAppliedHash(t *testing.T) {
+	newRule := func() *monitoringv1.PrometheusRule {
+		return &monitoringv1.PrometheusRule{
+			ObjectMeta: metav1.ObjectMeta{Name: "rules", Labels: map[string]string{"prometheus": "rook-prometheus"}},
+			Spec: monitoringv1.PrometheusRuleSpec{
+				Groups: []monitoringv1.RuleGroup{{Name: "ceph.rules"}},
+			},
+		}
+	}
+
+	// the rendered content is not deployed yet
+	deployed := newRule()
+	desired := newRule()
+	assert.Nil(t, setLastAppliedHash(desired, desired.Spec))
+	assert.NotEmpty(t, desired.GetAnnotations()[lastAppliedHashAnnotation])
+	assert.False(t, lastAppliedHashMatches(deployed, desired))
+
+	// the same content is not applied again
+	assert.Nil(t, setLastAppliedHash(deployed, deployed.Spec))
+	assert.True(t, lastAppliedHashMatches(deployed, desired))
+
+	// a change of the spec or the labels is applied
+	desired.Spec.Groups[0].Name = "ceph-v14.rules"
+	assert.Nil(t, setLastAppliedHash(desired, desired.Spec))
+	assert.False(t, lastAppliedHashMatches(deployed, desired))
+	desired = newRule()
+	desired.Labels["release"] = "prometheus"
+	assert.Nil(t, setLastAppliedHash(desired, desired.Spec))
+	assert.False(t, lastAppliedHashMatches(deployed, desired))
+}
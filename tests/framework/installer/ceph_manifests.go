@@ -626,6 +626,14 @@ rules:
   - get
   - list
   - watch
+- apiGroups:
+  - scheduling.k8s.io
+  resources:
+  # Priority class access is needed to validate the priority class names of the cluster CR
+  - priorityclasses
+  verbs:
+  - get
+  - list
 - apiGroups:
   - batch
   resources: